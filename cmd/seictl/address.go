@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/your-org/seictl/internal/address"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// mnemonicPassphraseEnv holds the optional BIP-39 passphrase, kept out of
+// flags so it does not end up in shell history
+const mnemonicPassphraseEnv = "SEICTL_BIP39_PASSPHRASE"
+
+func newAddressCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "address",
+		Short: "Convert Sei addresses and derive them from keys, offline",
+		// Address utilities need no config file
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	cmd.AddCommand(newAddressConvertCmd())
+	cmd.AddCommand(newAddressDeriveCmd())
+
+	return cmd
+}
+
+func newAddressConvertCmd() *cobra.Command {
+	var to string
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "convert <address>...",
+		Short: "Convert between sei, seivaloper, seivalcons and hex addresses",
+		Long: `Convert between sei, seivaloper, seivalcons and hex addresses.
+
+Accepts sei1..., seivaloper1..., seivalcons1..., 0x... and bare hex addresses
+and prints every encoding of the same bytes. --to prints only one format
+(sei, seivaloper, seivalcons, evm or hex), one line per address.
+
+This is a byte-for-byte conversion. A key's own sei and 0x addresses hash the
+public key differently; use "seictl address derive" to get them.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var conversions []*address.Conversion
+			for _, arg := range args {
+				c, err := address.Convert(arg)
+				if err != nil {
+					return err
+				}
+				conversions = append(conversions, c)
+			}
+
+			if to != "" {
+				for _, c := range conversions {
+					out, err := c.Format(to)
+					if err != nil {
+						return fmt.Errorf("%s: %w", c.Input, err)
+					}
+					fmt.Println(out)
+				}
+				return nil
+			}
+			if jsonOutput {
+				return printJSON(conversions)
+			}
+			for i, c := range conversions {
+				if i > 0 {
+					fmt.Println()
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintf(w, "Input:\t%s (%s)\n", c.Input, c.Kind)
+				fmt.Fprintf(w, "Hex:\t%s\n", c.Hex)
+				for _, row := range [][2]string{
+					{"EVM:", c.EVM}, {"Account:", c.Account}, {"Operator:", c.Valoper}, {"Consensus:", c.Valcons},
+				} {
+					if row[1] != "" {
+						fmt.Fprintf(w, "%s\t%s\n", row[0], row[1])
+					}
+				}
+				if err := w.Flush(); err != nil {
+					return err
+				}
+				printNotes(c.Notes)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "print only this format: sei, seivaloper, seivalcons, evm or hex")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func newAddressDeriveCmd() *cobra.Command {
+	var pubKey string
+	var fromMnemonic, jsonOutput bool
+	var coinTypes []uint
+	var opts address.MnemonicOptions
+
+	cmd := &cobra.Command{
+		Use:   "derive",
+		Short: "Derive addresses from a public key or a mnemonic",
+		Long: `Derive addresses from a public key or a mnemonic.
+
+--pubkey takes a secp256k1 account key or an ed25519 consensus key as hex,
+base64 or the JSON printed by "seid keys show --pubkey" and "seid tendermint
+show-validator". A secp256k1 key gives its sei address (Cosmos scheme) and its
+0x address (Ethereum scheme), which Sei links into one account.
+
+--mnemonic reads a BIP-39 mnemonic from stdin and derives the key at
+m/44'/<coin type>'/<account>'/0/<index> for coin type 118 (Cosmos wallets)
+and 60 (Ethereum wallets), showing both addresses of each key. The optional
+BIP-39 passphrase is read from ` + mnemonicPassphraseEnv + `. Nothing is stored or sent
+over the network.`,
+		Example: `  seictl address derive --pubkey '{"@type":"/cosmos.crypto.secp256k1.PubKey","key":"A..."}'
+  seictl address derive --mnemonic < mnemonic.txt
+  seictl address derive --mnemonic --coin-type 60 --index 1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (pubKey == "") == !fromMnemonic {
+				return fmt.Errorf("pass exactly one of --pubkey or --mnemonic")
+			}
+
+			if pubKey != "" {
+				keys, err := address.FromPubKey(pubKey)
+				if err != nil {
+					return err
+				}
+				if jsonOutput {
+					return printJSON(keys)
+				}
+				if err := printKeyAddresses([]*address.KeyAddresses{keys}); err != nil {
+					return err
+				}
+				printNotes(keys.Notes)
+				return nil
+			}
+
+			for _, ct := range coinTypes {
+				// Coin types are hardened in the path, leaving 31 bits
+				if ct > math.MaxInt32 {
+					return fmt.Errorf("coin type %d is out of range, the maximum is %d", ct, math.MaxInt32)
+				}
+				opts.CoinTypes = append(opts.CoinTypes, uint32(ct))
+			}
+			mnemonic, err := readMnemonic()
+			if err != nil {
+				return err
+			}
+			opts.Passphrase = os.Getenv(mnemonicPassphraseEnv)
+			keys, err := address.FromMnemonic(mnemonic, opts)
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(keys)
+			}
+			if err := printKeyAddresses(keys); err != nil {
+				return err
+			}
+			printNotes(address.MnemonicNotes)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pubKey, "pubkey", "", "public key as hex, base64 or JSON")
+	cmd.Flags().BoolVar(&fromMnemonic, "mnemonic", false, "read a mnemonic from stdin")
+	cmd.Flags().UintSliceVar(&coinTypes, "coin-type", nil, "coin types to derive, 118 and 60 when unset")
+	cmd.Flags().Uint32Var(&opts.Account, "account", 0, "BIP-44 account")
+	cmd.Flags().Uint32Var(&opts.Index, "index", 0, "BIP-44 address index")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+// readMnemonic reads the mnemonic from stdin, prompting without echo on a
+// terminal
+func readMnemonic() (string, error) {
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Enter mnemonic: ")
+		line, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read mnemonic: %w", err)
+		}
+		return string(line), nil
+	}
+	var b strings.Builder
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		b.WriteString(scanner.Text())
+		b.WriteByte(' ')
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read mnemonic: %w", err)
+	}
+	return b.String(), nil
+}
+
+func printKeyAddresses(keys []*address.KeyAddresses) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if keys[0].Type == address.KeyEd25519 {
+		fmt.Fprintln(w, "TYPE\tCONSENSUS\tHEX\tPUBKEY")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.Type, k.Valcons, k.Hex, k.PubKey)
+		}
+		return w.Flush()
+	}
+	fmt.Fprintln(w, "PATH\tACCOUNT\tEVM\tPUBKEY")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", orDash(k.Path), k.Account, k.EVM, k.PubKey)
+	}
+	return w.Flush()
+}
+
+func printNotes(notes []string) {
+	if len(notes) == 0 {
+		return
+	}
+	fmt.Println()
+	for _, n := range notes {
+		fmt.Printf("  - %s\n", n)
+	}
+}
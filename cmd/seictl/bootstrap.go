@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-org/seictl/internal/state"
+	"github.com/your-org/seictl/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+func newBootstrapCmd() *cobra.Command {
+	var opts state.BootstrapOptions
+	var env, strategy string
+	var planOnly, jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Bring a fresh node to the chain tip the fastest valid way",
+		Long: `Bring a fresh node to the chain tip the fastest valid way.
+
+Inspects local data, local snapshots, the snapshot server and feed when given,
+RPC state sync availability and free disk space, then ranks restoring a
+snapshot, state sync and block sync from genesis by estimated time. Archive
+nodes only consider snapshots taken without pruning, and block sync.
+
+With --plan the recommendation is printed and nothing is changed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			opts.Env = types.Environment(env)
+			plan, err := mgr.PlanBootstrap(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				if err := printJSON(plan); err != nil {
+					return err
+				}
+			} else {
+				printBootstrapPlan(plan)
+			}
+			if planOnly {
+				return nil
+			}
+			if plan.HasData && !opts.Force {
+				return fmt.Errorf("node already has chain data, pass --force to replace it")
+			}
+
+			return mgr.ExecuteBootstrap(ctx, plan, opts, state.BootstrapStrategy(strategy))
+		},
+	}
+
+	cmd.Flags().BoolVar(&planOnly, "plan", false, "only print the plan")
+	cmd.Flags().StringVar(&env, "env", "", "environment to bootstrap (defaults to the one matching the local chain ID)")
+	cmd.Flags().StringVar(&opts.FeedURL, "feed", "", "snapshot feed URL to consider")
+	cmd.Flags().StringVar(&opts.Server, "from", "", "seictl snapshot server to consider")
+	cmd.Flags().StringVar(&opts.Token, "token", "", "bearer token for the snapshot server")
+	cmd.Flags().BoolVar(&opts.Archive, "archive", false, "the node must keep full block history")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "bootstrap even if the node already has data")
+	cmd.Flags().StringVar(&strategy, "strategy", "", "override the recommended strategy (local-snapshot, server-snapshot, feed-snapshot, state-sync, block-sync)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output the plan as JSON")
+
+	return cmd
+}
+
+func printBootstrapPlan(plan *state.BootstrapPlan) {
+	free := "unknown"
+	if plan.FreeBytes >= 0 {
+		free = formatBytes(plan.FreeBytes)
+	}
+	fmt.Printf("Chain %s, disk free %s\n\n", plan.ChainID, free)
+
+	if len(plan.Candidates) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STRATEGY\tVIABLE\tHEIGHT\tSIZE\tESTIMATE\tNOTES")
+		for _, c := range plan.Candidates {
+			height, size, estimate := "-", "-", "-"
+			if c.Height > 0 {
+				height = fmt.Sprint(c.Height)
+			}
+			if c.Bytes > 0 {
+				size = formatBytes(c.Bytes)
+			}
+			if c.Viable && c.Estimate > 0 && c.Estimate.Duration() < time.Duration(1<<63-1) {
+				estimate = c.Estimate.Duration().Round(time.Minute).String()
+			}
+			fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\t%s\n", c.Strategy, c.Viable, height, size, estimate, c.Reason)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	for _, line := range plan.Explanation {
+		fmt.Println(line)
+	}
+}
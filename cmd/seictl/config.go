@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/your-org/seictl/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and change the node's configuration",
+	}
+
+	cmd.AddCommand(newConfigPruningCmd())
+
+	return cmd
+}
+
+func newConfigPruningCmd() *cobra.Command {
+	var keepRecent, keepEvery, interval int64
+	var fromConfig, force, jsonOutput bool
+	var seidVersion string
+	var scEnable, ssEnable bool
+	var scAsyncBuffer, scKeepRecent, ssPruneInterval, ssAsyncBuffer int
+	var scSnapshotInterval, ssKeepRecent int64
+	var ssBackend string
+
+	cmd := &cobra.Command{
+		Use:   "pruning",
+		Short: "Show or change pruning for the legacy and SeiDB storage layouts",
+		Long: `Show or change pruning for the legacy and SeiDB storage layouts.
+
+Without flags the current settings of both layouts are shown, along with the
+layout in use: SeiDB when [state-commit] sc-enable is true in app.toml,
+otherwise the legacy IAVL store.
+
+--keep-recent applies to the layout in use: pruning-keep-recent on legacy
+nodes, ss-keep-recent on SeiDB nodes. --keep-every and --interval only exist
+on legacy nodes. The --sc-* and --ss-* flags change the SeiDB sections
+directly, and --from-config applies node_configs.seidb from the seictl
+config. SeiDB settings are checked against the installed seid version.
+Enabling state commit on a node whose application.db holds IAVL state is
+refused without --force, as SeiDB starts from empty state; state sync or
+restore a SeiDB snapshot afterwards. A --keep-recent of 0 is refused on SeiDB
+nodes, set --ss-keep-recent 0 to keep all history.
+Restart seid to apply changes.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			settings, err := mgr.ReadPruning()
+			if err != nil {
+				return err
+			}
+
+			flags := cmd.Flags()
+			seidbChanged := fromConfig
+			for _, name := range []string{
+				"sc-enable", "sc-async-commit-buffer", "sc-keep-recent", "sc-snapshot-interval",
+				"ss-enable", "ss-backend", "ss-keep-recent", "ss-prune-interval", "ss-async-write-buffer",
+			} {
+				seidbChanged = seidbChanged || flags.Changed(name)
+			}
+			legacyChanged := flags.Changed("keep-recent") || flags.Changed("keep-every") || flags.Changed("interval")
+
+			if seidbChanged {
+				cfg := settings.SeiDB
+				if fromConfig {
+					if config.NodeConfigs.SeiDB == nil {
+						return fmt.Errorf("node_configs.seidb is not set in the seictl config")
+					}
+					cfg = *config.NodeConfigs.SeiDB
+				}
+				sc, ss := &cfg.StateCommit, &cfg.StateStore
+				if flags.Changed("sc-enable") {
+					sc.Enable = scEnable
+				}
+				if flags.Changed("sc-async-commit-buffer") {
+					sc.AsyncCommitBuffer = scAsyncBuffer
+				}
+				if flags.Changed("sc-keep-recent") {
+					sc.KeepRecent = scKeepRecent
+				}
+				if flags.Changed("sc-snapshot-interval") {
+					sc.SnapshotInterval = scSnapshotInterval
+				}
+				if flags.Changed("ss-enable") {
+					ss.Enable = ssEnable
+				}
+				if flags.Changed("ss-backend") {
+					ss.Backend = ssBackend
+				}
+				if flags.Changed("ss-keep-recent") {
+					ss.KeepRecent = ssKeepRecent
+				}
+				if flags.Changed("ss-prune-interval") {
+					ss.PruneIntervalSeconds = ssPruneInterval
+				}
+				if flags.Changed("ss-async-write-buffer") {
+					ss.AsyncWriteBuffer = ssAsyncBuffer
+				}
+
+				warnings, err := mgr.ApplySeiDB(cfg, seidVersion, force)
+				if err != nil {
+					return err
+				}
+				for _, w := range warnings {
+					fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+				}
+				if settings, err = mgr.ReadPruning(); err != nil {
+					return err
+				}
+			}
+
+			if legacyChanged {
+				if settings.Layout == state.LayoutSeiDB && (flags.Changed("keep-every") || flags.Changed("interval")) {
+					return fmt.Errorf("this node uses SeiDB, which has no keep-every or block interval; use --ss-keep-recent and --ss-prune-interval")
+				}
+				if !flags.Changed("keep-recent") {
+					keepRecent = settings.Legacy.KeepRecent
+				}
+				if !flags.Changed("keep-every") {
+					keepEvery = settings.Legacy.KeepEvery
+				}
+				if !flags.Changed("interval") {
+					interval = settings.Legacy.Interval
+				}
+				if err := mgr.UpdatePruning(ctx, keepRecent, keepEvery, interval); err != nil {
+					return err
+				}
+				if settings, err = mgr.ReadPruning(); err != nil {
+					return err
+				}
+			}
+
+			if jsonOutput {
+				return printJSON(settings)
+			}
+			printPruningSettings(settings)
+			if seidbChanged || legacyChanged {
+				fmt.Println("\nRestart seid to apply the new settings")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&keepRecent, "keep-recent", 0, "versions to keep, for the layout in use")
+	cmd.Flags().Int64Var(&keepEvery, "keep-every", 0, "legacy pruning-keep-every")
+	cmd.Flags().Int64Var(&interval, "interval", 0, "legacy pruning-interval in blocks")
+	cmd.Flags().BoolVar(&scEnable, "sc-enable", false, "enable SeiDB state commit (memiavl)")
+	cmd.Flags().IntVar(&scAsyncBuffer, "sc-async-commit-buffer", 0, "blocks committed asynchronously, 0 for synchronous commits")
+	cmd.Flags().IntVar(&scKeepRecent, "sc-keep-recent", 0, "old memiavl snapshots to keep")
+	cmd.Flags().Int64Var(&scSnapshotInterval, "sc-snapshot-interval", 0, "blocks between memiavl snapshots")
+	cmd.Flags().BoolVar(&ssEnable, "ss-enable", false, "enable the SeiDB state store for historical queries")
+	cmd.Flags().StringVar(&ssBackend, "ss-backend", "", "state store backend: pebbledb, rocksdb or sqlite")
+	cmd.Flags().Int64Var(&ssKeepRecent, "ss-keep-recent", 0, "versions kept by the state store, 0 keeps everything")
+	cmd.Flags().IntVar(&ssPruneInterval, "ss-prune-interval", 0, "seconds between state store pruning runs")
+	cmd.Flags().IntVar(&ssAsyncBuffer, "ss-async-write-buffer", 0, "state store asynchronous write buffer")
+	cmd.Flags().BoolVar(&fromConfig, "from-config", false, "apply node_configs.seidb from the seictl config")
+	cmd.Flags().BoolVar(&force, "force", false, "enable state commit even though application.db holds IAVL state")
+	cmd.Flags().StringVar(&seidVersion, "seid-version", "", "seid version to validate against (default: ask the installed seid)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func printPruningSettings(s *state.PruningSettings) {
+	fmt.Printf("Layout: %s\n", s.Layout)
+	fmt.Printf("Pruning: %s\n\n", s.Describe())
+
+	l := s.Legacy
+	fmt.Println("Legacy (IAVL):")
+	fmt.Printf("  pruning = %s, keep-recent %d, keep-every %d, interval %d\n", orDash(l.Mode), l.KeepRecent, l.KeepEvery, l.Interval)
+	if s.Layout == state.LayoutSeiDB {
+		fmt.Println("  (ignored while SeiDB is enabled)")
+	}
+
+	sc, ss := s.SeiDB.StateCommit, s.SeiDB.StateStore
+	fmt.Println("\nSeiDB [state-commit]:")
+	fmt.Printf("  sc-enable %t, async-commit-buffer %d, keep-recent %d, snapshot-interval %d\n",
+		sc.Enable, sc.AsyncCommitBuffer, sc.KeepRecent, sc.SnapshotInterval)
+	fmt.Println("SeiDB [state-store]:")
+	fmt.Printf("  ss-enable %t, backend %s, keep-recent %d, prune-interval %ds, async-write-buffer %d\n",
+		ss.Enable, ss.Backend, ss.KeepRecent, ss.PruneIntervalSeconds, ss.AsyncWriteBuffer)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/your-org/seictl/internal/state"
+	"github.com/your-org/seictl/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+func newDataCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "data",
+		Short: "Manage the node's data directories",
+	}
+
+	cmd.AddCommand(newDataMoveCmd())
+
+	return cmd
+}
+
+func newDataMoveCmd() *cobra.Command {
+	var opts state.MoveOptions
+	var env string
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "move <data|wasm> <destination>",
+		Short: "Move the data or wasm directory to another volume",
+		Long: `Move the data or wasm directory to another volume.
+
+The node is stopped through snapshots.schedule.stop_command (or by signalling
+seid). On the same filesystem the directory is renamed; otherwise every file is
+copied and its SHA-256 checked against the source. An interrupted copy resumes
+when the same command is run again.
+
+seid is then pointed at the new location: db_dir in config.toml is updated
+when it is customised, otherwise the directory in the node home is replaced by
+a symlink. The node is restarted and must reach the height it stopped at, or
+the move is rolled back.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			opts.Name, opts.Dest = args[0], args[1]
+			opts.Env = types.Environment(env)
+			if !jsonOutput {
+				opts.Progress = moveProgressPrinter()
+			}
+
+			result, err := mgr.MoveData(ctx, opts)
+			if !jsonOutput {
+				fmt.Fprintln(os.Stderr)
+			}
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(result)
+			}
+			fmt.Printf("Moved %s from %s to %s (%s, %s)\n", result.Name, result.Source, result.Dest, result.Method, result.Link)
+			if result.Method == "copy" {
+				fmt.Printf("Copied and verified %d files, %s", result.Files, formatBytes(result.Bytes))
+				if result.Resumed > 0 {
+					fmt.Printf(" (%s resumed)", formatBytes(result.Resumed))
+				}
+				fmt.Println()
+			}
+			if result.HeightAfter > 0 {
+				fmt.Printf("Node resumed at height %d (stopped at %d)\n", result.HeightAfter, result.HeightBefore)
+			}
+			if result.OldCopy != "" {
+				fmt.Printf("Old copy kept at %s\n", result.OldCopy)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "environment whose local RPC port is used (defaults to the one matching the local chain ID)")
+	cmd.Flags().StringVar(&opts.RPC, "rpc", "", "local node RPC address (default: 127.0.0.1 on the environment's RPC port)")
+	cmd.Flags().BoolVar(&opts.NoRestart, "no-restart", false, "leave the node stopped after the move")
+	cmd.Flags().BoolVar(&opts.KeepSource, "keep-source", false, "keep the old copy after a cross-volume move")
+	cmd.Flags().DurationVar(&opts.ResumeTimeout, "timeout", 10*time.Minute, "how long to wait for the node to resume")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+// moveProgressPrinter prints copy progress to stderr at most twice a second
+func moveProgressPrinter() func(state.MoveProgress) {
+	var last time.Time
+	return func(p state.MoveProgress) {
+		if time.Since(last) < 500*time.Millisecond && p.BytesDone < p.BytesTotal {
+			return
+		}
+		last = time.Now()
+		pct := 100.0
+		if p.BytesTotal > 0 {
+			pct = float64(p.BytesDone) / float64(p.BytesTotal) * 100
+		}
+		fmt.Fprintf(os.Stderr, "\r%5.1f%%  %s / %s  %d files", pct, formatBytes(p.BytesDone), formatBytes(p.BytesTotal), p.Files)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-org/seictl/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+func newDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Inspect the node's databases offline",
+	}
+
+	cmd.AddCommand(newDBInspectCmd())
+
+	return cmd
+}
+
+func newDBInspectCmd() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Show the heights and app hashes stored in each database",
+		Long: `Show the heights and app hashes stored in each database.
+
+Opens blockstore.db, state.db and application.db read-only with the backend set
+by db_backend in config.toml (goleveldb or pebbledb) and reports their base and
+latest heights and the last app hash. Inconsistencies that would stop the node
+from starting, such as the application being ahead of the blockstore, are
+listed and make the command exit non-zero. seid must be stopped first.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			result, err := mgr.InspectDatabases()
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				if err := printJSON(result); err != nil {
+					return err
+				}
+			} else {
+				printDBInspection(result)
+			}
+
+			if len(result.Issues) > 0 {
+				return fmt.Errorf("found %d issue(s)", len(result.Issues))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func printDBInspection(r *state.DBInspection) {
+	fmt.Printf("Data directory: %s (%s)\n\n", r.DataDir, r.Backend)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATABASE\tBASE\tHEIGHT\tTIME\tAPP HASH")
+
+	store := r.BlockStore
+	if store.Error != "" {
+		fmt.Fprintf(w, "blockstore.db\t-\t-\t-\t%s\n", store.Error)
+	} else {
+		fmt.Fprintf(w, "blockstore.db\t%d\t%d\t%s\t%s\n", store.Base, store.Height, formatTime(store.LatestTime), orDash(store.HeaderAppHash))
+	}
+
+	st := r.State
+	if st.Error != "" {
+		fmt.Fprintf(w, "state.db\t-\t-\t-\t%s\n", st.Error)
+	} else {
+		fmt.Fprintf(w, "state.db\t-\t%d\t%s\t%s\n", st.LastBlockHeight, formatTime(st.LastBlockTime), orDash(st.AppHash))
+	}
+
+	app := r.Application
+	if app == nil {
+		fmt.Fprintf(w, "SeiDB\t-\t-\t-\tnot inspected\n")
+	} else if app.Error != "" {
+		fmt.Fprintf(w, "application.db\t-\t-\t-\t%s\n", app.Error)
+	} else {
+		fmt.Fprintf(w, "application.db\t-\t%d\t-\t%s\n", app.LatestVersion, orDash(app.AppHash))
+	}
+	w.Flush()
+
+	if st.ChainID != "" {
+		fmt.Printf("\nChain ID: %s\n", st.ChainID)
+	}
+	if store.HeaderAppHash != "" {
+		fmt.Println("The blockstore app hash is from the latest header, i.e. the state before that block.")
+	}
+
+	if len(r.Issues) == 0 {
+		fmt.Println("\nNo inconsistencies found.")
+		return
+	}
+	fmt.Println("\nIssues:")
+	for _, issue := range r.Issues {
+		fmt.Printf("  - %s\n", issue)
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
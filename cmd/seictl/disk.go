@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-org/seictl/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+func newDiskCmd() *cobra.Command {
+	var noRecord, applyPruning, jsonOutput bool
+	var watch time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "disk",
+		Short: "Show disk usage by component and forecast when the volume fills",
+		Long: `Show disk usage by component and forecast when the volume fills.
+
+Breaks down the node home into each database, wasm, state sync snapshots,
+cosmovisor, logs and seictl backups. Every run records a sample in the disk
+history kept in the backup directory; run with --watch to record periodically.
+The growth rate over the last two weeks of samples gives the days until the
+volume is full, and pruning settings are recommended from it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			if watch > 0 {
+				return mgr.WatchDisk(ctx, watch)
+			}
+
+			report, err := mgr.DiskUsage(!noRecord)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				if err := printJSON(report); err != nil {
+					return err
+				}
+			} else {
+				printDiskReport(report)
+			}
+
+			if applyPruning {
+				if !report.Pruning.Change {
+					fmt.Fprintln(os.Stderr, "Pruning settings unchanged")
+					return nil
+				}
+				p := report.Pruning
+				if err := mgr.UpdatePruning(ctx, p.KeepRecent, p.KeepEvery, p.Interval); err != nil {
+					return err
+				}
+				fmt.Fprintln(os.Stderr, "Pruning updated; restart seid to apply it")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&noRecord, "no-record", false, "do not add this run to the disk history")
+	cmd.Flags().DurationVar(&watch, "watch", 0, "record a sample at this interval until interrupted, e.g. 1h")
+	cmd.Flags().BoolVar(&applyPruning, "apply-pruning", false, "write the recommended pruning settings to app.toml")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func printDiskReport(r *state.DiskReport) {
+	fmt.Printf("Volume of %s: %s used of %s, %s free\n\n", r.Volume, formatBytes(r.Total-r.Free), formatBytes(r.Total), formatBytes(r.Free))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tSIZE\tSHARE\tPATH")
+	for _, c := range r.Components {
+		share := "-"
+		if r.Total > 0 && c.SameVolume {
+			share = fmt.Sprintf("%.1f%%", float64(c.Bytes)/float64(r.Total)*100)
+		}
+		path := c.Path
+		if !c.SameVolume {
+			path += " (other volume)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, formatBytes(c.Bytes), share, path)
+	}
+	w.Flush()
+
+	fmt.Println()
+	f := r.Forecast
+	if f.Unavailable != "" {
+		fmt.Printf("Forecast: %s (%d samples)\n", f.Unavailable, f.Samples)
+	} else {
+		fmt.Printf("Forecast: growing %s/day, full in about %.0f days (%d samples)\n",
+			formatBytes(f.GrowthDay), f.DaysToFull, f.Samples)
+	}
+
+	p := r.Pruning
+	fmt.Printf("Pruning: %s\n", p.Current)
+	if p.Change {
+		fmt.Printf("Recommended: keep-recent %d, keep-every %d, interval %d (apply with --apply-pruning)\n",
+			p.KeepRecent, p.KeepEvery, p.Interval)
+	}
+	fmt.Printf("  %s\n", p.Reason)
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/your-org/seictl/internal/chain"
+	"github.com/your-org/seictl/internal/state"
 	"github.com/your-org/seictl/pkg/types"
 
 	"github.com/rs/zerolog"
@@ -38,6 +40,16 @@ func main() {
 		newInitCmd(),
 		newSnapshotCmd(),
 		newStateSyncCmd(),
+		newRPCCmd(),
+		newBootstrapCmd(),
+		newDBCmd(),
+		newDiskCmd(),
+		newDataCmd(),
+		newConfigCmd(),
+		newResetCmd(),
+		newTopologyCmd(),
+		newAddressCmd(),
+		newOptimizeCmd(),
 		newStartCmd(),
 		newVersionCmd(),
 	)
@@ -49,8 +61,8 @@ func main() {
 }
 
 func initConfig() error {
-	// Setup logger
-	logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+	// Setup logger on stderr so JSON output on stdout stays machine readable
+	logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
 
 	// Read config file
 	data, err := os.ReadFile(cfgFile)
@@ -69,21 +81,31 @@ func initConfig() error {
 func newInitCmd() *cobra.Command {
 	var env string
 	var skipBinary bool
+	var opts chain.InitOptions
 
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize a new Sei node",
+		Long: `Initialize a new Sei node.
+
+--role layers a built-in preset over node_configs, then the role's entry in
+node_configs.roles, then any --set overrides:
+
+` + describeRoles() + `
+A sentry needs the validator's node ID in --private-peer-ids; a validator
+needs its sentries in --persistent-peers.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := setupContext()
+			// Signals stop seid gracefully instead of exiting, so the final
+			// tmpfs sync runs to completion
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
 
 			mgr, err := chain.NewManager(config, logger)
 			if err != nil {
 				return err
 			}
 
-			opts := chain.InitOptions{
-				SkipBinary: skipBinary,
-			}
+			opts.SkipBinary = skipBinary
 
 			return mgr.InitChain(ctx, types.Environment(env), opts)
 		},
@@ -91,6 +113,11 @@ func newInitCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&env, "env", "", "environment (local, testnet, mainnet)")
 	cmd.Flags().BoolVar(&skipBinary, "skip-binary", false, "skip binary download/compilation")
+	cmd.Flags().StringVar(&opts.Role, "role", "", "node role preset ("+strings.Join(chain.RoleNames(), ", ")+")")
+	cmd.Flags().StringVar(&opts.Moniker, "moniker", "", "node moniker")
+	cmd.Flags().StringSliceVar(&opts.PrivatePeerIDs, "private-peer-ids", nil, "node IDs a sentry keeps private, usually its validator")
+	cmd.Flags().StringVar(&opts.PersistentPeers, "persistent-peers", "", "persistent peers as id@host:port,...")
+	cmd.Flags().StringArrayVar(&opts.Overrides, "set", nil, "override a key, e.g. app.toml:api.enable=false (repeatable)")
 
 	if err := cmd.MarkFlagRequired("env"); err != nil {
 		logger.Fatal().Err(err).Msg("Failed to mark env flag as required")
@@ -99,8 +126,21 @@ func newInitCmd() *cobra.Command {
 	return cmd
 }
 
+func describeRoles() string {
+	var b strings.Builder
+	roles := chain.Roles()
+	for _, name := range chain.RoleNames() {
+		fmt.Fprintf(&b, "  %-10s %s\n", name, roles[name])
+	}
+	return b.String()
+}
+
 func newSnapshotCmd() *cobra.Command {
 	var height int64
+	var daemonOpts state.DaemonOptions
+	var daemon bool
+	var incremental bool
+	var env string
 
 	cmd := &cobra.Command{
 		Use:   "snapshot",
@@ -108,6 +148,24 @@ func newSnapshotCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := setupContext()
 
+			if daemon || daemonOpts.Interval > 0 || daemonOpts.Cron != "" {
+				stateMgr, err := state.NewManager(config, logger)
+				if err != nil {
+					return err
+				}
+				daemonOpts.Env = types.Environment(env)
+				daemonOpts.Incremental = incremental
+				return stateMgr.RunSnapshotDaemon(ctx, daemonOpts)
+			}
+
+			if incremental || config.Snapshots.Incremental {
+				stateMgr, err := state.NewManager(config, logger)
+				if err != nil {
+					return err
+				}
+				return stateMgr.CreateIncrementalSnapshot(ctx, height)
+			}
+
 			mgr, err := chain.NewManager(config, logger)
 			if err != nil {
 				return err
@@ -118,38 +176,92 @@ func newSnapshotCmd() *cobra.Command {
 	}
 
 	cmd.Flags().Int64Var(&height, "height", 0, "block height for snapshot")
+	cmd.Flags().BoolVar(&incremental, "incremental", false, "store the snapshot as deduplicated chunks")
+	cmd.Flags().BoolVar(&daemon, "daemon", false, "run as a daemon using the configured schedule")
+	cmd.Flags().Int64Var(&daemonOpts.Interval, "interval", 0, "run as a daemon taking a snapshot every N blocks")
+	cmd.Flags().StringVar(&daemonOpts.Cron, "cron", "", "run as a daemon taking snapshots on a cron schedule")
+	cmd.Flags().StringVar(&daemonOpts.Mode, "mode", "", "how to quiesce the node: stop or hardlink")
+	cmd.Flags().StringVar(&daemonOpts.RPC, "node", "", "local node RPC endpoint (defaults to the environment's RPC port)")
+	cmd.Flags().StringVar(&env, "env", "", "environment used for ports and the default snapshot interval")
+
+	cmd.AddCommand(
+		newSnapshotListCmd(),
+		newSnapshotInspectCmd(),
+		newSnapshotVerifyCmd(),
+		newSnapshotDeleteCmd(),
+		newSnapshotPruneCmd(),
+		newSnapshotStatusCmd(),
+		newSnapshotPushCmd(),
+		newSnapshotPullCmd(),
+		newSnapshotRestoreCmd(),
+		newSnapshotGCCmd(),
+		newSnapshotServeCmd(),
+		newSnapshotPublishCmd(),
+	)
 
 	return cmd
 }
 
 func newStateSyncCmd() *cobra.Command {
-	var rpcEndpoint string
-	var trustHeight int64
+	var env string
+	var dryRun bool
+	var opts state.StateSyncOptions
 
 	cmd := &cobra.Command{
 		Use:   "state-sync",
 		Short: "Perform state synchronization",
+		Long: `Perform state synchronization.
+
+Without --trust-height the trust height is the latest height minus the
+environment's trust_height_delta, rounded down to its snapshot_interval. The
+trust hash is fetched from every RPC endpoint and state sync only starts when
+a quorum of them agree. Otherwise state sync fails, unless --manual-trust is
+given to enter a trust block by hand.
+
+With --verify-light the trust block is additionally checked like a light
+client: its commit must carry signatures from more than 2/3 of the voting
+power, and it must chain back to the checkpoint stored with
+"seictl state-sync checkpoint set".`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := setupContext()
 
+			opts.Env = types.Environment(env)
+
+			if dryRun {
+				stateMgr, err := state.NewManager(config, logger)
+				if err != nil {
+					return err
+				}
+				trust, err := stateMgr.ResolveTrustBlock(ctx, opts)
+				if trust != nil {
+					if printErr := printJSON(trust); printErr != nil {
+						return printErr
+					}
+				}
+				return err
+			}
+
 			mgr, err := chain.NewManager(config, logger)
 			if err != nil {
 				return err
 			}
 
-			return mgr.StateSync(ctx, trustHeight)
+			return mgr.StateSync(ctx, opts)
 		},
 	}
 
-	cmd.Flags().StringVar(&rpcEndpoint, "rpc", "", "RPC endpoint for state sync")
-	cmd.Flags().Int64Var(&trustHeight, "trust-height", 0, "trusted block height")
-
-	if err := cmd.MarkFlagRequired("rpc"); err != nil {
-		logger.Fatal().Err(err).Msg("Failed to mark rpc flag as required")
-	}
-	if err := cmd.MarkFlagRequired("trust-height"); err != nil {
-		logger.Fatal().Err(err).Msg("Failed to mark trust-height flag as required")
-	}
+	cmd.Flags().StringVar(&env, "env", "", "environment whose RPC endpoints are used (defaults to the one matching the local chain ID)")
+	cmd.Flags().StringSliceVar(&opts.RPC, "rpc", nil, "RPC endpoints to use instead of the environment's")
+	cmd.Flags().Int64Var(&opts.TrustHeight, "trust-height", 0, "trusted block height (computed automatically when omitted)")
+	cmd.Flags().IntVar(&opts.Quorum, "quorum", 0, "number of RPC endpoints that must agree on the trust hash (default: majority)")
+	cmd.Flags().BoolVar(&opts.VerifyLight, "verify-light", false, "verify the trust block against the stored light client checkpoint")
+	cmd.Flags().BoolVar(&opts.ManualTrust, "manual-trust", false, "prompt for the trust height and hash when the RPC endpoints do not reach quorum")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only resolve and print the trust block")
+
+	cmd.AddCommand(
+		newStateSyncCheckpointCmd(),
+		newStateSyncMonitorCmd(),
+	)
 
 	return cmd
 }
@@ -159,14 +271,39 @@ func newStartCmd() *cobra.Command {
 		Use:   "start",
 		Short: "Start the Sei node",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := setupContext()
+			// Signals stop seid gracefully instead of exiting, so the final
+			// tmpfs sync runs to completion
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
 
 			mgr, err := chain.NewManager(config, logger)
 			if err != nil {
 				return err
 			}
 
-			return mgr.StartNode(ctx)
+			// Remount tmpfs directories lost on reboot and keep them synced to
+			// disk while seid runs, with a final sync once it has exited
+			stateMgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+			if _, err := stateMgr.RestoreTmpfs(ctx); err != nil {
+				return err
+			}
+			if status, err := stateMgr.TmpfsStatus(); err != nil || !status.Enabled {
+				return mgr.StartNode(ctx)
+			}
+
+			syncCtx, stopSync := context.WithCancel(context.Background())
+			synced := make(chan error, 1)
+			go func() { synced <- stateMgr.RunTmpfsSync(syncCtx, 0) }()
+
+			err = mgr.StartNode(ctx)
+			stopSync()
+			if syncErr := <-synced; syncErr != nil {
+				logger.Error().Err(syncErr).Msg("Final tmpfs sync failed")
+			}
+			return err
 		},
 	}
 }
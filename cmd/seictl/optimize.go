@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-org/seictl/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+func newOptimizeCmd() *cobra.Command {
+	var opts state.TmpfsOptions
+
+	cmd := &cobra.Command{
+		Use:   "optimize",
+		Short: "Move node directories onto tmpfs for faster I/O",
+		Long: `Move node directories onto tmpfs for faster I/O.
+
+Each directory (data/application.db by default) is moved aside under
+<home>/.seictl-tmpfs and a tmpfs of --tmpfs-size is mounted in its place.
+All mounts together must fit in 80% of the available memory. seid must be
+stopped first.
+
+"seictl start" remounts the directories after a reboot and syncs them back
+to disk every tmpfs.sync_interval_seconds and when seid exits. Use
+"optimize sync" to sync by hand and "optimize disable" to move the data back
+to disk.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			tmpfs, err := mgr.EnableTmpfs(ctx, opts)
+			if err != nil {
+				return err
+			}
+			for _, dir := range tmpfs.Dirs {
+				fmt.Printf("%s is on tmpfs (%s)\n", dir, formatBytes(tmpfs.Size))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Size, "tmpfs-size", "", "size of each tmpfs mount, e.g. 12G (default: tmpfs.size from config)")
+	cmd.Flags().StringSliceVar(&opts.Dirs, "dirs", nil, "directories under the node home to move (default: tmpfs.dirs from config)")
+
+	cmd.AddCommand(
+		newOptimizeStatusCmd(),
+		newOptimizeSyncCmd(),
+		newOptimizeRestoreCmd(),
+		newOptimizeDisableCmd(),
+	)
+
+	return cmd
+}
+
+func newOptimizeStatusCmd() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show tmpfs mounts and their usage",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			status, err := mgr.TmpfsStatus()
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(status)
+			}
+
+			if !status.Enabled {
+				fmt.Println("tmpfs acceleration is not enabled")
+				return nil
+			}
+			fmt.Printf("Enabled: %s\n", status.State.EnabledAt.Format(time.RFC3339))
+			fmt.Printf("Last sync: %s ago\n", formatAge(time.Since(status.State.LastSync)))
+			fmt.Printf("Memory available: %s\n\n", formatBytes(status.MemAvail))
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "DIR\tMOUNTED\tUSED\tSIZE")
+			for _, d := range status.Dirs {
+				if !d.Mounted {
+					fmt.Fprintf(w, "%s\tno\t-\t%s\n", d.Dir, formatBytes(status.State.Size))
+					continue
+				}
+				fmt.Fprintf(w, "%s\tyes\t%s\t%s\n", d.Dir, formatBytes(d.Used), formatBytes(d.Size))
+			}
+			w.Flush()
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func newOptimizeSyncCmd() *cobra.Command {
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Copy tmpfs directories back to disk",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			if interval > 0 {
+				return mgr.RunTmpfsSync(ctx, interval)
+			}
+			return mgr.SyncTmpfs(ctx)
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 0, "keep syncing at this interval until interrupted, e.g. 15m")
+
+	return cmd
+}
+
+func newOptimizeRestoreCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore",
+		Short: "Remount tmpfs directories after a reboot",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			restored, err := mgr.RestoreTmpfs(ctx)
+			if err != nil {
+				return err
+			}
+			if len(restored) == 0 {
+				fmt.Println("Nothing to restore")
+			}
+			for _, dir := range restored {
+				fmt.Printf("Restored %s\n", dir)
+			}
+			return nil
+		},
+	}
+}
+
+func newOptimizeDisableCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable",
+		Short: "Sync tmpfs directories to disk and unmount them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			if err := mgr.DisableTmpfs(ctx); err != nil {
+				return err
+			}
+			fmt.Println("tmpfs acceleration disabled, data is back on disk")
+			return nil
+		},
+	}
+}
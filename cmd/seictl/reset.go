@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/your-org/seictl/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+func newResetCmd() *cobra.Command {
+	var opts state.ResetOptions
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "reset",
+		Short: "Reset node state while keeping keys and validator signing state",
+		Long: `Reset node state while keeping keys and validator signing state.
+
+Levels:
+  data  remove the chain data
+  wasm  remove the chain data and the wasm cache
+  full  also remove the address book
+
+Keys in the config directory are never touched. The highest validator signing
+state found in the node and in seictl's backups is written back after the
+reset, so the validator cannot double sign. Data and wasm are backed up first
+unless --no-backup is given. When a validator key is present the chain ID must
+be typed to confirm, or passed with --confirm.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			if mgr.HasValidatorKey() && opts.Confirm == "" {
+				phrase := mgr.ResetConfirmation()
+				fmt.Printf("This node has a validator key. Type %q to reset it: ", phrase)
+				answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil {
+					return fmt.Errorf("failed to read confirmation: %w", err)
+				}
+				opts.Confirm = strings.TrimSpace(answer)
+			}
+
+			result, err := mgr.ResetNode(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(result)
+			}
+			for _, path := range result.Removed {
+				fmt.Printf("Removed %s\n", path)
+			}
+			if result.Backup != "" {
+				fmt.Printf("Backup: %s\n", result.Backup)
+			}
+			if s := result.SignState; s != nil {
+				fmt.Printf("Validator signing state: height %d, round %d, step %d (from %s)\n",
+					s.Height, s.Round, s.Step, result.SignStateSource)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Level, "level", state.ResetData, "what to reset: data, wasm or full")
+	cmd.Flags().BoolVar(&opts.NoBackup, "no-backup", false, "skip backing up data and wasm first")
+	cmd.Flags().StringVar(&opts.Confirm, "confirm", "", "confirmation phrase for validator nodes (the chain ID)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-org/seictl/internal/state"
+	"github.com/your-org/seictl/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+func newRPCCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rpc",
+		Short: "Inspect the RPC endpoints of an environment",
+	}
+
+	cmd.AddCommand(newRPCHealthCmd())
+	cmd.AddCommand(newRPCEVMCmd())
+
+	return cmd
+}
+
+func newRPCHealthCmd() *cobra.Command {
+	var env string
+	var samples int
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "Probe RPC endpoints and show their health scores",
+		Long: `Probe RPC endpoints and show their health scores.
+
+Endpoints are scored from 0 to 100 by latency, error rate, whether they are
+catching up and how far they trail the highest endpoint. Calls are routed to
+the highest scoring endpoint and fail over to the next one on errors.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+			pool, err := mgr.RPCPool(types.Environment(env))
+			if err != nil {
+				return err
+			}
+
+			for i := 0; i < samples; i++ {
+				pool.Check(ctx)
+			}
+			board := pool.Health()
+
+			if jsonOutput {
+				return printJSON(board)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ENDPOINT\tSCORE\tLATENCY\tHEIGHT\tCATCHING UP\tERRORS\tRATE LIMIT\tLAST ERROR")
+			for _, h := range board {
+				limit := "-"
+				if h.RateLimit > 0 {
+					limit = fmt.Sprintf("%g/s", h.RateLimit)
+				}
+				fmt.Fprintf(w, "%s\t%.0f\t%s\t%d\t%t\t%.0f%%\t%s\t%s\n",
+					h.URL, h.Score, h.Latency.Duration().Round(time.Millisecond), h.Height, h.CatchingUp,
+					h.ErrorRate*100, limit, h.LastError)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "environment to check (defaults to the one matching the local chain ID)")
+	cmd.Flags().IntVar(&samples, "samples", 3, "number of probes per endpoint")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func newRPCEVMCmd() *cobra.Command {
+	var opts state.EVMCheckOptions
+	var env string
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "evm",
+		Short: "Check the EVM JSON-RPC endpoint against the Tendermint RPC",
+		Long: `Check the EVM JSON-RPC endpoint against the Tendermint RPC.
+
+Calls eth_chainId, eth_blockNumber and net_version and compares the EVM block
+height with the Tendermint height, which advance together on a healthy node.
+eth_chainId is checked against the environment's evm_chain_id when set. By
+default the local node's evm_rpc and rpc ports are checked. Exits non-zero
+when a check fails.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+			opts.Env = types.Environment(env)
+			health, err := mgr.CheckEVM(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				if err := printJSON(health); err != nil {
+					return err
+				}
+			} else {
+				fmt.Printf("EVM endpoint:      %s\n", health.Endpoint)
+				fmt.Printf("Chain ID:          %d\n", health.ChainID)
+				fmt.Printf("Net version:       %s\n", orDash(health.NetVersion))
+				fmt.Printf("EVM height:        %d (%s)\n", health.EVMHeight, health.Latency.Duration().Round(time.Millisecond))
+				fmt.Printf("Tendermint height: %d (%s)\n", health.TendermintHeight, health.RPC)
+				for _, p := range health.Problems {
+					fmt.Printf("  - %s\n", p)
+				}
+			}
+			if !health.Healthy {
+				return fmt.Errorf("EVM endpoint %s is unhealthy", health.Endpoint)
+			}
+			if !jsonOutput {
+				fmt.Println("\nEVM endpoint is healthy")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "environment to check (defaults to the one matching the local chain ID)")
+	cmd.Flags().StringVar(&opts.EVMRPC, "evm-rpc", "", "EVM JSON-RPC endpoint (default: the local node)")
+	cmd.Flags().StringVar(&opts.RPC, "rpc", "", "Tendermint RPC endpoint (default: the local node)")
+	cmd.Flags().Int64Var(&opts.MaxLag, "max-lag", 5, "blocks the EVM height may differ from the Tendermint height")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
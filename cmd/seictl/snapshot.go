@@ -0,0 +1,519 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-org/seictl/internal/chain"
+	"github.com/your-org/seictl/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+func newSnapshotListCmd() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List snapshots in the backup directory",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			snapshots, err := mgr.ListSnapshots()
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(snapshots)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "HEIGHT\tCHAIN\tTYPE\tSIZE\tAGE\tINTEGRITY")
+			for _, s := range snapshots {
+				kind, size := "full", s.Size
+				if s.Type != "" {
+					kind, size = s.Type, s.LogicalSize
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+					s.Height, s.ChainID, kind, formatBytes(size), formatAge(s.Age.Duration()), s.Integrity)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func newSnapshotInspectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect <height>",
+		Short: "Show the full manifest of a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			height, err := parseHeightArg(args[0])
+			if err != nil {
+				return err
+			}
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			manifest, err := mgr.InspectSnapshot(height)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(manifest)
+		},
+	}
+}
+
+func newSnapshotVerifyCmd() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "verify <height>",
+		Short: "Re-hash snapshot archives and compare against the manifest",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			height, err := parseHeightArg(args[0])
+			if err != nil {
+				return err
+			}
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			result, err := mgr.VerifySnapshotIntegrity(height)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				if err := printJSON(result); err != nil {
+					return err
+				}
+			} else {
+				for _, a := range result.Archives {
+					status := "OK"
+					if !a.OK {
+						status = "FAILED"
+					}
+					fmt.Printf("%-8s %s\n", status, a.Name)
+				}
+			}
+
+			if !result.OK {
+				return fmt.Errorf("snapshot at height %d failed verification", height)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func newSnapshotDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <height>",
+		Short: "Delete a snapshot from the backup directory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			height, err := parseHeightArg(args[0])
+			if err != nil {
+				return err
+			}
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			return mgr.DeleteSnapshot(height)
+		},
+	}
+}
+
+func newSnapshotPruneCmd() *cobra.Command {
+	var dryRun bool
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove snapshots and backups according to retention policies",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			plan, err := mgr.PruneSnapshots(dryRun)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(plan)
+			}
+
+			action := "PRUNE"
+			if dryRun {
+				action = "WOULD PRUNE"
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ACTION\tKIND\tPATH\tSIZE\tREASON")
+			for _, item := range plan.Keep {
+				fmt.Fprintf(w, "KEEP\t%s\t%s\t%s\t%s\n", item.Kind, item.Path, formatBytes(item.Size), item.Reason)
+			}
+			for _, item := range plan.Prune {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", action, item.Kind, item.Path, formatBytes(item.Size), item.Reason)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			fmt.Printf("\n%d item(s), %s reclaimed\n", len(plan.Prune), formatBytes(plan.FreedBytes))
+			for _, warning := range plan.Warnings {
+				fmt.Printf("Warning: %s\n", warning)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be removed without deleting")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func newSnapshotStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the last result of the scheduled snapshot daemon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			status, err := mgr.ReadDaemonStatus()
+			if err != nil {
+				return err
+			}
+
+			return printJSON(status)
+		},
+	}
+}
+
+func newSnapshotPushCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "push <height|backup_name>",
+		Short: "Upload a snapshot or backup to the configured storage backend",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			storage, err := mgr.NewStorage()
+			if err != nil {
+				return err
+			}
+
+			return mgr.PushSnapshot(ctx, storage, args[0])
+		},
+	}
+}
+
+func newSnapshotPullCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull <height|backup_name>",
+		Short: "Download a snapshot or backup from the configured storage backend",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			storage, err := mgr.NewStorage()
+			if err != nil {
+				return err
+			}
+
+			return mgr.PullSnapshot(ctx, storage, args[0])
+		},
+	}
+}
+
+func newSnapshotRestoreCmd() *cobra.Command {
+	var path string
+	var url string
+	var from string
+	var feed string
+	var token string
+	var rollback bool
+	var urlOpts state.URLRestoreOptions
+
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore node data from a snapshot directory or URL",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			if rollback {
+				if path != "" || url != "" || from != "" || feed != "" {
+					return fmt.Errorf("--rollback cannot be combined with another restore source")
+				}
+				mgr, err := state.NewManager(config, logger)
+				if err != nil {
+					return err
+				}
+				return mgr.RollbackRestore(ctx)
+			}
+
+			sources := 0
+			for _, source := range []string{path, url, from, feed} {
+				if source != "" {
+					sources++
+				}
+			}
+			if sources != 1 {
+				return fmt.Errorf("exactly one of --path, --url, --from or --feed is required")
+			}
+
+			if feed != "" {
+				mgr, err := state.NewManager(config, logger)
+				if err != nil {
+					return err
+				}
+				return mgr.RestoreFromFeed(ctx, feed)
+			}
+
+			if from != "" {
+				mgr, err := state.NewManager(config, logger)
+				if err != nil {
+					return err
+				}
+				if token == "" {
+					token = config.Snapshots.Serve.Token
+				}
+				return mgr.RestoreFromServer(ctx, from, token)
+			}
+
+			if url != "" {
+				mgr, err := state.NewManager(config, logger)
+				if err != nil {
+					return err
+				}
+				return mgr.RestoreFromURL(ctx, url, urlOpts)
+			}
+
+			mgr, err := chain.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+			return mgr.RestoreSnapshot(ctx, path)
+		},
+	}
+
+	cmd.Flags().StringVar(&path, "path", "", "path to a local snapshot directory")
+	cmd.Flags().StringVar(&url, "url", "", "URL of a .tar, .tar.gz, .tar.lz4 or .tar.zst snapshot to stream")
+	cmd.Flags().StringVar(&urlOpts.Checksum, "checksum", "", "expected SHA256 of the archive (defaults to <url>.sha256)")
+	cmd.Flags().BoolVar(&urlOpts.NoResume, "no-resume", false, "ignore progress from an interrupted restore")
+	cmd.Flags().StringVar(&from, "from", "", "URL of a seictl snapshot server to restore the newest matching snapshot from")
+	cmd.Flags().StringVar(&feed, "feed", "", "URL of a published snapshot index.json to restore the newest matching snapshot from")
+	cmd.Flags().StringVar(&token, "token", "", "bearer token for --from (defaults to snapshots.serve.token)")
+	cmd.Flags().BoolVar(&rollback, "rollback", false, "put back the node data saved before the last restore")
+
+	return cmd
+}
+
+func newSnapshotPublishCmd() *cobra.Command {
+	var opts state.PublishOptions
+	var toStorage bool
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "publish",
+		Short: "Generate index.json and an Atom feed describing available snapshots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			if opts.BaseURL == "" {
+				opts.BaseURL = config.Snapshots.Publish.BaseURL
+			}
+			if opts.Title == "" {
+				opts.Title = config.Snapshots.Publish.Title
+			}
+			if toStorage {
+				opts.Storage, err = mgr.NewStorage()
+				if err != nil {
+					return err
+				}
+			}
+
+			index, err := mgr.PublishFeed(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(index)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "HEIGHT\tCHAIN\tSIZE\tSEID\tPRUNING\tURL")
+			for _, e := range index.Snapshots {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+					e.Height, e.ChainID, formatBytes(e.Size), e.SeidVersion, e.Pruning, e.URL)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.BaseURL, "base-url", "", "public URL snapshots are downloaded from (defaults to snapshots.publish.base_url)")
+	cmd.Flags().StringVar(&opts.Title, "title", "", "feed title (defaults to snapshots.publish.title)")
+	cmd.Flags().StringVar(&opts.OutputDir, "output", "", "directory to write index.json and feed.xml to")
+	cmd.Flags().BoolVar(&toStorage, "storage", false, "upload index.json and feed.xml to the configured storage backend")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func newSnapshotServeCmd() *cobra.Command {
+	var listen string
+	var token string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve local snapshots over HTTP to other nodes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := setupContext()
+
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			if listen == "" {
+				listen = config.Snapshots.Serve.GetListen()
+			}
+			if token == "" {
+				token = config.Snapshots.Serve.Token
+			}
+			if token == "" {
+				logger.Warn().Msg("No token configured, snapshots are served without authentication")
+			}
+
+			return mgr.ServeSnapshots(ctx, listen, token)
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", "", "address to listen on (defaults to snapshots.serve.listen)")
+	cmd.Flags().StringVar(&token, "token", "", "bearer token clients must send (defaults to snapshots.serve.token)")
+
+	return cmd
+}
+
+func newSnapshotGCCmd() *cobra.Command {
+	var dryRun bool
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove chunks no incremental snapshot references",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			result, err := mgr.GarbageCollectChunks(dryRun)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(result)
+			}
+
+			verb := "Removed"
+			if dryRun {
+				verb = "Would remove"
+			}
+			fmt.Printf("%s %d chunk(s), %s reclaimed; %d chunk(s) still referenced\n",
+				verb, result.Removed, formatBytes(result.ReclaimedBytes), result.Referenced)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be removed without deleting")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func parseHeightArg(arg string) (int64, error) {
+	height, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || height < 0 {
+		return 0, fmt.Errorf("invalid height: %s", arg)
+	}
+	return height, nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/your-org/seictl/internal/state"
+	"github.com/your-org/seictl/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+func newStateSyncCheckpointCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "checkpoint",
+		Short: "Manage the light client checkpoint used to verify trust blocks",
+	}
+
+	cmd.AddCommand(
+		newStateSyncCheckpointSetCmd(),
+		newStateSyncCheckpointShowCmd(),
+	)
+
+	return cmd
+}
+
+func newStateSyncCheckpointSetCmd() *cobra.Command {
+	var env, hash string
+	var opts state.StateSyncOptions
+
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Store a trusted header as the light client checkpoint",
+		Long: `Store a trusted header as the light client checkpoint.
+
+The hash should come from a source you trust, such as a block explorer or
+another operator. When --hash is omitted it is fetched from the environment's
+RPC endpoints, which must reach quorum.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			opts.Env = types.Environment(env)
+			cp, err := mgr.TrustCheckpoint(setupContext(), opts, hash)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Checkpoint for %s set to height %d (%s)\n", cp.ChainID, cp.Height, cp.Hash)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "environment the checkpoint belongs to (defaults to the one matching the local chain ID)")
+	cmd.Flags().Int64Var(&opts.TrustHeight, "height", 0, "height of the trusted header")
+	cmd.Flags().StringVar(&hash, "hash", "", "hash of the trusted header (fetched from RPC when omitted)")
+	cmd.Flags().StringSliceVar(&opts.RPC, "rpc", nil, "RPC endpoints to use instead of the environment's")
+	_ = cmd.MarkFlagRequired("height")
+
+	return cmd
+}
+
+func newStateSyncCheckpointShowCmd() *cobra.Command {
+	var env string
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the stored light client checkpoint",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			cp, err := mgr.EnvCheckpoint(types.Environment(env))
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(cp)
+			}
+
+			fmt.Printf("Chain ID: %s\n", cp.ChainID)
+			fmt.Printf("Height:   %d\n", cp.Height)
+			fmt.Printf("Hash:     %s\n", cp.Hash)
+			if !cp.Time.IsZero() {
+				fmt.Printf("Time:     %s\n", cp.Time.Format("2006-01-02 15:04:05 MST"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "environment to show (defaults to the one matching the local chain ID)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
+
+func newStateSyncMonitorCmd() *cobra.Command {
+	var env string
+	var jsonOutput bool
+	var opts state.MonitorOptions
+
+	cmd := &cobra.Command{
+		Use:   "monitor",
+		Short: "Follow a state syncing local node until it catches up",
+		Long: `Follow a state syncing local node until it catches up.
+
+Progress is read from the node's log and its local RPC port: snapshot
+discovery, chunk restore (N/M chunks) and blocksync, each with an ETA. When
+nothing progresses for --stall-timeout the stall is reported along with
+suggested fixes.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := state.NewManager(config, logger)
+			if err != nil {
+				return err
+			}
+
+			opts.Env = types.Environment(env)
+			if jsonOutput {
+				opts.Progress = func(p state.SyncProgress) {
+					_ = printJSON(p)
+				}
+			}
+			return mgr.MonitorStateSync(setupContext(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "environment whose local RPC port is used (defaults to the one matching the local chain ID)")
+	cmd.Flags().StringVar(&opts.RPC, "rpc", "", "local node RPC address (default: 127.0.0.1 on the environment's RPC port)")
+	cmd.Flags().StringVar(&opts.LogPath, "log", "", "seid log file to follow (default: seid.log in the home directory)")
+	cmd.Flags().BoolVar(&opts.FromStart, "from-start", false, "read the whole log instead of only new lines")
+	cmd.Flags().DurationVar(&opts.StallTimeout, "stall-timeout", 10*time.Minute, "time without progress before a stall is reported")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "print every update as JSON")
+
+	return cmd
+}
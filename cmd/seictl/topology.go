@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/your-org/seictl/internal/chain"
+
+	"github.com/spf13/cobra"
+)
+
+func newTopologyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "topology",
+		Short: "Generate p2p configuration for validator and sentry deployments",
+	}
+
+	cmd.AddCommand(newTopologyRenderCmd())
+
+	return cmd
+}
+
+func newTopologyRenderCmd() *cobra.Command {
+	var outDir string
+	var generateKeys, jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "render <topology.yaml>",
+		Short: "Render each host's config.toml p2p section from a topology file",
+		Long: `Render each host's config.toml p2p section from a topology file.
+
+The topology names a validator and its sentries with the address the other
+hosts dial, and optionally each sentry's public external_address and the
+public seeds and persistent_peers the sentries join:
+
+  validator:
+    name: validator
+    address: 10.0.0.10:26656
+  sentries:
+    - name: sentry-1
+      address: 10.0.0.11:26656
+      external_address: 203.0.113.11:26656
+  seeds: ["id@seed.example.com:26656"]
+
+Node IDs are computed from each host's node_key.json, by default
+keys/<name>/node_key.json next to the topology file; --generate-keys creates
+missing keys. The validator dials only its sentries with pex off, and each
+sentry keeps the validator in private_peer_ids. Rendering fails unless the
+validator is reachable only through its sentries.
+
+The bundle in --out has a directory per host holding config/p2p.toml, to
+merge into config.toml, and config/node_key.json.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundle, err := chain.RenderTopology(args[0], chain.RenderOptions{GenerateKeys: generateKeys})
+			if err != nil {
+				return err
+			}
+			if err := bundle.Write(outDir); err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(bundle)
+			}
+			for _, h := range bundle.Hosts {
+				generated := ""
+				if h.GeneratedKey {
+					generated = " (new key)"
+				}
+				fmt.Printf("%-10s %-20s %s@%s%s\n", h.Role, h.Name, h.NodeID, h.Address, generated)
+			}
+			for _, w := range bundle.Warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+			}
+			fmt.Printf("\nConfig bundle written to %s\n", outDir)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&outDir, "out", "topology", "directory to write the per-host bundle to")
+	cmd.Flags().BoolVar(&generateKeys, "generate-keys", false, "create missing node keys")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
+
+	return cmd
+}
@@ -0,0 +1,215 @@
+// Package address converts between Sei's bech32 and EVM hex address formats
+// and derives addresses from public keys and mnemonics, without network access
+package address
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Bech32 prefixes of Sei addresses
+const (
+	// AccountPrefix is used by account addresses, sei1...
+	AccountPrefix = "sei"
+	// ValoperPrefix is used by validator operator addresses, seivaloper1...
+	ValoperPrefix = "seivaloper"
+	// ValconsPrefix is used by validator consensus addresses, seivalcons1...
+	ValconsPrefix = "seivalcons"
+)
+
+// Kinds of keys an address can belong to
+const (
+	// KindAccount is a secp256k1 account key, also used by validator operators
+	KindAccount = "account"
+	// KindConsensus is a validator's ed25519 consensus key
+	KindConsensus = "consensus"
+	// KindUnknown is bare hex, which may be either
+	KindUnknown = "unknown"
+)
+
+// Output formats for Conversion.Format
+const (
+	FormatEVM = "evm"
+	FormatHex = "hex"
+)
+
+// Conversion is an address in every encoding that applies to it
+type Conversion struct {
+	Input string `json:"input"`
+	Kind  string `json:"kind"`
+	// Hex is the raw address bytes, upper case as Tendermint prints them
+	Hex string `json:"hex"`
+	// EVM is the EIP-55 checksummed 0x address with the same bytes
+	EVM     string   `json:"evm,omitempty"`
+	Account string   `json:"account,omitempty"`
+	Valoper string   `json:"valoper,omitempty"`
+	Valcons string   `json:"valcons,omitempty"`
+	Notes   []string `json:"notes,omitempty"`
+}
+
+// Format returns the address in one format: evm, hex or a bech32 prefix
+func (c *Conversion) Format(format string) (string, error) {
+	var out string
+	switch format {
+	case FormatEVM:
+		out = c.EVM
+	case FormatHex:
+		out = c.Hex
+	case AccountPrefix:
+		out = c.Account
+	case ValoperPrefix:
+		out = c.Valoper
+	case ValconsPrefix:
+		out = c.Valcons
+	default:
+		return "", fmt.Errorf("unknown format %q, use evm, hex, %s, %s or %s", format, AccountPrefix, ValoperPrefix, ValconsPrefix)
+	}
+	if out == "" {
+		return "", fmt.Errorf("a %s address has no %s form", c.Kind, format)
+	}
+	return out, nil
+}
+
+// Convert parses a sei, seivaloper, seivalcons, 0x or bare hex address and
+// returns it in every encoding of the same bytes. Converting between bech32
+// and 0x keeps the bytes; it does not find the EVM address associated with an
+// account, which needs the public key.
+func Convert(input string) (*Conversion, error) {
+	input = strings.TrimSpace(input)
+	c := &Conversion{Input: input}
+
+	var raw []byte
+	switch {
+	case strings.HasPrefix(input, "0x") || strings.HasPrefix(input, "0X"):
+		b, err := decodeHex(input[2:])
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != 20 {
+			return nil, fmt.Errorf("an EVM address is 20 bytes, got %d", len(b))
+		}
+		if mixedCase(input[2:]) && checksumHex(b) != "0x"+input[2:] {
+			return nil, fmt.Errorf("invalid EIP-55 checksum in %s, expected %s", input, checksumHex(b))
+		}
+		raw, c.Kind = b, KindAccount
+	case isHex(input):
+		b, err := decodeHex(input)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != 20 {
+			return nil, fmt.Errorf("expected a 20 byte hex address, got %d bytes", len(b))
+		}
+		raw, c.Kind = b, KindUnknown
+	default:
+		hrp, b, err := Bech32Decode(input)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a 0x, hex or bech32 address: %w", input, err)
+		}
+		switch hrp {
+		case AccountPrefix, ValoperPrefix:
+			c.Kind = KindAccount
+		case ValconsPrefix:
+			c.Kind = KindConsensus
+		default:
+			return nil, fmt.Errorf("unknown bech32 prefix %q, use %s, %s or %s", hrp, AccountPrefix, ValoperPrefix, ValconsPrefix)
+		}
+		if len(b) != 20 && !(hrp == AccountPrefix && len(b) == 32) {
+			return nil, fmt.Errorf("a %s address is 20 bytes, got %d", hrp, len(b))
+		}
+		raw = b
+	}
+
+	c.Hex = strings.ToUpper(hex.EncodeToString(raw))
+	var err error
+	if c.Kind != KindConsensus {
+		if c.Account, err = Bech32Encode(AccountPrefix, raw); err != nil {
+			return nil, err
+		}
+		if len(raw) == 20 {
+			c.EVM = checksumHex(raw)
+			if c.Valoper, err = Bech32Encode(ValoperPrefix, raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c.Kind != KindAccount {
+		if c.Valcons, err = Bech32Encode(ValconsPrefix, raw); err != nil {
+			return nil, err
+		}
+	}
+	c.Notes = conversionNotes(c, len(raw))
+	return c, nil
+}
+
+func conversionNotes(c *Conversion, size int) []string {
+	switch {
+	case size == 32:
+		return []string{
+			"A 32 byte sei address belongs to a CosmWasm contract or module account and has no 0x or operator form.",
+		}
+	case c.Kind == KindConsensus:
+		return []string{
+			"seivalcons addresses hash the validator's ed25519 consensus key (priv_validator_key.json); Tendermint prints the same bytes as upper case hex.",
+			"The consensus address is unrelated to the validator's operator (seivaloper) and account (sei) addresses.",
+		}
+	case c.Kind == KindUnknown:
+		return []string{
+			"Bare hex is ambiguous: Tendermint prints consensus addresses this way. Prefix 0x for an EVM address.",
+		}
+	default:
+		return []string{
+			"sei, seivaloper and 0x here are the same 20 bytes; seivaloper is the operator address of that account.",
+			"A key's own sei and 0x addresses differ (ripemd160(sha256(pubkey)) versus keccak256(pubkey)); " +
+				"Sei links them once the public key is known on chain. Use `seictl address derive` for a key's pair.",
+			"Until then, funds sent to a 0x address are held by the sei account with the same bytes, and vice versa.",
+		}
+	}
+}
+
+// checksumHex returns the EIP-55 mixed case 0x encoding of an EVM address
+func checksumHex(addr []byte) string {
+	lower := hex.EncodeToString(addr)
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(lower))
+	digest := hash.Sum(nil)
+
+	out := []byte(lower)
+	for i, ch := range out {
+		nibble := digest[i/2] >> 4
+		if i%2 == 1 {
+			nibble = digest[i/2] & 0x0f
+		}
+		if ch >= 'a' && nibble >= 8 {
+			out[i] = ch - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+func decodeHex(s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex %q: %w", s, err)
+	}
+	return b, nil
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, ch := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", ch) {
+			return false
+		}
+	}
+	return true
+}
+
+func mixedCase(s string) bool {
+	return strings.ToLower(s) != s && strings.ToUpper(s) != s
+}
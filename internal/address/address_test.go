@@ -0,0 +1,163 @@
+package address
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The BIP-39 test mnemonic of twelve words
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestBech32(t *testing.T) {
+	// BIP-173 test vectors
+	for _, valid := range []string{
+		"A12UEL5L",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	} {
+		hrp, data, err := Bech32Decode(valid)
+		require.NoError(t, err, valid)
+		encoded, err := Bech32Encode(hrp, data)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToLower(valid), encoded)
+	}
+
+	for _, invalid := range []string{
+		"pzry9x0s0muk",  // no separator
+		"1pzry9x0s0muk", // empty prefix
+		"a12UEL5L",      // mixed case
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxx", // bad checksum
+		"x1b4n0q5v", // invalid character
+	} {
+		_, _, err := Bech32Decode(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	c, err := Convert("0x9858effd232b4033e47d90003d41ec34ecaeda94")
+	require.NoError(t, err)
+	assert.Equal(t, KindAccount, c.Kind)
+	assert.Equal(t, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", c.EVM)
+	assert.Equal(t, "9858EFFD232B4033E47D90003D41EC34ECAEDA94", c.Hex)
+	assert.True(t, strings.HasPrefix(c.Account, "sei1"))
+	assert.True(t, strings.HasPrefix(c.Valoper, "seivaloper1"))
+	assert.Empty(t, c.Valcons)
+	assert.NotEmpty(t, c.Notes)
+
+	// Every form converts back to the same bytes
+	for _, form := range []string{c.Account, c.Valoper, c.EVM} {
+		back, err := Convert(form)
+		require.NoError(t, err)
+		assert.Equal(t, c.Hex, back.Hex)
+		assert.Equal(t, c.Account, back.Account)
+	}
+	out, err := c.Format(ValoperPrefix)
+	require.NoError(t, err)
+	assert.Equal(t, c.Valoper, out)
+	_, err = c.Format(ValconsPrefix)
+	assert.Error(t, err)
+
+	_, err = Convert("0x9858EFFD232B4033E47d90003D41EC34EcaEda94")
+	assert.ErrorContains(t, err, "EIP-55")
+
+	// Bare hex, as Tendermint prints validator addresses, may be either
+	c, err = Convert("9858EFFD232B4033E47D90003D41EC34ECAEDA94")
+	require.NoError(t, err)
+	assert.Equal(t, KindUnknown, c.Kind)
+	assert.NotEmpty(t, c.Account)
+	assert.True(t, strings.HasPrefix(c.Valcons, "seivalcons1"))
+
+	valcons, err := Convert(c.Valcons)
+	require.NoError(t, err)
+	assert.Equal(t, KindConsensus, valcons.Kind)
+	assert.Empty(t, valcons.Account)
+	assert.Empty(t, valcons.EVM)
+
+	// 32 byte contract addresses have no EVM form
+	contract, err := Bech32Encode(AccountPrefix, make([]byte, 32))
+	require.NoError(t, err)
+	c, err = Convert(contract)
+	require.NoError(t, err)
+	assert.Empty(t, c.EVM)
+
+	cosmos, err := Bech32Encode("cosmos", make([]byte, 20))
+	require.NoError(t, err)
+	_, err = Convert(cosmos)
+	assert.ErrorContains(t, err, "unknown bech32 prefix")
+}
+
+func TestFromMnemonic(t *testing.T) {
+	keys, err := FromMnemonic(testMnemonic, MnemonicOptions{})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	cosmos, evm := keys[0], keys[1]
+	assert.Equal(t, "m/44'/118'/0'/0/0", cosmos.Path)
+	// Well-known addresses of the test mnemonic
+	_, data, err := Bech32Decode(cosmos.Account)
+	require.NoError(t, err)
+	expected, err := Bech32Encode("cosmos", data)
+	require.NoError(t, err)
+	assert.Equal(t, "cosmos19rl4cm2hmr8afy4kldpxz3fka4jguq0auqdal4", expected)
+
+	assert.Equal(t, "m/44'/60'/0'/0/0", evm.Path)
+	assert.Equal(t, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", evm.EVM)
+	assert.NotEqual(t, cosmos.Account, evm.Account)
+
+	// The derived public key gives the same addresses
+	fromPub, err := FromPubKey(evm.PubKey)
+	require.NoError(t, err)
+	assert.Equal(t, evm.Account, fromPub.Account)
+	assert.Equal(t, evm.EVM, fromPub.EVM)
+
+	keys, err = FromMnemonic("  "+strings.ReplaceAll(testMnemonic, " ", "\n")+"\n", MnemonicOptions{CoinTypes: []uint32{CoinTypeEVM}, Index: 1})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "m/44'/60'/0'/0/1", keys[0].Path)
+	assert.NotEqual(t, evm.EVM, keys[0].EVM)
+
+	_, err = FromMnemonic(strings.Replace(testMnemonic, "about", "abandon", 1), MnemonicOptions{})
+	assert.ErrorContains(t, err, "invalid mnemonic")
+}
+
+func TestFromPubKey(t *testing.T) {
+	// The secp256k1 generator point, compressed and uncompressed
+	compressed := "0279BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798"
+	uncompressed := "0479BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798" +
+		"483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8"
+
+	a, err := FromPubKey(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, KeySecp256k1, a.Type)
+	// Private key 1 is the well-known Ethereum address below
+	assert.Equal(t, "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf", a.EVM)
+
+	b, err := FromPubKey("0x" + strings.ToLower(uncompressed))
+	require.NoError(t, err)
+	assert.Equal(t, a.Account, b.Account)
+	assert.Equal(t, a.EVM, b.EVM)
+
+	c, err := FromPubKey(`{"@type":"/cosmos.crypto.secp256k1.PubKey","key":"` + a.PubKey + `"}`)
+	require.NoError(t, err)
+	assert.Equal(t, a.Account, c.Account)
+
+	ed := make([]byte, 32)
+	v, err := FromPubKey(`{"type":"tendermint/PubKeyEd25519","value":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`)
+	require.NoError(t, err)
+	assert.Equal(t, KeyEd25519, v.Type)
+	assert.True(t, strings.HasPrefix(v.Valcons, "seivalcons1"))
+	assert.Empty(t, v.Account)
+	w, err := FromPubKey(hex.EncodeToString(ed))
+	require.NoError(t, err)
+	assert.Equal(t, v.Valcons, w.Valcons)
+
+	_, err = FromPubKey("0x0102")
+	assert.ErrorContains(t, err, "got 2")
+	_, err = FromPubKey("03" + strings.Repeat("00", 32))
+	assert.ErrorContains(t, err, "invalid secp256k1")
+}
@@ -0,0 +1,277 @@
+package address
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/go-bip39"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/ripemd160"
+	"golang.org/x/crypto/sha3"
+)
+
+// BIP-44 coin types used by Sei wallets
+const (
+	// CoinTypeCosmos is used by Cosmos wallets and `seid keys add` by default
+	CoinTypeCosmos uint32 = 118
+	// CoinTypeEVM is used by Ethereum wallets such as MetaMask
+	CoinTypeEVM uint32 = 60
+)
+
+// Key types
+const (
+	KeySecp256k1 = "secp256k1"
+	KeyEd25519   = "ed25519"
+)
+
+const hardened = 0x80000000
+
+// KeyAddresses are the addresses of one public key
+type KeyAddresses struct {
+	Type string `json:"type"`
+	// Path is the BIP-44 derivation path, for keys derived from a mnemonic
+	Path     string `json:"path,omitempty"`
+	CoinType uint32 `json:"coin_type,omitempty"`
+	// PubKey is the compressed secp256k1 or raw ed25519 public key, base64
+	// encoded as seid prints it
+	PubKey string `json:"pubkey"`
+	// Account is the Cosmos address, bech32 of ripemd160(sha256(pubkey))
+	Account string `json:"account,omitempty"`
+	Valoper string `json:"valoper,omitempty"`
+	// EVM is the Ethereum address, the last 20 bytes of keccak256(pubkey)
+	EVM string `json:"evm,omitempty"`
+	// Valcons and Hex are the consensus address of an ed25519 key
+	Valcons string   `json:"valcons,omitempty"`
+	Hex     string   `json:"hex,omitempty"`
+	Notes   []string `json:"notes,omitempty"`
+}
+
+// FromPubKey derives the addresses of a public key given as hex, base64 or
+// JSON as printed by seid ({"@type": ..., "key": ...}) or Tendermint
+// ({"type": ..., "value": ...}). 33 and 65 byte keys are secp256k1, 32 byte
+// keys ed25519.
+func FromPubKey(input string) (*KeyAddresses, error) {
+	input = strings.TrimSpace(input)
+	keyType := ""
+	if strings.HasPrefix(input, "{") {
+		var wrapped struct {
+			AtType string `json:"@type"`
+			Type   string `json:"type"`
+			Key    string `json:"key"`
+			Value  string `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(input), &wrapped); err != nil {
+			return nil, fmt.Errorf("failed to parse public key JSON: %w", err)
+		}
+		typ := strings.ToLower(wrapped.AtType + wrapped.Type)
+		switch {
+		case strings.Contains(typ, "secp256k1"):
+			keyType = KeySecp256k1
+		case strings.Contains(typ, "ed25519"):
+			keyType = KeyEd25519
+		default:
+			return nil, fmt.Errorf("unsupported public key type %q", wrapped.AtType+wrapped.Type)
+		}
+		input = wrapped.Key + wrapped.Value
+	}
+
+	pub, err := decodeKey(input)
+	if err != nil {
+		return nil, err
+	}
+	if keyType == "" {
+		switch len(pub) {
+		case 32:
+			keyType = KeyEd25519
+		case 33, 65:
+			keyType = KeySecp256k1
+		default:
+			return nil, fmt.Errorf("a public key is 32 (ed25519), 33 or 65 (secp256k1) bytes, got %d", len(pub))
+		}
+	}
+
+	if keyType == KeyEd25519 {
+		if len(pub) != 32 {
+			return nil, fmt.Errorf("an ed25519 public key is 32 bytes, got %d", len(pub))
+		}
+		return ed25519Addresses(pub)
+	}
+	key, err := secp256k1.ParsePubKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secp256k1 public key: %w", err)
+	}
+	return secp256k1Addresses(key)
+}
+
+// decodeKey accepts hex, with or without 0x, and standard base64. Hex wins
+// when it decodes to a key size, as base64 may only use hex digits.
+func decodeKey(s string) ([]byte, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	var fromHex []byte
+	if isHex(trimmed) && len(trimmed)%2 == 0 {
+		fromHex, _ = hex.DecodeString(trimmed)
+		if n := len(fromHex); n == 32 || n == 33 || n == 65 || trimmed != s {
+			return fromHex, nil
+		}
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		if fromHex != nil {
+			return fromHex, nil
+		}
+		return nil, fmt.Errorf("public key is neither hex nor base64")
+	}
+	return b, nil
+}
+
+func secp256k1Addresses(key *secp256k1.PublicKey) (*KeyAddresses, error) {
+	compressed := key.SerializeCompressed()
+
+	sha := sha256.Sum256(compressed)
+	ripe := ripemd160.New()
+	ripe.Write(sha[:])
+	cosmos := ripe.Sum(nil)
+
+	keccak := sha3.NewLegacyKeccak256()
+	keccak.Write(key.SerializeUncompressed()[1:])
+	evm := keccak.Sum(nil)[12:]
+
+	a := &KeyAddresses{
+		Type:   KeySecp256k1,
+		PubKey: base64.StdEncoding.EncodeToString(compressed),
+		EVM:    checksumHex(evm),
+	}
+	var err error
+	if a.Account, err = Bech32Encode(AccountPrefix, cosmos); err != nil {
+		return nil, err
+	}
+	if a.Valoper, err = Bech32Encode(ValoperPrefix, cosmos); err != nil {
+		return nil, err
+	}
+	a.Notes = []string{
+		"account is ripemd160(sha256(compressed pubkey)) and evm is keccak256(uncompressed pubkey)[12:]; both belong to this key.",
+		"Sei links the two once the key signs a transaction or is associated, after which they are one account.",
+	}
+	return a, nil
+}
+
+func ed25519Addresses(pub []byte) (*KeyAddresses, error) {
+	sum := sha256.Sum256(pub)
+	addr := sum[:20]
+	valcons, err := Bech32Encode(ValconsPrefix, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyAddresses{
+		Type:    KeyEd25519,
+		PubKey:  base64.StdEncoding.EncodeToString(pub),
+		Valcons: valcons,
+		Hex:     strings.ToUpper(hex.EncodeToString(addr)),
+		Notes: []string{
+			"An ed25519 key is a validator consensus key; its address is sha256(pubkey)[:20] and it has no account or EVM address.",
+		},
+	}, nil
+}
+
+// MnemonicOptions select the keys derived from a mnemonic
+type MnemonicOptions struct {
+	// Passphrase is the optional BIP-39 passphrase
+	Passphrase string
+	Account    uint32
+	Index      uint32
+	// CoinTypes defaults to both CoinTypeCosmos and CoinTypeEVM
+	CoinTypes []uint32
+}
+
+// FromMnemonic derives the key at m/44'/coin'/account'/0/index for each coin
+// type and returns its addresses. The same mnemonic yields unrelated keys, and
+// so different accounts, under each coin type.
+func FromMnemonic(mnemonic string, opts MnemonicOptions) ([]*KeyAddresses, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, opts.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mnemonic: %w", err)
+	}
+	if opts.Account >= hardened || opts.Index >= hardened {
+		return nil, fmt.Errorf("account and index must be below %d", uint32(hardened))
+	}
+	coinTypes := opts.CoinTypes
+	if len(coinTypes) == 0 {
+		coinTypes = []uint32{CoinTypeCosmos, CoinTypeEVM}
+	}
+
+	var out []*KeyAddresses
+	for _, coinType := range coinTypes {
+		if coinType >= hardened {
+			return nil, fmt.Errorf("invalid coin type %d", coinType)
+		}
+		path := []uint32{44 | hardened, coinType | hardened, opts.Account | hardened, 0, opts.Index}
+		priv, err := derivePath(seed, path)
+		if err != nil {
+			return nil, err
+		}
+		a, err := secp256k1Addresses(priv.PubKey())
+		priv.Zero()
+		if err != nil {
+			return nil, err
+		}
+		a.Path = fmt.Sprintf("m/44'/%d'/%d'/0/%d", coinType, opts.Account, opts.Index)
+		a.CoinType = coinType
+		a.Notes = nil
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// MnemonicNotes explains how coin types relate to wallets
+var MnemonicNotes = []string{
+	"Coin type 118 is used by Cosmos wallets and `seid keys add`; coin type 60 by Ethereum wallets such as MetaMask and `seid keys add --coin-type 60`.",
+	"The two coin types give different keys from the same mnemonic: a wallet only shows the addresses of its own coin type.",
+	"Each key has a sei and a 0x address, linked by Sei into one account once the key is used on chain.",
+}
+
+// derivePath derives a BIP-32 secp256k1 private key from a seed
+func derivePath(seed []byte, path []uint32) (*secp256k1.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	var key secp256k1.ModNScalar
+	if overflow := key.SetByteSlice(sum[:32]); overflow || key.IsZero() {
+		return nil, fmt.Errorf("invalid master key, use another mnemonic")
+	}
+	chainCode := sum[32:]
+
+	for _, index := range path {
+		data := make([]byte, 0, 37)
+		if index >= hardened {
+			keyBytes := key.Bytes()
+			data = append(append(data, 0), keyBytes[:]...)
+		} else {
+			data = append(data, secp256k1.NewPrivateKey(&key).PubKey().SerializeCompressed()...)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		var tweak secp256k1.ModNScalar
+		if overflow := tweak.SetByteSlice(sum[:32]); overflow {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+		key.Add(&tweak)
+		if key.IsZero() {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+		chainCode = sum[32:]
+	}
+	return secp256k1.NewPrivateKey(&key), nil
+}
@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/your-org/seictl/internal/binary"
@@ -17,7 +18,6 @@ import (
 	"github.com/your-org/seictl/pkg/types"
 
 	"github.com/rs/zerolog"
-	"gopkg.in/yaml.v3"
 )
 
 // InitOptions defines options for chain initialization
@@ -26,6 +26,14 @@ type InitOptions struct {
 	Moniker       string
 	ChainID       string
 	WithStateSync bool
+	// Role selects a built-in preset layered over NodeConfigs
+	Role string
+	// PrivatePeerIDs are the node IDs a sentry must not gossip
+	PrivatePeerIDs []string
+	// PersistentPeers is an "id@host:port,..." list, e.g. a validator's sentries
+	PersistentPeers string
+	// Overrides set individual keys last, as "app.toml:section.key=value"
+	Overrides []string
 }
 
 // Manager handles chain operations
@@ -77,6 +85,11 @@ func (m *Manager) InitChain(ctx context.Context, env types.Environment, opts Ini
 		chainCfg.ChainID = opts.ChainID
 	}
 
+	// Reject bad role options before downloading anything
+	if _, err := layerRole(m.config.NodeConfigs, opts); err != nil {
+		return err
+	}
+
 	// Only handle binary if not skipped
 	if !opts.SkipBinary {
 		if err := m.binMgr.EnsureBinary(ctx, chainCfg.Version); err != nil {
@@ -113,19 +126,50 @@ func (m *Manager) RestoreSnapshot(ctx context.Context, path string) error {
 }
 
 // StateSync performs state synchronization
-func (m *Manager) StateSync(ctx context.Context, targetHeight int64) error {
-	return m.stateMgr.SyncState(ctx, targetHeight)
+func (m *Manager) StateSync(ctx context.Context, opts state.StateSyncOptions) error {
+	return m.stateMgr.SyncState(ctx, opts)
 }
 
-// StartNode starts the node
+// nodeStopTimeout is how long seid gets to shut down after SIGTERM before it
+// is killed
+var nodeStopTimeout = 2 * time.Minute
+
+// StartNode runs the node until it exits. When ctx is cancelled seid is sent
+// SIGTERM and waited for, so it can flush its databases, and only killed if
+// it has not exited within nodeStopTimeout.
 func (m *Manager) StartNode(ctx context.Context) error {
 	m.logger.Info().Msg("Starting node...")
 
-	cmd := exec.CommandContext(ctx, "seid", "start")
+	cmd := exec.Command("seid", "start", "--home", m.homePath)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start seid: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
 
-	return cmd.Run()
+	select {
+	case err := <-exited:
+		return err
+	case <-ctx.Done():
+	}
+
+	m.logger.Info().Msg("Stopping seid...")
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to signal seid")
+	}
+	select {
+	case err := <-exited:
+		if err != nil {
+			m.logger.Warn().Err(err).Msg("seid exited with an error after SIGTERM")
+		}
+		return nil
+	case <-time.After(nodeStopTimeout):
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("seid did not exit within %s of SIGTERM and was killed", nodeStopTimeout)
+	}
 }
 
 // StopNode stops the node
@@ -158,20 +202,18 @@ func (m *Manager) initChainDir(cfg types.ChainConfig) error {
 }
 
 func (m *Manager) configureNode(cfg types.ChainConfig, opts InitOptions) error {
-	// Use cfg parameter in implementation
 	m.logger.Info().
 		Str("chain_id", cfg.ChainID).
 		Str("moniker", opts.Moniker).
+		Str("role", opts.Role).
 		Msg("Configuring node")
 
-	// Update node configs with options
-	nodeConfigs := m.config.NodeConfigs
-
-	// Create deep copies of the configs to modify
-	configToml := make(map[string]interface{})
-	for k, v := range nodeConfigs.ConfigToml {
-		configToml[k] = v
+	// Layer the role preset over the node config templates
+	templates, err := layerRole(m.config.NodeConfigs, opts)
+	if err != nil {
+		return err
 	}
+	configToml := templates.configToml
 
 	// Set chain-specific configurations
 	configToml["chain_id"] = cfg.ChainID
@@ -189,21 +231,38 @@ func (m *Manager) configureNode(cfg types.ChainConfig, opts InitOptions) error {
 		statesync["enable"] = true
 	}
 
+	// Individual keys given on the command line win over everything else
+	if err := applyOverrides(templates, opts.Overrides); err != nil {
+		return err
+	}
+
 	// Write configs
-	if err := m.writeConfig("app.toml", nodeConfigs.AppToml); err != nil {
+	if err := m.writeConfig("app.toml", substitutePorts(templates.appToml, cfg.Ports)); err != nil {
 		return fmt.Errorf("failed to write app.toml: %w", err)
 	}
 
-	if err := m.writeConfig("config.toml", configToml); err != nil {
+	if err := m.writeConfig("config.toml", substitutePorts(configToml, cfg.Ports)); err != nil {
 		return fmt.Errorf("failed to write config.toml: %w", err)
 	}
 
+	// Checked as written, with any overrides of the SeiDB keys applied
+	if templates.seidb != nil {
+		warnings, err := m.stateMgr.CheckSeiDB(cfg.Version)
+		if err != nil {
+			return fmt.Errorf("failed to configure SeiDB: %w", err)
+		}
+		for _, w := range warnings {
+			m.logger.Warn().Msg(w)
+		}
+	}
+
 	return nil
 }
 
-func (m *Manager) writeConfig(filename string, data interface{}) error {
+// writeConfig renders a config template as TOML into the config directory
+func (m *Manager) writeConfig(filename string, data map[string]interface{}) error {
 	path := filepath.Join(m.configPath, filename)
-	bytes, err := yaml.Marshal(data)
+	bytes, err := encodeTOML(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
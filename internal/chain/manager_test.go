@@ -150,3 +150,34 @@ func TestConfigureNode(t *testing.T) {
 	_, err = os.Stat(appToml)
 	assert.NoError(t, err)
 }
+
+func TestStartNodeStopsGracefully(t *testing.T) {
+	manager, tmpDir, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	// A fake seid that records SIGTERM and exits cleanly
+	bin := filepath.Join(tmpDir, "bin")
+	require.NoError(t, os.MkdirAll(bin, 0755))
+	marker := filepath.Join(tmpDir, "seid")
+	script := "#!/bin/sh\ntrap 'echo stopped > " + marker + ".stopped; exit 0' TERM\n" +
+		"echo started > " + marker + ".started\nwhile true; do sleep 0.05; done\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "seid"), []byte(script), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- manager.StartNode(ctx) }()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(marker + ".started")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartNode did not return after cancel")
+	}
+	assert.FileExists(t, marker+".stopped")
+}
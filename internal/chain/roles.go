@@ -0,0 +1,312 @@
+package chain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/seictl/internal/state"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// Node roles selectable with `seictl init --role`
+const (
+	// RoleValidator signs blocks and only talks to its sentries
+	RoleValidator = "validator"
+	// RoleSentry shields a validator from the public network
+	RoleSentry = "sentry"
+	// RoleRPC serves public RPC, API and state sync snapshots
+	RoleRPC = "rpc"
+	// RoleArchive keeps all history
+	RoleArchive = "archive"
+	// RoleSeed only crawls and hands out peer addresses
+	RoleSeed = "seed"
+)
+
+// rolePreset is layered over NodeConfigs for a role
+type rolePreset struct {
+	description string
+	appToml     map[string]interface{}
+	configToml  map[string]interface{}
+	// seidb adjusts the SeiDB settings, when NodeConfigs has any
+	seidb func(*types.SeiDBConfig)
+}
+
+var rolePresets = map[string]rolePreset{
+	RoleValidator: {
+		description: "pex off, RPC, API and gRPC bound to localhost, EVM RPC off, no state sync snapshots",
+		appToml: map[string]interface{}{
+			"api":        map[string]interface{}{"address": "tcp://127.0.0.1:{ports.api}"},
+			"evm":        map[string]interface{}{"http_enabled": false, "ws_enabled": false},
+			"grpc":       map[string]interface{}{"address": "127.0.0.1:{ports.grpc}"},
+			"grpc-web":   map[string]interface{}{"enable": false},
+			"state-sync": map[string]interface{}{"snapshot-interval": 0},
+		},
+		configToml: map[string]interface{}{
+			"rpc": map[string]interface{}{
+				"laddr":                "tcp://127.0.0.1:{ports.rpc}",
+				"cors_allowed_origins": []interface{}{},
+			},
+			"p2p": map[string]interface{}{"pex": false},
+		},
+	},
+	RoleSentry: {
+		description: "pex on, the validator's node ID kept private and always accepted",
+		appToml: map[string]interface{}{
+			"state-sync": map[string]interface{}{"snapshot-interval": 0},
+		},
+		configToml: map[string]interface{}{
+			"p2p": map[string]interface{}{"pex": true},
+		},
+	},
+	RoleRPC: {
+		description: "public RPC, API, gRPC and EVM RPC, serves state sync snapshots",
+		appToml: map[string]interface{}{
+			"api":        map[string]interface{}{"enable": true, "address": "tcp://0.0.0.0:{ports.api}"},
+			"evm":        map[string]interface{}{"http_enabled": true, "ws_enabled": true},
+			"grpc":       map[string]interface{}{"enable": true, "address": "0.0.0.0:{ports.grpc}"},
+			"state-sync": map[string]interface{}{"snapshot-interval": 2000, "snapshot-keep-recent": 2},
+		},
+		configToml: map[string]interface{}{
+			"rpc": map[string]interface{}{"laddr": "tcp://0.0.0.0:{ports.rpc}"},
+			"p2p": map[string]interface{}{"pex": true},
+		},
+	},
+	RoleArchive: {
+		description: "pruning nothing, all state store history, transactions indexed",
+		appToml: map[string]interface{}{
+			"pruning":    "nothing",
+			"api":        map[string]interface{}{"enable": true},
+			"state-sync": map[string]interface{}{"snapshot-interval": 0},
+		},
+		configToml: map[string]interface{}{
+			"p2p":      map[string]interface{}{"pex": true},
+			"tx_index": map[string]interface{}{"indexer": "kv"},
+		},
+		seidb: func(cfg *types.SeiDBConfig) {
+			// Legacy nodes are already archives through pruning "nothing"
+			if !cfg.StateCommit.Enable {
+				return
+			}
+			cfg.StateStore.Enable = true
+			cfg.StateStore.KeepRecent = 0
+		},
+	},
+	RoleSeed: {
+		description: "seed mode, many inbound peers, API, gRPC and EVM RPC off",
+		appToml: map[string]interface{}{
+			"api":        map[string]interface{}{"enable": false},
+			"evm":        map[string]interface{}{"http_enabled": false, "ws_enabled": false},
+			"grpc":       map[string]interface{}{"enable": false},
+			"grpc-web":   map[string]interface{}{"enable": false},
+			"state-sync": map[string]interface{}{"snapshot-interval": 0},
+		},
+		configToml: map[string]interface{}{
+			"p2p": map[string]interface{}{
+				"seed_mode":             true,
+				"pex":                   true,
+				"max_num_inbound_peers": 1000,
+			},
+		},
+	},
+}
+
+// Roles returns the built-in role names and what each preset changes
+func Roles() map[string]string {
+	roles := make(map[string]string, len(rolePresets))
+	for name, preset := range rolePresets {
+		roles[name] = preset.description
+	}
+	return roles
+}
+
+// RoleNames returns the built-in role names in order
+func RoleNames() []string {
+	names := make([]string, 0, len(rolePresets))
+	for name := range rolePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nodeTemplates are the app.toml and config.toml templates for one node
+type nodeTemplates struct {
+	appToml    map[string]interface{}
+	configToml map[string]interface{}
+	seidb      *types.SeiDBConfig
+}
+
+// layerRole builds the node's templates from NodeConfigs, the role preset and
+// the role's overrides in the seictl config
+func layerRole(base types.NodeConfigs, opts InitOptions) (*nodeTemplates, error) {
+	t := &nodeTemplates{
+		appToml:    mergeMaps(nil, base.AppToml),
+		configToml: mergeMaps(nil, base.ConfigToml),
+	}
+	if base.SeiDB != nil {
+		seidb := *base.SeiDB
+		t.seidb = &seidb
+	}
+	if base.EVM != nil {
+		t.appToml = mergeMaps(t.appToml, map[string]interface{}{"evm": evmSection(*base.EVM)})
+	}
+	if opts.Role == "" {
+		t.renderSeiDB()
+		return t, nil
+	}
+
+	preset, ok := rolePresets[opts.Role]
+	if !ok {
+		return nil, fmt.Errorf("unknown role %q, use one of %s", opts.Role, strings.Join(RoleNames(), ", "))
+	}
+	t.appToml = mergeMaps(t.appToml, preset.appToml)
+	t.configToml = mergeMaps(t.configToml, preset.configToml)
+	if preset.seidb != nil && t.seidb != nil {
+		preset.seidb(t.seidb)
+	}
+	t.renderSeiDB()
+
+	p2p := map[string]interface{}{}
+	if opts.PersistentPeers != "" {
+		p2p["persistent_peers"] = opts.PersistentPeers
+	}
+	switch opts.Role {
+	case RoleSentry:
+		if len(opts.PrivatePeerIDs) == 0 {
+			return nil, fmt.Errorf("a sentry needs the validator's node ID in --private-peer-ids")
+		}
+		ids := strings.Join(opts.PrivatePeerIDs, ",")
+		p2p["private_peer_ids"] = ids
+		p2p["unconditional_peer_ids"] = ids
+	case RoleValidator:
+		if opts.PersistentPeers == "" {
+			return nil, fmt.Errorf("a validator with pex off needs its sentries in --persistent-peers")
+		}
+		// Keep the sentries connected past the peer limits and without backoff
+		p2p["unconditional_peer_ids"] = peerIDs(opts.PersistentPeers)
+	}
+	t.configToml = mergeMaps(t.configToml, map[string]interface{}{"p2p": p2p})
+
+	if role, ok := base.Roles[opts.Role]; ok {
+		t.appToml = mergeMaps(t.appToml, role.AppToml)
+		t.configToml = mergeMaps(t.configToml, role.ConfigToml)
+	}
+	return t, nil
+}
+
+// renderSeiDB writes the SeiDB settings into the app.toml template, so that
+// role and command line overrides of their keys are layered on top
+func (t *nodeTemplates) renderSeiDB() {
+	if t.seidb != nil {
+		t.appToml = mergeMaps(t.appToml, state.SeiDBSections(*t.seidb))
+	}
+}
+
+// evmSection renders the [evm] section of app.toml; the ports are filled in
+// from the environment
+func evmSection(cfg types.EVMConfig) map[string]interface{} {
+	evm := map[string]interface{}{
+		"http_enabled": cfg.HTTPEnabled,
+		"http_port":    "{ports.evm_rpc}",
+		"ws_enabled":   cfg.WSEnabled,
+		"ws_port":      "{ports.evm_ws}",
+	}
+	if cfg.CORSOrigins != "" {
+		evm["cors_origins"] = cfg.CORSOrigins
+	}
+	if cfg.WSOrigins != "" {
+		evm["ws_origins"] = cfg.WSOrigins
+	}
+	if cfg.SimulationGasLimit != 0 {
+		evm["simulation_gas_limit"] = cfg.SimulationGasLimit
+	}
+	if cfg.MaxBlocksForLog != 0 {
+		evm["max_blocks_for_log"] = cfg.MaxBlocksForLog
+	}
+	if cfg.MaxSubscriptionsNewHead != 0 {
+		evm["max_subscriptions_new_head"] = cfg.MaxSubscriptionsNewHead
+	}
+	return evm
+}
+
+// applyOverrides applies "app.toml:api.enable=false" style overrides
+func applyOverrides(t *nodeTemplates, overrides []string) error {
+	for _, o := range overrides {
+		file, rest, ok := strings.Cut(o, ":")
+		key, value, hasValue := strings.Cut(rest, "=")
+		if !ok || !hasValue || key == "" {
+			return fmt.Errorf("invalid override %q, use app.toml:section.key=value", o)
+		}
+
+		var target map[string]interface{}
+		switch strings.TrimSuffix(file, ".toml") {
+		case "app":
+			target = t.appToml
+		case "config":
+			target = t.configToml
+		default:
+			return fmt.Errorf("invalid override %q: file must be app.toml or config.toml", o)
+		}
+
+		path := strings.Split(key, ".")
+		for _, p := range path[:len(path)-1] {
+			next, ok := target[p].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				target[p] = next
+			}
+			target = next
+		}
+		target[path[len(path)-1]] = parseOverrideValue(value)
+	}
+	return nil
+}
+
+// parseOverrideValue types an override value; quote it to force a string
+func parseOverrideValue(s string) interface{} {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	if s == "true" || s == "false" {
+		return s == "true"
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// mergeMaps returns a deep copy of dst with src layered on top
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(dst)+len(src))
+	for k, v := range dst {
+		if m, ok := v.(map[string]interface{}); ok {
+			v = mergeMaps(nil, m)
+		}
+		out[k] = v
+	}
+	for k, v := range src {
+		if m, ok := v.(map[string]interface{}); ok {
+			existing, _ := out[k].(map[string]interface{})
+			v = mergeMaps(existing, m)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// peerIDs extracts the node IDs from an "id@host:port,..." peer list
+func peerIDs(peers string) string {
+	var ids []string
+	for _, peer := range strings.Split(peers, ",") {
+		if id, _, ok := strings.Cut(strings.TrimSpace(peer), "@"); ok && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return strings.Join(ids, ",")
+}
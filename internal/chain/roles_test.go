@@ -0,0 +1,237 @@
+package chain
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+func testNodeConfigs() types.NodeConfigs {
+	return types.NodeConfigs{
+		AppToml: map[string]interface{}{
+			"pruning": "custom",
+			"api":     map[string]interface{}{"enable": true, "address": "tcp://0.0.0.0:{ports.api}"},
+		},
+		ConfigToml: map[string]interface{}{
+			"p2p": map[string]interface{}{"pex": true, "max_num_inbound_peers": 40},
+			"rpc": map[string]interface{}{"laddr": "tcp://0.0.0.0:{ports.rpc}"},
+		},
+	}
+}
+
+func section(t *testing.T, m map[string]interface{}, name string) map[string]interface{} {
+	s, ok := m[name].(map[string]interface{})
+	require.True(t, ok, "missing section %s", name)
+	return s
+}
+
+func TestLayerRolePresets(t *testing.T) {
+	base := testNodeConfigs()
+
+	tmpl, err := layerRole(base, InitOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, base.AppToml, tmpl.appToml)
+
+	tmpl, err = layerRole(base, InitOptions{Role: RoleValidator, PersistentPeers: "aaa@10.0.0.1:26656, bbb@10.0.0.2:26656"})
+	require.NoError(t, err)
+	p2p := section(t, tmpl.configToml, "p2p")
+	assert.Equal(t, false, p2p["pex"])
+	assert.Equal(t, 40, p2p["max_num_inbound_peers"])
+	assert.Equal(t, "aaa,bbb", p2p["unconditional_peer_ids"])
+	assert.Equal(t, "tcp://127.0.0.1:{ports.rpc}", section(t, tmpl.configToml, "rpc")["laddr"])
+	assert.Equal(t, "tcp://127.0.0.1:{ports.api}", section(t, tmpl.appToml, "api")["address"])
+	// The preset must not leak into NodeConfigs
+	assert.Equal(t, true, base.ConfigToml["p2p"].(map[string]interface{})["pex"])
+
+	tmpl, err = layerRole(base, InitOptions{Role: RoleSentry, PrivatePeerIDs: []string{"aaa"}})
+	require.NoError(t, err)
+	p2p = section(t, tmpl.configToml, "p2p")
+	assert.Equal(t, "aaa", p2p["private_peer_ids"])
+	assert.Equal(t, "aaa", p2p["unconditional_peer_ids"])
+
+	tmpl, err = layerRole(base, InitOptions{Role: RoleSeed})
+	require.NoError(t, err)
+	assert.Equal(t, true, section(t, tmpl.configToml, "p2p")["seed_mode"])
+	assert.Equal(t, false, section(t, tmpl.appToml, "api")["enable"])
+}
+
+func TestLayerRoleArchiveSeiDB(t *testing.T) {
+	base := testNodeConfigs()
+
+	tmpl, err := layerRole(base, InitOptions{Role: RoleArchive})
+	require.NoError(t, err)
+	assert.Equal(t, "nothing", tmpl.appToml["pruning"])
+	assert.Nil(t, tmpl.seidb)
+
+	base.SeiDB = &types.SeiDBConfig{
+		StateCommit: types.StateCommitConfig{Enable: true},
+		StateStore:  types.StateStoreConfig{Backend: "pebbledb", KeepRecent: 100000},
+	}
+	tmpl, err = layerRole(base, InitOptions{Role: RoleArchive})
+	require.NoError(t, err)
+	require.NotNil(t, tmpl.seidb)
+	assert.True(t, tmpl.seidb.StateStore.Enable)
+	assert.Equal(t, int64(0), tmpl.seidb.StateStore.KeepRecent)
+	assert.Equal(t, int64(100000), base.SeiDB.StateStore.KeepRecent)
+
+	// Without state commit there is no state store to keep history in
+	base.SeiDB.StateCommit.Enable = false
+	tmpl, err = layerRole(base, InitOptions{Role: RoleArchive})
+	require.NoError(t, err)
+	assert.False(t, tmpl.seidb.StateStore.Enable)
+}
+
+func TestLayerRoleErrors(t *testing.T) {
+	base := testNodeConfigs()
+
+	_, err := layerRole(base, InitOptions{Role: "miner"})
+	assert.ErrorContains(t, err, "unknown role")
+
+	_, err = layerRole(base, InitOptions{Role: RoleSentry})
+	assert.ErrorContains(t, err, "--private-peer-ids")
+
+	_, err = layerRole(base, InitOptions{Role: RoleValidator})
+	assert.ErrorContains(t, err, "--persistent-peers")
+}
+
+func TestRoleOverrides(t *testing.T) {
+	base := testNodeConfigs()
+	base.Roles = map[string]types.RoleConfig{
+		RoleRPC: {
+			AppToml: map[string]interface{}{
+				"state-sync": map[string]interface{}{"snapshot-interval": 500},
+			},
+		},
+	}
+
+	tmpl, err := layerRole(base, InitOptions{Role: RoleRPC})
+	require.NoError(t, err)
+	stateSync := section(t, tmpl.appToml, "state-sync")
+	assert.Equal(t, 500, stateSync["snapshot-interval"])
+	assert.Equal(t, 2, stateSync["snapshot-keep-recent"])
+
+	require.NoError(t, applyOverrides(tmpl, []string{
+		"app.toml:api.enable=false",
+		"config.toml:p2p.max_num_inbound_peers=80",
+		`config:moniker="123"`,
+		"app.toml:evm.http_address=0.0.0.0:8545",
+	}))
+	assert.Equal(t, false, section(t, tmpl.appToml, "api")["enable"])
+	assert.Equal(t, int64(80), section(t, tmpl.configToml, "p2p")["max_num_inbound_peers"])
+	assert.Equal(t, "123", tmpl.configToml["moniker"])
+	assert.Equal(t, "0.0.0.0:8545", section(t, tmpl.appToml, "evm")["http_address"])
+
+	for _, bad := range []string{"api.enable=false", "app.toml:api.enable", "genesis.json:a=b"} {
+		assert.Error(t, applyOverrides(tmpl, []string{bad}), bad)
+	}
+}
+
+func TestEncodeTOML(t *testing.T) {
+	data, err := encodeTOML(substitutePorts(map[string]interface{}{
+		"moniker": "node",
+		"rpc": map[string]interface{}{
+			"laddr":                "tcp://127.0.0.1:{ports.rpc}",
+			"cors_allowed_origins": []interface{}{"*"},
+		},
+		"p2p": map[string]interface{}{"pex": false, "max_num_inbound_peers": 40},
+	}, &types.NodePorts{RPC: 36657}))
+	require.NoError(t, err)
+
+	assert.Equal(t, `moniker = "node"
+
+[p2p]
+max_num_inbound_peers = 40
+pex = false
+
+[rpc]
+cors_allowed_origins = ["*"]
+laddr = "tcp://127.0.0.1:36657"
+`, string(data))
+
+	_, err = encodeTOML(map[string]interface{}{"bad": struct{}{}})
+	assert.Error(t, err)
+}
+
+func TestInitChainWithRole(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	manager.config.NodeConfigs = testNodeConfigs()
+	require.NoError(t, manager.initChainDir(manager.config.Environments["testnet"]))
+
+	err := manager.configureNode(manager.config.Environments["testnet"], InitOptions{
+		Role:            RoleValidator,
+		PersistentPeers: "aaa@10.0.0.1:26656",
+		Overrides:       []string{"config.toml:p2p.max_num_inbound_peers=5"},
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(manager.configPath, "config.toml"))
+	require.NoError(t, err)
+	content := string(data)
+	assert.Contains(t, content, "chain_id = \"test-1\"")
+	assert.Contains(t, content, "max_num_inbound_peers = 5")
+	assert.Contains(t, content, "pex = false")
+	assert.Contains(t, content, "laddr = \"tcp://127.0.0.1:26657\"")
+}
+
+func TestInitChainSeiDBOverrides(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	manager.config.NodeConfigs = testNodeConfigs()
+	manager.config.NodeConfigs.SeiDB = &types.SeiDBConfig{
+		StateCommit: types.StateCommitConfig{Enable: true, SnapshotInterval: 10000},
+		StateStore:  types.StateStoreConfig{Enable: true, Backend: "pebbledb", KeepRecent: 100000, PruneIntervalSeconds: 600},
+	}
+	env := manager.config.Environments["testnet"]
+	env.Version = "v5.9.0"
+	require.NoError(t, manager.initChainDir(env))
+
+	err := manager.configureNode(env, InitOptions{
+		Overrides: []string{"app.toml:state-store.ss-keep-recent=50"},
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(manager.configPath, "app.toml"))
+	require.NoError(t, err)
+	content := string(data)
+	assert.Contains(t, content, "[state-commit]")
+	assert.Contains(t, content, "sc-enable = true")
+	assert.Contains(t, content, "ss-keep-recent = 50")
+	assert.NotContains(t, content, "ss-keep-recent = 100000")
+
+	// The settings are validated as written, overrides included
+	err = manager.configureNode(env, InitOptions{
+		Overrides: []string{`app.toml:state-store.ss-backend="leveldb"`},
+	})
+	assert.ErrorContains(t, err, "unknown state store backend")
+}
+
+func TestEVMSection(t *testing.T) {
+	base := testNodeConfigs()
+	base.EVM = &types.EVMConfig{HTTPEnabled: true, WSEnabled: true, CORSOrigins: "*", MaxBlocksForLog: 2000}
+
+	tmpl, err := layerRole(base, InitOptions{})
+	require.NoError(t, err)
+	data, err := encodeTOML(substitutePorts(tmpl.appToml, &types.NodePorts{EVMRPC: 18545}))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `[evm]
+cors_origins = "*"
+http_enabled = true
+http_port = 18545
+max_blocks_for_log = 2000
+ws_enabled = true
+ws_port = 8546
+`)
+
+	// Validators keep the EVM RPC closed
+	tmpl, err = layerRole(base, InitOptions{Role: RoleValidator, PersistentPeers: "aaa@10.0.0.1:26656"})
+	require.NoError(t, err)
+	evm := section(t, tmpl.appToml, "evm")
+	assert.Equal(t, false, evm["http_enabled"])
+	assert.Equal(t, false, evm["ws_enabled"])
+	assert.Equal(t, "*", evm["cors_origins"])
+}
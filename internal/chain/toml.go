@@ -0,0 +1,185 @@
+package chain
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// Ports used for {ports.*} placeholders when an environment does not set them
+var defaultPorts = types.NodePorts{
+	RPC:     26657,
+	P2P:     26656,
+	API:     1317,
+	GRPC:    9090,
+	GRPCWeb: 9091,
+	PProf:   6060,
+	EVMRPC:  8545,
+	EVMWS:   8546,
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// encodeTOML renders a config template as TOML. Scalars come first, followed
+// by one table per nested map, both in key order.
+func encodeTOML(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeTOMLTable(&buf, nil, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeTOMLTable(buf *bytes.Buffer, path []string, table map[string]interface{}) error {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tables []string
+	for _, k := range keys {
+		v := table[k]
+		if v == nil {
+			continue
+		}
+		if _, ok := v.(map[string]interface{}); ok {
+			tables = append(tables, k)
+			continue
+		}
+		value, err := tomlValue(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(append(path, k), "."), err)
+		}
+		fmt.Fprintf(buf, "%s = %s\n", tomlKey(k), value)
+	}
+
+	for _, k := range tables {
+		sub := append(append([]string{}, path...), k)
+		quoted := make([]string, len(sub))
+		for i, p := range sub {
+			quoted[i] = tomlKey(p)
+		}
+		fmt.Fprintf(buf, "\n[%s]\n", strings.Join(quoted, "."))
+		if err := writeTOMLTable(buf, sub, table[k].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tomlKey(k string) string {
+	if bareKey.MatchString(k) {
+		return k
+	}
+	return strconv.Quote(k)
+}
+
+func tomlValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return tomlValue(items)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	default:
+		return "", fmt.Errorf("unsupported value %v (%T)", v, v)
+	}
+}
+
+// resolvePorts fills the ports an environment does not set with the defaults
+func resolvePorts(ports *types.NodePorts) types.NodePorts {
+	p := defaultPorts
+	if ports == nil {
+		return p
+	}
+	for _, pair := range []struct {
+		dst *int
+		src int
+	}{
+		{&p.RPC, ports.RPC}, {&p.P2P, ports.P2P}, {&p.API, ports.API},
+		{&p.GRPC, ports.GRPC}, {&p.GRPCWeb, ports.GRPCWeb}, {&p.PProf, ports.PProf},
+		{&p.EVMRPC, ports.EVMRPC}, {&p.EVMWS, ports.EVMWS},
+	} {
+		if pair.src != 0 {
+			*pair.dst = pair.src
+		}
+	}
+	return p
+}
+
+// substitutePorts replaces {ports.rpc} style placeholders in every string of
+// the template with the environment's ports. A string that is only a
+// placeholder becomes a number, for keys such as the [evm] http_port.
+func substitutePorts(data map[string]interface{}, ports *types.NodePorts) map[string]interface{} {
+	p := resolvePorts(ports)
+	values := map[string]int{
+		"{ports.rpc}":      p.RPC,
+		"{ports.p2p}":      p.P2P,
+		"{ports.api}":      p.API,
+		"{ports.grpc}":     p.GRPC,
+		"{ports.grpc_web}": p.GRPCWeb,
+		"{ports.pprof}":    p.PProf,
+		"{ports.evm_rpc}":  p.EVMRPC,
+		"{ports.evm_ws}":   p.EVMWS,
+	}
+	pairs := make([]string, 0, 2*len(values))
+	for placeholder, port := range values {
+		pairs = append(pairs, placeholder, strconv.Itoa(port))
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	var walk func(v interface{}) interface{}
+	walk = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case string:
+			if port, ok := values[v]; ok {
+				return port
+			}
+			return replacer.Replace(v)
+		case map[string]interface{}:
+			out := make(map[string]interface{}, len(v))
+			for k, item := range v {
+				out[k] = walk(item)
+			}
+			return out
+		case []interface{}:
+			out := make([]interface{}, len(v))
+			for i, item := range v {
+				out[i] = walk(item)
+			}
+			return out
+		default:
+			return v
+		}
+	}
+	return walk(data).(map[string]interface{})
+}
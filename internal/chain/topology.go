@@ -0,0 +1,388 @@
+package chain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/seictl/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+const nodeKeyType = "tendermint/PrivKeyEd25519"
+
+// nodeKeyFile is the format of Tendermint's config/node_key.json
+type nodeKeyFile struct {
+	PrivKey struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"priv_key"`
+}
+
+// NodeID returns the node ID of a node_key.json: the hex encoded first 20
+// bytes of the SHA-256 of its ed25519 public key
+func NodeID(keyPath string) (string, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read node key: %w", err)
+	}
+	var key nodeKeyFile
+	if err := json.Unmarshal(data, &key); err != nil {
+		return "", fmt.Errorf("failed to parse node key %s: %w", keyPath, err)
+	}
+	if key.PrivKey.Type != nodeKeyType {
+		return "", fmt.Errorf("node key %s has type %q, expected %s", keyPath, key.PrivKey.Type, nodeKeyType)
+	}
+	priv, err := base64.StdEncoding.DecodeString(key.PrivKey.Value)
+	if err != nil || len(priv) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("node key %s is not a valid ed25519 private key", keyPath)
+	}
+	return nodeIDFromPubKey(ed25519.PrivateKey(priv).Public().(ed25519.PublicKey)), nil
+}
+
+func nodeIDFromPubKey(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:20])
+}
+
+// generateNodeKey writes a new node_key.json to keyPath
+func generateNodeKey(keyPath string) error {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate node key: %w", err)
+	}
+	var key nodeKeyFile
+	key.PrivKey.Type = nodeKeyType
+	key.PrivKey.Value = base64.StdEncoding.EncodeToString(priv)
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal node key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return fmt.Errorf("failed to create node key directory: %w", err)
+	}
+	if err := os.WriteFile(keyPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write node key: %w", err)
+	}
+	return nil
+}
+
+// RenderOptions controls how a topology is rendered
+type RenderOptions struct {
+	// GenerateKeys creates missing node keys instead of failing
+	GenerateKeys bool
+}
+
+// TopologyHost is the rendered configuration of one host
+type TopologyHost struct {
+	Name            string `json:"name"`
+	Role            string `json:"role"`
+	NodeID          string `json:"node_id"`
+	Address         string `json:"address"`
+	ExternalAddress string `json:"external_address,omitempty"`
+	// KeyPath is the node_key.json the ID was computed from
+	KeyPath      string `json:"node_key"`
+	GeneratedKey bool   `json:"generated_key,omitempty"`
+	// P2P is the [p2p] section of the host's config.toml
+	P2P map[string]interface{} `json:"p2p"`
+}
+
+// Peer returns the host as an "id@host:port" peer
+func (h *TopologyHost) Peer() string {
+	return h.NodeID + "@" + h.Address
+}
+
+// TopologyBundle is a rendered topology, one entry per host
+type TopologyBundle struct {
+	Hosts []*TopologyHost `json:"hosts"`
+	// Warnings are isolation concerns that do not stop the render
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// LoadTopology reads a topology file
+func LoadTopology(path string) (*types.Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topology: %w", err)
+	}
+	var topo types.Topology
+	if err := yaml.Unmarshal(data, &topo); err != nil {
+		return nil, fmt.Errorf("failed to parse topology: %w", err)
+	}
+	return &topo, nil
+}
+
+// RenderTopology computes the node IDs of the topology in path and renders
+// each host's p2p configuration. It fails unless the validator can only be
+// reached through its sentries.
+func RenderTopology(path string, opts RenderOptions) (*TopologyBundle, error) {
+	topo, err := LoadTopology(path)
+	if err != nil {
+		return nil, err
+	}
+	baseDir := filepath.Dir(path)
+
+	if len(topo.Sentries) == 0 {
+		return nil, fmt.Errorf("the topology has no sentries")
+	}
+
+	seen := map[string]bool{}
+	resolve := func(node types.TopologyNode, role string) (*TopologyHost, error) {
+		if node.Name == "" {
+			return nil, fmt.Errorf("every %s needs a name", role)
+		}
+		if seen[node.Name] {
+			return nil, fmt.Errorf("host name %q is used twice", node.Name)
+		}
+		seen[node.Name] = true
+
+		address, err := normalizeAddress(node.Address)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", node.Name, err)
+		}
+		host := &TopologyHost{Name: node.Name, Role: role, Address: address}
+		if node.ExternalAddress != "" {
+			if host.ExternalAddress, err = normalizeAddress(node.ExternalAddress); err != nil {
+				return nil, fmt.Errorf("%s: external address: %w", node.Name, err)
+			}
+		}
+
+		host.KeyPath = node.NodeKey
+		if host.KeyPath == "" {
+			host.KeyPath = filepath.Join("keys", node.Name, "node_key.json")
+		}
+		if !filepath.IsAbs(host.KeyPath) {
+			host.KeyPath = filepath.Join(baseDir, host.KeyPath)
+		}
+		if _, err := os.Stat(host.KeyPath); os.IsNotExist(err) {
+			if !opts.GenerateKeys {
+				return nil, fmt.Errorf("%s: node key %s not found, pass --generate-keys to create it", node.Name, host.KeyPath)
+			}
+			if err := generateNodeKey(host.KeyPath); err != nil {
+				return nil, fmt.Errorf("%s: %w", node.Name, err)
+			}
+			host.GeneratedKey = true
+		}
+		if host.NodeID, err = NodeID(host.KeyPath); err != nil {
+			return nil, fmt.Errorf("%s: %w", node.Name, err)
+		}
+		return host, nil
+	}
+
+	validator, err := resolve(topo.Validator, RoleValidator)
+	if err != nil {
+		return nil, err
+	}
+	if validator.ExternalAddress != "" {
+		return nil, fmt.Errorf("%s: a validator must not advertise an external address", validator.Name)
+	}
+	bundle := &TopologyBundle{Hosts: []*TopologyHost{validator}}
+	var sentries []*TopologyHost
+	for _, node := range topo.Sentries {
+		sentry, err := resolve(node, RoleSentry)
+		if err != nil {
+			return nil, err
+		}
+		sentries = append(sentries, sentry)
+		bundle.Hosts = append(bundle.Hosts, sentry)
+	}
+
+	// The validator only listens on its private address and dials its sentries
+	sentryPeers := make([]string, len(sentries))
+	sentryIDs := make([]string, len(sentries))
+	for i, s := range sentries {
+		sentryPeers[i] = s.Peer()
+		sentryIDs[i] = s.NodeID
+	}
+	validator.P2P = map[string]interface{}{
+		"laddr":                  "tcp://" + validator.Address,
+		"external_address":       "",
+		"seeds":                  "",
+		"persistent_peers":       strings.Join(sentryPeers, ","),
+		"unconditional_peer_ids": strings.Join(sentryIDs, ","),
+		"private_peer_ids":       "",
+		"pex":                    false,
+		"addr_book_strict":       false,
+	}
+
+	// Sentries keep the validator private and always accept it
+	for _, s := range sentries {
+		_, port, _ := net.SplitHostPort(s.Address)
+		peers := append([]string{validator.Peer()}, topo.PersistentPeers...)
+		s.P2P = map[string]interface{}{
+			"laddr":                  "tcp://0.0.0.0:" + port,
+			"external_address":       s.ExternalAddress,
+			"seeds":                  strings.Join(topo.Seeds, ","),
+			"persistent_peers":       strings.Join(peers, ","),
+			"unconditional_peer_ids": validator.NodeID,
+			"private_peer_ids":       validator.NodeID,
+			"pex":                    true,
+			"addr_book_strict":       false,
+		}
+	}
+
+	warnings, err := checkIsolation(bundle, append(append([]string{}, topo.Seeds...), topo.PersistentPeers...))
+	if err != nil {
+		return nil, err
+	}
+	bundle.Warnings = warnings
+	return bundle, nil
+}
+
+// checkIsolation verifies the rendered configuration only lets the validator
+// reach, and be reached by, its sentries. publicPeers are the peers given to
+// the sentries.
+func checkIsolation(bundle *TopologyBundle, publicPeers []string) ([]string, error) {
+	var validator *TopologyHost
+	sentries := map[string]*TopologyHost{}
+	ids := map[string]string{}
+	for _, h := range bundle.Hosts {
+		if other, ok := ids[h.NodeID]; ok {
+			return nil, fmt.Errorf("%s and %s share node ID %s", other, h.Name, h.NodeID)
+		}
+		ids[h.NodeID] = h.Name
+		if h.Role == RoleValidator {
+			validator = h
+		} else {
+			sentries[h.NodeID] = h
+		}
+	}
+	if validator == nil {
+		return nil, fmt.Errorf("the topology has no validator")
+	}
+
+	var warnings []string
+	p2p := validator.P2P
+	if pex, _ := p2p["pex"].(bool); pex {
+		return nil, fmt.Errorf("%s: pex must be off, or the validator's address is gossiped", validator.Name)
+	}
+	if p2p["seeds"] != "" {
+		return nil, fmt.Errorf("%s: a validator must not dial seeds", validator.Name)
+	}
+	if p2p["external_address"] != "" {
+		return nil, fmt.Errorf("%s: a validator must not advertise an external address", validator.Name)
+	}
+	connected := map[string]bool{}
+	for _, id := range strings.Split(peerIDs(fmt.Sprint(p2p["persistent_peers"])), ",") {
+		if id == "" {
+			continue
+		}
+		if _, ok := sentries[id]; !ok {
+			return nil, fmt.Errorf("%s: persistent peer %s is not one of its sentries", validator.Name, id)
+		}
+		connected[id] = true
+	}
+	for _, id := range strings.Split(fmt.Sprint(p2p["unconditional_peer_ids"]), ",") {
+		if _, ok := sentries[id]; !ok && id != "" {
+			return nil, fmt.Errorf("%s: unconditional peer %s is not one of its sentries", validator.Name, id)
+		}
+	}
+
+	host, _, _ := net.SplitHostPort(validator.Address)
+	if ip := net.ParseIP(host); ip == nil {
+		warnings = append(warnings, fmt.Sprintf("%s: cannot tell whether %s is a private address; make sure it is not reachable from the internet", validator.Name, host))
+	} else if !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() {
+		return nil, fmt.Errorf("%s: %s is a public address; the validator must listen on a private network", validator.Name, host)
+	}
+
+	for _, id := range strings.Split(peerIDs(strings.Join(publicPeers, ",")), ",") {
+		if id == validator.NodeID {
+			return nil, fmt.Errorf("the validator %s is listed as a public peer", validator.Name)
+		}
+	}
+
+	for _, s := range bundle.Hosts {
+		if s.Role != RoleSentry {
+			continue
+		}
+		private := strings.Split(fmt.Sprint(s.P2P["private_peer_ids"]), ",")
+		if !containsID(private, validator.NodeID) {
+			return nil, fmt.Errorf("%s: the validator's node ID must be in private_peer_ids, or it is gossiped", s.Name)
+		}
+		if s.ExternalAddress == validator.Address {
+			return nil, fmt.Errorf("%s: advertises the validator's address", s.Name)
+		}
+		if !connected[s.NodeID] {
+			warnings = append(warnings, fmt.Sprintf("%s: the validator does not connect to this sentry", s.Name))
+		}
+		if s.ExternalAddress == "" {
+			warnings = append(warnings, fmt.Sprintf("%s: no external_address, so public peers cannot dial it back", s.Name))
+		}
+	}
+	if len(sentries) == 1 {
+		warnings = append(warnings, "a single sentry is a single point of failure; the validator stops signing when it goes down")
+	}
+	return warnings, nil
+}
+
+// Write saves the bundle to dir: one directory per host holding
+// config/p2p.toml and config/node_key.json, and topology.json with the
+// node IDs of all hosts
+func (b *TopologyBundle) Write(dir string) error {
+	for _, h := range b.Hosts {
+		configDir := filepath.Join(dir, h.Name, "config")
+		if err := os.MkdirAll(configDir, 0700); err != nil {
+			return fmt.Errorf("failed to create %s: %w", configDir, err)
+		}
+		data, err := encodeTOML(map[string]interface{}{"p2p": h.P2P})
+		if err != nil {
+			return fmt.Errorf("failed to render p2p section of %s: %w", h.Name, err)
+		}
+		header := fmt.Sprintf("# [p2p] section of config.toml for %s (%s, node ID %s)\n", h.Name, h.Role, h.NodeID)
+		if err := os.WriteFile(filepath.Join(configDir, "p2p.toml"), append([]byte(header), data...), 0644); err != nil {
+			return fmt.Errorf("failed to write p2p.toml of %s: %w", h.Name, err)
+		}
+		key, err := os.ReadFile(h.KeyPath)
+		if err != nil {
+			return fmt.Errorf("failed to read node key of %s: %w", h.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(configDir, "node_key.json"), key, 0600); err != nil {
+			return fmt.Errorf("failed to write node key of %s: %w", h.Name, err)
+		}
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal topology: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "topology.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write topology.json: %w", err)
+	}
+	return nil
+}
+
+func containsID(ids []string, id string) bool {
+	for _, i := range ids {
+		if strings.TrimSpace(i) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAddress checks a host[:port] address, adding the default p2p port
+func normalizeAddress(address string) (string, error) {
+	if address == "" {
+		return "", fmt.Errorf("address is required")
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, strconv.Itoa(defaultPorts.P2P)
+	}
+	if host == "" || strings.Contains(host, "@") {
+		return "", fmt.Errorf("invalid address %q, use host:port", address)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return "", fmt.Errorf("invalid port in address %q", address)
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,148 @@
+package chain
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTopology = `validator:
+  name: validator
+  address: 10.0.0.10
+sentries:
+  - name: sentry-1
+    address: 10.0.0.11:26656
+    external_address: 203.0.113.11:26656
+  - name: sentry-2
+    address: 10.0.0.12:26656
+    external_address: 203.0.113.12
+seeds: ["aaaa@seed.example.com:26656"]
+`
+
+func writeTopology(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "topology.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestNodeID(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "node_key.json")
+	priv := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	require.NoError(t, os.WriteFile(path, []byte(`{"priv_key":{"type":"tendermint/PrivKeyEd25519","value":"`+
+		"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA7aie8zrakLWKjqNAqbw1zZTIVdx3iQ6Y6wEihi1naKQ=="+`"}}`), 0600))
+
+	id, err := NodeID(path)
+	require.NoError(t, err)
+	assert.Equal(t, nodeIDFromPubKey(priv.Public().(ed25519.PublicKey)), id)
+	assert.Len(t, id, 40)
+
+	require.NoError(t, generateNodeKey(filepath.Join(dir, "new", "node_key.json")))
+	other, err := NodeID(filepath.Join(dir, "new", "node_key.json"))
+	require.NoError(t, err)
+	assert.NotEqual(t, id, other)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"priv_key":{"type":"tendermint/PrivKeySecp256k1","value":"AA=="}}`), 0600))
+	_, err = NodeID(path)
+	assert.ErrorContains(t, err, "type")
+}
+
+func TestRenderTopology(t *testing.T) {
+	path := writeTopology(t, testTopology)
+
+	_, err := RenderTopology(path, RenderOptions{})
+	assert.ErrorContains(t, err, "--generate-keys")
+
+	bundle, err := RenderTopology(path, RenderOptions{GenerateKeys: true})
+	require.NoError(t, err)
+	require.Len(t, bundle.Hosts, 3)
+	assert.Empty(t, bundle.Warnings)
+
+	validator, s1, s2 := bundle.Hosts[0], bundle.Hosts[1], bundle.Hosts[2]
+	assert.True(t, validator.GeneratedKey)
+	assert.Equal(t, "10.0.0.10:26656", validator.Address)
+	assert.Equal(t, false, validator.P2P["pex"])
+	assert.Equal(t, "tcp://10.0.0.10:26656", validator.P2P["laddr"])
+	assert.Equal(t, s1.Peer()+","+s2.Peer(), validator.P2P["persistent_peers"])
+	assert.Equal(t, s1.NodeID+","+s2.NodeID, validator.P2P["unconditional_peer_ids"])
+
+	assert.Equal(t, validator.NodeID, s1.P2P["private_peer_ids"])
+	assert.Equal(t, validator.NodeID, s1.P2P["unconditional_peer_ids"])
+	assert.Equal(t, validator.Peer(), s1.P2P["persistent_peers"])
+	assert.Equal(t, "203.0.113.12:26656", s2.P2P["external_address"])
+	assert.Equal(t, "aaaa@seed.example.com:26656", s2.P2P["seeds"])
+
+	// Existing keys are reused
+	again, err := RenderTopology(path, RenderOptions{GenerateKeys: true})
+	require.NoError(t, err)
+	assert.Equal(t, validator.NodeID, again.Hosts[0].NodeID)
+	assert.False(t, again.Hosts[0].GeneratedKey)
+
+	out := t.TempDir()
+	require.NoError(t, bundle.Write(out))
+	data, err := os.ReadFile(filepath.Join(out, "sentry-1", "config", "p2p.toml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "[p2p]\n")
+	assert.Contains(t, string(data), `private_peer_ids = "`+validator.NodeID+`"`)
+	info, err := os.Stat(filepath.Join(out, "validator", "config", "node_key.json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	id, err := NodeID(filepath.Join(out, "validator", "config", "node_key.json"))
+	require.NoError(t, err)
+	assert.Equal(t, validator.NodeID, id)
+	assert.FileExists(t, filepath.Join(out, "topology.json"))
+}
+
+func TestRenderTopologyIsolation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		topology string
+		err      string
+	}{
+		"public validator": {
+			topology: "validator: {name: v, address: 198.51.100.1}\nsentries: [{name: s, address: 10.0.0.2}]\n",
+			err:      "public address",
+		},
+		"advertised validator": {
+			topology: "validator: {name: v, address: 10.0.0.1, external_address: 198.51.100.1}\nsentries: [{name: s, address: 10.0.0.2}]\n",
+			err:      "external address",
+		},
+		"no sentries": {
+			topology: "validator: {name: v, address: 10.0.0.1}\n",
+			err:      "no sentries",
+		},
+		"duplicate name": {
+			topology: "validator: {name: v, address: 10.0.0.1}\nsentries: [{name: v, address: 10.0.0.2}]\n",
+			err:      "used twice",
+		},
+		"shared key": {
+			topology: "validator: {name: v, address: 10.0.0.1}\nsentries: [{name: s, node_key: keys/v/node_key.json, address: 10.0.0.2}]\n",
+			err:      "share node ID",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := RenderTopology(writeTopology(t, tc.topology), RenderOptions{GenerateKeys: true})
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	// A hostname cannot be checked, and one sentry has no redundancy
+	bundle, err := RenderTopology(writeTopology(t, "validator: {name: v, address: validator.internal}\nsentries: [{name: s, address: 10.0.0.2, external_address: 203.0.113.2}]\n"), RenderOptions{GenerateKeys: true})
+	require.NoError(t, err)
+	assert.Len(t, bundle.Warnings, 2)
+	validatorID := bundle.Hosts[0].NodeID
+
+	_, err = checkIsolation(bundle, []string{validatorID + "@validator.internal:26656"})
+	assert.ErrorContains(t, err, "public peer")
+
+	bundle.Hosts[1].P2P["private_peer_ids"] = ""
+	_, err = checkIsolation(bundle, nil)
+	assert.ErrorContains(t, err, "private_peer_ids")
+
+	bundle.Hosts[0].P2P["pex"] = true
+	_, err = checkIsolation(bundle, nil)
+	assert.ErrorContains(t, err, "pex")
+}
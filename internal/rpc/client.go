@@ -0,0 +1,279 @@
+// Package rpc is a typed client for the Tendermint/CometBFT RPC served by seid
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Style selects how requests are encoded
+type Style int
+
+const (
+	// URIStyle sends GET requests with parameters in the query string
+	URIStyle Style = iota
+	// JSONRPCStyle POSTs JSON-RPC 2.0 requests to the root path
+	JSONRPCStyle
+)
+
+// maxErrorBody bounds how much of a non-JSON error response is kept
+const maxErrorBody = 4096
+
+// Client talks to a single RPC endpoint
+type Client struct {
+	remote string
+	http   *http.Client
+	style  Style
+	nextID int64
+}
+
+// NewClient creates a client for remote, e.g. https://rpc.sei.io or
+// localhost:26657. A nil httpClient uses http.DefaultClient.
+func NewClient(remote string, httpClient *http.Client) (*Client, error) {
+	if !strings.Contains(remote, "://") {
+		remote = "http://" + remote
+	}
+	u, err := url.Parse(remote)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RPC address %q: %w", remote, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported RPC scheme %q", u.Scheme)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		remote: strings.TrimSuffix(u.String(), "/"),
+		http:   httpClient,
+	}, nil
+}
+
+// WithStyle returns a copy of the client that encodes requests in style
+func (c *Client) WithStyle(style Style) *Client {
+	return &Client{remote: c.remote, http: c.http, style: style}
+}
+
+// Remote returns the endpoint the client talks to
+func (c *Client) Remote() string {
+	return c.remote
+}
+
+// Status queries /status
+func (c *Client) Status(ctx context.Context) (*ResultStatus, error) {
+	result := &ResultStatus{}
+	return result, c.Call(ctx, "status", nil, result)
+}
+
+// Block queries /block at height; a nil height returns the latest block
+func (c *Client) Block(ctx context.Context, height *int64) (*ResultBlock, error) {
+	result := &ResultBlock{}
+	return result, c.Call(ctx, "block", heightParams(height), result)
+}
+
+// Commit queries /commit at height; a nil height returns the latest commit
+func (c *Client) Commit(ctx context.Context, height *int64) (*ResultCommit, error) {
+	result := &ResultCommit{}
+	return result, c.Call(ctx, "commit", heightParams(height), result)
+}
+
+// Validators queries one page of /validators at height. Zero page or
+// perPage use the server defaults.
+func (c *Client) Validators(ctx context.Context, height *int64, page, perPage int) (*ResultValidators, error) {
+	params := heightParams(height)
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	if page > 0 {
+		params["page"] = page
+	}
+	if perPage > 0 {
+		params["per_page"] = perPage
+	}
+	result := &ResultValidators{}
+	return result, c.Call(ctx, "validators", params, result)
+}
+
+// AllValidators fetches every page of the validator set at height
+func (c *Client) AllValidators(ctx context.Context, height *int64) ([]Validator, error) {
+	const perPage = 100
+
+	var vals []Validator
+	for page := 1; ; page++ {
+		result, err := c.Validators(ctx, height, page, perPage)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, result.Validators...)
+		if len(result.Validators) == 0 || len(vals) >= result.Total {
+			return vals, nil
+		}
+	}
+}
+
+// NetInfo queries /net_info
+func (c *Client) NetInfo(ctx context.Context) (*ResultNetInfo, error) {
+	result := &ResultNetInfo{}
+	return result, c.Call(ctx, "net_info", nil, result)
+}
+
+// ABCIInfo queries /abci_info
+func (c *Client) ABCIInfo(ctx context.Context) (*ResultABCIInfo, error) {
+	result := &ResultABCIInfo{}
+	return result, c.Call(ctx, "abci_info", nil, result)
+}
+
+// ConsensusState queries /consensus_state
+func (c *Client) ConsensusState(ctx context.Context) (*ResultConsensusState, error) {
+	result := &ResultConsensusState{}
+	return result, c.Call(ctx, "consensus_state", nil, result)
+}
+
+// DumpConsensusState queries /dump_consensus_state
+func (c *Client) DumpConsensusState(ctx context.Context) (*ResultDumpConsensusState, error) {
+	result := &ResultDumpConsensusState{}
+	return result, c.Call(ctx, "dump_consensus_state", nil, result)
+}
+
+func heightParams(height *int64) map[string]interface{} {
+	if height == nil {
+		return nil
+	}
+	return map[string]interface{}{"height": *height}
+}
+
+// response is the JSON-RPC envelope shared by the Tendermint and EVM
+// endpoints
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	// Data is a string from Tendermint, and may be any JSON from EVM
+	Data json.RawMessage `json:"data"`
+}
+
+// toError converts the error of a call to method
+func (e *responseError) toError(method string) *Error {
+	rpcErr := &Error{Code: e.Code, Message: e.Message, Method: method}
+	if len(e.Data) > 0 && string(e.Data) != "null" {
+		if json.Unmarshal(e.Data, &rpcErr.Data) != nil {
+			rpcErr.Data = string(e.Data)
+		}
+	}
+	return rpcErr
+}
+
+// Call invokes method with params and decodes the result into result.
+// Errors returned by the node are *Error; HTTP failures without a JSON-RPC
+// body are *HTTPError.
+func (c *Client) Call(ctx context.Context, method string, params map[string]interface{}, result interface{}) error {
+	req, err := c.newRequest(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	return call(c.http, req, c.remote, method, result)
+}
+
+// call sends req and decodes the JSON-RPC response to method into result
+func call(httpClient *http.Client, req *http.Request, remote, method string, result interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s on %s: %w", method, remote, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", method, err)
+	}
+
+	var envelope response
+	if jsonErr := json.Unmarshal(body, &envelope); jsonErr != nil || (envelope.Error == nil && envelope.Result == nil) {
+		if resp.StatusCode != http.StatusOK {
+			return newHTTPError(method, resp, body)
+		}
+		if jsonErr != nil {
+			return fmt.Errorf("failed to decode %s response: %w", method, jsonErr)
+		}
+		return fmt.Errorf("%s response has no result", method)
+	}
+	if envelope.Error != nil {
+		return envelope.Error.toError(method)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newHTTPError(method, resp, body)
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// newJSONRPCRequest builds a JSON-RPC 2.0 POST to remote
+func newJSONRPCRequest(ctx context.Context, remote string, id int64, method string, params interface{}) (*http.Request, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, remote, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, params map[string]interface{}) (*http.Request, error) {
+	if c.style == JSONRPCStyle {
+		encoded := make(map[string]interface{}, len(params))
+		for k, v := range params {
+			// Amino JSON carries 64-bit integers as strings
+			if i, ok := v.(int64); ok {
+				v = strconv.FormatInt(i, 10)
+			}
+			encoded[k] = v
+		}
+		return newJSONRPCRequest(ctx, c.remote, atomic.AddInt64(&c.nextID, 1), method, encoded)
+	}
+
+	query := url.Values{}
+	for k, v := range params {
+		switch v := v.(type) {
+		case string:
+			// URI string arguments are quoted
+			query.Set(k, strconv.Quote(v))
+		default:
+			query.Set(k, fmt.Sprint(v))
+		}
+	}
+	target := c.remote + "/" + method
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return req, nil
+}
@@ -0,0 +1,266 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const statusResult = `{
+  "node_info": {"id": "abc123", "network": "test-1", "version": "0.37.4", "moniker": "node0",
+    "other": {"tx_index": "on", "rpc_address": "tcp://0.0.0.0:26657"}},
+  "sync_info": {"latest_block_hash": "0A0B", "latest_block_height": "1234",
+    "latest_block_time": "2024-01-01T00:00:00Z", "earliest_block_height": "1", "catching_up": true},
+  "validator_info": {"address": "C0FFEE", "pub_key": {"type": "tendermint/PubKeyEd25519", "value": "AAEC"},
+    "voting_power": "10"}
+}`
+
+// fakeNode answers both URI and JSON-RPC requests and records what it saw
+type fakeNode struct {
+	methods []string
+	params  []map[string]string
+}
+
+func (f *fakeNode) serve(t *testing.T, results map[string]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, params := r.URL.Path[1:], map[string]string{}
+		var id interface{} = -1
+		if r.Method == http.MethodPost {
+			var req struct {
+				ID     interface{}            `json:"id"`
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			}
+			body, _ := io.ReadAll(r.Body)
+			require.NoError(t, json.Unmarshal(body, &req))
+			method, id = req.Method, req.ID
+			for k, v := range req.Params {
+				params[k] = fmt.Sprint(v)
+			}
+		} else {
+			for k := range r.URL.Query() {
+				params[k] = r.URL.Query().Get(k)
+			}
+		}
+		f.methods = append(f.methods, method)
+		f.params = append(f.params, params)
+
+		result, ok := results[method]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"error":{"code":-32603,"message":"Internal error","data":"height 99 must be less than or equal to the current blockchain height 10"}}`, id)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"result":%s}`, id, result)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestStatusBothStyles(t *testing.T) {
+	for _, style := range []Style{URIStyle, JSONRPCStyle} {
+		node := &fakeNode{}
+		srv := node.serve(t, map[string]string{"status": statusResult})
+
+		client, err := NewClient(srv.URL, nil)
+		require.NoError(t, err)
+		status, err := client.WithStyle(style).Status(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"status"}, node.methods)
+		assert.Equal(t, int64(1234), status.SyncInfo.LatestBlockHeight)
+		assert.True(t, status.SyncInfo.CatchingUp)
+		assert.Equal(t, "test-1", status.NodeInfo.Network)
+		assert.Equal(t, "0A0B", status.SyncInfo.LatestBlockHash.String())
+		assert.Equal(t, int64(10), status.ValidatorInfo.VotingPower)
+		assert.Equal(t, []byte{0, 1, 2}, status.ValidatorInfo.PubKey.Value)
+	}
+}
+
+func TestParamsEncoding(t *testing.T) {
+	results := map[string]string{
+		"validators": `{"block_height":"7","validators":[],"count":"0","total":"0"}`,
+	}
+	height := int64(7)
+
+	node := &fakeNode{}
+	client, err := NewClient(node.serve(t, results).URL, nil)
+	require.NoError(t, err)
+	_, err = client.Validators(context.Background(), &height, 2, 50)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"height": "7", "page": "2", "per_page": "50"}, node.params[0])
+
+	// JSON-RPC carries 64-bit integers as strings
+	node = &fakeNode{}
+	client, err = NewClient(node.serve(t, results).URL, nil)
+	require.NoError(t, err)
+	_, err = client.WithStyle(JSONRPCStyle).Validators(context.Background(), &height, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"height": "7"}, node.params[0])
+}
+
+func TestAllValidatorsPages(t *testing.T) {
+	page := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page++
+		fmt.Fprintf(w, `{"result":{"block_height":"5","validators":[{"address":"%02X","pub_key":{"type":"tendermint/PubKeyEd25519","value":""},"voting_power":"1","proposer_priority":"0"}],"count":"1","total":"3"}}`, page)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(srv.URL, nil)
+	require.NoError(t, err)
+	vals, err := client.AllValidators(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, vals, 3)
+	assert.Equal(t, HexBytes{3}, vals[2].Address)
+}
+
+func TestDecodableErrors(t *testing.T) {
+	node := &fakeNode{}
+	client, err := NewClient(node.serve(t, nil).URL, nil)
+	require.NoError(t, err)
+
+	height := int64(99)
+	_, err = client.Block(context.Background(), &height)
+	var rpcErr *Error
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, -32603, rpcErr.Code)
+	assert.Equal(t, "block", rpcErr.Method)
+	assert.True(t, rpcErr.IsHeightUnavailable())
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	}))
+	t.Cleanup(down.Close)
+	client, err = NewClient(down.URL, nil)
+	require.NoError(t, err)
+	_, err = client.NetInfo(context.Background())
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	assert.Equal(t, "upstream unavailable", httpErr.Body)
+}
+
+func TestContextCancellation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(srv.URL, nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.ABCIInfo(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConsensusResults(t *testing.T) {
+	node := &fakeNode{}
+	client, err := NewClient(node.serve(t, map[string]string{
+		"abci_info":            `{"response":{"data":"sei","version":"v5.9.0","app_version":"1","last_block_height":"42","last_block_app_hash":"AAE="}}`,
+		"consensus_state":      `{"round_state":{"height/round/step":"43/0/1","start_time":"2024-01-01T00:00:00Z","proposal_block_hash":"","locked_block_hash":"","valid_block_hash":"","height_vote_set":[{"round":0,"prevotes":["nil-Vote"],"prevotes_bit_array":"BA{1:_} 0/10 = 0.00","precommits":["nil-Vote"],"precommits_bit_array":"BA{1:_} 0/10 = 0.00"}],"proposer":{"address":"C0FFEE","index":0}}}`,
+		"dump_consensus_state": `{"round_state":{"height":"43","round":0,"step":1,"start_time":"2024-01-01T00:00:00Z","votes":[]},"peers":[{"node_address":"id@1.2.3.4:26656","peer_state":{"round_state":{}}}]}`,
+		"net_info":             `{"listening":true,"listeners":["Listener(@)"],"n_peers":"1","peers":[{"node_info":{"id":"peer1","moniker":"p"},"is_outbound":true,"remote_ip":"1.2.3.4"}]}`,
+	}).URL, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	info, err := client.ABCIInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), info.Response.LastBlockHeight)
+
+	cs, err := client.ConsensusState(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "43/0/1", cs.RoundState.HeightRoundStep)
+	require.Len(t, cs.RoundState.HeightVoteSet, 1)
+
+	dump, err := client.DumpConsensusState(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(43), dump.RoundState.Height)
+	assert.Contains(t, string(dump.RoundState.Raw), `"votes"`)
+	require.Len(t, dump.Peers, 1)
+
+	net, err := client.NetInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, net.NPeers)
+	assert.Equal(t, "1.2.3.4", net.Peers[0].RemoteIP)
+}
+
+func TestSubscribe(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/websocket", r.URL.Path)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var req struct {
+			Method string            `json:"method"`
+			Params map[string]string `json:"params"`
+		}
+		require.NoError(t, conn.ReadJSON(&req))
+		assert.Equal(t, "subscribe", req.Method)
+		assert.Equal(t, "tm.event='NewBlock'", req.Params["query"])
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":0,"result":{}}`))
+		for h := 1; h <= 2; h++ {
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+				`{"jsonrpc":"2.0","id":0,"result":{"query":"tm.event='NewBlock'","data":{"type":"tendermint/event/NewBlock","value":{"block":{"header":{"height":"%d"}}}},"events":{"tm.event":["NewBlock"]}}}`, h)))
+		}
+		// Hold the connection until the client closes it
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(srv.URL, nil)
+	require.NoError(t, err)
+	sub, err := client.Subscribe(context.Background(), "tm.event='NewBlock'")
+	require.NoError(t, err)
+
+	for h := 1; h <= 2; h++ {
+		event := <-sub.Events
+		assert.Equal(t, "tendermint/event/NewBlock", event.Data.Type)
+		assert.Equal(t, []string{"NewBlock"}, event.Events["tm.event"])
+		assert.Contains(t, string(event.Data.Value), fmt.Sprintf(`"height":"%d"`, h))
+	}
+
+	sub.Close()
+	_, open := <-sub.Events
+	assert.False(t, open)
+	assert.NoError(t, sub.Err())
+}
+
+func TestSubscribeRejected(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		var req json.RawMessage
+		conn.ReadJSON(&req)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":0,"error":{"code":-32603,"message":"Internal error","data":"max_subscriptions_per_client 5 reached"}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(srv.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Subscribe(context.Background(), "tm.event='Tx'")
+	var rpcErr *Error
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Contains(t, rpcErr.Data, "max_subscriptions")
+}
@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error is an error returned by the node in a JSON-RPC response
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+	// Method is the RPC method that failed
+	Method string `json:"-"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: RPC error %d: %s", e.Method, e.Code, e.Message)
+	if e.Data != "" {
+		msg += ": " + e.Data
+	}
+	return msg
+}
+
+// IsHeightUnavailable reports whether the node does not have the requested
+// height, either because it is pruned or not yet reached
+func (e *Error) IsHeightUnavailable() bool {
+	return strings.Contains(e.Data, "height") &&
+		(strings.Contains(e.Data, "lowest height") || strings.Contains(e.Data, "must be less than or equal") ||
+			strings.Contains(e.Data, "is not available"))
+}
+
+// HTTPError is returned when the endpoint answers with a non-200 status and
+// no JSON-RPC error
+type HTTPError struct {
+	Method     string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func newHTTPError(method string, resp *http.Response, body []byte) *HTTPError {
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+	return &HTTPError{
+		Method:     method,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s: %s", e.Method, e.Status)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Method, e.Status, e.Body)
+}
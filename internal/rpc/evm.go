@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// EVMClient talks to the Ethereum JSON-RPC endpoint served by Sei v2 nodes
+type EVMClient struct {
+	remote string
+	http   *http.Client
+	nextID int64
+}
+
+// NewEVMClient creates a client for remote, e.g. https://evm-rpc.sei-apis.com
+// or localhost:8545. A nil httpClient uses http.DefaultClient.
+func NewEVMClient(remote string, httpClient *http.Client) (*EVMClient, error) {
+	c, err := NewClient(remote, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return &EVMClient{remote: c.remote, http: c.http}, nil
+}
+
+// Remote returns the endpoint the client talks to
+func (c *EVMClient) Remote() string {
+	return c.remote
+}
+
+// ChainID calls eth_chainId
+func (c *EVMClient) ChainID(ctx context.Context) (uint64, error) {
+	var result string
+	if err := c.Call(ctx, "eth_chainId", nil, &result); err != nil {
+		return 0, err
+	}
+	return parseQuantity("eth_chainId", result)
+}
+
+// BlockNumber calls eth_blockNumber
+func (c *EVMClient) BlockNumber(ctx context.Context) (int64, error) {
+	var result string
+	if err := c.Call(ctx, "eth_blockNumber", nil, &result); err != nil {
+		return 0, err
+	}
+	n, err := parseQuantity("eth_blockNumber", result)
+	return int64(n), err
+}
+
+// NetVersion calls net_version, the network ID as a decimal string
+func (c *EVMClient) NetVersion(ctx context.Context) (string, error) {
+	var result string
+	return result, c.Call(ctx, "net_version", nil, &result)
+}
+
+// parseQuantity decodes a hex encoded JSON-RPC quantity such as "0x531"
+func parseQuantity(method, s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("%s returned %q, expected a hex quantity", method, s)
+	}
+	n, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s returned %q, expected a hex quantity", method, s)
+	}
+	return n, nil
+}
+
+// Call invokes method with positional params and decodes the result into
+// result. Errors returned by the node are *Error; HTTP failures without a
+// JSON-RPC body are *HTTPError.
+func (c *EVMClient) Call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	req, err := newJSONRPCRequest(ctx, c.remote, atomic.AddInt64(&c.nextID, 1), method, params)
+	if err != nil {
+		return err
+	}
+	return call(c.http, req, c.remote, method, result)
+}
@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEVM answers Ethereum JSON-RPC requests, which must use positional params
+func fakeEVM(t *testing.T, results map[string]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     interface{}       `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":null,"error":{"code":-32602,"message":"non-array args"}}`)
+			return
+		}
+		result, ok := results[req.Method]
+		if !ok {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"error":{"code":-32601,"message":"the method %s does not exist/is not available","data":{"reason":"disabled"}}}`, req.ID, req.Method)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%v,"result":%s}`, req.ID, result)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEVMClient(t *testing.T) {
+	srv := fakeEVM(t, map[string]string{
+		"eth_chainId":     `"0x531"`,
+		"eth_blockNumber": `"0x7b"`,
+		"net_version":     `"1329"`,
+	})
+	client, err := NewEVMClient(srv.URL, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	chainID, err := client.ChainID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1329), chainID)
+
+	height, err := client.BlockNumber(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(123), height)
+
+	version, err := client.NetVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "1329", version)
+
+	err = client.Call(ctx, "debug_traceBlock", nil, nil)
+	var rpcErr *Error
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, -32601, rpcErr.Code)
+	assert.Equal(t, `{"reason":"disabled"}`, rpcErr.Data)
+}
+
+func TestEVMClientBadQuantity(t *testing.T) {
+	client, err := NewEVMClient(fakeEVM(t, map[string]string{"eth_blockNumber": `"123"`}).URL, nil)
+	require.NoError(t, err)
+	_, err = client.BlockNumber(context.Background())
+	assert.ErrorContains(t, err, "hex quantity")
+}
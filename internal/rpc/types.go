@@ -0,0 +1,289 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// HexBytes is a byte slice encoded as upper-case hex, as used for hashes and
+// addresses
+type HexBytes []byte
+
+// UnmarshalJSON decodes a hex string
+func (h *HexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*h = b
+	return nil
+}
+
+// MarshalJSON encodes the bytes as upper-case hex
+func (h HexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.String())
+}
+
+// String returns the upper-case hex encoding
+func (h HexBytes) String() string {
+	return strings.ToUpper(hex.EncodeToString(h))
+}
+
+// PubKey is an amino-JSON encoded public key
+type PubKey struct {
+	Type  string `json:"type"`
+	Value []byte `json:"value"`
+}
+
+// PartSetHeader identifies the parts a block was gossiped in
+type PartSetHeader struct {
+	Total uint32   `json:"total"`
+	Hash  HexBytes `json:"hash"`
+}
+
+// BlockID identifies a block by its hash and part set header
+type BlockID struct {
+	Hash  HexBytes      `json:"hash"`
+	Parts PartSetHeader `json:"parts"`
+}
+
+// Version holds the block and app protocol versions of a header
+type Version struct {
+	Block uint64 `json:"block,string"`
+	App   uint64 `json:"app,string"`
+}
+
+// Header is a block header
+type Header struct {
+	Version            Version   `json:"version"`
+	ChainID            string    `json:"chain_id"`
+	Height             int64     `json:"height,string"`
+	Time               time.Time `json:"time"`
+	LastBlockID        BlockID   `json:"last_block_id"`
+	LastCommitHash     HexBytes  `json:"last_commit_hash"`
+	DataHash           HexBytes  `json:"data_hash"`
+	ValidatorsHash     HexBytes  `json:"validators_hash"`
+	NextValidatorsHash HexBytes  `json:"next_validators_hash"`
+	ConsensusHash      HexBytes  `json:"consensus_hash"`
+	AppHash            HexBytes  `json:"app_hash"`
+	LastResultsHash    HexBytes  `json:"last_results_hash"`
+	EvidenceHash       HexBytes  `json:"evidence_hash"`
+	ProposerAddress    HexBytes  `json:"proposer_address"`
+}
+
+// BlockIDFlag values of a commit signature
+const (
+	BlockIDFlagAbsent = 1
+	BlockIDFlagCommit = 2
+	BlockIDFlagNil    = 3
+)
+
+// CommitSig is a validator's precommit included in a commit
+type CommitSig struct {
+	BlockIDFlag      int       `json:"block_id_flag"`
+	ValidatorAddress HexBytes  `json:"validator_address"`
+	Timestamp        time.Time `json:"timestamp"`
+	Signature        []byte    `json:"signature"`
+}
+
+// Commit is the set of precommits that finalised a block
+type Commit struct {
+	Height     int64       `json:"height,string"`
+	Round      int32       `json:"round"`
+	BlockID    BlockID     `json:"block_id"`
+	Signatures []CommitSig `json:"signatures"`
+}
+
+// SignedHeader is a header with the commit that signed it
+type SignedHeader struct {
+	Header Header `json:"header"`
+	Commit Commit `json:"commit"`
+}
+
+// Validator is a member of a validator set
+type Validator struct {
+	Address          HexBytes `json:"address"`
+	PubKey           PubKey   `json:"pub_key"`
+	VotingPower      int64    `json:"voting_power,string"`
+	ProposerPriority int64    `json:"proposer_priority,string"`
+}
+
+// Block is a block with its transactions left undecoded
+type Block struct {
+	Header     Header          `json:"header"`
+	Data       json.RawMessage `json:"data"`
+	Evidence   json.RawMessage `json:"evidence"`
+	LastCommit *Commit         `json:"last_commit"`
+}
+
+// NodeInfo describes a node on the p2p network
+type NodeInfo struct {
+	ID         string `json:"id"`
+	ListenAddr string `json:"listen_addr"`
+	Network    string `json:"network"`
+	Version    string `json:"version"`
+	Channels   string `json:"channels"`
+	Moniker    string `json:"moniker"`
+	Other      struct {
+		TxIndex    string `json:"tx_index"`
+		RPCAddress string `json:"rpc_address"`
+	} `json:"other"`
+}
+
+// SyncInfo is the sync state reported by /status
+type SyncInfo struct {
+	LatestBlockHash     HexBytes  `json:"latest_block_hash"`
+	LatestAppHash       HexBytes  `json:"latest_app_hash"`
+	LatestBlockHeight   int64     `json:"latest_block_height,string"`
+	LatestBlockTime     time.Time `json:"latest_block_time"`
+	EarliestBlockHash   HexBytes  `json:"earliest_block_hash"`
+	EarliestAppHash     HexBytes  `json:"earliest_app_hash"`
+	EarliestBlockHeight int64     `json:"earliest_block_height,string"`
+	EarliestBlockTime   time.Time `json:"earliest_block_time"`
+	CatchingUp          bool      `json:"catching_up"`
+}
+
+// ValidatorInfo is the node's own validator key and power
+type ValidatorInfo struct {
+	Address     HexBytes `json:"address"`
+	PubKey      PubKey   `json:"pub_key"`
+	VotingPower int64    `json:"voting_power,string"`
+}
+
+// ResultStatus is the result of /status
+type ResultStatus struct {
+	NodeInfo      NodeInfo      `json:"node_info"`
+	SyncInfo      SyncInfo      `json:"sync_info"`
+	ValidatorInfo ValidatorInfo `json:"validator_info"`
+}
+
+// ResultBlock is the result of /block
+type ResultBlock struct {
+	BlockID BlockID `json:"block_id"`
+	Block   Block   `json:"block"`
+}
+
+// ResultCommit is the result of /commit
+type ResultCommit struct {
+	SignedHeader SignedHeader `json:"signed_header"`
+	Canonical    bool         `json:"canonical"`
+}
+
+// ResultValidators is one page of /validators
+type ResultValidators struct {
+	BlockHeight int64       `json:"block_height,string"`
+	Validators  []Validator `json:"validators"`
+	Count       int         `json:"count,string"`
+	Total       int         `json:"total,string"`
+}
+
+// Peer is a connected peer listed by /net_info
+type Peer struct {
+	NodeInfo         NodeInfo        `json:"node_info"`
+	IsOutbound       bool            `json:"is_outbound"`
+	ConnectionStatus json.RawMessage `json:"connection_status"`
+	RemoteIP         string          `json:"remote_ip"`
+}
+
+// ResultNetInfo is the result of /net_info
+type ResultNetInfo struct {
+	Listening bool     `json:"listening"`
+	Listeners []string `json:"listeners"`
+	NPeers    int      `json:"n_peers,string"`
+	Peers     []Peer   `json:"peers"`
+}
+
+// ABCIInfo is the application's response to an info request
+type ABCIInfo struct {
+	Data             string `json:"data"`
+	Version          string `json:"version"`
+	AppVersion       uint64 `json:"app_version,string"`
+	LastBlockHeight  int64  `json:"last_block_height,string"`
+	LastBlockAppHash []byte `json:"last_block_app_hash"`
+}
+
+// ResultABCIInfo is the result of /abci_info
+type ResultABCIInfo struct {
+	Response ABCIInfo `json:"response"`
+}
+
+// HeightVoteSet summarises the votes seen in one round
+type HeightVoteSet struct {
+	Round              int      `json:"round"`
+	Prevotes           []string `json:"prevotes"`
+	PrevotesBitArray   string   `json:"prevotes_bit_array"`
+	Precommits         []string `json:"precommits"`
+	PrecommitsBitArray string   `json:"precommits_bit_array"`
+}
+
+// RoundStateSummary is the compact round state reported by /consensus_state
+type RoundStateSummary struct {
+	HeightRoundStep   string          `json:"height/round/step"`
+	StartTime         time.Time       `json:"start_time"`
+	ProposalBlockHash HexBytes        `json:"proposal_block_hash"`
+	LockedBlockHash   HexBytes        `json:"locked_block_hash"`
+	ValidBlockHash    HexBytes        `json:"valid_block_hash"`
+	HeightVoteSet     []HeightVoteSet `json:"height_vote_set"`
+	Proposer          struct {
+		Address HexBytes `json:"address"`
+		Index   int      `json:"index"`
+	} `json:"proposer"`
+}
+
+// ResultConsensusState is the result of /consensus_state
+type ResultConsensusState struct {
+	RoundState RoundStateSummary `json:"round_state"`
+}
+
+// RoundState is the full round state reported by /dump_consensus_state. Only
+// the commonly used fields are decoded; Raw keeps the complete object.
+type RoundState struct {
+	Height    int64           `json:"height,string"`
+	Round     int32           `json:"round"`
+	Step      int             `json:"step"`
+	StartTime time.Time       `json:"start_time"`
+	Raw       json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the known fields and keeps the raw object
+func (r *RoundState) UnmarshalJSON(data []byte) error {
+	type roundState RoundState
+	var rs roundState
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return err
+	}
+	*r = RoundState(rs)
+	r.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// PeerState is a peer's view of consensus in /dump_consensus_state
+type PeerState struct {
+	NodeAddress string          `json:"node_address"`
+	PeerState   json.RawMessage `json:"peer_state"`
+}
+
+// ResultDumpConsensusState is the result of /dump_consensus_state
+type ResultDumpConsensusState struct {
+	RoundState RoundState  `json:"round_state"`
+	Peers      []PeerState `json:"peers"`
+}
+
+// Event is a message delivered on a websocket subscription
+type Event struct {
+	Query  string              `json:"query"`
+	Data   EventData           `json:"data"`
+	Events map[string][]string `json:"events"`
+}
+
+// EventData is the typed payload of an event, e.g. tendermint/event/NewBlock
+type EventData struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
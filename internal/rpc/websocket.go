@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsPath         = "/websocket"
+	wsPingInterval = 20 * time.Second
+	wsWriteTimeout = 10 * time.Second
+	eventBuffer    = 64
+)
+
+// Subscription delivers events matching a query until it is closed, its
+// context is cancelled or the connection fails
+type Subscription struct {
+	// Events is closed when the subscription ends; Err reports why
+	Events <-chan Event
+
+	conn   *websocket.Conn
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// Subscribe opens a websocket to the endpoint and subscribes to query, e.g.
+// "tm.event='NewBlock'"
+func (c *Client) Subscribe(ctx context.Context, query string) (*Subscription, error) {
+	wsURL := "ws" + strings.TrimPrefix(c.remote, "http") + wsPath
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: wsWriteTimeout,
+	}
+	conn, _, err := dialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", wsURL, err)
+	}
+
+	request := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      0,
+		"method":  "subscribe",
+		"params":  map[string]string{"query": query},
+	}
+	if err := conn.WriteJSON(request); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	// The first message acknowledges the subscription
+	var ack response
+	if err := conn.ReadJSON(&ack); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read subscription response: %w", err)
+	}
+	if ack.Error != nil {
+		conn.Close()
+		return nil, ack.Error.toError("subscribe")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	events := make(chan Event, eventBuffer)
+	sub := &Subscription{
+		Events: events,
+		conn:   conn,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go sub.keepalive(ctx)
+	go sub.read(ctx, events)
+
+	return sub, nil
+}
+
+// Close ends the subscription and waits for the connection to shut down
+func (s *Subscription) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// Err returns the error that ended the subscription, if any
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *Subscription) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *Subscription) read(ctx context.Context, events chan<- Event) {
+	defer close(s.done)
+	defer close(events)
+
+	go func() {
+		<-ctx.Done()
+		// Unblocks ReadJSON below
+		s.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteTimeout))
+		s.conn.Close()
+	}()
+
+	for {
+		var msg response
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			if ctx.Err() == nil {
+				s.fail(fmt.Errorf("subscription connection lost: %w", err))
+				s.cancel()
+			}
+			return
+		}
+		if msg.Error != nil {
+			s.fail(msg.Error.toError("subscribe"))
+			s.cancel()
+			return
+		}
+		if len(msg.Result) == 0 || string(msg.Result) == "{}" {
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal(msg.Result, &event); err != nil {
+			s.fail(fmt.Errorf("failed to decode event: %w", err))
+			s.cancel()
+			return
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Subscription) keepalive(ctx context.Context) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
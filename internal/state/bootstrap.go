@@ -0,0 +1,404 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-org/seictl/internal/utils"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// BootstrapStrategy is a way of bringing a fresh node to the chain tip
+type BootstrapStrategy string
+
+const (
+	StrategyLocalSnapshot  BootstrapStrategy = "local-snapshot"
+	StrategyServerSnapshot BootstrapStrategy = "server-snapshot"
+	StrategyFeedSnapshot   BootstrapStrategy = "feed-snapshot"
+	StrategyStateSync      BootstrapStrategy = "state-sync"
+	StrategyBlockSync      BootstrapStrategy = "block-sync"
+)
+
+// Rough throughput figures used to rank strategies. They only need to be
+// right relative to each other.
+const (
+	planDownloadBytesPerSec = 50 << 20
+	planExtractBytesPerSec  = 150 << 20
+	planBlocksPerSec        = 15
+	planStateSyncOverhead   = 20 * time.Minute
+	// Extracted data is about this many times the compressed archives
+	planExpansionFactor = 3
+)
+
+// BootstrapOptions configures PlanBootstrap
+type BootstrapOptions struct {
+	Env types.Environment
+	// FeedURL is a published snapshot feed to consider
+	FeedURL string
+	// Server is a seictl snapshot server to consider
+	Server string
+	Token  string
+	// Archive requires the node to keep full block history
+	Archive bool
+	// Force plans even when the node already has data
+	Force bool
+}
+
+// BootstrapCandidate is one strategy the planner evaluated
+type BootstrapCandidate struct {
+	Strategy BootstrapStrategy `json:"strategy"`
+	Viable   bool              `json:"viable"`
+	// Reason explains why the candidate is not viable, or notes caveats
+	Reason     string   `json:"reason,omitempty"`
+	Source     string   `json:"source,omitempty"`
+	Height     int64    `json:"height,omitempty"`
+	Bytes      int64    `json:"bytes,omitempty"`
+	CatchUp    int64    `json:"catch_up_blocks,omitempty"`
+	Estimate   Seconds  `json:"estimate_seconds,omitempty"`
+	Upgrades   []string `json:"upgrades_crossed,omitempty"`
+	needsBytes int64
+}
+
+// BootstrapPlan is the outcome of PlanBootstrap
+type BootstrapPlan struct {
+	ChainID     string               `json:"chain_id"`
+	TipHeight   int64                `json:"tip_height,omitempty"`
+	HasData     bool                 `json:"has_data"`
+	FreeBytes   int64                `json:"free_bytes"`
+	Candidates  []BootstrapCandidate `json:"candidates"`
+	Choice      *BootstrapCandidate  `json:"choice,omitempty"`
+	Explanation []string             `json:"explanation"`
+}
+
+// PlanBootstrap inspects local state, snapshots, RPC state sync availability
+// and disk space, and recommends the fastest valid way to bootstrap the node
+func (m *Manager) PlanBootstrap(ctx context.Context, opts BootstrapOptions) (*BootstrapPlan, error) {
+	env, err := m.stateSyncEnv(opts.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &BootstrapPlan{ChainID: env.ChainID, FreeBytes: -1}
+	if local := m.localChainID(); local != "" && local != env.ChainID {
+		return nil, fmt.Errorf("node home is initialised for %s, not %s", local, env.ChainID)
+	}
+
+	plan.HasData = m.nodeHasData()
+	if plan.HasData && !opts.Force {
+		plan.Explanation = append(plan.Explanation,
+			"the node already has chain data; bootstrapping would replace it, pass --force to plan anyway")
+		return plan, nil
+	}
+
+	if free, err := utils.DiskFree(m.config.Global.HomeDir); err == nil {
+		plan.FreeBytes = free
+	}
+	if pool, err := m.RPCPool(opts.Env); err == nil {
+		var status *NodeStatus
+		if err := pool.Do(ctx, func(endpoint string) error {
+			status, err = m.queryStatus(ctx, endpoint)
+			return err
+		}); err == nil {
+			plan.TipHeight = status.LatestHeight
+		}
+	}
+
+	plan.Candidates = append(plan.Candidates, m.localSnapshotCandidate(env, opts, plan))
+	if opts.Server != "" {
+		plan.Candidates = append(plan.Candidates, m.serverSnapshotCandidate(ctx, env, opts, plan))
+	}
+	if opts.FeedURL != "" {
+		plan.Candidates = append(plan.Candidates, m.feedSnapshotCandidate(ctx, env, opts, plan))
+	}
+	plan.Candidates = append(plan.Candidates,
+		m.stateSyncCandidate(ctx, env, opts, plan),
+		blockSyncCandidate(env, opts, plan))
+
+	for i := range plan.Candidates {
+		c := &plan.Candidates[i]
+		if c.Viable && plan.FreeBytes >= 0 && c.needsBytes > plan.FreeBytes {
+			c.Viable = false
+			c.Reason = fmt.Sprintf("needs about %.1f GiB of disk, only %.1f GiB free", gib(c.needsBytes), gib(plan.FreeBytes))
+		}
+	}
+
+	ranked := make([]*BootstrapCandidate, 0, len(plan.Candidates))
+	for i := range plan.Candidates {
+		if plan.Candidates[i].Viable {
+			ranked = append(ranked, &plan.Candidates[i])
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Estimate < ranked[j].Estimate })
+	if len(ranked) > 0 {
+		plan.Choice = ranked[0]
+	}
+	plan.Explanation = append(plan.Explanation, explainPlan(plan, ranked)...)
+
+	return plan, nil
+}
+
+// ExecuteBootstrap carries out the plan's choice, or strategy when given
+func (m *Manager) ExecuteBootstrap(ctx context.Context, plan *BootstrapPlan, opts BootstrapOptions, strategy BootstrapStrategy) error {
+	choice := plan.Choice
+	if strategy != "" {
+		choice = nil
+		for i := range plan.Candidates {
+			if plan.Candidates[i].Strategy == strategy {
+				choice = &plan.Candidates[i]
+			}
+		}
+		if choice == nil {
+			return fmt.Errorf("strategy %s was not evaluated", strategy)
+		}
+		if !choice.Viable {
+			return fmt.Errorf("strategy %s is not viable: %s", strategy, choice.Reason)
+		}
+	}
+	if choice == nil {
+		return fmt.Errorf("no viable bootstrap strategy")
+	}
+
+	m.logger.Info().Str("strategy", string(choice.Strategy)).Str("source", choice.Source).Msg("Bootstrapping node")
+
+	switch choice.Strategy {
+	case StrategyLocalSnapshot:
+		return m.RestoreSnapshot(ctx, choice.Source)
+	case StrategyServerSnapshot:
+		return m.RestoreFromServer(ctx, opts.Server, opts.Token)
+	case StrategyFeedSnapshot:
+		return m.RestoreFromFeed(ctx, opts.FeedURL)
+	case StrategyStateSync:
+		return m.SyncState(ctx, StateSyncOptions{Env: opts.Env})
+	case StrategyBlockSync:
+		m.logger.Info().Msg("Nothing to restore for block sync; start the node with `seictl start` to sync from genesis")
+		return nil
+	}
+	return fmt.Errorf("unknown strategy %s", choice.Strategy)
+}
+
+// nodeHasData reports whether the node holds block or application data
+func (m *Manager) nodeHasData() bool {
+	for _, name := range []string{"blockstore.db", "application.db", "state.db"} {
+		if _, err := os.Stat(filepath.Join(m.dataDir(), name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) localSnapshotCandidate(env types.ChainConfig, opts BootstrapOptions, plan *BootstrapPlan) BootstrapCandidate {
+	c := BootstrapCandidate{Strategy: StrategyLocalSnapshot}
+
+	snapshots, err := m.ListSnapshots()
+	if err != nil {
+		c.Reason = err.Error()
+		return c
+	}
+	var best *SnapshotInfo
+	for i := range snapshots {
+		s := &snapshots[i]
+		if s.ChainID != env.ChainID || s.Integrity != IntegrityOK {
+			continue
+		}
+		if best == nil || s.Height > best.Height {
+			best = s
+		}
+	}
+	if best == nil {
+		c.Reason = "no verified local snapshot for " + env.ChainID
+		return c
+	}
+
+	manifest, _ := readManifest(best.Path)
+	size := best.Size
+	if best.LogicalSize > 0 {
+		size = best.LogicalSize
+	}
+	c.Source, c.Height, c.Bytes = best.Path, best.Height, size
+	c.needsBytes = size * planExpansionFactor
+	c.Estimate = Seconds(bytesDuration(size, planExtractBytesPerSec))
+	pruning := ""
+	if manifest != nil {
+		pruning = manifest.Pruning
+	}
+	m.finishSnapshotCandidate(&c, env, opts, plan, pruning)
+	return c
+}
+
+func (m *Manager) serverSnapshotCandidate(ctx context.Context, env types.ChainConfig, opts BootstrapOptions, plan *BootstrapPlan) BootstrapCandidate {
+	c := BootstrapCandidate{Strategy: StrategyServerSnapshot, Source: opts.Server}
+
+	index, err := m.NewHTTPStorage(opts.Server, opts.Token).Index(ctx)
+	if err != nil {
+		c.Reason = err.Error()
+		return c
+	}
+	s := newestCompatible(index, env.ChainID)
+	if s == nil {
+		c.Reason = "server has no snapshot for " + env.ChainID
+		return c
+	}
+
+	c.Height, c.Bytes = s.Height, s.TotalSize()
+	c.needsBytes = c.Bytes + c.Bytes*planExpansionFactor
+	c.Estimate = Seconds(bytesDuration(c.Bytes, planDownloadBytesPerSec) + bytesDuration(c.Bytes, planExtractBytesPerSec))
+	m.finishSnapshotCandidate(&c, env, opts, plan, s.Pruning)
+	return c
+}
+
+func (m *Manager) feedSnapshotCandidate(ctx context.Context, env types.ChainConfig, opts BootstrapOptions, plan *BootstrapPlan) BootstrapCandidate {
+	c := BootstrapCandidate{Strategy: StrategyFeedSnapshot, Source: opts.FeedURL}
+
+	index, err := fetchFeedIndex(ctx, opts.FeedURL)
+	if err != nil {
+		c.Reason = err.Error()
+		return c
+	}
+	var best *FeedEntry
+	for i := range index.Snapshots {
+		e := &index.Snapshots[i]
+		if e.ChainID == env.ChainID && (best == nil || e.Height > best.Height) {
+			best = e
+		}
+	}
+	if best == nil {
+		c.Reason = "feed has no snapshot for " + env.ChainID
+		return c
+	}
+
+	for _, a := range best.Archives {
+		c.Bytes += a.Size
+	}
+	c.Height = best.Height
+	c.needsBytes = c.Bytes + c.Bytes*planExpansionFactor
+	c.Estimate = Seconds(bytesDuration(c.Bytes, planDownloadBytesPerSec) + bytesDuration(c.Bytes, planExtractBytesPerSec))
+	m.finishSnapshotCandidate(&c, env, opts, plan, best.Pruning)
+	return c
+}
+
+// finishSnapshotCandidate applies the checks shared by all snapshot sources:
+// archive requirements, upgrades crossed and the blocks left to catch up
+func (m *Manager) finishSnapshotCandidate(c *BootstrapCandidate, env types.ChainConfig, opts BootstrapOptions, plan *BootstrapPlan, pruning string) {
+	if opts.Archive && pruning != "nothing" {
+		c.Reason = fmt.Sprintf("snapshot was taken with pruning %q and lacks the full history an archive node needs", orUnknown(pruning))
+		return
+	}
+	c.Viable = true
+	addCatchUp(c, env, plan)
+}
+
+func (m *Manager) stateSyncCandidate(ctx context.Context, env types.ChainConfig, opts BootstrapOptions, plan *BootstrapPlan) BootstrapCandidate {
+	c := BootstrapCandidate{Strategy: StrategyStateSync}
+	if opts.Archive {
+		c.Reason = "state sync starts from a recent height and cannot provide the full history an archive node needs"
+		return c
+	}
+	if len(env.RPCEndpoints) == 0 {
+		c.Reason = "no RPC endpoints configured for state sync"
+		return c
+	}
+
+	trust, err := m.ResolveTrustBlock(ctx, StateSyncOptions{Env: opts.Env})
+	if err != nil {
+		c.Reason = "trust block unavailable: " + err.Error()
+		return c
+	}
+	c.Viable = true
+	c.Height = trust.Height
+	c.Source = strings.Join(trust.Agreeing, ",")
+	c.Estimate = Seconds(planStateSyncOverhead)
+	c.Reason = "depends on peers serving state sync snapshots; the estimate assumes they do"
+	addCatchUp(&c, env, plan)
+	return c
+}
+
+func blockSyncCandidate(env types.ChainConfig, opts BootstrapOptions, plan *BootstrapPlan) BootstrapCandidate {
+	c := BootstrapCandidate{Strategy: StrategyBlockSync, Viable: true, Height: 1}
+	addCatchUp(&c, env, plan)
+	if plan.TipHeight == 0 {
+		// Unknown tip: rank block sync last
+		c.Estimate = 1<<63 - 1
+		c.Reason = strings.TrimSpace(c.Reason + " chain tip unknown, cannot estimate")
+	}
+	if !opts.Archive {
+		note := "replays every block from genesis; only worth it when full history is needed"
+		if c.Reason == "" {
+			c.Reason = note
+		}
+	}
+	return c
+}
+
+// addCatchUp adds the time to block sync from the candidate's height to the
+// tip and records the upgrades crossed on the way
+func addCatchUp(c *BootstrapCandidate, env types.ChainConfig, plan *BootstrapPlan) {
+	if plan.TipHeight > c.Height {
+		c.CatchUp = plan.TipHeight - c.Height
+		c.Estimate += Seconds(time.Duration(c.CatchUp/planBlocksPerSec) * time.Second)
+	}
+
+	for _, u := range env.Upgrades {
+		if u.Height > c.Height && (plan.TipHeight == 0 || u.Height <= plan.TipHeight) {
+			c.Upgrades = append(c.Upgrades, fmt.Sprintf("%s at %d (%s)", u.Name, u.Height, u.Version))
+		}
+	}
+	if len(c.Upgrades) > 0 && c.Viable {
+		c.Reason = strings.TrimSpace(fmt.Sprintf("%s catching up crosses %d upgrade(s); start with the binary for height %d and switch at each upgrade",
+			c.Reason, len(c.Upgrades), c.Height))
+	}
+}
+
+func explainPlan(plan *BootstrapPlan, ranked []*BootstrapCandidate) []string {
+	var lines []string
+	if plan.TipHeight > 0 {
+		lines = append(lines, fmt.Sprintf("chain %s is at height %d", plan.ChainID, plan.TipHeight))
+	} else {
+		lines = append(lines, "chain tip unknown: no RPC endpoint answered, estimates exclude catch-up time")
+	}
+	if plan.Choice == nil {
+		lines = append(lines, "no strategy is viable; see the reasons above")
+		return lines
+	}
+
+	c := plan.Choice
+	line := fmt.Sprintf("recommended: %s, estimated %s", c.Strategy, formatEstimate(c.Estimate.Duration()))
+	if c.Height > 1 {
+		line += fmt.Sprintf(" starting at height %d", c.Height)
+	}
+	lines = append(lines, line)
+	if len(ranked) > 1 {
+		next := ranked[1]
+		lines = append(lines, fmt.Sprintf("next best: %s, estimated %s", next.Strategy, formatEstimate(next.Estimate.Duration())))
+	}
+	if len(c.Upgrades) > 0 {
+		lines = append(lines, "upgrades crossed while catching up: "+strings.Join(c.Upgrades, ", "))
+	}
+	return lines
+}
+
+func bytesDuration(n, perSec int64) time.Duration {
+	return time.Duration(n/perSec) * time.Second
+}
+
+func formatEstimate(d time.Duration) string {
+	if d == 1<<63-1 {
+		return "unknown"
+	}
+	return d.Round(time.Minute).String()
+}
+
+func gib(n int64) float64 {
+	return float64(n) / (1 << 30)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
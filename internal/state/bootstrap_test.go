@@ -0,0 +1,109 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/seictl/pkg/types"
+)
+
+func setupBootstrapManager(t *testing.T, tip int64) *Manager {
+	rpc := fakeBlockRPC(t, tip, canonicalHash)
+	manager := setupTrustManager(t, []string{rpc.URL}, 1)
+
+	configDir := filepath.Join(manager.config.Global.HomeDir, "config")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "genesis.json"), []byte(`{"chain_id":"test-1"}`), 0644))
+	return manager
+}
+
+func candidate(plan *BootstrapPlan, strategy BootstrapStrategy) BootstrapCandidate {
+	for _, c := range plan.Candidates {
+		if c.Strategy == strategy {
+			return c
+		}
+	}
+	return BootstrapCandidate{}
+}
+
+func TestPlanBootstrapPrefersRecentLocalSnapshot(t *testing.T) {
+	manager := setupBootstrapManager(t, 1000000)
+	writeTestSnapshot(t, manager, 999000, map[string]string{"data.tar.gz": "archive"})
+
+	plan, err := manager.PlanBootstrap(context.Background(), BootstrapOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1000000), plan.TipHeight)
+	require.NotNil(t, plan.Choice)
+	assert.Equal(t, StrategyLocalSnapshot, plan.Choice.Strategy)
+	assert.Equal(t, int64(1000), plan.Choice.CatchUp)
+
+	stateSync := candidate(plan, StrategyStateSync)
+	assert.True(t, stateSync.Viable)
+	assert.Equal(t, int64(998000), stateSync.Height)
+	// Replaying a million blocks is the slowest option
+	assert.Greater(t, candidate(plan, StrategyBlockSync).Estimate, stateSync.Estimate)
+}
+
+func TestPlanBootstrapFallsBackToStateSync(t *testing.T) {
+	manager := setupBootstrapManager(t, 1000000)
+
+	plan, err := manager.PlanBootstrap(context.Background(), BootstrapOptions{})
+	require.NoError(t, err)
+
+	assert.False(t, candidate(plan, StrategyLocalSnapshot).Viable)
+	require.NotNil(t, plan.Choice)
+	assert.Equal(t, StrategyStateSync, plan.Choice.Strategy)
+}
+
+func TestPlanBootstrapArchive(t *testing.T) {
+	manager := setupBootstrapManager(t, 1000000)
+	writeTestSnapshot(t, manager, 999000, map[string]string{"data.tar.gz": "archive"})
+
+	plan, err := manager.PlanBootstrap(context.Background(), BootstrapOptions{Archive: true})
+	require.NoError(t, err)
+
+	assert.False(t, candidate(plan, StrategyLocalSnapshot).Viable)
+	assert.Contains(t, candidate(plan, StrategyLocalSnapshot).Reason, "archive")
+	assert.False(t, candidate(plan, StrategyStateSync).Viable)
+	require.NotNil(t, plan.Choice)
+	assert.Equal(t, StrategyBlockSync, plan.Choice.Strategy)
+}
+
+func TestPlanBootstrapUpgradesCrossed(t *testing.T) {
+	manager := setupBootstrapManager(t, 1000000)
+	env := manager.config.Environments["testnet"]
+	env.Upgrades = []types.ChainUpgrade{
+		{Name: "v2", Height: 500000, Version: "v2.0.0"},
+		{Name: "v3", Height: 999500, Version: "v3.0.0"},
+	}
+	manager.config.Environments["testnet"] = env
+	writeTestSnapshot(t, manager, 999000, map[string]string{"data.tar.gz": "archive"})
+
+	plan, err := manager.PlanBootstrap(context.Background(), BootstrapOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"v3 at 999500 (v3.0.0)"}, candidate(plan, StrategyLocalSnapshot).Upgrades)
+	assert.Len(t, candidate(plan, StrategyBlockSync).Upgrades, 2)
+	// State sync lands at 998000, before v3
+	assert.Equal(t, []string{"v3 at 999500 (v3.0.0)"}, candidate(plan, StrategyStateSync).Upgrades)
+}
+
+func TestPlanBootstrapExistingData(t *testing.T) {
+	manager := setupBootstrapManager(t, 1000000)
+	require.NoError(t, os.MkdirAll(filepath.Join(manager.config.Global.HomeDir, "data", "blockstore.db"), 0755))
+
+	plan, err := manager.PlanBootstrap(context.Background(), BootstrapOptions{})
+	require.NoError(t, err)
+	assert.True(t, plan.HasData)
+	assert.Nil(t, plan.Choice)
+	assert.Empty(t, plan.Candidates)
+
+	err = manager.ExecuteBootstrap(context.Background(), plan, BootstrapOptions{}, "")
+	assert.Error(t, err)
+}
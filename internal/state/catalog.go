@@ -0,0 +1,496 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-org/seictl/internal/utils"
+)
+
+const (
+	snapshotDirPrefix = "snapshot_"
+	manifestFileName  = "manifest.json"
+)
+
+// Snapshot integrity states reported by the catalog
+const (
+	IntegrityOK         = "ok"
+	IntegrityIncomplete = "incomplete"
+	IntegrityCorrupt    = "corrupt"
+	IntegrityUnknown    = "unknown"
+)
+
+// SnapshotManifest describes the contents of a snapshot directory
+type SnapshotManifest struct {
+	Height    int64             `json:"height"`
+	ChainID   string            `json:"chain_id,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Archives  []SnapshotArchive `json:"archives"`
+	// Type is empty for full tar snapshots
+	Type string `json:"type,omitempty"`
+	// LogicalSize is the size of the restored files for incremental snapshots
+	LogicalSize int64 `json:"logical_size,omitempty"`
+	// SeidVersion and Pruning describe the node the snapshot was taken from
+	SeidVersion string `json:"seid_version,omitempty"`
+	Pruning     string `json:"pruning,omitempty"`
+}
+
+// SnapshotArchive describes a single file stored in a snapshot
+type SnapshotArchive struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// TotalSize returns the combined size of all archives in the manifest
+func (sm *SnapshotManifest) TotalSize() int64 {
+	var total int64
+	for _, a := range sm.Archives {
+		total += a.Size
+	}
+	return total
+}
+
+// SnapshotInfo is a catalog entry for a snapshot found in the backup directory
+type SnapshotInfo struct {
+	Height      int64     `json:"height"`
+	Path        string    `json:"path"`
+	ChainID     string    `json:"chain_id,omitempty"`
+	Type        string    `json:"type,omitempty"`
+	Size        int64     `json:"size"`
+	LogicalSize int64     `json:"logical_size,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	Age         Seconds   `json:"age_seconds"`
+	Integrity   string    `json:"integrity"`
+}
+
+// Seconds is a duration encoded in JSON as a number of seconds, which unlike
+// time.Duration's nanoseconds needs no explanation to API consumers
+type Seconds time.Duration
+
+// MarshalJSON encodes the duration as seconds
+func (s Seconds) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(s).Seconds())
+}
+
+// Duration returns s as a time.Duration
+func (s Seconds) Duration() time.Duration {
+	return time.Duration(s)
+}
+
+// ArchiveCheck is the result of verifying a single archive
+type ArchiveCheck struct {
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+// VerifyResult is the result of re-hashing all archives of a snapshot
+type VerifyResult struct {
+	Height   int64          `json:"height"`
+	Path     string         `json:"path"`
+	OK       bool           `json:"ok"`
+	Archives []ArchiveCheck `json:"archives"`
+}
+
+// ListSnapshots scans the backup directory and returns all snapshots, newest first
+func (m *Manager) ListSnapshots() ([]SnapshotInfo, error) {
+	entries, err := os.ReadDir(m.config.Global.BackupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	now := time.Now()
+	var snapshots []SnapshotInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		height, ok := parseSnapshotDirName(entry.Name())
+		if !ok {
+			continue
+		}
+
+		info, err := m.describeSnapshot(filepath.Join(m.config.Global.BackupDir, entry.Name()), height)
+		if err != nil {
+			m.logger.Warn().Err(err).Str("snapshot", entry.Name()).Msg("Failed to read snapshot")
+			continue
+		}
+		info.Age = Seconds(now.Sub(info.CreatedAt))
+		snapshots = append(snapshots, *info)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Height > snapshots[j].Height
+	})
+
+	return snapshots, nil
+}
+
+// InspectSnapshot returns the full manifest of the snapshot at the given height
+func (m *Manager) InspectSnapshot(height int64) (*SnapshotManifest, error) {
+	dir, err := m.snapshotDir(height)
+	if err != nil {
+		return nil, err
+	}
+
+	return readManifest(dir)
+}
+
+// VerifySnapshotIntegrity re-hashes every archive of a snapshot and compares
+// it against the checksums recorded in its manifest
+func (m *Manager) VerifySnapshotIntegrity(height int64) (*VerifyResult, error) {
+	dir, err := m.snapshotDir(height)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{
+		Height: height,
+		Path:   dir,
+		OK:     true,
+	}
+
+	for _, archive := range manifest.Archives {
+		check := ArchiveCheck{
+			Name:     archive.Name,
+			Expected: archive.SHA256,
+		}
+
+		sum, err := utils.CalculateFileChecksum(filepath.Join(dir, archive.Name))
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Actual = sum
+			check.OK = sum == archive.SHA256
+		}
+
+		if !check.OK {
+			result.OK = false
+		}
+		result.Archives = append(result.Archives, check)
+	}
+
+	if manifest.Type == SnapshotTypeIncremental {
+		failures, err := m.verifyIndexChunks(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(failures) > 0 {
+			result.OK = false
+			result.Archives = append(result.Archives, failures...)
+		}
+	}
+
+	m.logger.Info().
+		Int64("height", height).
+		Bool("ok", result.OK).
+		Msg("Snapshot verification finished")
+
+	return result, nil
+}
+
+// DeleteSnapshot removes the snapshot at the given height from the backup directory
+func (m *Manager) DeleteSnapshot(height int64) error {
+	dir, err := m.snapshotDir(height)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+
+	m.logger.Info().Int64("height", height).Str("path", dir).Msg("Snapshot deleted")
+	return nil
+}
+
+func (m *Manager) snapshotDir(height int64) (string, error) {
+	dir := filepath.Join(m.config.Global.BackupDir, snapshotDirName(height))
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("snapshot at height %d not found", height)
+		}
+		return "", fmt.Errorf("failed to stat snapshot: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("snapshot path is not a directory: %s", dir)
+	}
+
+	return dir, nil
+}
+
+func (m *Manager) describeSnapshot(dir string, height int64) (*SnapshotInfo, error) {
+	info := &SnapshotInfo{
+		Height: height,
+		Path:   dir,
+	}
+
+	manifest, err := readManifest(dir)
+	if err != nil {
+		// Snapshots created before manifests existed still show up in the catalog
+		stat, statErr := os.Stat(dir)
+		if statErr != nil {
+			return nil, statErr
+		}
+		size, sizeErr := dirSize(dir)
+		if sizeErr != nil {
+			return nil, sizeErr
+		}
+		info.CreatedAt = stat.ModTime()
+		info.Size = size
+		info.Integrity = IntegrityUnknown
+		return info, nil
+	}
+
+	info.ChainID = manifest.ChainID
+	info.Type = manifest.Type
+	info.CreatedAt = manifest.CreatedAt
+	info.Size = manifest.TotalSize()
+	info.LogicalSize = manifest.LogicalSize
+	info.Integrity = checkArchiveSizes(dir, manifest)
+
+	return info, nil
+}
+
+// writeManifest hashes the given archives and writes the manifest into the snapshot directory
+func (m *Manager) writeManifest(dir string, height int64, archives []string) (*SnapshotManifest, error) {
+	manifest, err := m.buildManifest(dir, height, archives)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := saveManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// buildManifest hashes the given archives of a snapshot directory
+func (m *Manager) buildManifest(dir string, height int64, archives []string) (*SnapshotManifest, error) {
+	manifest := &SnapshotManifest{
+		Height:      height,
+		ChainID:     m.localChainID(),
+		CreatedAt:   time.Now().UTC(),
+		SeidVersion: m.seidVersion(),
+		Pruning:     m.pruningMode(),
+	}
+
+	for _, name := range archives {
+		path := filepath.Join(dir, name)
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat archive %s: %w", name, err)
+		}
+		sum, err := utils.CalculateFileChecksum(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash archive %s: %w", name, err)
+		}
+		manifest.Archives = append(manifest.Archives, SnapshotArchive{
+			Name:   name,
+			Size:   stat.Size(),
+			SHA256: sum,
+		})
+	}
+
+	return manifest, nil
+}
+
+func saveManifest(dir string, manifest *SnapshotManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, manifestFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// localChainID reads the chain ID from the node's genesis file
+func (m *Manager) localChainID() string {
+	data, err := os.ReadFile(filepath.Join(m.config.Global.HomeDir, "config", "genesis.json"))
+	if err != nil {
+		return ""
+	}
+
+	var genesis struct {
+		ChainID string `json:"chain_id"`
+	}
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return ""
+	}
+
+	return genesis.ChainID
+}
+
+// seidVersion returns the version reported by the installed seid binary
+func (m *Manager) seidVersion() string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "seid", "version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// pruningMode reads the pruning strategy from the node's app.toml. SeiDB
+// archive nodes report "nothing" like legacy ones.
+func (m *Manager) pruningMode() string {
+	settings, err := m.ReadPruning()
+	if err != nil {
+		return ""
+	}
+	switch {
+	case settings.Archive():
+		return "nothing"
+	case settings.Layout == LayoutSeiDB:
+		return LayoutSeiDB
+	}
+	return settings.Legacy.Mode
+}
+
+// nodeConfigValue reads a top-level key from one of the node's config files
+func (m *Manager) nodeConfigValue(file, name string) string {
+	data, err := os.ReadFile(filepath.Join(m.config.Global.HomeDir, "config", file))
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "[") {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(key) == name {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
+
+// dataDir is the node's database directory: db_dir from config.toml, relative
+// to the home directory unless absolute
+func (m *Manager) dataDir() string {
+	dir := m.nodeConfigValue("config.toml", "db_dir")
+	if dir == "" {
+		dir = "data"
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(m.config.Global.HomeDir, dir)
+}
+
+// componentDir returns the real directory behind data or wasm, following
+// db_dir and the symlink left by a data move
+func (m *Manager) componentDir(name string) string {
+	path := filepath.Join(m.config.Global.HomeDir, name)
+	if name == "data" {
+		path = m.dataDir()
+	}
+	return resolvePath(path)
+}
+
+// relWithin returns path relative to base when it lies inside base
+func relWithin(base, path string) (string, bool) {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// resolvePath follows the symlinks in path. A path that does not exist yet is
+// resolved as far as its parent.
+func resolvePath(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(dir, filepath.Base(path))
+	}
+	return path
+}
+
+func readManifest(dir string) (*SnapshotManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	manifest := &SnapshotManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func checkArchiveSizes(dir string, manifest *SnapshotManifest) string {
+	for _, archive := range manifest.Archives {
+		stat, err := os.Stat(filepath.Join(dir, archive.Name))
+		if err != nil {
+			return IntegrityIncomplete
+		}
+		if stat.Size() != archive.Size {
+			return IntegrityCorrupt
+		}
+	}
+	return IntegrityOK
+}
+
+func snapshotDirName(height int64) string {
+	return fmt.Sprintf("%s%d", snapshotDirPrefix, height)
+}
+
+func parseSnapshotDirName(name string) (int64, bool) {
+	if !strings.HasPrefix(name, snapshotDirPrefix) {
+		return 0, false
+	}
+
+	height, err := strconv.ParseInt(strings.TrimPrefix(name, snapshotDirPrefix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return height, true
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+func setupTestManager(t *testing.T) (*Manager, string) {
+	tmpDir := t.TempDir()
+
+	config := &types.Config{
+		Version: "1.0",
+		Global: types.GlobalConfig{
+			HomeDir:        filepath.Join(tmpDir, "home"),
+			BackupDir:      filepath.Join(tmpDir, "backup"),
+			TimeoutSeconds: 5,
+			LogLevel:       "info",
+		},
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Join(config.Global.HomeDir, "config"), 0755))
+	require.NoError(t, os.MkdirAll(config.Global.BackupDir, 0755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(config.Global.HomeDir, "config", "genesis.json"),
+		[]byte(`{"chain_id":"test-1"}`), 0644))
+
+	manager, err := NewManager(config, zerolog.Nop())
+	require.NoError(t, err)
+
+	return manager, tmpDir
+}
+
+func writeTestSnapshot(t *testing.T, m *Manager, height int64, files map[string]string) string {
+	dir := filepath.Join(m.config.Global.BackupDir, snapshotDirName(height))
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	var names []string
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		names = append(names, name)
+	}
+
+	_, err := m.writeManifest(dir, height, names)
+	require.NoError(t, err)
+
+	return dir
+}
+
+func TestListSnapshots(t *testing.T) {
+	manager, _ := setupTestManager(t)
+
+	writeTestSnapshot(t, manager, 100, map[string]string{"data_100.tar.gz": "aaaa"})
+	writeTestSnapshot(t, manager, 200, map[string]string{"data_200.tar.gz": "bbbbbbbb"})
+	require.NoError(t, os.MkdirAll(filepath.Join(manager.config.Global.BackupDir, "snapshot_300"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(manager.config.Global.BackupDir, "unrelated"), 0755))
+
+	snapshots, err := manager.ListSnapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+
+	assert.Equal(t, int64(300), snapshots[0].Height)
+	assert.Equal(t, IntegrityUnknown, snapshots[0].Integrity)
+
+	assert.Equal(t, int64(200), snapshots[1].Height)
+	assert.Equal(t, "test-1", snapshots[1].ChainID)
+	assert.Equal(t, int64(8), snapshots[1].Size)
+	assert.Equal(t, IntegrityOK, snapshots[1].Integrity)
+}
+
+func TestSecondsJSON(t *testing.T) {
+	data, err := json.Marshal(SnapshotInfo{Age: Seconds(90 * time.Second)})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"age_seconds":90`)
+
+	data, err = json.Marshal(EndpointHealth{Latency: Seconds(250 * time.Millisecond)})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"latency_seconds":0.25`)
+}
+
+func TestInspectSnapshot(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	writeTestSnapshot(t, manager, 100, map[string]string{"data_100.tar.gz": "aaaa"})
+
+	manifest, err := manager.InspectSnapshot(100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), manifest.Height)
+	require.Len(t, manifest.Archives, 1)
+	assert.Equal(t, "data_100.tar.gz", manifest.Archives[0].Name)
+	assert.Len(t, manifest.Archives[0].SHA256, 64)
+
+	_, err = manager.InspectSnapshot(999)
+	assert.Error(t, err)
+}
+
+func TestVerifySnapshotIntegrity(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	dir := writeTestSnapshot(t, manager, 100, map[string]string{"data_100.tar.gz": "aaaa"})
+
+	result, err := manager.VerifySnapshotIntegrity(100)
+	require.NoError(t, err)
+	assert.True(t, result.OK)
+
+	// Same size, different content
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data_100.tar.gz"), []byte("abcd"), 0644))
+
+	result, err = manager.VerifySnapshotIntegrity(100)
+	require.NoError(t, err)
+	assert.False(t, result.OK)
+	assert.False(t, result.Archives[0].OK)
+}
+
+func TestDeleteSnapshot(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	dir := writeTestSnapshot(t, manager, 100, map[string]string{"data_100.tar.gz": "aaaa"})
+
+	require.NoError(t, manager.DeleteSnapshot(100))
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Error(t, manager.DeleteSnapshot(100))
+}
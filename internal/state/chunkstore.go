@@ -0,0 +1,224 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	chunkStoreDirName = "chunks"
+	chunkLockFile     = ".lock"
+
+	// Content-defined chunking parameters. Changing these changes chunk
+	// boundaries and defeats deduplication against existing snapshots.
+	minChunkSize = 256 << 10
+	maxChunkSize = 8 << 20
+	chunkMask    = (1 << 21) - 1
+)
+
+// gearTable maps each byte to a pseudo-random value for the rolling hash
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	seed := uint64(0x5e1c71)
+	for i := range table {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// chunker splits a stream into content-defined chunks, so inserting data
+// into a file only changes the chunks around the insertion
+type chunker struct {
+	r   io.Reader
+	buf []byte
+	n   int
+	eof bool
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{r: r, buf: make([]byte, maxChunkSize)}
+}
+
+// next returns the next chunk, or io.EOF once the stream is exhausted. The
+// returned slice is only valid until the next call.
+func (c *chunker) next() ([]byte, error) {
+	for c.n < len(c.buf) && !c.eof {
+		k, err := c.r.Read(c.buf[c.n:])
+		c.n += k
+		if err == io.EOF {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if c.n == 0 {
+		return nil, io.EOF
+	}
+
+	cut := cutPoint(c.buf[:c.n])
+	chunk := make([]byte, cut)
+	copy(chunk, c.buf[:cut])
+	copy(c.buf, c.buf[cut:c.n])
+	c.n -= cut
+
+	return chunk, nil
+}
+
+func cutPoint(data []byte) int {
+	if len(data) <= minChunkSize {
+		return len(data)
+	}
+
+	var h uint64
+	for i := minChunkSize; i < len(data); i++ {
+		h = (h << 1) + gearTable[data[i]]
+		if h&chunkMask == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// chunkStore is a content-addressed repository of zstd-compressed chunks,
+// stored as <dir>/<first two hex chars>/<sha256>
+type chunkStore struct {
+	dir  string
+	enc  *zstd.Encoder
+	dec  *zstd.Decoder
+	lock *os.File
+}
+
+// openChunkStore opens the repository and takes an exclusive lock on it, so
+// garbage collection never races with a snapshot that is adding chunks
+func openChunkStore(dir string) (*chunkStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create chunk store: %w", err)
+	}
+
+	lock, err := os.OpenFile(filepath.Join(dir, chunkLockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chunk store lock: %w", err)
+	}
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("failed to lock chunk store: %w", err)
+	}
+
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	return &chunkStore{dir: dir, enc: enc, dec: dec, lock: lock}, nil
+}
+
+func (c *chunkStore) Close() error {
+	c.enc.Close()
+	c.dec.Close()
+	return c.lock.Close()
+}
+
+func (c *chunkStore) path(hash string) string {
+	return filepath.Join(c.dir, hash[:2], hash)
+}
+
+func (c *chunkStore) has(hash string) bool {
+	_, err := os.Stat(c.path(hash))
+	return err == nil
+}
+
+// put stores a chunk unless it already exists and returns its hash and the
+// number of bytes newly written to disk
+func (c *chunkStore) put(data []byte) (string, int64, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if c.has(hash) {
+		return hash, 0, nil
+	}
+
+	compressed := c.enc.EncodeAll(data, nil)
+	if err := c.writeRaw(hash, compressed); err != nil {
+		return "", 0, err
+	}
+
+	return hash, int64(len(compressed)), nil
+}
+
+// writeRaw stores an already compressed chunk atomically
+func (c *chunkStore) writeRaw(hash string, compressed []byte) error {
+	path := c.path(hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(compressed); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// get returns the decompressed chunk after checking its hash
+func (c *chunkStore) get(hash string) ([]byte, error) {
+	compressed, err := os.ReadFile(c.path(hash))
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s: %w", hash, err)
+	}
+
+	data, err := c.dec.DecodeAll(compressed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s is corrupt: %w", hash, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("chunk %s is corrupt: hash mismatch", hash)
+	}
+
+	return data, nil
+}
+
+// walk calls fn for every chunk in the store
+func (c *chunkStore) walk(fn func(hash string, size int64) error) error {
+	return filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || len(info.Name()) != 64 || !isValidHash(info.Name()) {
+			return nil
+		}
+		return fn(info.Name(), info.Size())
+	})
+}
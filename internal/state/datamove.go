@@ -0,0 +1,688 @@
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/your-org/seictl/internal/utils"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// How a moved directory is found by seid
+const (
+	// MoveLinkSymlink replaces the directory in the node home with a symlink
+	MoveLinkSymlink = "symlink"
+	// MoveLinkDBDir points db_dir in config.toml at the new location
+	MoveLinkDBDir = "db_dir"
+)
+
+const (
+	moveJournalFile = ".seictl-move.json"
+	movePartSuffix  = ".seictl-part"
+	moveOldSuffix   = ".seictl-old"
+	// moveJournalEvery is how many copied files are batched per journal write
+	moveJournalEvery = 256
+)
+
+// moveResumePoll is how often the local RPC is polled after the restart
+var moveResumePoll = 2 * time.Second
+
+// MoveOptions configures MoveData
+type MoveOptions struct {
+	// Name is "data" or "wasm"
+	Name string
+	// Dest is the new location, on the target volume
+	Dest string
+	Env  types.Environment
+	// RPC is the local node's RPC, used to confirm it resumes
+	RPC  string
+	Node NodeController
+	// NoRestart leaves the node stopped after the move
+	NoRestart bool
+	// KeepSource keeps the old copy after a verified cross-volume move
+	KeepSource bool
+	// ResumeTimeout bounds the wait for the node to reach its old height
+	ResumeTimeout time.Duration
+	Progress      func(MoveProgress)
+}
+
+// MoveProgress reports copy progress
+type MoveProgress struct {
+	File        string
+	Files       int
+	BytesDone   int64
+	BytesTotal  int64
+	BytesResume int64
+}
+
+// MoveResult describes what MoveData did
+type MoveResult struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Dest   string `json:"dest"`
+	// Method is "rename" on the same filesystem, otherwise "copy"
+	Method string `json:"method"`
+	// Link is how seid finds the new location
+	Link         string `json:"link"`
+	Files        int    `json:"files"`
+	Bytes        int64  `json:"bytes"`
+	Resumed      int64  `json:"resumed_bytes,omitempty"`
+	HeightBefore int64  `json:"height_before,omitempty"`
+	HeightAfter  int64  `json:"height_after,omitempty"`
+	// OldCopy is kept when KeepSource is set
+	OldCopy string `json:"old_copy,omitempty"`
+}
+
+// moveJournal records files already copied and verified, so an interrupted
+// copy resumes where it stopped
+type moveJournal struct {
+	Source string                      `json:"source"`
+	Files  map[string]moveJournalEntry `json:"files"`
+}
+
+type moveJournalEntry struct {
+	Size int64 `json:"size"`
+	// ModTime is the source's modification time in Unix nanoseconds
+	ModTime int64  `json:"mod_time"`
+	SHA256  string `json:"sha256"`
+}
+
+// matches reports whether the journaled copy is still of the current source
+func (e moveJournalEntry) matches(info fs.FileInfo) bool {
+	return e.Size == info.Size() && e.ModTime == info.ModTime().UnixNano()
+}
+
+// movePlan is where a directory lives and how seid is pointed at it
+type movePlan struct {
+	// linkPath is the path seid uses: the symlink or the db_dir directory
+	linkPath string
+	// source is the real directory
+	source string
+	// wasLink is set when linkPath already is a symlink
+	wasLink bool
+	link    string
+	dbDir   string
+}
+
+// MoveData moves the data or wasm directory to another location, usually a
+// bigger volume. The node is stopped, the directory renamed or copied with
+// verification, seid pointed at the new location and the node restarted. The
+// move is rolled back if the node does not resume at its previous height.
+func (m *Manager) MoveData(ctx context.Context, opts MoveOptions) (*MoveResult, error) {
+	if opts.Name != "data" && opts.Name != "wasm" {
+		return nil, fmt.Errorf("can only move data or wasm, not %q", opts.Name)
+	}
+	if !filepath.IsAbs(opts.Dest) {
+		return nil, fmt.Errorf("destination %q must be an absolute path", opts.Dest)
+	}
+	opts.Dest = filepath.Clean(opts.Dest)
+	if tmpfs, err := m.readTmpfsState(); err != nil {
+		return nil, err
+	} else if tmpfs != nil {
+		return nil, fmt.Errorf("tmpfs acceleration is enabled, run optimize disable first")
+	}
+
+	plan, err := m.planMove(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Dest == plan.source {
+		return nil, fmt.Errorf("%s is already at %s", opts.Name, opts.Dest)
+	}
+	if rel, err := filepath.Rel(plan.source, opts.Dest); err == nil && !strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("destination %s is inside %s", opts.Dest, plan.source)
+	}
+	sameFS, err := sameFilesystem(plan.source, opts.Dest)
+	if err != nil {
+		return nil, err
+	}
+	if sameFS {
+		// A rename needs an empty or missing destination
+		if entries, err := os.ReadDir(opts.Dest); err == nil && len(entries) > 0 {
+			return nil, fmt.Errorf("destination %s is not empty", opts.Dest)
+		}
+	} else if err := checkMoveDest(opts.Dest, plan.source); err != nil {
+		return nil, err
+	}
+
+	if opts.RPC == "" {
+		opts.RPC = m.localRPCFor(opts.Env)
+	}
+	if opts.Node == nil {
+		opts.Node = m.NewNodeController()
+	}
+	if opts.ResumeTimeout <= 0 {
+		opts.ResumeTimeout = 10 * time.Minute
+	}
+
+	result := &MoveResult{Name: opts.Name, Source: plan.source, Dest: opts.Dest, Link: plan.link, Method: "copy"}
+	if sameFS {
+		result.Method = "rename"
+	}
+
+	// The node reports its height while running; otherwise read it from the
+	// blockstore once stopped
+	if status, err := m.queryStatus(ctx, opts.RPC); err == nil {
+		result.HeightBefore = status.LatestHeight
+	}
+	m.logger.Info().Str("dir", opts.Name).Str("dest", opts.Dest).Msg("Stopping node to move directory")
+	if err := opts.Node.Stop(ctx); err != nil {
+		return nil, fmt.Errorf("failed to stop node: %w", err)
+	}
+	if result.HeightBefore == 0 {
+		if inspection, err := m.InspectDatabases(); err == nil && inspection.BlockStore != nil {
+			result.HeightBefore = inspection.BlockStore.Height
+		}
+	}
+
+	if sameFS {
+		if err := os.MkdirAll(filepath.Dir(opts.Dest), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(opts.Dest), err)
+		}
+		os.Remove(opts.Dest)
+		if err := os.Rename(plan.source, opts.Dest); err != nil {
+			return nil, fmt.Errorf("failed to rename %s: %w", plan.source, err)
+		}
+	} else {
+		stats, err := m.copyVerified(ctx, plan.source, opts.Dest, opts.Progress)
+		if err != nil {
+			return nil, fmt.Errorf("copy interrupted, run the same command again to resume: %w", err)
+		}
+		result.Files, result.Bytes, result.Resumed = stats.Files, stats.BytesTotal, stats.BytesResume
+	}
+
+	oldCopy, err := m.switchMove(plan, opts.Dest, sameFS)
+	if err != nil {
+		if uerr := m.undoMove(plan, opts.Dest, sameFS, oldCopy); uerr != nil {
+			return nil, fmt.Errorf("failed to switch to %s: %v; rollback also failed: %w", opts.Dest, err, uerr)
+		}
+		return nil, fmt.Errorf("failed to switch to %s: %w", opts.Dest, err)
+	}
+
+	if !opts.NoRestart {
+		height, err := m.restartAndConfirm(ctx, opts, result.HeightBefore)
+		if err != nil {
+			m.logger.Error().Err(err).Msg("Node did not resume, rolling back the move")
+			if serr := opts.Node.Stop(ctx); serr != nil {
+				return nil, fmt.Errorf("%v; failed to stop node for rollback: %w", err, serr)
+			}
+			if uerr := m.undoMove(plan, opts.Dest, sameFS, oldCopy); uerr != nil {
+				return nil, fmt.Errorf("%v; rollback also failed: %w", err, uerr)
+			}
+			if serr := opts.Node.Start(ctx); serr != nil {
+				return nil, fmt.Errorf("%v; rolled back but failed to restart node: %w", err, serr)
+			}
+			return nil, fmt.Errorf("move rolled back: %w", err)
+		}
+		result.HeightAfter = height
+	}
+
+	os.Remove(filepath.Join(opts.Dest, moveJournalFile))
+	if oldCopy != "" {
+		if opts.KeepSource {
+			result.OldCopy = oldCopy
+		} else if err := os.RemoveAll(oldCopy); err != nil {
+			m.logger.Warn().Err(err).Str("path", oldCopy).Msg("Failed to remove old copy")
+		}
+	}
+
+	m.logger.Info().
+		Str("dir", opts.Name).
+		Str("dest", opts.Dest).
+		Str("method", result.Method).
+		Str("link", result.Link).
+		Msg("Directory moved")
+	return result, nil
+}
+
+// planMove finds where name lives. data honours a custom db_dir in
+// config.toml, which is then updated instead of creating a symlink.
+func (m *Manager) planMove(name string) (*movePlan, error) {
+	home := m.config.Global.HomeDir
+	plan := &movePlan{linkPath: filepath.Join(home, name), link: MoveLinkSymlink}
+	if name == "data" {
+		if dbDir := m.nodeConfigValue("config.toml", "db_dir"); dbDir != "" && filepath.Clean(dbDir) != "data" {
+			plan.dbDir = dbDir
+			plan.link = MoveLinkDBDir
+			plan.linkPath = dbDir
+			if !filepath.IsAbs(dbDir) {
+				plan.linkPath = filepath.Join(home, dbDir)
+			}
+		}
+	}
+
+	info, err := os.Lstat(plan.linkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s: %w", name, err)
+	}
+	plan.wasLink = info.Mode()&os.ModeSymlink != 0
+	plan.source, err = filepath.EvalSymlinks(plan.linkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", plan.linkPath, err)
+	}
+	return plan, nil
+}
+
+// switchMove points seid at dest. It returns the old copy to delete once the
+// node has resumed, if any.
+func (m *Manager) switchMove(plan *movePlan, dest string, renamed bool) (string, error) {
+	if plan.link == MoveLinkDBDir {
+		if err := m.setNodeConfigValue("config.toml", "db_dir", fmt.Sprintf("%q", dest)); err != nil {
+			return "", err
+		}
+		if renamed {
+			return "", nil
+		}
+		return plan.source, nil
+	}
+
+	var oldCopy string
+	switch {
+	case plan.wasLink:
+		if err := os.Remove(plan.linkPath); err != nil {
+			return "", fmt.Errorf("failed to remove symlink %s: %w", plan.linkPath, err)
+		}
+		if !renamed {
+			oldCopy = plan.source
+		}
+	case !renamed:
+		oldCopy = plan.linkPath + moveOldSuffix
+		if err := os.Rename(plan.linkPath, oldCopy); err != nil {
+			return "", fmt.Errorf("failed to move %s aside: %w", plan.linkPath, err)
+		}
+	}
+	if err := os.Symlink(dest, plan.linkPath); err != nil {
+		return oldCopy, fmt.Errorf("failed to create symlink %s: %w", plan.linkPath, err)
+	}
+	return oldCopy, nil
+}
+
+// undoMove reverses switchMove and, after a rename, moves the data back
+func (m *Manager) undoMove(plan *movePlan, dest string, renamed bool, oldCopy string) error {
+	if plan.link == MoveLinkDBDir {
+		if err := m.setNodeConfigValue("config.toml", "db_dir", fmt.Sprintf("%q", plan.dbDir)); err != nil {
+			return err
+		}
+	} else if info, err := os.Lstat(plan.linkPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(plan.linkPath); err != nil {
+			return fmt.Errorf("failed to remove symlink %s: %w", plan.linkPath, err)
+		}
+	}
+
+	if renamed {
+		if err := os.Rename(dest, plan.source); err != nil {
+			return fmt.Errorf("failed to move %s back to %s: %w", dest, plan.source, err)
+		}
+	} else if oldCopy != "" && oldCopy != plan.source {
+		if err := os.Rename(oldCopy, plan.linkPath); err != nil {
+			return fmt.Errorf("failed to move %s back: %w", oldCopy, err)
+		}
+	}
+	if plan.wasLink {
+		if err := os.Symlink(plan.source, plan.linkPath); err != nil {
+			return fmt.Errorf("failed to restore symlink %s: %w", plan.linkPath, err)
+		}
+	}
+	return nil
+}
+
+// restartAndConfirm starts the node and waits until it reports at least the
+// height it stopped at
+func (m *Manager) restartAndConfirm(ctx context.Context, opts MoveOptions, before int64) (int64, error) {
+	if err := opts.Node.Start(ctx); err != nil {
+		return 0, fmt.Errorf("failed to start node: %w", err)
+	}
+
+	deadline := time.Now().Add(opts.ResumeTimeout)
+	var lastErr error
+	for {
+		status, err := m.queryStatus(ctx, opts.RPC)
+		if err == nil && status.LatestHeight >= before {
+			m.logger.Info().Int64("height", status.LatestHeight).Msg("Node resumed")
+			return status.LatestHeight, nil
+		}
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("node is at height %d, below %d before the move", status.LatestHeight, before)
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("node did not resume within %s: %w", opts.ResumeTimeout, lastErr)
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(moveResumePoll):
+		}
+	}
+}
+
+// copyVerified copies src to dst, checking each file's SHA-256 after it is
+// written. Verified files are recorded in a journal in dst and skipped when
+// the copy is run again, unless the source has since changed size or
+// modification time. Files in dst that are no longer in src are removed.
+func (m *Manager) copyVerified(ctx context.Context, src, dst string, progress func(MoveProgress)) (MoveProgress, error) {
+	var stats MoveProgress
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return stats, fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	journal, err := readMoveJournal(dst, src)
+	if err != nil {
+		return stats, err
+	}
+
+	type entry struct {
+		rel  string
+		info fs.FileInfo
+	}
+	var files []entry
+	inSource := map[string]bool{}
+	err = filepath.Walk(src, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		inSource[rel] = true
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			files = append(files, entry{rel, info})
+			stats.BytesTotal += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to scan %s: %w", src, err)
+	}
+
+	free, err := utils.DiskFree(dst)
+	if err != nil {
+		return stats, fmt.Errorf("failed to check free space: %w", err)
+	}
+	var needed int64
+	for _, f := range files {
+		if done, ok := journal.Files[f.rel]; !ok || !done.matches(f.info) {
+			needed += f.info.Size()
+		}
+	}
+	if needed > free {
+		return stats, fmt.Errorf("%s needs %s more but only %s is free", dst, formatSize(needed), formatSize(free))
+	}
+
+	report := func(file string) {
+		if progress != nil {
+			stats.File = file
+			progress(stats)
+		}
+	}
+	pending := 0
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			writeMoveJournal(dst, journal)
+			return stats, err
+		}
+		target := filepath.Join(dst, f.rel)
+		if done, ok := journal.Files[f.rel]; ok && done.matches(f.info) {
+			if st, err := os.Stat(target); err == nil && st.Size() == done.Size {
+				stats.Files++
+				stats.BytesDone += done.Size
+				stats.BytesResume += done.Size
+				continue
+			}
+		}
+
+		sum, err := copyFileVerified(filepath.Join(src, f.rel), target, f.info.Mode().Perm(), func(n int64) {
+			stats.BytesDone += n
+			report(f.rel)
+		})
+		if err != nil {
+			writeMoveJournal(dst, journal)
+			return stats, fmt.Errorf("failed to copy %s: %w", f.rel, err)
+		}
+		journal.Files[f.rel] = moveJournalEntry{Size: f.info.Size(), ModTime: f.info.ModTime().UnixNano(), SHA256: sum}
+		stats.Files++
+		report(f.rel)
+
+		if pending++; pending >= moveJournalEvery {
+			if err := writeMoveJournal(dst, journal); err != nil {
+				return stats, err
+			}
+			pending = 0
+		}
+	}
+
+	if err := removeStale(dst, inSource, journal); err != nil {
+		writeMoveJournal(dst, journal)
+		return stats, err
+	}
+	return stats, writeMoveJournal(dst, journal)
+}
+
+// removeStale deletes what an earlier run copied to dst that has since been
+// removed from the source, e.g. tables compacted away between runs
+func removeStale(dst string, inSource map[string]bool, journal *moveJournal) error {
+	for rel := range journal.Files {
+		if !inSource[rel] {
+			delete(journal.Files, rel)
+		}
+	}
+	return filepath.Walk(dst, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." || rel == moveJournalFile || inSource[rel] {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to remove stale %s: %w", rel, err)
+		}
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// copyFileVerified copies src to dst through a temporary file, then re-reads
+// dst and compares checksums. It returns the SHA-256 of the file.
+func copyFileVerified(src, dst string, perm os.FileMode, written func(int64)) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	part := dst + movePartSuffix
+	out, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return "", err
+	}
+	srcHash := sha256.New()
+	_, err = io.Copy(out, io.TeeReader(in, &countingWriter{w: srcHash, written: written}))
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(part)
+		return "", err
+	}
+
+	dstSum, err := fileSHA256(part)
+	if err != nil {
+		os.Remove(part)
+		return "", err
+	}
+	srcSum := hex.EncodeToString(srcHash.Sum(nil))
+	if dstSum != srcSum {
+		os.Remove(part)
+		return "", fmt.Errorf("checksum mismatch after copy (%s != %s)", dstSum, srcSum)
+	}
+	if err := os.Rename(part, dst); err != nil {
+		return "", err
+	}
+	return srcSum, nil
+}
+
+// countingWriter reports bytes as they pass through to w
+type countingWriter struct {
+	w       io.Writer
+	written func(int64)
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	if c.written != nil {
+		c.written(int64(n))
+	}
+	return n, err
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkMoveDest accepts a missing or empty destination, or one holding an
+// interrupted copy of the same source
+func checkMoveDest(dest, source string) error {
+	entries, err := os.ReadDir(dest)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(entries) == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dest, err)
+	}
+	if _, err := readMoveJournal(dest, source); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dest, moveJournalFile)); err != nil {
+		return fmt.Errorf("destination %s is not empty", dest)
+	}
+	return nil
+}
+
+func readMoveJournal(dst, source string) (*moveJournal, error) {
+	journal := &moveJournal{Source: source, Files: map[string]moveJournalEntry{}}
+	data, err := os.ReadFile(filepath.Join(dst, moveJournalFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return journal, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read move journal: %w", err)
+	}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("failed to parse move journal: %w", err)
+	}
+	if journal.Source != source {
+		return nil, fmt.Errorf("%s holds an interrupted copy of %s, not %s", dst, journal.Source, source)
+	}
+	if journal.Files == nil {
+		journal.Files = map[string]moveJournalEntry{}
+	}
+	return journal, nil
+}
+
+func writeMoveJournal(dst string, journal *moveJournal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal move journal: %w", err)
+	}
+	path := filepath.Join(dst, moveJournalFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write move journal: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// sameFilesystem reports whether dest, or its nearest existing parent, is on
+// the same filesystem as src
+func sameFilesystem(src, dest string) (bool, error) {
+	var s, d syscall.Stat_t
+	if err := syscall.Stat(src, &s); err != nil {
+		return false, fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+	for dir := dest; ; dir = filepath.Dir(dir) {
+		err := syscall.Stat(dir, &d)
+		if err == nil {
+			break
+		}
+		if dir == filepath.Dir(dir) {
+			return false, fmt.Errorf("failed to stat %s: %w", dest, err)
+		}
+	}
+	return s.Dev == d.Dev, nil
+}
+
+// setNodeConfigValue sets a top-level key in home/config/<file>, adding it
+// before the first section when missing
+func (m *Manager) setNodeConfigValue(file, key, value string) error {
+	path := filepath.Join(m.config.Global.HomeDir, "config", file)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	insert := len(lines)
+	found := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			insert = i
+			break
+		}
+		if name, _, ok := strings.Cut(trimmed, "="); ok && strings.TrimSpace(name) == key {
+			lines[i] = fmt.Sprintf("%s = %s", key, value)
+			found = true
+			break
+		}
+	}
+	if !found {
+		lines = append(lines[:insert], append([]string{fmt.Sprintf("%s = %s", key, value)}, lines[insert:]...)...)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHeightRPC reports the heights in order, repeating the last one
+func fakeHeightRPC(t *testing.T, heights ...int64) *httptest.Server {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&calls, 1)) - 1
+		if i >= len(heights) {
+			i = len(heights) - 1
+		}
+		fmt.Fprintf(w, `{"result":{"sync_info":{"catching_up":false,"latest_block_height":"%d"}}}`, heights[i])
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func setupMoveHome(t *testing.T) (*Manager, string) {
+	manager, tmp := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+	writeSizedFile(t, filepath.Join(home, "data", "application.db", "000001.ldb"), 8<<10)
+	writeSizedFile(t, filepath.Join(home, "data", "blockstore.db", "000001.ldb"), 4<<10)
+	writeSizedFile(t, filepath.Join(home, "config", "config.toml"), 0)
+
+	orig := moveResumePoll
+	moveResumePoll = time.Millisecond
+	t.Cleanup(func() { moveResumePoll = orig })
+	return manager, tmp
+}
+
+func TestMoveDataSameFilesystem(t *testing.T) {
+	manager, tmp := setupMoveHome(t)
+	home := manager.config.Global.HomeDir
+	dest := filepath.Join(tmp, "nvme", "data")
+	node := &fakeNode{}
+
+	result, err := manager.MoveData(context.Background(), MoveOptions{
+		Name: "data",
+		Dest: dest,
+		RPC:  fakeHeightRPC(t, 100, 101).URL,
+		Node: node,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "rename", result.Method)
+	assert.Equal(t, MoveLinkSymlink, result.Link)
+	assert.Equal(t, int64(100), result.HeightBefore)
+	assert.Equal(t, int64(101), result.HeightAfter)
+	assert.Equal(t, 1, node.stops)
+	assert.Equal(t, 1, node.starts)
+
+	link, err := os.Readlink(filepath.Join(home, "data"))
+	require.NoError(t, err)
+	assert.Equal(t, dest, link)
+	assert.FileExists(t, filepath.Join(home, "data", "application.db", "000001.ldb"))
+}
+
+func TestMoveDataRollsBackWhenNodeDoesNotResume(t *testing.T) {
+	manager, tmp := setupMoveHome(t)
+	home := manager.config.Global.HomeDir
+	dest := filepath.Join(tmp, "nvme", "data")
+	node := &fakeNode{}
+
+	_, err := manager.MoveData(context.Background(), MoveOptions{
+		Name:          "data",
+		Dest:          dest,
+		RPC:           fakeHeightRPC(t, 100, 1).URL,
+		Node:          node,
+		ResumeTimeout: 10 * time.Millisecond,
+	})
+	assert.ErrorContains(t, err, "rolled back")
+
+	info, err := os.Lstat(filepath.Join(home, "data"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.FileExists(t, filepath.Join(home, "data", "application.db", "000001.ldb"))
+	assert.NoDirExists(t, dest)
+	assert.Equal(t, 2, node.stops)
+	assert.Equal(t, 2, node.starts)
+}
+
+func TestMoveDataUpdatesDBDir(t *testing.T) {
+	manager, tmp := setupMoveHome(t)
+	home := manager.config.Global.HomeDir
+	require.NoError(t, os.WriteFile(filepath.Join(home, "config", "config.toml"),
+		[]byte("moniker = \"node\"\ndb_dir = \"data\"\n\n[rpc]\nladdr = \"tcp://0.0.0.0:26657\"\n"), 0644))
+	require.NoError(t, os.Rename(filepath.Join(home, "data"), filepath.Join(home, "db")))
+	require.NoError(t, manager.setNodeConfigValue("config.toml", "db_dir", `"db"`))
+	dest := filepath.Join(tmp, "nvme", "db")
+
+	result, err := manager.MoveData(context.Background(), MoveOptions{
+		Name:      "data",
+		Dest:      dest,
+		RPC:       fakeHeightRPC(t, 100).URL,
+		Node:      &fakeNode{},
+		NoRestart: true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, MoveLinkDBDir, result.Link)
+	assert.Equal(t, dest, manager.nodeConfigValue("config.toml", "db_dir"))
+	assert.NoDirExists(t, filepath.Join(home, "db"))
+	assert.FileExists(t, filepath.Join(dest, "blockstore.db", "000001.ldb"))
+}
+
+func TestMoveDataRefusesNonEmptyDest(t *testing.T) {
+	manager, tmp := setupMoveHome(t)
+	dest := filepath.Join(tmp, "nvme", "data")
+	writeSizedFile(t, filepath.Join(dest, "other"), 1)
+
+	_, err := manager.MoveData(context.Background(), MoveOptions{Name: "data", Dest: dest, Node: &fakeNode{}})
+	assert.ErrorContains(t, err, "not empty")
+
+	_, err = manager.MoveData(context.Background(), MoveOptions{Name: "config", Dest: dest})
+	assert.Error(t, err)
+}
+
+func TestCopyVerifiedResumes(t *testing.T) {
+	manager, tmp := setupMoveHome(t)
+	src := filepath.Join(manager.config.Global.HomeDir, "data")
+	dst := filepath.Join(tmp, "copy")
+	require.NoError(t, os.Symlink("application.db", filepath.Join(src, "app-link")))
+
+	// A previous run verified application.db before being interrupted
+	appFile := filepath.Join("application.db", "000001.ldb")
+	sum, err := fileSHA256(filepath.Join(src, appFile))
+	require.NoError(t, err)
+	info, err := os.Stat(filepath.Join(src, appFile))
+	require.NoError(t, err)
+	writeSizedFile(t, filepath.Join(dst, appFile), 8<<10)
+	// and a table that has since been compacted away
+	writeSizedFile(t, filepath.Join(dst, "application.db", "000000.ldb"), 1<<10)
+	require.NoError(t, writeMoveJournal(dst, &moveJournal{
+		Source: src,
+		Files: map[string]moveJournalEntry{
+			appFile: {Size: 8 << 10, ModTime: info.ModTime().UnixNano(), SHA256: sum},
+			filepath.Join("application.db", "000000.ldb"): {Size: 1 << 10},
+		},
+	}))
+
+	var updates int
+	stats, err := manager.copyVerified(context.Background(), src, dst, func(MoveProgress) { updates++ })
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, stats.Files)
+	assert.Equal(t, int64(12<<10), stats.BytesTotal)
+	assert.Equal(t, int64(12<<10), stats.BytesDone)
+	assert.Equal(t, int64(8<<10), stats.BytesResume)
+	assert.Greater(t, updates, 0)
+	assert.FileExists(t, filepath.Join(dst, "blockstore.db", "000001.ldb"))
+	link, err := os.Readlink(filepath.Join(dst, "app-link"))
+	require.NoError(t, err)
+	assert.Equal(t, "application.db", link)
+
+	assert.NoFileExists(t, filepath.Join(dst, "application.db", "000000.ldb"))
+
+	journal, err := readMoveJournal(dst, src)
+	require.NoError(t, err)
+	assert.Len(t, journal.Files, 2)
+
+	_, err = readMoveJournal(dst, "/elsewhere")
+	assert.ErrorContains(t, err, "interrupted copy")
+}
+
+func TestCopyVerifiedRecopiesChangedSource(t *testing.T) {
+	manager, tmp := setupMoveHome(t)
+	src := filepath.Join(manager.config.Global.HomeDir, "data")
+	dst := filepath.Join(tmp, "copy")
+
+	_, err := manager.copyVerified(context.Background(), src, dst, nil)
+	require.NoError(t, err)
+
+	// The source is rewritten in place with the same size before the copy
+	// is run again
+	appFile := filepath.Join(src, "application.db", "000001.ldb")
+	require.NoError(t, os.WriteFile(appFile, []byte(strings.Repeat("y", 8<<10)), 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(appFile, later, later))
+
+	stats, err := manager.copyVerified(context.Background(), src, dst, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4<<10), stats.BytesResume)
+
+	want, err := fileSHA256(appFile)
+	require.NoError(t, err)
+	got, err := fileSHA256(filepath.Join(dst, "application.db", "000001.ldb"))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,522 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/google/orderedcode"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Database backends as named by db_backend in config.toml
+const (
+	BackendGoLevelDB = "goleveldb"
+	BackendPebble    = "pebbledb"
+)
+
+// Keys used by the CometBFT stores
+var (
+	cometBlockStoreKey = []byte("blockStore")
+	cometStateKey      = []byte("stateKey")
+)
+
+// Key prefixes used by the sei-tendermint stores, encoded with orderedcode
+const (
+	tmPrefixBlockMeta = int64(0)
+	tmPrefixState     = int64(8)
+)
+
+// Keys used by the Cosmos SDK multistore in application.db
+const (
+	appLatestVersionKey = "s/latest"
+	appCommitInfoPrefix = "s/"
+)
+
+// BlockStoreInfo describes blockstore.db
+type BlockStoreInfo struct {
+	// Format is "cometbft" or "tendermint" depending on the key layout
+	Format     string    `json:"format,omitempty"`
+	Base       int64     `json:"base"`
+	Height     int64     `json:"height"`
+	LatestTime time.Time `json:"latest_time,omitempty"`
+	// HeaderAppHash is the app hash in the latest block's header, i.e. the
+	// state after the block before it
+	HeaderAppHash string `json:"header_app_hash,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// StateDBInfo describes state.db
+type StateDBInfo struct {
+	ChainID         string    `json:"chain_id,omitempty"`
+	InitialHeight   int64     `json:"initial_height,omitempty"`
+	LastBlockHeight int64     `json:"last_block_height"`
+	LastBlockTime   time.Time `json:"last_block_time,omitempty"`
+	AppHash         string    `json:"app_hash,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// AppDBInfo describes application.db
+type AppDBInfo struct {
+	LatestVersion int64    `json:"latest_version"`
+	AppHash       string   `json:"app_hash,omitempty"`
+	Stores        []string `json:"stores,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// DBInspection is the result of InspectDatabases
+type DBInspection struct {
+	DataDir    string          `json:"data_dir"`
+	Backend    string          `json:"backend"`
+	BlockStore *BlockStoreInfo `json:"blockstore"`
+	State      *StateDBInfo    `json:"state"`
+	// Layout is LayoutSeiDB when the app state lives in SeiDB, which is not
+	// inspected; Application is then nil
+	Layout      string     `json:"layout"`
+	Application *AppDBInfo `json:"application,omitempty"`
+	// Issues lists inconsistencies that will stop the node from starting
+	Issues []string `json:"issues,omitempty"`
+}
+
+// kvReader is the read-only subset of a key-value store needed to inspect
+// node databases
+type kvReader interface {
+	// Get returns nil when the key does not exist
+	Get(key []byte) ([]byte, error)
+	// Edge returns the first or last key in [lower, upper)
+	Edge(lower, upper []byte, last bool) (key, value []byte, err error)
+	Close() error
+}
+
+// InspectDatabases opens the node's databases read-only and reports their
+// heights, app hashes and any inconsistencies between them. seid must be
+// stopped, as it holds exclusive locks on the databases.
+func (m *Manager) InspectDatabases() (*DBInspection, error) {
+	dataDir := m.dataDir()
+	if _, err := os.Stat(dataDir); err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	result := &DBInspection{
+		DataDir: dataDir,
+		Backend: m.nodeConfigValue("config.toml", "db_backend"),
+		Layout:  m.appLayout(dataDir),
+	}
+
+	result.BlockStore = &BlockStoreInfo{}
+	if err := m.withDB(result, "blockstore", func(db kvReader) error {
+		return inspectBlockStore(db, result.BlockStore)
+	}); err != nil {
+		result.BlockStore.Error = err.Error()
+	}
+
+	result.State = &StateDBInfo{}
+	if err := m.withDB(result, "state", func(db kvReader) error {
+		return inspectStateDB(db, result.State)
+	}); err != nil {
+		result.State.Error = err.Error()
+	}
+
+	if result.Layout != LayoutSeiDB {
+		result.Application = &AppDBInfo{}
+		if err := m.withDB(result, "application", func(db kvReader) error {
+			return inspectAppDB(db, result.Application)
+		}); err != nil {
+			result.Application.Error = err.Error()
+		}
+	}
+
+	result.Issues = m.checkDatabases(result)
+	return result, nil
+}
+
+// appLayout reports LayoutSeiDB when state commit is enabled in app.toml or
+// its committer directory exists in dataDir. application.db is then at most a
+// leftover from before the migration.
+func (m *Manager) appLayout(dataDir string) string {
+	if pruning, err := m.ReadPruning(); err == nil && pruning.Layout == LayoutSeiDB {
+		return LayoutSeiDB
+	}
+	if info, err := os.Stat(filepath.Join(dataDir, seidbCommitterDir)); err == nil && info.IsDir() {
+		return LayoutSeiDB
+	}
+	return LayoutLegacy
+}
+
+// withDB opens name.db in the data directory and runs fn against it
+func (m *Manager) withDB(result *DBInspection, name string, fn func(kvReader) error) error {
+	path := filepath.Join(result.DataDir, name+".db")
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("not found")
+	}
+
+	backend := result.Backend
+	if backend == "" {
+		backend = detectBackend(path)
+		result.Backend = backend
+	}
+	db, err := openReadOnly(path, backend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return fn(db)
+}
+
+// detectBackend guesses the backend from the files in a database directory.
+// Pebble writes OPTIONS files, LevelDB does not.
+func detectBackend(path string) string {
+	matches, _ := filepath.Glob(filepath.Join(path, "OPTIONS-*"))
+	if len(matches) > 0 {
+		return BackendPebble
+	}
+	return BackendGoLevelDB
+}
+
+func openReadOnly(path, backend string) (kvReader, error) {
+	var db kvReader
+	var err error
+	switch backend {
+	case BackendGoLevelDB, "cleveldb":
+		db, err = openLevelDB(path)
+	case BackendPebble:
+		db, err = openPebble(path)
+	default:
+		return nil, fmt.Errorf("unsupported db_backend %q", backend)
+	}
+	if err != nil {
+		if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("%s is locked, stop seid before inspecting", filepath.Base(path))
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	return db, nil
+}
+
+type levelReader struct {
+	db *leveldb.DB
+}
+
+func openLevelDB(path string) (*levelReader, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+	if err != nil {
+		return nil, err
+	}
+	return &levelReader{db: db}, nil
+}
+
+func (r *levelReader) Get(key []byte) ([]byte, error) {
+	value, err := r.db.Get(key, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (r *levelReader) Edge(lower, upper []byte, last bool) ([]byte, []byte, error) {
+	it := r.db.NewIterator(&util.Range{Start: lower, Limit: upper}, nil)
+	defer it.Release()
+
+	ok := it.First()
+	if last {
+		ok = it.Last()
+	}
+	if !ok {
+		return nil, nil, it.Error()
+	}
+	return append([]byte(nil), it.Key()...), append([]byte(nil), it.Value()...), nil
+}
+
+func (r *levelReader) Close() error {
+	return r.db.Close()
+}
+
+type pebbleReader struct {
+	db *pebble.DB
+}
+
+func openPebble(path string) (*pebbleReader, error) {
+	db, err := pebble.Open(path, &pebble.Options{ReadOnly: true, ErrorIfNotExists: true})
+	if err != nil {
+		return nil, err
+	}
+	return &pebbleReader{db: db}, nil
+}
+
+func (r *pebbleReader) Get(key []byte) ([]byte, error) {
+	value, closer, err := r.db.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return append([]byte(nil), value...), nil
+}
+
+func (r *pebbleReader) Edge(lower, upper []byte, last bool) ([]byte, []byte, error) {
+	it, err := r.db.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer it.Close()
+
+	ok := it.First()
+	if last {
+		ok = it.Last()
+	}
+	if !ok {
+		return nil, nil, it.Error()
+	}
+	return append([]byte(nil), it.Key()...), append([]byte(nil), it.Value()...), nil
+}
+
+func (r *pebbleReader) Close() error {
+	return r.db.Close()
+}
+
+func inspectBlockStore(db kvReader, info *BlockStoreInfo) error {
+	state, err := db.Get(cometBlockStoreKey)
+	if err != nil {
+		return fmt.Errorf("failed to read blockstore state: %w", err)
+	}
+
+	var meta []byte
+	if state != nil {
+		info.Format = "cometbft"
+		fields, err := decodeProto(state)
+		if err != nil {
+			return fmt.Errorf("failed to decode blockstore state: %w", err)
+		}
+		info.Base, info.Height = protoInt(fields, 1), protoInt(fields, 2)
+		if info.Height > 0 {
+			if meta, err = db.Get([]byte(fmt.Sprintf("H:%d", info.Height))); err != nil {
+				return fmt.Errorf("failed to read block meta: %w", err)
+			}
+		}
+	} else {
+		// sei-tendermint keeps no summary; the range of block meta keys is
+		// the range of stored blocks
+		info.Format = "tendermint"
+		lower := tmBlockMetaKey(1)
+		upper := tmBlockMetaKey(math.MaxInt64)
+		key, _, err := db.Edge(lower, upper, false)
+		if err != nil {
+			return fmt.Errorf("failed to read first block: %w", err)
+		}
+		if key == nil {
+			return nil
+		}
+		if info.Base, err = tmBlockMetaHeight(key); err != nil {
+			return err
+		}
+		key, meta, err = db.Edge(lower, upper, true)
+		if err != nil {
+			return fmt.Errorf("failed to read last block: %w", err)
+		}
+		if info.Height, err = tmBlockMetaHeight(key); err != nil {
+			return err
+		}
+	}
+
+	if meta != nil {
+		// BlockMeta.header
+		fields, err := decodeProto(meta)
+		if err != nil {
+			return fmt.Errorf("failed to decode block meta: %w", err)
+		}
+		header, err := protoMessage(fields, 3)
+		if err != nil {
+			return fmt.Errorf("failed to decode block header: %w", err)
+		}
+		info.LatestTime = decodeTimestamp(protoBytes(header, 4))
+		info.HeaderAppHash = strings.ToUpper(hex.EncodeToString(protoBytes(header, 11)))
+	}
+	return nil
+}
+
+func tmBlockMetaKey(height int64) []byte {
+	key, _ := orderedcode.Append(nil, tmPrefixBlockMeta, height)
+	return key
+}
+
+func tmBlockMetaHeight(key []byte) (int64, error) {
+	var prefix, height int64
+	if _, err := orderedcode.Parse(string(key), &prefix, &height); err != nil {
+		return 0, fmt.Errorf("failed to parse block meta key: %w", err)
+	}
+	return height, nil
+}
+
+func inspectStateDB(db kvReader, info *StateDBInfo) error {
+	value, err := db.Get(cometStateKey)
+	if err == nil && value == nil {
+		key, _ := orderedcode.Append(nil, tmPrefixState)
+		value, err = db.Get(key)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	if value == nil {
+		return fmt.Errorf("no state saved")
+	}
+
+	fields, err := decodeProto(value)
+	if err != nil {
+		return fmt.Errorf("failed to decode state: %w", err)
+	}
+	info.ChainID = string(protoBytes(fields, 2))
+	info.LastBlockHeight = protoInt(fields, 3)
+	info.LastBlockTime = decodeTimestamp(protoBytes(fields, 5))
+	info.AppHash = strings.ToUpper(hex.EncodeToString(protoBytes(fields, 13)))
+	info.InitialHeight = protoInt(fields, 14)
+	return nil
+}
+
+func inspectAppDB(db kvReader, info *AppDBInfo) error {
+	value, err := db.Get([]byte(appLatestVersionKey))
+	if err != nil {
+		return fmt.Errorf("failed to read latest version: %w", err)
+	}
+	if value == nil {
+		return nil
+	}
+	// gogoproto Int64Value
+	fields, err := decodeProto(value)
+	if err != nil {
+		return fmt.Errorf("failed to decode latest version: %w", err)
+	}
+	info.LatestVersion = protoInt(fields, 1)
+	if info.LatestVersion == 0 {
+		return nil
+	}
+
+	value, err = db.Get([]byte(appCommitInfoPrefix + strconv.FormatInt(info.LatestVersion, 10)))
+	if err != nil {
+		return fmt.Errorf("failed to read commit info: %w", err)
+	}
+	if value == nil {
+		return fmt.Errorf("commit info for version %d is missing", info.LatestVersion)
+	}
+	hash, stores, err := commitInfoHash(value)
+	if err != nil {
+		return err
+	}
+	info.AppHash = strings.ToUpper(hex.EncodeToString(hash))
+	info.Stores = stores
+	return nil
+}
+
+// commitInfoHash computes the app hash of a multistore CommitInfo: a Merkle
+// root over the store names and the SHA-256 of each store's root hash
+func commitInfoHash(value []byte) ([]byte, []string, error) {
+	fields, err := decodeProto(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode commit info: %w", err)
+	}
+
+	type pair struct {
+		name string
+		hash []byte
+	}
+	var pairs []pair
+	for _, f := range fields {
+		if f.Num != 2 || f.Wire != wireBytes {
+			continue
+		}
+		// StoreInfo{name, commit_id{version, hash}}
+		store, err := decodeProto(f.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode store info: %w", err)
+		}
+		commitID, err := protoMessage(store, 2)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode commit id: %w", err)
+		}
+		pairs = append(pairs, pair{name: string(protoBytes(store, 1)), hash: protoBytes(commitID, 2)})
+	}
+	if len(pairs) == 0 {
+		return nil, nil, nil
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].name < pairs[j].name })
+	items := make([][]byte, len(pairs))
+	names := make([]string, len(pairs))
+	for i, p := range pairs {
+		valueHash := sha256.Sum256(p.hash)
+		items[i] = appendLengthPrefixed(appendLengthPrefixed(nil, []byte(p.name)), valueHash[:])
+		names[i] = p.name
+	}
+	return merkleRoot(items), names, nil
+}
+
+func appendLengthPrefixed(b, data []byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(data)))
+	return append(append(b, buf[:n]...), data...)
+}
+
+// checkDatabases applies the consistency rules of the Tendermint handshake
+// to the inspected heights
+func (m *Manager) checkDatabases(r *DBInspection) []string {
+	var issues []string
+	store, state, app := r.BlockStore, r.State, r.Application
+	errs := map[string]string{"blockstore.db": store.Error, "state.db": state.Error}
+	if app != nil {
+		errs["application.db"] = app.Error
+	}
+	for name, err := range errs {
+		if err != "" {
+			issues = append(issues, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+	sort.Strings(issues)
+
+	if chainID := m.localChainID(); state.Error == "" && state.ChainID != "" && chainID != "" && state.ChainID != chainID {
+		issues = append(issues, fmt.Sprintf("state.db belongs to chain %s but genesis.json is for %s", state.ChainID, chainID))
+	}
+	if store.Error != "" || state.Error != "" {
+		return issues
+	}
+
+	switch {
+	case state.LastBlockHeight > store.Height:
+		issues = append(issues, fmt.Sprintf("state.db (height %d) is ahead of blockstore.db (height %d)",
+			state.LastBlockHeight, store.Height))
+	case store.Height > state.LastBlockHeight+1:
+		issues = append(issues, fmt.Sprintf("blockstore.db (height %d) is more than one block ahead of state.db (height %d)",
+			store.Height, state.LastBlockHeight))
+	}
+	// SeiDB's height is not read, so there is nothing to compare it with
+	if app == nil || app.Error != "" {
+		return issues
+	}
+
+	switch {
+	case app.LatestVersion > store.Height:
+		issues = append(issues, fmt.Sprintf("application.db (height %d) is ahead of blockstore.db (height %d)",
+			app.LatestVersion, store.Height))
+	case app.LatestVersion < state.LastBlockHeight && app.LatestVersion+1 < store.Base:
+		issues = append(issues, fmt.Sprintf("application.db (height %d) is behind state.db (height %d) and the blocks to replay from %d are pruned (base %d)",
+			app.LatestVersion, state.LastBlockHeight, app.LatestVersion+1, store.Base))
+	case app.LatestVersion == state.LastBlockHeight && app.AppHash != "" && state.AppHash != "" && app.AppHash != state.AppHash:
+		issues = append(issues, fmt.Sprintf("app hash mismatch at height %d: application.db has %s, state.db expects %s",
+			app.LatestVersion, app.AppHash, state.AppHash))
+	}
+	return issues
+}
@@ -0,0 +1,219 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/google/orderedcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// writeTestDB creates name.db in the node's data directory with the given
+// keys, using the given backend
+func writeTestDB(t *testing.T, m *Manager, backend, name string, kvs map[string][]byte) {
+	path := filepath.Join(m.config.Global.HomeDir, "data", name+".db")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+
+	switch backend {
+	case BackendGoLevelDB:
+		db, err := leveldb.OpenFile(path, nil)
+		require.NoError(t, err)
+		for k, v := range kvs {
+			require.NoError(t, db.Put([]byte(k), v, nil))
+		}
+		require.NoError(t, db.Close())
+	case BackendPebble:
+		db, err := pebble.Open(path, &pebble.Options{})
+		require.NoError(t, err)
+		for k, v := range kvs {
+			require.NoError(t, db.Set([]byte(k), v, pebble.Sync))
+		}
+		require.NoError(t, db.Close())
+	}
+}
+
+func testBlockMeta(height int64, appHash []byte, at time.Time) []byte {
+	header := appendVarintField(nil, 3, uint64(height))
+	header = appendMessageField(header, 4, encodeTimestamp(at))
+	header = appendBytesField(header, 11, appHash)
+	return appendMessageField(nil, 3, header)
+}
+
+func testState(chainID string, height int64, appHash []byte) []byte {
+	b := appendBytesField(nil, 2, []byte(chainID))
+	b = appendVarintField(b, 3, uint64(height))
+	b = appendBytesField(b, 13, appHash)
+	return appendVarintField(b, 14, 1)
+}
+
+// testAppDB returns application.db contents at version with two stores,
+// and the app hash they commit to
+func testAppDB(version int64) (map[string][]byte, []byte) {
+	stores := map[string][]byte{"bank": {0xBA}, "acc": {0xAC}}
+	var info []byte
+	info = appendVarintField(info, 1, uint64(version))
+	for name, hash := range stores {
+		commitID := appendVarintField(nil, 1, uint64(version))
+		commitID = appendBytesField(commitID, 2, hash)
+		store := appendBytesField(nil, 1, []byte(name))
+		store = appendMessageField(store, 2, commitID)
+		info = appendMessageField(info, 2, store)
+	}
+
+	leaf := func(name string) []byte {
+		vh := sha256.Sum256(stores[name])
+		kv := append([]byte{byte(len(name))}, name...)
+		kv = append(append(kv, 32), vh[:]...)
+		sum := sha256.Sum256(append([]byte{0}, kv...))
+		return sum[:]
+	}
+	// Two leaves sorted by store name
+	root := sha256.Sum256(append(append([]byte{1}, leaf("acc")...), leaf("bank")...))
+
+	return map[string][]byte{
+		appLatestVersionKey:          appendVarintField(nil, 1, uint64(version)),
+		fmt.Sprintf("s/%d", version): info,
+	}, root[:]
+}
+
+func TestInspectDatabasesCometBFT(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	app, appHash := testAppDB(204)
+	writeTestDB(t, manager, BackendGoLevelDB, "blockstore", map[string][]byte{
+		"blockStore": append(appendVarintField(nil, 1, 100), appendVarintField(nil, 2, 205)...),
+		"H:205":      testBlockMeta(205, appHash, at),
+	})
+	writeTestDB(t, manager, BackendGoLevelDB, "state", map[string][]byte{
+		"stateKey": testState("test-1", 204, appHash),
+	})
+	writeTestDB(t, manager, BackendGoLevelDB, "application", app)
+
+	result, err := manager.InspectDatabases()
+	require.NoError(t, err)
+
+	assert.Equal(t, BackendGoLevelDB, result.Backend)
+	assert.Equal(t, "cometbft", result.BlockStore.Format)
+	assert.Equal(t, int64(100), result.BlockStore.Base)
+	assert.Equal(t, int64(205), result.BlockStore.Height)
+	assert.Equal(t, at, result.BlockStore.LatestTime)
+
+	hash := strings.ToUpper(hex.EncodeToString(appHash))
+	assert.Equal(t, hash, result.BlockStore.HeaderAppHash)
+	assert.Equal(t, "test-1", result.State.ChainID)
+	assert.Equal(t, int64(204), result.State.LastBlockHeight)
+	assert.Equal(t, int64(204), result.Application.LatestVersion)
+	assert.Equal(t, hash, result.Application.AppHash)
+	assert.Equal(t, []string{"acc", "bank"}, result.Application.Stores)
+	assert.Empty(t, result.Issues)
+}
+
+func TestInspectDatabasesTendermintPebble(t *testing.T) {
+	manager, _ := setupTestManager(t)
+
+	blocks := map[string][]byte{}
+	for h := int64(10); h <= 20; h++ {
+		key, err := orderedcode.Append(nil, tmPrefixBlockMeta, h)
+		require.NoError(t, err)
+		blocks[string(key)] = testBlockMeta(h, []byte{byte(h)}, time.Unix(h, 0))
+	}
+	// Block parts sort after every block meta key and must be ignored
+	part, _ := orderedcode.Append(nil, int64(1), int64(99), int64(0))
+	blocks[string(part)] = []byte("part")
+	writeTestDB(t, manager, BackendPebble, "blockstore", blocks)
+
+	stateKey, _ := orderedcode.Append(nil, tmPrefixState)
+	writeTestDB(t, manager, BackendPebble, "state", map[string][]byte{
+		string(stateKey): testState("test-1", 20, []byte{0xAA}),
+	})
+	app, _ := testAppDB(25)
+	writeTestDB(t, manager, BackendPebble, "application", app)
+
+	result, err := manager.InspectDatabases()
+	require.NoError(t, err)
+
+	assert.Equal(t, BackendPebble, result.Backend)
+	assert.Equal(t, "tendermint", result.BlockStore.Format)
+	assert.Equal(t, int64(10), result.BlockStore.Base)
+	assert.Equal(t, int64(20), result.BlockStore.Height)
+	assert.Equal(t, "14", result.BlockStore.HeaderAppHash)
+	assert.Equal(t, int64(20), result.State.LastBlockHeight)
+	require.Len(t, result.Issues, 1)
+	assert.Contains(t, result.Issues[0], "application.db (height 25) is ahead of blockstore.db (height 20)")
+}
+
+func TestInspectDatabasesIssues(t *testing.T) {
+	manager, _ := setupTestManager(t)
+
+	app, _ := testAppDB(204)
+	writeTestDB(t, manager, BackendGoLevelDB, "blockstore", map[string][]byte{
+		"blockStore": append(appendVarintField(nil, 1, 100), appendVarintField(nil, 2, 204)...),
+	})
+	writeTestDB(t, manager, BackendGoLevelDB, "state", map[string][]byte{
+		"stateKey": testState("test-1", 204, []byte{0xAA}),
+	})
+	writeTestDB(t, manager, BackendGoLevelDB, "application", app)
+
+	result, err := manager.InspectDatabases()
+	require.NoError(t, err)
+	require.Len(t, result.Issues, 1)
+	assert.Contains(t, result.Issues[0], "app hash mismatch at height 204")
+
+	// The app is behind and the blocks needed to catch it up are pruned
+	app, _ = testAppDB(50)
+	require.NoError(t, os.RemoveAll(filepath.Join(manager.config.Global.HomeDir, "data", "application.db")))
+	writeTestDB(t, manager, BackendGoLevelDB, "application", app)
+	result, err = manager.InspectDatabases()
+	require.NoError(t, err)
+	require.Len(t, result.Issues, 1)
+	assert.Contains(t, result.Issues[0], "blocks to replay from 51 are pruned (base 100)")
+
+	require.NoError(t, os.RemoveAll(filepath.Join(manager.config.Global.HomeDir, "data", "state.db")))
+	result, err = manager.InspectDatabases()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state.db: not found"}, result.Issues)
+}
+
+func TestInspectDatabasesSeiDBAndDBDir(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+
+	// A SeiDB node has no current application.db, only a stale one left
+	// from before the migration
+	app, _ := testAppDB(50)
+	writeTestDB(t, manager, BackendGoLevelDB, "blockstore", map[string][]byte{
+		"blockStore": append(appendVarintField(nil, 1, 100), appendVarintField(nil, 2, 204)...),
+	})
+	writeTestDB(t, manager, BackendGoLevelDB, "state", map[string][]byte{
+		"stateKey": testState("test-1", 204, []byte{0xAA}),
+	})
+	writeTestDB(t, manager, BackendGoLevelDB, "application", app)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "data", seidbCommitterDir), 0755))
+
+	result, err := manager.InspectDatabases()
+	require.NoError(t, err)
+	assert.Equal(t, LayoutSeiDB, result.Layout)
+	assert.Nil(t, result.Application)
+	assert.Empty(t, result.Issues)
+
+	// db_dir moves the databases out of <home>/data
+	require.NoError(t, os.Rename(filepath.Join(home, "data"), filepath.Join(home, "db")))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "config"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "config", "config.toml"), []byte("db_dir = \"db\"\n"), 0644))
+
+	result, err = manager.InspectDatabases()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "db"), result.DataDir)
+	assert.Equal(t, int64(204), result.BlockStore.Height)
+	assert.Empty(t, result.Issues)
+}
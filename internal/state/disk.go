@@ -0,0 +1,460 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/your-org/seictl/internal/utils"
+)
+
+const (
+	diskHistoryFile = "disk_history.json"
+	// maxDiskSamples bounds the history file; hourly samples cover 3 months
+	maxDiskSamples = 2160
+	// forecastWindow is how far back samples are used to fit the growth rate
+	forecastWindow = 14 * 24 * time.Hour
+	// minForecastSpan is the least history needed for a forecast
+	minForecastSpan = time.Hour
+)
+
+// DiskComponent is the space used by one part of the node home
+type DiskComponent struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	// SameVolume is false when the component lives on another filesystem
+	// than the data directory and does not count towards it filling up
+	SameVolume bool `json:"same_volume"`
+}
+
+// DiskSample is one point in the disk usage history
+type DiskSample struct {
+	Time       time.Time        `json:"time"`
+	Used       int64            `json:"used"`
+	Free       int64            `json:"free"`
+	Total      int64            `json:"total"`
+	Components map[string]int64 `json:"components"`
+}
+
+// DiskForecast estimates when the data volume fills at the current rate
+type DiskForecast struct {
+	Samples     int     `json:"samples"`
+	GrowthDay   int64   `json:"growth_bytes_per_day"`
+	DaysToFull  float64 `json:"days_to_full,omitempty"`
+	Unavailable string  `json:"unavailable,omitempty"`
+}
+
+// PruningAdvice is a suggested UpdatePruning call
+type PruningAdvice struct {
+	Current    string `json:"current"`
+	KeepRecent int64  `json:"keep_recent"`
+	KeepEvery  int64  `json:"keep_every"`
+	Interval   int64  `json:"interval"`
+	// Change is false when the current settings are fine
+	Change bool   `json:"change"`
+	Reason string `json:"reason"`
+}
+
+// DiskReport is the result of DiskUsage
+type DiskReport struct {
+	Home string `json:"home"`
+	// Volume is the data directory whose filesystem Total and Free describe,
+	// which a data move or db_dir may have put outside the node home
+	Volume     string          `json:"volume"`
+	Total      int64           `json:"total"`
+	Free       int64           `json:"free"`
+	Components []DiskComponent `json:"components"`
+	Forecast   DiskForecast    `json:"forecast"`
+	Pruning    PruningAdvice   `json:"pruning"`
+}
+
+// DiskUsage breaks down the space used by the node home, forecasts when the
+// volume holding the data directory fills from the recorded history and
+// recommends pruning settings. With record set, the current usage is added to
+// the history first.
+func (m *Manager) DiskUsage(record bool) (*DiskReport, error) {
+	home := m.config.Global.HomeDir
+	volume := m.diskVolume()
+	total, err := utils.DiskSize(volume)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read volume size: %w", err)
+	}
+	free, err := utils.DiskFree(volume)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read free space: %w", err)
+	}
+
+	components, err := m.diskComponents(volume)
+	if err != nil {
+		return nil, err
+	}
+	report := &DiskReport{Home: home, Volume: volume, Total: total, Free: free, Components: components}
+
+	history, err := m.ReadDiskHistory()
+	if err != nil {
+		return nil, err
+	}
+	sample := DiskSample{
+		Time:       time.Now().UTC(),
+		Used:       total - free,
+		Free:       free,
+		Total:      total,
+		Components: map[string]int64{},
+	}
+	for _, c := range components {
+		sample.Components[c.Name] = c.Bytes
+	}
+	history = append(history, sample)
+	if record {
+		if err := m.writeDiskHistory(history); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Forecast = forecastDisk(history)
+	report.Pruning = m.adviseDiskPruning(report)
+	return report, nil
+}
+
+// WatchDisk records a disk usage sample every interval until the context is
+// cancelled
+func (m *Manager) WatchDisk(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	for {
+		report, err := m.DiskUsage(true)
+		if err != nil {
+			m.logger.Error().Err(err).Msg("Failed to record disk usage")
+		} else {
+			event := m.logger.Info().Int64("free", report.Free).Int64("growth_per_day", report.Forecast.GrowthDay)
+			if report.Forecast.DaysToFull > 0 {
+				event = event.Float64("days_to_full", report.Forecast.DaysToFull)
+			}
+			event.Msg("Recorded disk usage")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ReadDiskHistory returns the recorded disk usage samples, oldest first
+func (m *Manager) ReadDiskHistory() ([]DiskSample, error) {
+	data, err := os.ReadFile(filepath.Join(m.config.Global.BackupDir, diskHistoryFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read disk history: %w", err)
+	}
+
+	var history []DiskSample
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse disk history: %w", err)
+	}
+	return history, nil
+}
+
+func (m *Manager) writeDiskHistory(history []DiskSample) error {
+	if len(history) > maxDiskSamples {
+		history = history[len(history)-maxDiskSamples:]
+	}
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal disk history: %w", err)
+	}
+	if err := os.MkdirAll(m.config.Global.BackupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	path := filepath.Join(m.config.Global.BackupDir, diskHistoryFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write disk history: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// diskVolume returns the real data directory, where the chain grows, or the
+// node home before the node has any data
+func (m *Manager) diskVolume() string {
+	dir := m.componentDir("data")
+	if _, err := os.Stat(dir); err != nil {
+		return m.config.Global.HomeDir
+	}
+	return dir
+}
+
+// diskComponents measures each part of the node home. Files hard-linked into
+// several components, e.g. by a hardlink snapshot, are counted once.
+func (m *Manager) diskComponents(volume string) ([]DiskComponent, error) {
+	home := m.config.Global.HomeDir
+	dataDir := m.dataDir()
+	seen := map[fileID]bool{}
+	var components []DiskComponent
+	claimed := map[string]bool{}
+
+	add := func(name, path string) error {
+		if _, err := os.Stat(path); err != nil {
+			return nil
+		}
+		size, err := diskSize(path, seen, nil)
+		if err != nil {
+			return fmt.Errorf("failed to measure %s: %w", path, err)
+		}
+		claimed[path] = true
+		components = mergeComponent(components, DiskComponent{Name: name, Path: path, Bytes: size, SameVolume: sameVolume(volume, path)})
+		return nil
+	}
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	for _, e := range entries {
+		path := filepath.Join(dataDir, e.Name())
+		// Stat rather than e.IsDir() so databases symlinked elsewhere count
+		if info, err := os.Stat(path); err == nil && info.IsDir() && strings.HasSuffix(e.Name(), ".db") {
+			if err := add(e.Name(), path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, c := range []struct{ name, path string }{
+		{"wasm", filepath.Join(home, "wasm")},
+		{"wasm", filepath.Join(dataDir, "wasm")},
+		{"state-sync snapshots", filepath.Join(dataDir, "snapshots")},
+		{"cosmovisor", filepath.Join(home, "cosmovisor")},
+		{"logs", filepath.Join(home, "logs")},
+		{"backups", m.config.Global.BackupDir},
+	} {
+		if err := add(c.name, c.path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Logs written in the node home, e.g. seid.log and its rotations
+	logs, _ := filepath.Glob(filepath.Join(home, "*.log*"))
+	var logBytes int64
+	for _, path := range logs {
+		size, err := diskSize(path, seen, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s: %w", path, err)
+		}
+		logBytes += size
+		claimed[path] = true
+	}
+	components = mergeComponent(components, DiskComponent{Name: "logs", Path: home, Bytes: logBytes, SameVolume: sameVolume(volume, home)})
+
+	other, err := diskSize(home, seen, claimed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure %s: %w", home, err)
+	}
+	components = mergeComponent(components, DiskComponent{Name: "other", Path: home, Bytes: other, SameVolume: sameVolume(volume, home)})
+
+	sort.SliceStable(components, func(i, j int) bool { return components[i].Bytes > components[j].Bytes })
+	return components, nil
+}
+
+// mergeComponent adds c, folding it into an existing component of the same
+// name. Empty components are dropped.
+func mergeComponent(components []DiskComponent, c DiskComponent) []DiskComponent {
+	if c.Bytes == 0 {
+		return components
+	}
+	for i := range components {
+		if components[i].Name == c.Name {
+			components[i].Bytes += c.Bytes
+			return components
+		}
+	}
+	return append(components, c)
+}
+
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// diskSize returns the space allocated to path, skipping files already seen
+// and any paths in skip. A symlinked path, e.g. data moved to another volume,
+// is followed; symlinks below it are not.
+func diskSize(path string, seen map[fileID]bool, skip map[string]bool) (int64, error) {
+	root, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var total int64
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		// skip holds paths under path, not under the resolved root
+		if rel, _ := filepath.Rel(root, p); skip[filepath.Join(path, rel)] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		st, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			total += info.Size()
+			return nil
+		}
+		id := fileID{dev: uint64(st.Dev), ino: st.Ino}
+		if seen[id] {
+			return nil
+		}
+		seen[id] = true
+		total += st.Blocks * 512
+		return nil
+	})
+	return total, err
+}
+
+func sameVolume(a, b string) bool {
+	var sa, sb syscall.Stat_t
+	if syscall.Stat(a, &sa) != nil || syscall.Stat(b, &sb) != nil {
+		return true
+	}
+	return sa.Dev == sb.Dev
+}
+
+// forecastDisk fits a line through the used space in the recent samples and
+// projects when the free space runs out
+func forecastDisk(history []DiskSample) DiskForecast {
+	var recent []DiskSample
+	if len(history) > 0 {
+		cutoff := history[len(history)-1].Time.Add(-forecastWindow)
+		for _, s := range history {
+			if !s.Time.Before(cutoff) {
+				recent = append(recent, s)
+			}
+		}
+	}
+	f := DiskForecast{Samples: len(recent)}
+	if len(recent) < 2 || recent[len(recent)-1].Time.Sub(recent[0].Time) < minForecastSpan {
+		f.Unavailable = "not enough history; record samples over at least an hour with seictl disk --watch"
+		return f
+	}
+
+	// Least squares over days since the first sample
+	t0 := recent[0].Time
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range recent {
+		x := s.Time.Sub(t0).Hours() / 24
+		y := float64(s.Used)
+		sumX, sumY, sumXY, sumXX = sumX+x, sumY+y, sumXY+x*y, sumXX+x*x
+	}
+	n := float64(len(recent))
+	slope := (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	f.GrowthDay = int64(slope)
+
+	last := recent[len(recent)-1]
+	if slope <= 0 {
+		f.Unavailable = "usage is not growing"
+		return f
+	}
+	f.DaysToFull = float64(last.Free) / slope
+	return f
+}
+
+// Pruning tiers recommended by adviseDiskPruning
+const (
+	pruneCriticalDays  = 7
+	pruneTightDays     = 30
+	pruneTightRecent   = 1000
+	pruneMinimalRecent = 100
+	pruneDefaultPeriod = 10
+)
+
+// adviseDiskPruning recommends UpdatePruning parameters from the forecast and
+// the node's current app.toml settings
+func (m *Manager) adviseDiskPruning(r *DiskReport) PruningAdvice {
+	settings, err := m.ReadPruning()
+	if err != nil {
+		settings = parsePruning("")
+	}
+	mode := settings.Legacy.Mode
+	keepRecent, keepEvery, interval := settings.Legacy.KeepRecent, settings.Legacy.KeepEvery, settings.Legacy.Interval
+	if settings.Layout == LayoutSeiDB {
+		// The state store is what grows; it prunes on its own timer
+		mode = "custom"
+		keepRecent, keepEvery, interval = settings.SeiDB.StateStore.KeepRecent, 0, 0
+	}
+	if settings.Archive() {
+		mode = "nothing"
+	}
+
+	advice := PruningAdvice{KeepRecent: keepRecent, KeepEvery: keepEvery, Interval: interval, Current: settings.Describe()}
+
+	days := r.Forecast.DaysToFull
+	usedPct := 0.0
+	if r.Total > 0 {
+		usedPct = float64(r.Total-r.Free) / float64(r.Total) * 100
+	}
+	critical := (days > 0 && days < pruneCriticalDays) || usedPct > 90
+	tight := critical || (days > 0 && days < pruneTightDays) || usedPct > 80
+
+	if !tight {
+		advice.Reason = "disk space is sufficient at the current growth rate"
+		return advice
+	}
+	if mode == "nothing" {
+		advice.Reason = "this is an archive node and cannot prune; add disk space"
+		return advice
+	}
+
+	target := int64(pruneTightRecent)
+	if critical {
+		target = pruneMinimalRecent
+	}
+	if mode == "custom" && keepRecent <= target && keepEvery == 0 {
+		advice.Reason = "pruning is already aggressive; free space by removing old backups or logs, or add disk space"
+		return advice
+	}
+
+	advice.Change = true
+	advice.KeepRecent, advice.KeepEvery, advice.Interval = target, 0, pruneDefaultPeriod
+	if settings.Layout == LayoutSeiDB {
+		advice.Interval = 0
+	}
+	if days > 0 {
+		advice.Reason = fmt.Sprintf("the volume fills in about %.0f days at the current rate", days)
+	} else {
+		advice.Reason = fmt.Sprintf("the volume is %.0f%% full", usedPct)
+	}
+	for _, c := range r.Components {
+		if c.Name == "application.db" && r.Total > 0 {
+			advice.Reason += fmt.Sprintf("; application.db, which pruning shrinks, uses %.0f%% of the volume", float64(c.Bytes)/float64(r.Total)*100)
+		}
+	}
+	return advice
+}
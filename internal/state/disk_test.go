@@ -0,0 +1,149 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSizedFile(t *testing.T, path string, size int) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644))
+}
+
+func TestDiskUsageComponents(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+
+	writeSizedFile(t, filepath.Join(home, "data", "application.db", "000001.ldb"), 64<<10)
+	writeSizedFile(t, filepath.Join(home, "data", "blockstore.db", "000001.ldb"), 16<<10)
+	writeSizedFile(t, filepath.Join(home, "wasm", "wasm", "state", "code"), 8<<10)
+	writeSizedFile(t, filepath.Join(home, "seid.log"), 4<<10)
+	writeSizedFile(t, filepath.Join(home, "config", "app.toml"), 4<<10)
+	// A hard link is only counted once
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "data", "snapshots"), 0755))
+	require.NoError(t, os.Link(filepath.Join(home, "data", "application.db", "000001.ldb"),
+		filepath.Join(home, "data", "snapshots", "linked.ldb")))
+
+	report, err := manager.DiskUsage(true)
+	require.NoError(t, err)
+
+	sizes := map[string]int64{}
+	for _, c := range report.Components {
+		sizes[c.Name] = c.Bytes
+	}
+	assert.GreaterOrEqual(t, sizes["application.db"], int64(64<<10))
+	assert.GreaterOrEqual(t, sizes["blockstore.db"], int64(16<<10))
+	assert.GreaterOrEqual(t, sizes["wasm"], int64(8<<10))
+	assert.GreaterOrEqual(t, sizes["logs"], int64(4<<10))
+	assert.GreaterOrEqual(t, sizes["other"], int64(4<<10))
+	assert.Less(t, sizes["state-sync snapshots"], int64(64<<10))
+	assert.Equal(t, "application.db", report.Components[0].Name)
+
+	history, err := manager.ReadDiskHistory()
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, sizes["application.db"], history[0].Components["application.db"])
+	assert.NotEmpty(t, report.Forecast.Unavailable)
+
+	_, err = manager.DiskUsage(false)
+	require.NoError(t, err)
+	history, err = manager.ReadDiskHistory()
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
+
+func TestDiskUsageFollowsSymlinkedData(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+
+	// data moved to another directory and symlinked back into the home
+	moved := filepath.Join(t.TempDir(), "data")
+	writeSizedFile(t, filepath.Join(moved, "application.db", "000001.ldb"), 64<<10)
+	require.NoError(t, os.RemoveAll(filepath.Join(home, "data")))
+	require.NoError(t, os.Symlink(moved, filepath.Join(home, "data")))
+
+	// and one database symlinked on its own
+	db := filepath.Join(t.TempDir(), "blockstore.db")
+	writeSizedFile(t, filepath.Join(db, "000001.ldb"), 16<<10)
+	require.NoError(t, os.Symlink(db, filepath.Join(moved, "blockstore.db")))
+
+	report, err := manager.DiskUsage(false)
+	require.NoError(t, err)
+
+	sizes := map[string]int64{}
+	for _, c := range report.Components {
+		sizes[c.Name] = c.Bytes
+	}
+	assert.GreaterOrEqual(t, sizes["application.db"], int64(64<<10))
+	assert.GreaterOrEqual(t, sizes["blockstore.db"], int64(16<<10))
+
+	// Total, free space and the forecast describe the volume data moved to
+	real, err := filepath.EvalSymlinks(moved)
+	require.NoError(t, err)
+	assert.Equal(t, real, report.Volume)
+}
+
+func TestForecastDisk(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var history []DiskSample
+	// 10 GiB a day with 100 GiB free at the last sample
+	for day := 0; day <= 5; day++ {
+		history = append(history, DiskSample{
+			Time: start.Add(time.Duration(day) * 24 * time.Hour),
+			Used: int64(400+10*day) << 30,
+			Free: int64(150-10*day) << 30,
+		})
+	}
+	// Samples outside the window are ignored
+	old := DiskSample{Time: start.Add(-30 * 24 * time.Hour), Used: 1 << 30}
+	history = append([]DiskSample{old}, history...)
+
+	f := forecastDisk(history)
+	assert.Equal(t, 6, f.Samples)
+	assert.Equal(t, int64(10<<30), f.GrowthDay)
+	assert.InDelta(t, 10, f.DaysToFull, 0.01)
+
+	f = forecastDisk(history[len(history)-1:])
+	assert.NotEmpty(t, f.Unavailable)
+}
+
+func TestAdviseDiskPruning(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	appToml := filepath.Join(manager.config.Global.HomeDir, "config", "app.toml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(appToml), 0755))
+	require.NoError(t, os.WriteFile(appToml, []byte("pruning = \"default\"\n"), 0644))
+
+	report := &DiskReport{Total: 1000 << 30, Free: 500 << 30, Forecast: DiskForecast{DaysToFull: 90}}
+	advice := manager.adviseDiskPruning(report)
+	assert.False(t, advice.Change)
+	assert.Equal(t, "default", advice.Current)
+
+	report.Forecast.DaysToFull = 20
+	report.Components = []DiskComponent{{Name: "application.db", Bytes: 300 << 30}}
+	advice = manager.adviseDiskPruning(report)
+	assert.True(t, advice.Change)
+	assert.Equal(t, int64(pruneTightRecent), advice.KeepRecent)
+	assert.Equal(t, int64(0), advice.KeepEvery)
+	assert.Equal(t, int64(10), advice.Interval)
+	assert.Contains(t, advice.Reason, "application.db")
+
+	report.Forecast.DaysToFull = 3
+	advice = manager.adviseDiskPruning(report)
+	assert.Equal(t, int64(pruneMinimalRecent), advice.KeepRecent)
+
+	// Settings at least as aggressive are left alone
+	require.NoError(t, os.WriteFile(appToml, []byte("pruning = \"custom\"\npruning-keep-recent = \"100\"\npruning-keep-every = \"0\"\npruning-interval = \"10\"\n"), 0644))
+	advice = manager.adviseDiskPruning(report)
+	assert.False(t, advice.Change)
+
+	require.NoError(t, os.WriteFile(appToml, []byte("pruning = \"nothing\"\n"), 0644))
+	advice = manager.adviseDiskPruning(report)
+	assert.False(t, advice.Change)
+	assert.Contains(t, advice.Reason, "archive")
+}
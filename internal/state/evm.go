@@ -0,0 +1,116 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/your-org/seictl/internal/rpc"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+const (
+	defaultEVMRPCPort = 8545
+	// defaultEVMMaxLag is how many blocks the EVM height may trail the
+	// Tendermint height; both advance together on a healthy node
+	defaultEVMMaxLag = 5
+)
+
+// EVMCheckOptions selects the endpoints compared by CheckEVM
+type EVMCheckOptions struct {
+	Env types.Environment
+	// EVMRPC is the EVM JSON-RPC endpoint, the local node's by default
+	EVMRPC string
+	// RPC is the Tendermint RPC endpoint, the local node's by default
+	RPC string
+	// MaxLag is how many blocks apart the EVM and Tendermint heights may be
+	MaxLag int64
+}
+
+// EVMHealth is the result of an EVM JSON-RPC health check
+type EVMHealth struct {
+	Endpoint string `json:"endpoint"`
+	RPC      string `json:"rpc"`
+	ChainID  uint64 `json:"chain_id"`
+	// ExpectedChainID is the environment's evm_chain_id, when configured
+	ExpectedChainID  uint64   `json:"expected_chain_id,omitempty"`
+	NetVersion       string   `json:"net_version"`
+	EVMHeight        int64    `json:"evm_height"`
+	TendermintHeight int64    `json:"tendermint_height"`
+	Lag              int64    `json:"lag"`
+	Latency          Seconds  `json:"latency_seconds"`
+	Healthy          bool     `json:"healthy"`
+	Problems         []string `json:"problems,omitempty"`
+}
+
+// LocalEVMEndpoint returns the EVM JSON-RPC address of the local node for env
+func (m *Manager) LocalEVMEndpoint(env types.Environment) string {
+	port := defaultEVMRPCPort
+	if chainCfg, ok := m.config.Environments[string(env)]; ok && chainCfg.Ports != nil && chainCfg.Ports.EVMRPC != 0 {
+		port = chainCfg.Ports.EVMRPC
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", port)
+}
+
+// CheckEVM calls eth_chainId, eth_blockNumber and net_version on the EVM
+// endpoint and compares the EVM height with the Tendermint height. Failed
+// checks are reported as problems; an error means nothing could be checked.
+func (m *Manager) CheckEVM(ctx context.Context, opts EVMCheckOptions) (*EVMHealth, error) {
+	env := m.localEnv(opts.Env)
+	if opts.EVMRPC == "" {
+		opts.EVMRPC = m.LocalEVMEndpoint(env)
+	}
+	if opts.RPC == "" {
+		opts.RPC = m.LocalRPCEndpoint(env)
+	}
+	if opts.MaxLag <= 0 {
+		opts.MaxLag = defaultEVMMaxLag
+	}
+
+	client, err := rpc.NewEVMClient(opts.EVMRPC, m.httpClient())
+	if err != nil {
+		return nil, err
+	}
+	health := &EVMHealth{Endpoint: client.Remote(), RPC: opts.RPC}
+	if chainCfg, ok := m.config.Environments[string(env)]; ok {
+		health.ExpectedChainID = chainCfg.EVMChainID
+	}
+	problem := func(format string, args ...interface{}) {
+		health.Problems = append(health.Problems, fmt.Sprintf(format, args...))
+	}
+
+	if health.ChainID, err = client.ChainID(ctx); err != nil {
+		problem("eth_chainId failed: %v", err)
+	} else if health.ExpectedChainID != 0 && health.ChainID != health.ExpectedChainID {
+		problem("eth_chainId is %d, expected %d for %s", health.ChainID, health.ExpectedChainID, env)
+	}
+
+	if health.NetVersion, err = client.NetVersion(ctx); err != nil {
+		problem("net_version failed: %v", err)
+	} else if health.ChainID != 0 && health.NetVersion != strconv.FormatUint(health.ChainID, 10) {
+		problem("net_version %s does not match eth_chainId %d", health.NetVersion, health.ChainID)
+	}
+
+	start := time.Now()
+	evmHeight, evmErr := client.BlockNumber(ctx)
+	health.Latency = Seconds(time.Since(start))
+	status, tmErr := m.queryStatus(ctx, opts.RPC)
+	switch {
+	case evmErr != nil:
+		problem("eth_blockNumber failed: %v", evmErr)
+	case tmErr != nil:
+		health.EVMHeight = evmHeight
+		problem("cannot compare heights, Tendermint RPC failed: %v", tmErr)
+	default:
+		health.EVMHeight = evmHeight
+		health.TendermintHeight = status.LatestHeight
+		health.Lag = status.LatestHeight - evmHeight
+		if health.Lag > opts.MaxLag || -health.Lag > opts.MaxLag {
+			problem("EVM height %d is %d blocks from Tendermint height %d", evmHeight, health.Lag, status.LatestHeight)
+		}
+	}
+
+	health.Healthy = len(health.Problems) == 0
+	return health, nil
+}
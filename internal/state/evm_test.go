@@ -0,0 +1,78 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// fakeEVMRPC answers eth_chainId, eth_blockNumber and net_version
+func fakeEVMRPC(t *testing.T, chainID uint64, height int64, netVersion string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var result string
+		switch req.Method {
+		case "eth_chainId":
+			result = fmt.Sprintf("0x%x", chainID)
+		case "eth_blockNumber":
+			result = fmt.Sprintf("0x%x", height)
+		case "net_version":
+			result = netVersion
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%q}`, req.ID, result)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheckEVM(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	manager.config.Environments = map[string]types.ChainConfig{
+		"testnet": {ChainID: "test-1", EVMChainID: 1328, Ports: &types.NodePorts{EVMRPC: 18545}},
+	}
+	assert.Equal(t, "http://127.0.0.1:18545", manager.LocalEVMEndpoint("testnet"))
+	assert.Equal(t, "http://127.0.0.1:8545", manager.LocalEVMEndpoint("unknown"))
+	ctx := context.Background()
+
+	health, err := manager.CheckEVM(ctx, EVMCheckOptions{
+		EVMRPC: fakeEVMRPC(t, 1328, 1000, "1328").URL,
+		RPC:    fakeHeightRPC(t, 1002).URL,
+	})
+	require.NoError(t, err)
+	assert.True(t, health.Healthy, health.Problems)
+	assert.Equal(t, uint64(1328), health.ExpectedChainID)
+	assert.Equal(t, int64(1000), health.EVMHeight)
+	assert.Equal(t, int64(2), health.Lag)
+
+	// Wrong chain, mismatched net_version and a stalled EVM height
+	health, err = manager.CheckEVM(ctx, EVMCheckOptions{
+		EVMRPC: fakeEVMRPC(t, 1329, 900, "1").URL,
+		RPC:    fakeHeightRPC(t, 1000).URL,
+		MaxLag: 10,
+	})
+	require.NoError(t, err)
+	assert.False(t, health.Healthy)
+	require.Len(t, health.Problems, 3)
+	assert.Contains(t, health.Problems[0], "expected 1328")
+	assert.Contains(t, health.Problems[1], "net_version")
+	assert.Contains(t, health.Problems[2], "100 blocks")
+
+	// An unreachable EVM endpoint is a problem, not an error
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	health, err = manager.CheckEVM(ctx, EVMCheckOptions{EVMRPC: down.URL, RPC: fakeHeightRPC(t, 1000).URL})
+	require.NoError(t, err)
+	assert.False(t, health.Healthy)
+	assert.Len(t, health.Problems, 3)
+}
@@ -0,0 +1,465 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	indexFileName = "index.json"
+	indexVersion  = 1
+
+	// SnapshotTypeIncremental marks snapshots stored in the chunk repository
+	SnapshotTypeIncremental = "incremental"
+)
+
+// snapshotIndex lists every file of an incremental snapshot with the chunks
+// holding its content. Each index is self-contained: restoring a snapshot
+// never needs another snapshot's index.
+type snapshotIndex struct {
+	Version int          `json:"version"`
+	Height  int64        `json:"height"`
+	Entries []indexEntry `json:"entries"`
+}
+
+type indexEntry struct {
+	Path    string    `json:"path"`
+	Mode    uint32    `json:"mode"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"mod_time"`
+	Link    string    `json:"link,omitempty"`
+	Chunks  []string  `json:"chunks,omitempty"`
+}
+
+// GCResult summarises a chunk garbage collection run
+type GCResult struct {
+	DryRun         bool  `json:"dry_run"`
+	Referenced     int   `json:"referenced"`
+	Removed        int   `json:"removed"`
+	ReclaimedBytes int64 `json:"reclaimed_bytes"`
+}
+
+// CreateIncrementalSnapshot creates a snapshot whose file contents are
+// stored as deduplicated chunks in the repository under the backup directory
+func (m *Manager) CreateIncrementalSnapshot(ctx context.Context, height int64) error {
+	return m.createIncrementalSnapshotFrom(ctx, height, m.config.Global.HomeDir)
+}
+
+func (m *Manager) chunkStoreDir() string {
+	return filepath.Join(m.config.Global.BackupDir, chunkStoreDirName)
+}
+
+func (m *Manager) createIncrementalSnapshotFrom(ctx context.Context, height int64, sourceDir string) error {
+	if err := m.writeIncrementalSnapshot(ctx, height, sourceDir); err != nil {
+		return err
+	}
+
+	// Retention may garbage collect chunks, so it runs once the store is unlocked
+	m.applyRetention()
+	return nil
+}
+
+func (m *Manager) writeIncrementalSnapshot(ctx context.Context, height int64, sourceDir string) error {
+	m.logger.Info().Int64("height", height).Msg("Creating incremental snapshot")
+
+	store, err := openChunkStore(m.chunkStoreDir())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	snapshotDir := filepath.Join(m.config.Global.BackupDir, snapshotDirName(height))
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	if err := m.backupValidatorState(sourceDir, snapshotDir); err != nil {
+		return fmt.Errorf("failed to backup validator state: %w", err)
+	}
+
+	previous := m.previousIndexEntries(height)
+	index := &snapshotIndex{Version: indexVersion, Height: height}
+	var logicalSize, newBytes int64
+	var reused int
+
+	for _, name := range []string{"data", "wasm"} {
+		root := filepath.Join(sourceDir, name)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(sourceDir, path)
+			if err != nil {
+				return err
+			}
+			entry := indexEntry{
+				Path:    filepath.ToSlash(rel),
+				Mode:    uint32(info.Mode()),
+				ModTime: info.ModTime().UTC(),
+			}
+
+			switch {
+			case info.Mode()&os.ModeSymlink != 0:
+				entry.Link, err = os.Readlink(path)
+				if err != nil {
+					return err
+				}
+			case info.Mode().IsRegular():
+				entry.Size = info.Size()
+				logicalSize += entry.Size
+
+				// Unchanged files reuse the previous chunk list without rehashing
+				if prev, ok := previous[entry.Path]; ok && prev.Size == entry.Size &&
+					prev.ModTime.Equal(entry.ModTime) && allChunksPresent(store, prev.Chunks) {
+					entry.Chunks = prev.Chunks
+					reused++
+					break
+				}
+
+				written, err := chunkFile(store, path, &entry)
+				if err != nil {
+					return fmt.Errorf("failed to chunk %s: %w", entry.Path, err)
+				}
+				newBytes += written
+			case !info.IsDir():
+				// Sockets, devices and fifos are not part of node state
+				return nil
+			}
+
+			index.Entries = append(index.Entries, entry)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(snapshotDir, indexFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	manifest, err := m.buildManifest(snapshotDir, height, []string{"priv_validator_state.json", indexFileName})
+	if err != nil {
+		return err
+	}
+	manifest.Type = SnapshotTypeIncremental
+	manifest.LogicalSize = logicalSize
+	if err := saveManifest(snapshotDir, manifest); err != nil {
+		return err
+	}
+
+	m.logger.Info().
+		Str("path", snapshotDir).
+		Int("files", len(index.Entries)).
+		Int("unchanged_files", reused).
+		Int64("logical_bytes", logicalSize).
+		Int64("new_bytes", newBytes).
+		Msg("Incremental snapshot created successfully")
+
+	return nil
+}
+
+// previousIndexEntries returns the entries of the newest incremental
+// snapshot below height, keyed by path
+func (m *Manager) previousIndexEntries(height int64) map[string]indexEntry {
+	entries := make(map[string]indexEntry)
+
+	snapshots, err := m.ListSnapshots()
+	if err != nil {
+		return entries
+	}
+	for _, s := range snapshots {
+		if s.Height >= height || s.Type != SnapshotTypeIncremental {
+			continue
+		}
+		index, err := readIndex(s.Path)
+		if err != nil {
+			continue
+		}
+		for _, e := range index.Entries {
+			entries[e.Path] = e
+		}
+		break
+	}
+
+	return entries
+}
+
+func chunkFile(store *chunkStore, path string, entry *indexEntry) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var written int64
+	c := newChunker(f)
+	for {
+		chunk, err := c.next()
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+
+		hash, n, err := store.put(chunk)
+		if err != nil {
+			return written, err
+		}
+		written += n
+		entry.Chunks = append(entry.Chunks, hash)
+	}
+}
+
+func allChunksPresent(store *chunkStore, chunks []string) bool {
+	for _, hash := range chunks {
+		if !store.has(hash) {
+			return false
+		}
+	}
+	return true
+}
+
+func readIndex(snapshotDir string) (*snapshotIndex, error) {
+	data, err := os.ReadFile(filepath.Join(snapshotDir, indexFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	index := &snapshotIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse index: %w", err)
+	}
+	if index.Version != indexVersion {
+		return nil, fmt.Errorf("unsupported index version %d", index.Version)
+	}
+
+	return index, nil
+}
+
+func isIncrementalSnapshot(snapshotDir string) bool {
+	manifest, err := readManifest(snapshotDir)
+	return err == nil && manifest.Type == SnapshotTypeIncremental
+}
+
+// restoreIncremental rebuilds the files of an incremental snapshot below destHome
+func (m *Manager) restoreIncremental(ctx context.Context, snapshotDir, destHome string) error {
+	index, err := readIndex(snapshotDir)
+	if err != nil {
+		return err
+	}
+
+	store, err := openChunkStore(m.chunkStoreDir())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var dirs []indexEntry
+	for _, entry := range index.Entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		target, err := restoreTarget(destHome, entry.Path)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(entry.Mode)
+
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, mode.Perm()|0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, entry)
+		case mode&os.ModeSymlink != 0:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(entry.Link, target); err != nil {
+				return err
+			}
+		default:
+			if err := restoreIndexedFile(store, entry, target); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", entry.Path, err)
+			}
+		}
+	}
+
+	// Directory times are set last since writing files into them changes them
+	for _, entry := range dirs {
+		target, _ := restoreTarget(destHome, entry.Path)
+		os.Chtimes(target, entry.ModTime, entry.ModTime)
+	}
+
+	return nil
+}
+
+func restoreIndexedFile(store *chunkStore, entry indexEntry, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(entry.Mode).Perm()|0600)
+	if err != nil {
+		return err
+	}
+
+	var written int64
+	for _, hash := range entry.Chunks {
+		data, err := store.get(hash)
+		if err != nil {
+			out.Close()
+			return err
+		}
+		n, err := out.Write(data)
+		written += int64(n)
+		if err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if written != entry.Size {
+		return fmt.Errorf("size mismatch: expected %d, got %d", entry.Size, written)
+	}
+
+	return os.Chtimes(target, entry.ModTime, entry.ModTime)
+}
+
+// verifyIndexChunks checks that every chunk referenced by an incremental
+// snapshot exists and matches its hash
+func (m *Manager) verifyIndexChunks(snapshotDir string) ([]ArchiveCheck, error) {
+	index, err := readIndex(snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := openChunkStore(m.chunkStoreDir())
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	var failures []ArchiveCheck
+	seen := make(map[string]bool)
+	for _, entry := range index.Entries {
+		for _, hash := range entry.Chunks {
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+
+			if _, err := store.get(hash); err != nil {
+				failures = append(failures, ArchiveCheck{
+					Name:     "chunk " + hash + " (" + entry.Path + ")",
+					Expected: hash,
+					Error:    err.Error(),
+				})
+			}
+		}
+	}
+
+	return failures, nil
+}
+
+// referencedChunks returns every chunk referenced by incremental snapshots
+// in the backup directory
+func (m *Manager) referencedChunks() (map[string]bool, error) {
+	snapshots, err := m.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, s := range snapshots {
+		if s.Type != SnapshotTypeIncremental {
+			continue
+		}
+		index, err := readIndex(s.Path)
+		if err != nil {
+			// Refuse to collect rather than delete chunks a damaged index may need
+			return nil, fmt.Errorf("snapshot at height %d: %w", s.Height, err)
+		}
+		for _, entry := range index.Entries {
+			for _, hash := range entry.Chunks {
+				referenced[hash] = true
+			}
+		}
+	}
+
+	return referenced, nil
+}
+
+// GarbageCollectChunks removes chunks no incremental snapshot references
+func (m *Manager) GarbageCollectChunks(dryRun bool) (*GCResult, error) {
+	result := &GCResult{DryRun: dryRun}
+	if _, err := os.Stat(m.chunkStoreDir()); os.IsNotExist(err) {
+		return result, nil
+	}
+
+	store, err := openChunkStore(m.chunkStoreDir())
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	referenced, err := m.referencedChunks()
+	if err != nil {
+		return nil, err
+	}
+	result.Referenced = len(referenced)
+
+	var unreferenced []string
+	err = store.walk(func(hash string, size int64) error {
+		if !referenced[hash] {
+			unreferenced = append(unreferenced, hash)
+			result.Removed++
+			result.ReclaimedBytes += size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan chunk store: %w", err)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	sort.Strings(unreferenced)
+	for _, hash := range unreferenced {
+		if err := os.Remove(store.path(hash)); err != nil && !os.IsNotExist(err) {
+			return result, fmt.Errorf("failed to remove chunk %s: %w", hash, err)
+		}
+	}
+
+	m.logger.Info().
+		Int("removed", result.Removed).
+		Int64("reclaimed_bytes", result.ReclaimedBytes).
+		Msg("Chunk garbage collection finished")
+
+	return result, nil
+}
@@ -0,0 +1,184 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomBytes(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func chunkAll(t *testing.T, data []byte) [][]byte {
+	c := newChunker(bytes.NewReader(data))
+	var chunks [][]byte
+	for {
+		chunk, err := c.next()
+		if err == io.EOF {
+			return chunks
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestChunkerBoundaries(t *testing.T) {
+	data := randomBytes(1, 6<<20)
+	chunks := chunkAll(t, data)
+	require.Greater(t, len(chunks), 1)
+	assert.Equal(t, data, bytes.Join(chunks, nil))
+	for _, chunk := range chunks[:len(chunks)-1] {
+		assert.GreaterOrEqual(t, len(chunk), minChunkSize)
+		assert.LessOrEqual(t, len(chunk), maxChunkSize)
+	}
+
+	// Inserting bytes at the front only changes the leading chunks
+	shifted := chunkAll(t, append([]byte("inserted"), data...))
+	assert.Equal(t, chunks[len(chunks)-1], shifted[len(shifted)-1])
+}
+
+func writeNodeData(t *testing.T, home string, files map[string][]byte) {
+	if _, ok := files["data/priv_validator_state.json"]; !ok {
+		files["data/priv_validator_state.json"] = []byte(`{"height":"0"}`)
+	}
+	for name, content := range files {
+		path := filepath.Join(home, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, content, 0644))
+	}
+}
+
+func TestIncrementalSnapshotDedup(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	ctx := context.Background()
+	home := manager.config.Global.HomeDir
+
+	big := randomBytes(2, 3<<20)
+	writeNodeData(t, home, map[string][]byte{
+		"data/blockstore.db/000001.ldb":      big,
+		"data/priv_validator_state.json":     []byte(`{"height":"100"}`),
+		"wasm/wasm/state/wasm/contract.wasm": []byte("wasm"),
+	})
+	require.NoError(t, manager.CreateIncrementalSnapshot(ctx, 100))
+
+	chunkDir := manager.chunkStoreDir()
+	before, err := dirSize(chunkDir)
+	require.NoError(t, err)
+
+	writeNodeData(t, home, map[string][]byte{
+		"data/application.db/000002.ldb": []byte("new data"),
+		"data/priv_validator_state.json": []byte(`{"height":"200"}`),
+	})
+	require.NoError(t, manager.CreateIncrementalSnapshot(ctx, 200))
+
+	after, err := dirSize(chunkDir)
+	require.NoError(t, err)
+	assert.Less(t, after-before, int64(1<<20), "unchanged data must not be stored twice")
+
+	snapshots, err := manager.ListSnapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, SnapshotTypeIncremental, snapshots[0].Type)
+	assert.Greater(t, snapshots[0].LogicalSize, int64(3<<20))
+
+	// The newer snapshot restores on its own after the older one is gone
+	require.NoError(t, manager.DeleteSnapshot(100))
+	gc, err := manager.GarbageCollectChunks(false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, gc.Removed, "only the old validator state chunk is unreferenced")
+
+	require.NoError(t, os.RemoveAll(filepath.Join(home, "data")))
+	require.NoError(t, os.RemoveAll(filepath.Join(home, "wasm")))
+	require.NoError(t, manager.restoreIncremental(ctx, snapshots[0].Path, home))
+
+	restored, err := os.ReadFile(filepath.Join(home, "data/blockstore.db/000001.ldb"))
+	require.NoError(t, err)
+	assert.Equal(t, big, restored)
+	restored, err = os.ReadFile(filepath.Join(home, "data/application.db/000002.ldb"))
+	require.NoError(t, err)
+	assert.Equal(t, "new data", string(restored))
+	restored, err = os.ReadFile(filepath.Join(home, "wasm/wasm/state/wasm/contract.wasm"))
+	require.NoError(t, err)
+	assert.Equal(t, "wasm", string(restored))
+}
+
+func TestGarbageCollectChunks(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	ctx := context.Background()
+	home := manager.config.Global.HomeDir
+
+	writeNodeData(t, home, map[string][]byte{"data/a.db": randomBytes(3, 1<<20)})
+	require.NoError(t, manager.CreateIncrementalSnapshot(ctx, 100))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "data/a.db"), randomBytes(4, 1<<20), 0644))
+	require.NoError(t, manager.CreateIncrementalSnapshot(ctx, 200))
+
+	require.NoError(t, manager.DeleteSnapshot(100))
+
+	plan, err := manager.GarbageCollectChunks(true)
+	require.NoError(t, err)
+	assert.Greater(t, plan.Removed, 0)
+
+	result, err := manager.GarbageCollectChunks(false)
+	require.NoError(t, err)
+	assert.Equal(t, plan.Removed, result.Removed)
+
+	again, err := manager.GarbageCollectChunks(false)
+	require.NoError(t, err)
+	assert.Zero(t, again.Removed)
+
+	verify, err := manager.VerifySnapshotIntegrity(200)
+	require.NoError(t, err)
+	assert.True(t, verify.OK)
+}
+
+func TestVerifyIncrementalDetectsCorruptChunk(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+
+	writeNodeData(t, home, map[string][]byte{"data/a.db": []byte("chunk content")})
+	require.NoError(t, manager.CreateIncrementalSnapshot(context.Background(), 100))
+
+	index, err := readIndex(filepath.Join(manager.config.Global.BackupDir, snapshotDirName(100)))
+	require.NoError(t, err)
+	var hash string
+	for _, entry := range index.Entries {
+		if len(entry.Chunks) > 0 {
+			hash = entry.Chunks[0]
+		}
+	}
+	require.NotEmpty(t, hash)
+
+	chunkPath := filepath.Join(manager.chunkStoreDir(), hash[:2], hash)
+	require.NoError(t, os.WriteFile(chunkPath, []byte("garbage"), 0644))
+
+	result, err := manager.VerifySnapshotIntegrity(100)
+	require.NoError(t, err)
+	assert.False(t, result.OK)
+}
+
+func TestPushPullIncrementalSnapshot(t *testing.T) {
+	ctx := context.Background()
+	storage := NewLocalStorage(t.TempDir())
+
+	source, _ := setupTestManager(t)
+	writeNodeData(t, source.config.Global.HomeDir, map[string][]byte{"data/a.db": randomBytes(5, 1<<20)})
+	require.NoError(t, source.CreateIncrementalSnapshot(ctx, 100))
+	require.NoError(t, source.PushSnapshot(ctx, storage, "100"))
+
+	target, _ := setupTestManager(t)
+	require.NoError(t, target.PullSnapshot(ctx, storage, "100"))
+
+	result, err := target.VerifySnapshotIntegrity(100)
+	require.NoError(t, err)
+	assert.True(t, result.OK)
+}
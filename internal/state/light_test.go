@@ -0,0 +1,251 @@
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/seictl/internal/rpc"
+)
+
+// Fixtures in testdata/light are a ten block chain (test-1). Heights 1-5 are
+// signed by four validators of equal power; from height 6 the set changes so
+// the old validators keep a quarter of the power, which forces bisection.
+// They are produced with the encoding under test, so TestHeaderHashKnownAnswer
+// pins that encoding to a vector published by Tendermint.
+const lightHashAt2 = "6FD33A9415346FE249E82673237F4650E99B8C6A7146570BC0A91EDB25050257"
+
+// fakeLightRPC serves the commit and validators fixtures. tamper may rewrite
+// a response before it is sent.
+func fakeLightRPC(t *testing.T, tamper func(path string, height string, body []byte) []byte) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if name != "commit" && name != "validators" {
+			http.NotFound(w, r)
+			return
+		}
+		height := r.URL.Query().Get("height")
+		body, err := os.ReadFile(filepath.Join("testdata", "light", fmt.Sprintf("%s_%s.json", name, height)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if tamper != nil {
+			body = tamper(name, height, body)
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func setupLightManager(t *testing.T, endpoints []string) *Manager {
+	manager := setupTrustManager(t, endpoints, 0)
+	// The fixtures are dated 2024
+	manager.config.Environments["testnet"].StateSync.TrustPeriod = "1000000h"
+	return manager
+}
+
+func lightBlockHash(t *testing.T, height int64) string {
+	data, err := os.ReadFile(filepath.Join("testdata", "light", fmt.Sprintf("commit_%d.json", height)))
+	require.NoError(t, err)
+	// The commit's block_id hash is the last 64 character hash before "signatures"
+	commit := string(data)[strings.Index(string(data), `"commit":`):]
+	start := strings.Index(commit, `"hash":"`) + len(`"hash":"`)
+	return commit[start : start+64]
+}
+
+// TestHeaderHashKnownAnswer uses the header from TestHeaderHash in
+// tendermint/types/block_test.go, whose hash was computed by Tendermint itself
+func TestHeaderHashKnownAnswer(t *testing.T) {
+	sum := func(s string) rpc.HexBytes {
+		h := sha256.Sum256([]byte(s))
+		return h[:]
+	}
+	h := rpc.Header{
+		Version: rpc.Version{Block: 1, App: 2},
+		ChainID: "chainId",
+		Height:  3,
+		Time:    time.Date(2019, 10, 13, 16, 14, 44, 0, time.UTC),
+		LastBlockID: rpc.BlockID{
+			Hash:  make([]byte, 32),
+			Parts: rpc.PartSetHeader{Total: 6, Hash: make([]byte, 32)},
+		},
+		LastCommitHash:     sum("last_commit_hash"),
+		DataHash:           sum("data_hash"),
+		ValidatorsHash:     sum("validators_hash"),
+		NextValidatorsHash: sum("next_validators_hash"),
+		ConsensusHash:      sum("consensus_hash"),
+		AppHash:            sum("app_hash"),
+		LastResultsHash:    sum("last_results_hash"),
+		EvidenceHash:       sum("evidence_hash"),
+		ProposerAddress:    sum("proposer_address")[:20],
+	}
+
+	assert.Equal(t, "F740121F553B5418C3EFBD343C2DBFE9E007BB67B0D020A0741374BAB65242A4",
+		strings.ToUpper(hex.EncodeToString(headerHash(&h))))
+}
+
+func TestVerifyTrustBlockBisects(t *testing.T) {
+	srv := fakeLightRPC(t, nil)
+	manager := setupLightManager(t, []string{srv.URL})
+	require.NoError(t, manager.SaveLightCheckpoint(LightCheckpoint{ChainID: "test-1", Height: 2, Hash: lightHashAt2}))
+
+	target := Block{Height: 10, Hash: lightBlockHash(t, 10)}
+	result, err := manager.VerifyTrustBlock(context.Background(), "test-1", []string{srv.URL}, target)
+	require.NoError(t, err)
+
+	// 2 -> 10 and 2 -> 6 fail on trust, 2 -> 4 -> 5 -> 6 is walked, then 6 -> 10 skips
+	assert.Equal(t, []int64{4, 5, 6, 10}, result.Path)
+
+	cp, err := manager.LightCheckpoint("test-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), cp.Height)
+	assert.Equal(t, target.Hash, cp.Hash)
+	assert.False(t, cp.Time.IsZero())
+}
+
+func TestVerifyTrustBlockSkipsWithinValidatorSet(t *testing.T) {
+	srv := fakeLightRPC(t, nil)
+	manager := setupLightManager(t, []string{srv.URL})
+	require.NoError(t, manager.SaveLightCheckpoint(LightCheckpoint{ChainID: "test-1", Height: 2, Hash: lightHashAt2}))
+
+	// Height 3 has an absent validator, still above 2/3
+	result, err := manager.VerifyTrustBlock(context.Background(), "test-1", []string{srv.URL},
+		Block{Height: 5, Hash: lightBlockHash(t, 5)})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{5}, result.Path)
+}
+
+func TestVerifyTrustBlockRejectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(path, height string, body []byte) []byte
+		want   string
+	}{
+		{
+			name: "forged app hash",
+			tamper: func(path, height string, body []byte) []byte {
+				if path != "commit" || height != "10" {
+					return body
+				}
+				i := strings.Index(string(body), `"app_hash":"`) + len(`"app_hash":"`)
+				forged := []byte(string(body))
+				forged[i] = flipHex(forged[i])
+				return forged
+			},
+			want: "not for the returned header",
+		},
+		{
+			name: "bad signature",
+			tamper: func(path, height string, body []byte) []byte {
+				if path != "commit" || height != "10" {
+					return body
+				}
+				i := strings.Index(string(body), `"signature":"`) + len(`"signature":"`)
+				forged := []byte(string(body))
+				if forged[i] == 'A' {
+					forged[i] = 'B'
+				} else {
+					forged[i] = 'A'
+				}
+				return forged
+			},
+			want: "invalid signature",
+		},
+		{
+			name: "swapped validator set",
+			tamper: func(path, height string, body []byte) []byte {
+				if path != "validators" || height != "10" {
+					return body
+				}
+				data, _ := os.ReadFile(filepath.Join("testdata", "light", "validators_2.json"))
+				return data
+			},
+			want: "does not match the header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeLightRPC(t, tt.tamper)
+			manager := setupLightManager(t, []string{srv.URL})
+			require.NoError(t, manager.SaveLightCheckpoint(LightCheckpoint{ChainID: "test-1", Height: 2, Hash: lightHashAt2}))
+
+			_, err := manager.VerifyTrustBlock(context.Background(), "test-1", []string{srv.URL},
+				Block{Height: 10, Hash: lightBlockHash(t, 10)})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+
+			// A failed verification leaves the checkpoint alone
+			cp, err := manager.LightCheckpoint("test-1")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), cp.Height)
+		})
+	}
+}
+
+func TestVerifyTrustBlockWrongTargetHash(t *testing.T) {
+	srv := fakeLightRPC(t, nil)
+	manager := setupLightManager(t, []string{srv.URL})
+	require.NoError(t, manager.SaveLightCheckpoint(LightCheckpoint{ChainID: "test-1", Height: 2, Hash: lightHashAt2}))
+
+	_, err := manager.VerifyTrustBlock(context.Background(), "test-1", []string{srv.URL},
+		Block{Height: 10, Hash: canonicalHash(10)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match the signed header")
+}
+
+func TestVerifyTrustBlockCheckpointErrors(t *testing.T) {
+	srv := fakeLightRPC(t, nil)
+	manager := setupLightManager(t, []string{srv.URL})
+	target := Block{Height: 10, Hash: lightBlockHash(t, 10)}
+
+	_, err := manager.VerifyTrustBlock(context.Background(), "test-1", []string{srv.URL}, target)
+	assert.ErrorIs(t, err, ErrNoCheckpoint)
+
+	require.NoError(t, manager.SaveLightCheckpoint(LightCheckpoint{ChainID: "test-1", Height: 2, Hash: canonicalHash(2)}))
+	_, err = manager.VerifyTrustBlock(context.Background(), "test-1", []string{srv.URL}, target)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checkpoint hash")
+
+	require.NoError(t, manager.SaveLightCheckpoint(LightCheckpoint{ChainID: "test-1", Height: 2, Hash: lightHashAt2}))
+	manager.config.Environments["testnet"].StateSync.TrustPeriod = "168h"
+	_, err = manager.VerifyTrustBlock(context.Background(), "test-1", []string{srv.URL}, target)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "trusting period")
+
+	_, err = manager.VerifyTrustBlock(context.Background(), "test-1", []string{srv.URL}, Block{Height: 1, Hash: canonicalHash(1)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not above the checkpoint")
+}
+
+func TestVerifyTrustBlockFailsOver(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(down.Close)
+	srv := fakeLightRPC(t, nil)
+	manager := setupLightManager(t, []string{down.URL, srv.URL})
+	require.NoError(t, manager.SaveLightCheckpoint(LightCheckpoint{ChainID: "test-1", Height: 2, Hash: lightHashAt2}))
+
+	_, err := manager.VerifyTrustBlock(context.Background(), "test-1", []string{down.URL, srv.URL},
+		Block{Height: 10, Hash: lightBlockHash(t, 10)})
+	require.NoError(t, err)
+}
+
+func flipHex(c byte) byte {
+	if c == '0' {
+		return '1'
+	}
+	return '0'
+}
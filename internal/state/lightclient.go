@@ -0,0 +1,423 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/your-org/seictl/internal/rpc"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+const (
+	lightCheckpointFile = "light_checkpoints.json"
+	pubKeyEd25519       = "tendermint/PubKeyEd25519"
+)
+
+// ErrNoCheckpoint is returned when light verification has nothing to start from
+var ErrNoCheckpoint = errors.New("no trusted checkpoint stored for this chain")
+
+// lightBlock is a signed header with the validator set that signed it
+type lightBlock struct {
+	rpc.SignedHeader
+	Validators []rpc.Validator
+}
+
+// LightCheckpoint is a header seictl trusts, used as the root for verifying
+// later headers
+type LightCheckpoint struct {
+	ChainID string    `json:"chain_id"`
+	Height  int64     `json:"height"`
+	Hash    string    `json:"hash"`
+	Time    time.Time `json:"time,omitempty"`
+}
+
+// LightVerification reports how a trust block was verified
+type LightVerification struct {
+	Checkpoint LightCheckpoint `json:"checkpoint"`
+	Target     Block           `json:"target"`
+	// Path lists the heights verified on the way from the checkpoint
+	Path []int64 `json:"path"`
+}
+
+// SaveLightCheckpoint stores a trusted header for a chain, replacing any
+// previous checkpoint
+func (m *Manager) SaveLightCheckpoint(cp LightCheckpoint) error {
+	if cp.ChainID == "" || cp.Height <= 0 || !isValidHash(cp.Hash) {
+		return fmt.Errorf("a checkpoint needs a chain ID, a positive height and a 64 character hash")
+	}
+	cp.Hash = strings.ToUpper(strings.TrimPrefix(cp.Hash, "0x"))
+
+	checkpoints, err := m.readLightCheckpoints()
+	if err != nil {
+		return err
+	}
+	checkpoints[cp.ChainID] = cp
+
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoints: %w", err)
+	}
+	if err := os.MkdirAll(m.config.Global.BackupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	path := filepath.Join(m.config.Global.BackupDir, lightCheckpointFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// TrustCheckpoint stores the block at opts.TrustHeight as the light client
+// checkpoint for the environment. Without a hash, the hash is resolved from
+// the environment's RPC endpoints and must reach quorum.
+func (m *Manager) TrustCheckpoint(ctx context.Context, opts StateSyncOptions, hash string) (*LightCheckpoint, error) {
+	if opts.TrustHeight <= 0 {
+		return nil, fmt.Errorf("a checkpoint height is required")
+	}
+	env, err := m.stateSyncEnv(opts.Env)
+	if err != nil {
+		return nil, err
+	}
+	if hash == "" {
+		trust, err := m.ResolveTrustBlock(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		hash = trust.Hash
+	}
+
+	cp := LightCheckpoint{ChainID: env.ChainID, Height: opts.TrustHeight, Hash: hash}
+	if err := m.SaveLightCheckpoint(cp); err != nil {
+		return nil, err
+	}
+	m.logger.Info().Str("chain_id", cp.ChainID).Int64("height", cp.Height).Msg("Light client checkpoint stored")
+	return m.LightCheckpoint(cp.ChainID)
+}
+
+// EnvCheckpoint returns the stored checkpoint for an environment's chain
+func (m *Manager) EnvCheckpoint(env types.Environment) (*LightCheckpoint, error) {
+	cfg, err := m.stateSyncEnv(env)
+	if err != nil {
+		return nil, err
+	}
+	return m.LightCheckpoint(cfg.ChainID)
+}
+
+// LightCheckpoint returns the stored checkpoint for a chain
+func (m *Manager) LightCheckpoint(chainID string) (*LightCheckpoint, error) {
+	checkpoints, err := m.readLightCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+	cp, ok := checkpoints[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoCheckpoint, chainID)
+	}
+	return &cp, nil
+}
+
+func (m *Manager) readLightCheckpoints() (map[string]LightCheckpoint, error) {
+	checkpoints := make(map[string]LightCheckpoint)
+	data, err := os.ReadFile(filepath.Join(m.config.Global.BackupDir, lightCheckpointFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return checkpoints, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// VerifyTrustBlock checks that target is a header signed by more than 2/3 of
+// its validators and chains it back to the stored checkpoint using skipping
+// verification with bisection. Endpoints are only used as data sources and
+// are tried in order. On success the checkpoint advances to target.
+func (m *Manager) VerifyTrustBlock(ctx context.Context, chainID string, endpoints []string, target Block) (*LightVerification, error) {
+	cp, err := m.LightCheckpoint(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Height <= cp.Height {
+		return nil, fmt.Errorf("trust height %d is not above the checkpoint at %d", target.Height, cp.Height)
+	}
+
+	var poolCfg *types.RPCPoolConfig
+	for _, env := range m.config.Environments {
+		if env.ChainID == chainID {
+			poolCfg = env.RPCPool
+		}
+	}
+	v := &lightVerifier{
+		m:       m,
+		chainID: chainID,
+		pool:    m.newRPCPool(chainID, endpoints, poolCfg),
+		blocks:  make(map[int64]*lightBlock),
+		valSets: make(map[int64][]rpc.Validator),
+	}
+
+	trusted, err := v.block(ctx, cp.Height)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(hex.EncodeToString(trusted.Commit.BlockID.Hash), cp.Hash) {
+		return nil, fmt.Errorf("checkpoint hash %s does not match the chain's header at height %d", cp.Hash, cp.Height)
+	}
+	period := m.trustPeriod(chainID)
+	if age := time.Since(trusted.Header.Time); age > period {
+		return nil, fmt.Errorf("checkpoint at height %d is %s old, beyond the %s trusting period; store a newer one",
+			cp.Height, age.Round(time.Hour), period)
+	}
+
+	untrusted, err := v.block(ctx, target.Height)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(hex.EncodeToString(untrusted.Commit.BlockID.Hash), target.Hash) {
+		return nil, fmt.Errorf("trust hash %s does not match the signed header at height %d", target.Hash, target.Height)
+	}
+
+	if err := v.verifyRange(ctx, trusted, untrusted, 0); err != nil {
+		return nil, err
+	}
+
+	result := &LightVerification{Checkpoint: *cp, Target: target, Path: v.path}
+	if err := m.SaveLightCheckpoint(LightCheckpoint{
+		ChainID: chainID,
+		Height:  target.Height,
+		Hash:    target.Hash,
+		Time:    untrusted.Header.Time,
+	}); err != nil {
+		return nil, err
+	}
+
+	m.logger.Info().
+		Int64("checkpoint", cp.Height).
+		Int64("height", target.Height).
+		Ints64("path", v.path).
+		Msg("Trust block verified by light client")
+
+	return result, nil
+}
+
+// trustPeriod returns the trusting period of the environment for chainID
+func (m *Manager) trustPeriod(chainID string) time.Duration {
+	for _, env := range m.config.Environments {
+		if env.ChainID == chainID && env.StateSync != nil {
+			return env.StateSync.GetTrustPeriod()
+		}
+	}
+	return defaultTrustPeriod
+}
+
+const (
+	defaultTrustPeriod   = 168 * time.Hour
+	maxBisectionDepth    = 64
+	validatorsPageLength = 100
+)
+
+type lightVerifier struct {
+	m       *Manager
+	chainID string
+	pool    *RPCPool
+	blocks  map[int64]*lightBlock
+	valSets map[int64][]rpc.Validator
+	path    []int64
+}
+
+// verifyRange verifies untrusted from trusted, bisecting when the trusted
+// validators no longer hold enough power in the untrusted commit
+func (v *lightVerifier) verifyRange(ctx context.Context, trusted, untrusted *lightBlock, depth int) error {
+	if depth > maxBisectionDepth {
+		return fmt.Errorf("light client bisection exceeded %d steps", maxBisectionDepth)
+	}
+
+	err := v.verifyStep(ctx, trusted, untrusted)
+	if err == nil {
+		v.path = append(v.path, untrusted.Header.Height)
+		return nil
+	}
+	if !errors.Is(err, errNotEnoughTrust) {
+		return err
+	}
+
+	pivotHeight := (trusted.Header.Height + untrusted.Header.Height) / 2
+	pivot, err := v.block(ctx, pivotHeight)
+	if err != nil {
+		return err
+	}
+	if err := v.verifyRange(ctx, trusted, pivot, depth+1); err != nil {
+		return err
+	}
+	return v.verifyRange(ctx, pivot, untrusted, depth+1)
+}
+
+var errNotEnoughTrust = errors.New("trusted validators hold less than 1/3 of the signing power")
+
+func (v *lightVerifier) verifyStep(ctx context.Context, trusted, untrusted *lightBlock) error {
+	if !untrusted.Header.Time.After(trusted.Header.Time) {
+		return fmt.Errorf("header at height %d is not newer than the trusted header", untrusted.Header.Height)
+	}
+
+	// Adjacent headers are linked by the validator set hash
+	if untrusted.Header.Height == trusted.Header.Height+1 {
+		if !bytes.Equal(untrusted.Header.ValidatorsHash, trusted.Header.NextValidatorsHash) {
+			return fmt.Errorf("validator set at height %d does not match the one announced at %d",
+				untrusted.Header.Height, trusted.Header.Height)
+		}
+		return nil
+	}
+
+	// Otherwise the validators trusted for the next height must still hold
+	// more than 1/3 of their power in the new commit
+	nextVals, err := v.validators(ctx, trusted.Header.Height+1)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(validatorSetHash(nextVals), trusted.Header.NextValidatorsHash) {
+		return fmt.Errorf("validator set at height %d does not match the trusted header", trusted.Header.Height+1)
+	}
+
+	signed, total, err := signedPower(v.chainID, &untrusted.SignedHeader, nextVals, false)
+	if err != nil {
+		return err
+	}
+	if signed*3 <= total {
+		return errNotEnoughTrust
+	}
+	return nil
+}
+
+// block fetches and self-verifies the light block at height: the header must
+// hash to the committed block ID and more than 2/3 of its validators must
+// have signed it
+func (v *lightVerifier) block(ctx context.Context, height int64) (*lightBlock, error) {
+	if b, ok := v.blocks[height]; ok {
+		return b, nil
+	}
+
+	var commit *rpc.ResultCommit
+	if err := v.pool.Do(ctx, func(endpoint string) error {
+		client, err := v.m.rpcClientFor(endpoint)
+		if err != nil {
+			return err
+		}
+		commit, err = client.Commit(ctx, &height)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	sh := commit.SignedHeader
+	vals, err := v.validators(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	if sh.Header.ChainID != v.chainID {
+		return nil, fmt.Errorf("header at height %d belongs to chain %q", height, sh.Header.ChainID)
+	}
+	if sh.Header.Height != height || sh.Commit.Height != height {
+		return nil, fmt.Errorf("endpoint returned the wrong height for %d", height)
+	}
+	if !bytes.Equal(headerHash(&sh.Header), sh.Commit.BlockID.Hash) {
+		return nil, fmt.Errorf("commit at height %d is not for the returned header", height)
+	}
+	if !bytes.Equal(validatorSetHash(vals), sh.Header.ValidatorsHash) {
+		return nil, fmt.Errorf("validator set at height %d does not match the header", height)
+	}
+
+	signed, total, err := signedPower(v.chainID, &sh, vals, true)
+	if err != nil {
+		return nil, err
+	}
+	if signed*3 <= total*2 {
+		return nil, fmt.Errorf("only %d of %d voting power signed height %d, more than 2/3 is required", signed, total, height)
+	}
+
+	b := &lightBlock{SignedHeader: sh, Validators: vals}
+	v.blocks[height] = b
+	return b, nil
+}
+
+// validators fetches the complete validator set at height
+func (v *lightVerifier) validators(ctx context.Context, height int64) ([]rpc.Validator, error) {
+	if vals, ok := v.valSets[height]; ok {
+		return vals, nil
+	}
+
+	// The data is verified by the caller, so any endpoint will do
+	var vals []rpc.Validator
+	if err := v.pool.Do(ctx, func(endpoint string) error {
+		client, err := v.m.rpcClientFor(endpoint)
+		if err != nil {
+			return err
+		}
+		vals, err = client.AllValidators(ctx, &height)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	v.valSets[height] = vals
+	return vals, nil
+}
+
+// signedPower sums the voting power of vals with a valid signature in the
+// commit. With ordered set the signatures must line up with vals, as for the
+// header's own validator set; otherwise they are matched by address.
+func signedPower(chainID string, sh *rpc.SignedHeader, vals []rpc.Validator, ordered bool) (int64, int64, error) {
+	commit := &sh.Commit
+	if ordered && len(commit.Signatures) != len(vals) {
+		return 0, 0, fmt.Errorf("commit at height %d has %d signatures for %d validators",
+			commit.Height, len(commit.Signatures), len(vals))
+	}
+
+	byAddress := make(map[string]rpc.CommitSig, len(commit.Signatures))
+	for _, sig := range commit.Signatures {
+		if sig.BlockIDFlag == rpc.BlockIDFlagCommit {
+			byAddress[string(sig.ValidatorAddress)] = sig
+		}
+	}
+
+	var signed, total int64
+	for i, val := range vals {
+		total += val.VotingPower
+
+		var sig rpc.CommitSig
+		if ordered {
+			sig = commit.Signatures[i]
+			if sig.BlockIDFlag != rpc.BlockIDFlagCommit {
+				continue
+			}
+			if !bytes.Equal(sig.ValidatorAddress, val.Address) {
+				return 0, 0, fmt.Errorf("signature %d at height %d is not from the expected validator", i, commit.Height)
+			}
+		} else {
+			var ok bool
+			if sig, ok = byAddress[string(val.Address)]; !ok {
+				continue
+			}
+		}
+
+		if val.PubKey.Type != pubKeyEd25519 || len(val.PubKey.Value) != ed25519.PublicKeySize {
+			return 0, 0, fmt.Errorf("unsupported validator key type %q", val.PubKey.Type)
+		}
+		if !ed25519.Verify(val.PubKey.Value, voteSignBytes(chainID, commit, sig), sig.Signature) {
+			return 0, 0, fmt.Errorf("invalid signature from validator %X at height %d", []byte(val.Address), commit.Height)
+		}
+		signed += val.VotingPower
+	}
+
+	return signed, total, nil
+}
@@ -0,0 +1,255 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/your-org/seictl/internal/rpc"
+)
+
+// Minimal protobuf encoding of the Tendermint structures that are hashed or
+// signed, and schema-less decoding for reading node databases. Field presence follows gogoproto: proto3 scalars are omitted when
+// zero, non-nullable embedded messages are always written.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+
+	precommitType = 2
+)
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendTag(b []byte, field, wire int) []byte {
+	return appendVarint(b, uint64(field<<3|wire))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return appendVarint(appendTag(b, field, wireVarint), v)
+}
+
+func appendFixed64Field(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireFixed64)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendBytesField(b []byte, field int, data []byte) []byte {
+	if len(data) == 0 {
+		return b
+	}
+	return appendMessageField(b, field, data)
+}
+
+// appendMessageField writes an embedded message even when it is empty
+func appendMessageField(b []byte, field int, msg []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+func encodeTimestamp(t time.Time) []byte {
+	var b []byte
+	b = appendVarintField(b, 1, uint64(t.Unix()))
+	b = appendVarintField(b, 2, uint64(t.Nanosecond()))
+	return b
+}
+
+func encodePartSetHeader(p rpc.PartSetHeader) []byte {
+	var b []byte
+	b = appendVarintField(b, 1, uint64(p.Total))
+	b = appendBytesField(b, 2, p.Hash)
+	return b
+}
+
+func encodeBlockID(id rpc.BlockID) []byte {
+	var b []byte
+	b = appendBytesField(b, 1, id.Hash)
+	b = appendMessageField(b, 2, encodePartSetHeader(id.Parts))
+	return b
+}
+
+// Wrapper types (StringValue, Int64Value, BytesValue) used for header fields
+func cdcString(s string) []byte { return appendBytesField(nil, 1, []byte(s)) }
+func cdcInt64(v int64) []byte   { return appendVarintField(nil, 1, uint64(v)) }
+func cdcBytes(v []byte) []byte  { return appendBytesField(nil, 1, v) }
+
+// headerHash computes the block hash: the Merkle root of the encoded header fields
+func headerHash(h *rpc.Header) []byte {
+	var version []byte
+	version = appendVarintField(version, 1, h.Version.Block)
+	version = appendVarintField(version, 2, h.Version.App)
+
+	return merkleRoot([][]byte{
+		version,
+		cdcString(h.ChainID),
+		cdcInt64(h.Height),
+		encodeTimestamp(h.Time),
+		encodeBlockID(h.LastBlockID),
+		cdcBytes(h.LastCommitHash),
+		cdcBytes(h.DataHash),
+		cdcBytes(h.ValidatorsHash),
+		cdcBytes(h.NextValidatorsHash),
+		cdcBytes(h.ConsensusHash),
+		cdcBytes(h.AppHash),
+		cdcBytes(h.LastResultsHash),
+		cdcBytes(h.EvidenceHash),
+		cdcBytes(h.ProposerAddress),
+	})
+}
+
+// validatorSetHash is the Merkle root of each validator's public key and power
+func validatorSetHash(vals []rpc.Validator) []byte {
+	items := make([][]byte, len(vals))
+	for i, v := range vals {
+		var b []byte
+		b = appendMessageField(b, 1, appendBytesField(nil, 1, v.PubKey.Value))
+		b = appendVarintField(b, 2, uint64(v.VotingPower))
+		items[i] = b
+	}
+	return merkleRoot(items)
+}
+
+// voteSignBytes returns the length-prefixed canonical precommit a validator
+// signed for a commit signature
+func voteSignBytes(chainID string, commit *rpc.Commit, sig rpc.CommitSig) []byte {
+	var blockID []byte
+	blockID = appendBytesField(blockID, 1, commit.BlockID.Hash)
+	blockID = appendMessageField(blockID, 2, encodePartSetHeader(commit.BlockID.Parts))
+
+	var b []byte
+	b = appendVarintField(b, 1, precommitType)
+	b = appendFixed64Field(b, 2, uint64(commit.Height))
+	b = appendFixed64Field(b, 3, uint64(commit.Round))
+	b = appendMessageField(b, 4, blockID)
+	b = appendMessageField(b, 5, encodeTimestamp(sig.Timestamp))
+	b = appendBytesField(b, 6, []byte(chainID))
+
+	return append(appendVarint(nil, uint64(len(b))), b...)
+}
+
+// merkleRoot hashes items into an RFC 6962 Merkle tree
+func merkleRoot(items [][]byte) []byte {
+	switch len(items) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		sum := sha256.Sum256(append([]byte{0}, items[0]...))
+		return sum[:]
+	}
+
+	// Split at the largest power of two smaller than the item count
+	k := 1
+	for k*2 < len(items) {
+		k *= 2
+	}
+	left := merkleRoot(items[:k])
+	right := merkleRoot(items[k:])
+	sum := sha256.Sum256(append(append([]byte{1}, left...), right...))
+	return sum[:]
+}
+
+// protoField is one field of a decoded protobuf message. Varint and fixed
+// values are in Value, length-delimited ones in Bytes.
+type protoField struct {
+	Num   int
+	Wire  int
+	Value uint64
+	Bytes []byte
+}
+
+// decodeProto splits a protobuf message into its fields without a schema
+func decodeProto(b []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid protobuf tag")
+		}
+		b = b[n:]
+
+		f := protoField{Num: int(tag >> 3), Wire: int(tag & 7)}
+		switch f.Wire {
+		case wireVarint:
+			f.Value, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid varint in field %d", f.Num)
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, fmt.Errorf("truncated field %d", f.Num)
+			}
+			f.Value, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return nil, fmt.Errorf("truncated field %d", f.Num)
+			}
+			f.Bytes, b = b[n:n+int(size)], b[n+int(size):]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, fmt.Errorf("truncated field %d", f.Num)
+			}
+			f.Value, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", f.Wire, f.Num)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// protoInt returns the last value of a varint field, or 0 when absent
+func protoInt(fields []protoField, num int) int64 {
+	var v int64
+	for _, f := range fields {
+		if f.Num == num && f.Wire == wireVarint {
+			v = int64(f.Value)
+		}
+	}
+	return v
+}
+
+// protoBytes returns the last value of a length-delimited field
+func protoBytes(fields []protoField, num int) []byte {
+	var v []byte
+	for _, f := range fields {
+		if f.Num == num && f.Wire == wireBytes {
+			v = f.Bytes
+		}
+	}
+	return v
+}
+
+// protoMessage decodes an embedded message field
+func protoMessage(fields []protoField, num int) ([]protoField, error) {
+	return decodeProto(protoBytes(fields, num))
+}
+
+// decodeTimestamp decodes a google.protobuf.Timestamp
+func decodeTimestamp(b []byte) time.Time {
+	fields, err := decodeProto(b)
+	if err != nil || len(fields) == 0 {
+		return time.Time{}
+	}
+	return time.Unix(protoInt(fields, 1), protoInt(fields, 2)).UTC()
+}
@@ -4,15 +4,15 @@ import (
 	"bufio"
 	"context"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -23,6 +23,15 @@ import (
 type Manager struct {
 	config *types.Config
 	logger zerolog.Logger
+
+	// RPC pools per chain ID and the HTTP client they share
+	poolsMu    sync.Mutex
+	pools      map[string]*RPCPool
+	clientOnce sync.Once
+	client     *http.Client
+
+	// mounter mounts tmpfs directories; nil uses mount(8)
+	mounter Mounter
 }
 
 // NewManager creates a new state manager
@@ -35,33 +44,50 @@ func NewManager(cfg *types.Config, logger zerolog.Logger) (*Manager, error) {
 
 // CreateSnapshot creates a chain state snapshot
 func (m *Manager) CreateSnapshot(ctx context.Context, height int64) error {
+	return m.createSnapshotFrom(ctx, height, m.config.Global.HomeDir)
+}
+
+// createSnapshotFrom snapshots the data and wasm directories found under
+// sourceDir, which is either the node home or a frozen copy of it
+func (m *Manager) createSnapshotFrom(ctx context.Context, height int64, sourceDir string) error {
 	m.logger.Info().Int64("height", height).Msg("Creating snapshot")
 
 	// Create snapshot directory
-	snapshotDir := filepath.Join(m.config.Global.BackupDir, fmt.Sprintf("snapshot_%d", height))
+	snapshotDir := filepath.Join(m.config.Global.BackupDir, snapshotDirName(height))
 	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
 		return fmt.Errorf("failed to create snapshot directory: %w", err)
 	}
 
 	// Backup validator state
-	if err := m.backupValidatorState(snapshotDir); err != nil {
+	if err := m.backupValidatorState(sourceDir, snapshotDir); err != nil {
 		return fmt.Errorf("failed to backup validator state: %w", err)
 	}
 
 	// Create data snapshot
-	if err := m.createDataSnapshot(ctx, snapshotDir, height); err != nil {
+	if err := m.createDataSnapshot(ctx, sourceDir, snapshotDir, height); err != nil {
 		return fmt.Errorf("failed to create data snapshot: %w", err)
 	}
+	archives := []string{"priv_validator_state.json", fmt.Sprintf("data_%d.tar.gz", height)}
 
 	// Create WASM snapshot if exists
-	wasmDir := filepath.Join(m.config.Global.HomeDir, "wasm")
+	wasmDir := filepath.Join(sourceDir, "wasm")
 	if _, err := os.Stat(wasmDir); err == nil {
-		if err := m.createWasmSnapshot(ctx, snapshotDir); err != nil {
+		if err := m.createWasmSnapshot(ctx, sourceDir, snapshotDir); err != nil {
 			return fmt.Errorf("failed to create wasm snapshot: %w", err)
 		}
+		archives = append(archives, "wasm.tar.gz")
+	}
+
+	// Record checksums so the snapshot can be verified later
+	if _, err := m.writeManifest(snapshotDir, height, archives); err != nil {
+		return fmt.Errorf("failed to write snapshot manifest: %w", err)
 	}
 
 	m.logger.Info().Str("path", snapshotDir).Msg("Snapshot created successfully")
+
+	// Enforce retention now that the new snapshot is safely on disk
+	m.applyRetention()
+
 	return nil
 }
 
@@ -84,77 +110,123 @@ func (m *Manager) RestoreSnapshot(ctx context.Context, snapshotPath string) erro
 		return fmt.Errorf("failed to backup current state: %w", err)
 	}
 
+	// Extract into staging so a failure never leaves a partial data directory
+	tx, err := m.beginRestore(snapshotPath, false)
+	if err != nil {
+		return err
+	}
+	if err := m.stageSnapshot(ctx, snapshotPath, tx.staging); err != nil {
+		return tx.abort(err)
+	}
+	if err := tx.verify(); err != nil {
+		return tx.abort(fmt.Errorf("staged snapshot failed verification: %w", err))
+	}
+	if err := tx.commit(); err != nil {
+		return err
+	}
+
+	m.logger.Info().Msg("Snapshot restored successfully")
+	return nil
+}
+
+// stageSnapshot extracts a snapshot below destHome
+func (m *Manager) stageSnapshot(ctx context.Context, snapshotPath, destHome string) error {
+	// Incremental snapshots are rebuilt from the chunk repository
+	if isIncrementalSnapshot(snapshotPath) {
+		if err := m.restoreIncremental(ctx, snapshotPath, destHome); err != nil {
+			return fmt.Errorf("failed to restore incremental snapshot: %w", err)
+		}
+		return nil
+	}
+
 	// Restore data
-	if err := m.restoreData(ctx, snapshotPath); err != nil {
+	if err := m.restoreData(ctx, snapshotPath, destHome); err != nil {
 		return fmt.Errorf("failed to restore data: %w", err)
 	}
 
 	// Restore WASM if exists
 	wasmSnapshot := filepath.Join(snapshotPath, "wasm.tar.gz")
 	if _, err := os.Stat(wasmSnapshot); err == nil {
-		if err := m.restoreWasm(ctx, wasmSnapshot); err != nil {
+		if err := m.restoreWasm(ctx, wasmSnapshot, destHome); err != nil {
 			return fmt.Errorf("failed to restore wasm: %w", err)
 		}
 	}
 
-	m.logger.Info().Msg("Snapshot restored successfully")
 	return nil
 }
 
 // SyncState performs state synchronization
-func (m *Manager) SyncState(ctx context.Context, targetHeight int64) error {
-	m.logger.Info().Int64("target_height", targetHeight).Msg("Starting state sync")
+func (m *Manager) SyncState(ctx context.Context, opts StateSyncOptions) error {
+	m.logger.Info().Int64("trust_height", opts.TrustHeight).Msg("Starting state sync")
 
 	// Configure state sync
-	if err := m.configureStateSync(targetHeight); err != nil {
+	if err := m.configureStateSync(ctx, opts); err != nil {
 		return fmt.Errorf("failed to configure state sync: %w", err)
 	}
 
 	// Start sync process
-	if err := m.startStateSync(ctx); err != nil {
+	if err := m.startStateSync(ctx, opts.Env); err != nil {
 		return fmt.Errorf("state sync failed: %w", err)
 	}
 
 	return nil
 }
 
-func (m *Manager) startStateSync(ctx context.Context) error {
+func (m *Manager) startStateSync(ctx context.Context, env types.Environment) error {
 	m.logger.Info().Msg("Starting state sync process")
 
+	// seid's output goes to a fresh log, which the monitor tails for progress.
+	// The previous log is kept alongside.
+	logPath := filepath.Join(m.config.Global.HomeDir, nodeLogFile)
+	if err := os.Rename(logPath, logPath+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate node log: %w", err)
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open node log: %w", err)
+	}
+	defer logFile.Close()
+	m.logger.Info().Str("log", logPath).Msg("Node output is written to the log")
+
+	monitor := MonitorOptions{Env: env, LogPath: logPath, FromStart: true}
+
 	// Start the node in state sync mode
-	cmd := exec.CommandContext(ctx, "seid", "start", "--state-sync")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := exec.CommandContext(ctx, "seid", "start", "--state-sync", "--home", m.config.Global.HomeDir)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
 
 	// Start the node
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start node: %w", err)
 	}
 
-	// Monitor state sync in a goroutine
-	errCh := make(chan error, 1)
-	go func() {
-		if err := m.MonitorStateSync(ctx); err != nil {
-			errCh <- err
-		}
-		close(errCh)
-	}()
+	waitCh := make(chan error, 1)
+	go func() { waitCh <- cmd.Wait() }()
+
+	monitorCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	monitorCh := make(chan error, 1)
+	go func() { monitorCh <- m.MonitorStateSync(monitorCtx, monitor) }()
 
-	// Wait for either context cancellation or monitoring completion
+	// Wait for the node to catch up, exit, or the context to end
 	select {
-	case <-ctx.Done():
-		return fmt.Errorf("state sync cancelled: %w", ctx.Err())
-	case err := <-errCh:
+	case err := <-monitorCh:
 		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("state sync cancelled: %w", ctx.Err())
+			}
 			return fmt.Errorf("state sync monitoring failed: %w", err)
 		}
+		m.logger.Info().Msg("State sync completed, node keeps running")
+	case err := <-waitCh:
+		return fmt.Errorf("seid exited during state sync (see %s): %v", logPath, err)
 	}
 
-	return cmd.Wait()
+	return <-waitCh
 }
 
-func (m *Manager) backupValidatorState(snapshotDir string) error {
-	valStateFile := filepath.Join(m.config.Global.HomeDir, "data", "priv_validator_state.json")
+func (m *Manager) backupValidatorState(sourceDir, snapshotDir string) error {
+	valStateFile := filepath.Join(sourceDir, "data", "priv_validator_state.json")
 	backupPath := filepath.Join(snapshotDir, "priv_validator_state.json")
 
 	if err := copyFile(valStateFile, backupPath); err != nil {
@@ -164,8 +236,8 @@ func (m *Manager) backupValidatorState(snapshotDir string) error {
 	return nil
 }
 
-func (m *Manager) createDataSnapshot(ctx context.Context, snapshotDir string, height int64) error {
-	dataDir := filepath.Join(m.config.Global.HomeDir, "data")
+func (m *Manager) createDataSnapshot(ctx context.Context, sourceDir, snapshotDir string, height int64) error {
+	dataDir := filepath.Join(sourceDir, "data")
 	outFile := filepath.Join(snapshotDir, fmt.Sprintf("data_%d.tar.gz", height))
 
 	select {
@@ -184,8 +256,8 @@ func (m *Manager) createDataSnapshot(ctx context.Context, snapshotDir string, he
 	}
 }
 
-func (m *Manager) createWasmSnapshot(ctx context.Context, snapshotDir string) error {
-	wasmDir := filepath.Join(m.config.Global.HomeDir, "wasm")
+func (m *Manager) createWasmSnapshot(ctx context.Context, sourceDir, snapshotDir string) error {
+	wasmDir := filepath.Join(sourceDir, "wasm")
 	outFile := filepath.Join(snapshotDir, "wasm.tar.gz")
 
 	cmd := exec.CommandContext(ctx, "tar", "-czf", outFile, "-C", wasmDir, ".")
@@ -198,9 +270,12 @@ func (m *Manager) createWasmSnapshot(ctx context.Context, snapshotDir string) er
 
 func (m *Manager) verifySnapshot(snapshotPath string) error {
 	required := []string{
-		filepath.Join(snapshotPath, "data.tar.gz"),
+		findDataArchive(snapshotPath),
 		filepath.Join(snapshotPath, "priv_validator_state.json"),
 	}
+	if isIncrementalSnapshot(snapshotPath) {
+		required[0] = filepath.Join(snapshotPath, indexFileName)
+	}
 
 	for _, file := range required {
 		if _, err := os.Stat(file); err != nil {
@@ -212,6 +287,10 @@ func (m *Manager) verifySnapshot(snapshotPath string) error {
 }
 
 func (m *Manager) stopNode(ctx context.Context) error {
+	if !m.isNodeRunning() {
+		return nil
+	}
+
 	m.logger.Info().Msg("Stopping node")
 
 	// First try graceful shutdown
@@ -240,24 +319,44 @@ func (m *Manager) isNodeRunning() bool {
 	cmd := exec.Command("pgrep", "seid")
 	return cmd.Run() == nil
 }
+
+// backupCurrentState saves data/ and wasm/ in a backup_<timestamp> directory.
+// Files are hard linked where possible so the backup of a multi-TB node takes
+// next to no extra space.
 func (m *Manager) backupCurrentState() error {
-	timestamp := time.Now().Format("20060102_150405")
-	backupDir := filepath.Join(m.config.Global.BackupDir, fmt.Sprintf("backup_%s", timestamp))
+	timestamp := time.Now().Format(backupTimeFmt)
+	backupDir := filepath.Join(m.config.Global.BackupDir, backupDirPrefix+timestamp)
 
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
-	// Backup current data
-	dataDir := filepath.Join(m.config.Global.HomeDir, "data")
-	if err := copyDir(dataDir, filepath.Join(backupDir, "data")); err != nil {
-		return fmt.Errorf("failed to backup data: %w", err)
+	// Backup current data, if the node has any yet. A data move or db_dir may
+	// have put it on another volume.
+	dataDir := m.componentDir("data")
+	if _, err := os.Stat(dataDir); err == nil {
+		if err := linkDir(dataDir, filepath.Join(backupDir, "data")); err != nil {
+			return fmt.Errorf("failed to backup data: %w", err)
+		}
+	}
+
+	// The signing state is looked up in backup/data, wherever seid keeps it
+	statePath := m.privValStatePath()
+	if _, ok := relWithin(dataDir, resolvePath(statePath)); !ok {
+		if _, err := os.Stat(statePath); err == nil {
+			if err := os.MkdirAll(filepath.Join(backupDir, "data"), 0755); err != nil {
+				return fmt.Errorf("failed to backup validator state: %w", err)
+			}
+			if err := copyFile(statePath, filepath.Join(backupDir, "data", privValStateFile)); err != nil {
+				return fmt.Errorf("failed to backup validator state: %w", err)
+			}
+		}
 	}
 
 	// Backup WASM if exists
-	wasmDir := filepath.Join(m.config.Global.HomeDir, "wasm")
+	wasmDir := m.componentDir("wasm")
 	if _, err := os.Stat(wasmDir); err == nil {
-		if err := copyDir(wasmDir, filepath.Join(backupDir, "wasm")); err != nil {
+		if err := linkDir(wasmDir, filepath.Join(backupDir, "wasm")); err != nil {
 			return fmt.Errorf("failed to backup wasm: %w", err)
 		}
 	}
@@ -265,20 +364,15 @@ func (m *Manager) backupCurrentState() error {
 	return nil
 }
 
-func (m *Manager) restoreData(ctx context.Context, snapshotPath string) error {
-	dataFile := filepath.Join(snapshotPath, "data.tar.gz")
-	dataDir := filepath.Join(m.config.Global.HomeDir, "data")
+func (m *Manager) restoreData(ctx context.Context, snapshotPath, destHome string) error {
+	dataFile := findDataArchive(snapshotPath)
+	dataDir := filepath.Join(destHome, "data")
 
-	// Clear existing data
-	if err := os.RemoveAll(dataDir); err != nil {
+	// Clear existing data, keeping a symlink to another volume
+	if err := clearDir(dataDir, 0755); err != nil {
 		return fmt.Errorf("failed to clear existing data: %w", err)
 	}
 
-	// Create data directory
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
-	}
-
 	// Extract data
 	cmd := exec.CommandContext(ctx, "tar", "-xzf", dataFile, "-C", dataDir)
 	if err := cmd.Run(); err != nil {
@@ -288,19 +382,14 @@ func (m *Manager) restoreData(ctx context.Context, snapshotPath string) error {
 	return nil
 }
 
-func (m *Manager) restoreWasm(ctx context.Context, wasmFile string) error {
-	wasmDir := filepath.Join(m.config.Global.HomeDir, "wasm")
+func (m *Manager) restoreWasm(ctx context.Context, wasmFile, destHome string) error {
+	wasmDir := filepath.Join(destHome, "wasm")
 
-	// Clear existing WASM
-	if err := os.RemoveAll(wasmDir); err != nil {
+	// Clear existing WASM, keeping a symlink to another volume
+	if err := clearDir(wasmDir, 0755); err != nil {
 		return fmt.Errorf("failed to clear existing wasm: %w", err)
 	}
 
-	// Create WASM directory
-	if err := os.MkdirAll(wasmDir, 0755); err != nil {
-		return fmt.Errorf("failed to create wasm directory: %w", err)
-	}
-
 	// Extract WASM
 	cmd := exec.CommandContext(ctx, "tar", "-xzf", wasmFile, "-C", wasmDir)
 	if err := cmd.Run(); err != nil {
@@ -310,68 +399,59 @@ func (m *Manager) restoreWasm(ctx context.Context, wasmFile string) error {
 	return nil
 }
 
-func (m *Manager) configureStateSync(targetHeight int64) error {
-	m.logger.Info().Int64("target_height", targetHeight).Msg("Configuring state sync")
+func (m *Manager) configureStateSync(ctx context.Context, opts StateSyncOptions) error {
+	m.logger.Info().Int64("trust_height", opts.TrustHeight).Msg("Configuring state sync")
 
 	// Fetch trust block
-	block, err := m.fetchTrustBlock(targetHeight)
+	trust, err := m.fetchTrustBlock(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("failed to fetch trust block: %w", err)
 	}
 
-	// Update config with trust block info
-	for _, env := range m.config.Environments {
-		if len(env.RPCEndpoints) > 0 {
-			return m.setupStateSync(context.Background(), env.RPCEndpoints[0], block.Height, block.Hash)
-		}
+	if err := m.verifyTrustLight(ctx, opts, trust); err != nil {
+		return err
 	}
 
-	return fmt.Errorf("no RPC endpoints configured")
+	// Update config with trust block info
+	return m.setupStateSync(ctx, trust.Agreeing, trust.Height, trust.Hash)
 }
 
+// Block identifies a block by height and hash
 type Block struct {
-	Height int64
-	Hash   string
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
 }
 
-func (m *Manager) fetchTrustBlock(height int64) (*Block, error) {
+func (m *Manager) fetchTrustBlock(ctx context.Context, opts StateSyncOptions) (*TrustResult, error) {
 	// Try automatic fetch first
-	block, err := m.fetchTrustBlockAutomatic(height)
+	trust, err := m.ResolveTrustBlock(ctx, opts)
 	if err == nil {
-		return block, nil
+		return trust, nil
 	}
 
-	// If automatic fetch fails, try interactive mode
-	m.logger.Info().Msg("Automatic trust block fetch failed, switching to interactive mode")
-	return m.fetchTrustBlockInteractive()
-}
-
-func (m *Manager) fetchTrustBlockAutomatic(height int64) (*Block, error) {
-	var rpcEndpoints []string
-	for _, env := range m.config.Environments {
-		if len(env.RPCEndpoints) > 0 {
-			rpcEndpoints = append(rpcEndpoints, env.RPCEndpoints...)
-		}
+	// Endpoints that answer but disagree must never be papered over
+	if errors.Is(err, ErrNoTrustQuorum) && trust != nil && len(trust.Disagreed) > 0 {
+		return nil, err
 	}
-
-	if len(rpcEndpoints) == 0 {
-		return nil, fmt.Errorf("no RPC endpoints configured")
+	if !opts.ManualTrust {
+		return nil, fmt.Errorf("%w; pass --manual-trust to enter a trust block by hand", err)
 	}
 
-	for _, endpoint := range rpcEndpoints {
-		block, err := m.queryBlockFromRPC(endpoint, height)
-		if err == nil {
-			m.logger.Info().
-				Str("endpoint", endpoint).
-				Int64("height", block.Height).
-				Str("hash", block.Hash).
-				Msg("Successfully fetched trust block")
-			return block, nil
-		}
-		m.logger.Warn().Str("endpoint", endpoint).Err(err).Msg("Failed to fetch block from RPC")
+	m.logger.Warn().Err(err).Msg("Automatic trust block fetch failed, entering the trust block by hand without quorum")
+	block, err := m.fetchTrustBlockInteractive()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("failed to fetch trust block from any configured RPC endpoint")
+	env, err := m.stateSyncEnv(opts.Env)
+	if err != nil {
+		return nil, err
+	}
+	rpcServers := opts.RPC
+	if len(rpcServers) == 0 {
+		rpcServers = env.RPCEndpoints
+	}
+	return &TrustResult{Block: *block, Agreeing: rpcServers}, nil
 }
 
 func (m *Manager) fetchTrustBlockInteractive() (*Block, error) {
@@ -417,60 +497,18 @@ func (m *Manager) fetchTrustBlockInteractive() (*Block, error) {
 	return block, nil
 }
 
-func (m *Manager) queryBlockFromRPC(endpoint string, height int64) (*Block, error) {
-	// Ensure endpoint has proper scheme
-	if !strings.HasPrefix(endpoint, "http") {
-		endpoint = "http://" + endpoint
-	}
-
-	// Add proper path if not a full URL
-	if !strings.Contains(endpoint, "/block") {
-		endpoint = strings.TrimSuffix(endpoint, "/") + "/block"
-	}
-
-	// Add height parameter
-	url := fmt.Sprintf("%s?height=%d", endpoint, height)
-
-	// Make request with timeout
-	client := &http.Client{
-		Timeout: time.Duration(m.config.Global.TimeoutSeconds) * time.Second,
-	}
-
-	resp, err := client.Get(url)
+func (m *Manager) queryBlockFromRPC(ctx context.Context, endpoint string, height int64) (*Block, error) {
+	client, err := m.rpcClientFor(endpoint)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query RPC endpoint: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("RPC request failed with status: %s", resp.Status)
-	}
-
-	var result struct {
-		Result struct {
-			BlockID struct {
-				Hash string `json:"hash"`
-			} `json:"block_id"`
-			Block struct {
-				Header struct {
-					Height string `json:"height"`
-				} `json:"header"`
-			} `json:"block"`
-		} `json:"result"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode RPC response: %w", err)
+		return nil, err
 	}
-
-	blockHeight, err := strconv.ParseInt(result.Result.Block.Header.Height, 10, 64)
+	result, err := client.Block(ctx, &height)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse block height: %w", err)
+		return nil, err
 	}
-
 	return &Block{
-		Height: blockHeight,
-		Hash:   result.Result.BlockID.Hash,
+		Height: result.Block.Header.Height,
+		Hash:   result.BlockID.Hash.String(),
 	}, nil
 }
 
@@ -490,6 +528,23 @@ func isValidHash(hash string) bool {
 
 // Helper functions
 
+// findDataArchive returns the data archive of a snapshot directory. Snapshots
+// name it data_<height>.tar.gz; data.tar.gz is accepted for older layouts.
+func findDataArchive(snapshotPath string) string {
+	if manifest, err := readManifest(snapshotPath); err == nil {
+		for _, a := range manifest.Archives {
+			if strings.HasPrefix(a.Name, "data") {
+				return filepath.Join(snapshotPath, a.Name)
+			}
+		}
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(snapshotPath, "data_*.tar.gz")); len(matches) > 0 {
+		return matches[0]
+	}
+	return filepath.Join(snapshotPath, "data.tar.gz")
+}
+
 func copyFile(src, dst string) error {
 	input, err := os.ReadFile(src)
 	if err != nil {
@@ -532,9 +587,93 @@ func copyDir(src, dst string) error {
 	return nil
 }
 
-func (m *Manager) setupStateSync(_ context.Context, rpcEndpoint string, trustHeight int64, trustHash string) error {
+// clearDir removes everything inside path but keeps path itself, so a
+// symlink to another volume survives. A missing directory is created.
+func clearDir(path string, perm os.FileMode) error {
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return os.MkdirAll(path, perm)
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(path, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkDir mirrors src into dst with hard links. Files a database keeps
+// writing to are copied, as writes through a link would change the backup
+// too, and so are files on another filesystem than dst.
+func linkDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		sourcePath, err := filepath.EvalSymlinks(filepath.Join(src, entry.Name()))
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(dst, entry.Name())
+
+		fileInfo, err := os.Stat(sourcePath)
+		if err != nil {
+			return err
+		}
+
+		if fileInfo.IsDir() {
+			if err := linkDir(sourcePath, destPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if !writtenInPlace(sourcePath) && os.Link(sourcePath, destPath) == nil {
+			continue
+		}
+		if err := copyFile(sourcePath, destPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writtenInPlace reports files databases append to rather than replace:
+// write-ahead logs and LevelDB/Pebble manifests and logs. Tables and
+// snapshot files are immutable once written.
+func writtenInPlace(path string) bool {
+	base := filepath.Base(path)
+	if strings.HasSuffix(base, ".log") || strings.HasPrefix(base, "LOG") || strings.HasPrefix(base, "MANIFEST") {
+		return true
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if dir == "cs.wal" || dir == "changelog" || dir == "wal" {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) setupStateSync(_ context.Context, rpcServers []string, trustHeight int64, trustHash string) error {
+	if len(rpcServers) == 0 {
+		return fmt.Errorf("no RPC servers for state sync")
+	}
+	// Tendermint requires at least two RPC servers for light client verification
+	if len(rpcServers) == 1 {
+		rpcServers = append(rpcServers, rpcServers[0])
+	}
+
 	m.logger.Info().
-		Str("rpc", rpcEndpoint).
+		Strs("rpc", rpcServers).
 		Int64("height", trustHeight).
 		Msg("Setting up state sync")
 
@@ -547,15 +686,15 @@ func (m *Manager) setupStateSync(_ context.Context, rpcEndpoint string, trustHei
 
 	// Update state sync configuration
 	updates := map[string]string{
-		"statesync.enable":       "true",
-		"statesync.rpc_servers":  fmt.Sprintf("\"%s,%s\"", rpcEndpoint, rpcEndpoint),
-		"statesync.trust_height": fmt.Sprintf("%d", trustHeight),
-		"statesync.trust_hash":   fmt.Sprintf("\"%s\"", trustHash),
+		"enable":       "true",
+		"rpc-servers":  fmt.Sprintf("\"%s\"", strings.Join(rpcServers, ",")),
+		"trust-height": fmt.Sprintf("%d", trustHeight),
+		"trust-hash":   fmt.Sprintf("\"%s\"", trustHash),
 	}
 
 	newContent := string(content)
 	for key, value := range updates {
-		newContent = updateConfig(newContent, key, value)
+		newContent = updateSectionConfig(newContent, "statesync", key, value)
 	}
 
 	// Write updated config
@@ -566,102 +705,41 @@ func (m *Manager) setupStateSync(_ context.Context, rpcEndpoint string, trustHei
 	return nil
 }
 
-func (m *Manager) MonitorStateSync(ctx context.Context) error {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			status, err := m.getNodeStatus(ctx)
-			if err != nil {
-				m.logger.Error().Err(err).Msg("Failed to get node status")
-				continue
-			}
-
-			if status.Syncing {
-				m.logger.Info().
-					Int64("current_height", status.LatestHeight).
-					Msg("State sync in progress")
-			} else {
-				m.logger.Info().Msg("State sync completed")
-				return nil
-			}
-		}
-	}
-}
-
 type NodeStatus struct {
 	Syncing      bool
 	LatestHeight int64
 }
 
-func (m *Manager) getNodeStatus(ctx context.Context) (*NodeStatus, error) {
-	// Get RPC endpoint from config
-	var rpcEndpoint string
-	for _, env := range m.config.Environments {
-		if len(env.RPCEndpoints) > 0 {
-			rpcEndpoint = env.RPCEndpoints[0]
-			break
-		}
-	}
-
-	if rpcEndpoint == "" {
-		return nil, fmt.Errorf("no RPC endpoint configured")
-	}
-
-	// Ensure endpoint has proper scheme
-	if !strings.HasPrefix(rpcEndpoint, "http") {
-		rpcEndpoint = "http://" + rpcEndpoint
+// getNodeStatus queries the healthiest RPC endpoint of env, or of the local
+// chain when env is empty
+func (m *Manager) getNodeStatus(ctx context.Context, env types.Environment) (*NodeStatus, error) {
+	pool, err := m.RPCPool(env)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add status endpoint
-	rpcEndpoint = strings.TrimSuffix(rpcEndpoint, "/") + "/status"
-
-	// Make request with timeout
-	client := &http.Client{
-		Timeout: time.Duration(m.config.Global.TimeoutSeconds) * time.Second,
-	}
+	var status *NodeStatus
+	err = pool.Do(ctx, func(endpoint string) error {
+		var err error
+		status, err = m.queryStatus(ctx, endpoint)
+		return err
+	})
+	return status, err
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", rpcEndpoint, nil)
+// queryStatus queries the /status endpoint of the given RPC server
+func (m *Manager) queryStatus(ctx context.Context, rpcEndpoint string) (*NodeStatus, error) {
+	client, err := m.rpcClientFor(rpcEndpoint)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
-
-	resp, err := client.Do(req)
+	status, err := client.Status(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query node status: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("status request failed: %s: %s", resp.Status, string(body))
-	}
-
-	var result struct {
-		Result struct {
-			SyncInfo struct {
-				CatchingUp        bool   `json:"catching_up"`
-				LatestBlockHeight string `json:"latest_block_height"`
-			} `json:"sync_info"`
-		} `json:"result"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode status response: %w", err)
-	}
-
-	height, err := strconv.ParseInt(result.Result.SyncInfo.LatestBlockHeight, 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse block height: %w", err)
-	}
-
 	return &NodeStatus{
-		Syncing:      result.Result.SyncInfo.CatchingUp,
-		LatestHeight: height,
+		Syncing:      status.SyncInfo.CatchingUp,
+		LatestHeight: status.SyncInfo.LatestBlockHeight,
 	}, nil
 }
 
@@ -676,7 +754,45 @@ func updateConfig(content, key, value string) string {
 	return strings.Join(lines, "\n")
 }
 
-// UpdatePruning updates the pruning configuration
+// updateSectionConfig sets key in a [section] of a TOML file, accepting both
+// dashed and underscored key spellings. Missing keys and sections are added.
+func updateSectionConfig(content, section, key, value string) string {
+	lines := strings.Split(content, "\n")
+	header := "[" + section + "]"
+	alt := strings.ReplaceAll(key, "-", "_")
+
+	start := -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == header {
+			start = i
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			break
+		}
+		name, _, ok := strings.Cut(trimmed, "=")
+		if name = strings.TrimSpace(name); ok && (name == key || name == alt) {
+			lines[i] = fmt.Sprintf("%s = %s", name, value)
+			return strings.Join(lines, "\n")
+		}
+	}
+
+	entry := fmt.Sprintf("%s = %s", key, value)
+	if start < 0 {
+		return strings.TrimRight(content, "\n") + "\n\n" + header + "\n" + entry + "\n"
+	}
+	lines = append(lines[:start+1], append([]string{entry}, lines[start+1:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+// UpdatePruning updates the pruning configuration. On SeiDB nodes keepRecent
+// sets ss-keep-recent and the interval, which counts blocks, is not used as
+// the state store prunes on a timer. A keepRecent of 0 is refused there, as
+// ss-keep-recent = 0 turns the node into an archive.
 func (m *Manager) UpdatePruning(ctx context.Context, keepRecent, keepEvery, interval int64) error {
 	m.logger.Info().
 		Int64("keep_recent", keepRecent).
@@ -691,6 +807,20 @@ func (m *Manager) UpdatePruning(ctx context.Context, keepRecent, keepEvery, inte
 		return fmt.Errorf("failed to read config: %w", err)
 	}
 
+	if parsePruning(string(content)).Layout == LayoutSeiDB {
+		if keepEvery != 0 {
+			return fmt.Errorf("SeiDB has no keep-every setting, use 0")
+		}
+		if keepRecent <= 0 {
+			return fmt.Errorf("ss-keep-recent 0 keeps all history; set it with --ss-keep-recent 0 to run an archive node")
+		}
+		newContent := updateSectionConfig(string(content), stateStoreSection, "ss-keep-recent", fmt.Sprintf("%d", keepRecent))
+		if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+		return nil
+	}
+
 	updates := map[string]string{
 		"pruning":             "\"custom\"",
 		"pruning-keep-recent": fmt.Sprintf("%d", keepRecent),
@@ -709,17 +839,3 @@ func (m *Manager) UpdatePruning(ctx context.Context, keepRecent, keepEvery, inte
 
 	return nil
 }
-
-// SetupTmpfs sets up a tmpfs mount for improved performance
-func (m *Manager) SetupTmpfs(ctx context.Context, size string) error {
-	m.logger.Info().Str("size", size).Msg("Setting up tmpfs")
-
-	cmd := exec.CommandContext(ctx, "sudo", "mount", "-t", "tmpfs",
-		"-o", fmt.Sprintf("size=%s,mode=1777", size), "overflow", "/tmp")
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to setup tmpfs: %w", err)
-	}
-
-	return nil
-}
@@ -0,0 +1,369 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	feedIndexFileName = "index.json"
+	feedAtomFileName  = "feed.xml"
+	feedVersion       = 1
+	// feedDownloadPrefix names the directory archives are downloaded to
+	// during a feed restore; the catalog and server skip it
+	feedDownloadPrefix = ".feed_"
+)
+
+// FeedIndex is the static snapshot index published for consumers outside
+// the fleet
+type FeedIndex struct {
+	Version     int         `json:"version"`
+	Title       string      `json:"title,omitempty"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Snapshots   []FeedEntry `json:"snapshots"`
+}
+
+// FeedEntry describes a published snapshot. URL and SHA256 refer to the data
+// archive; Archives lists every file needed for a full restore.
+type FeedEntry struct {
+	ChainID     string        `json:"chain_id"`
+	Height      int64         `json:"height"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Size        int64         `json:"size"`
+	SHA256      string        `json:"sha256"`
+	URL         string        `json:"url"`
+	SeidVersion string        `json:"seid_version,omitempty"`
+	Pruning     string        `json:"pruning,omitempty"`
+	Archives    []FeedArchive `json:"archives"`
+}
+
+// FeedArchive is a snapshot file with its download URL
+type FeedArchive struct {
+	SnapshotArchive
+	URL string `json:"url"`
+}
+
+// PublishOptions configures PublishFeed
+type PublishOptions struct {
+	// BaseURL is where the backup directory layout is served from
+	BaseURL string
+	Title   string
+	// OutputDir receives index.json and feed.xml when set
+	OutputDir string
+	// Storage, when set, limits the feed to snapshots present in it and
+	// receives index.json and feed.xml next to them
+	Storage Storage
+}
+
+// PublishFeed generates index.json and an Atom feed describing the local
+// full snapshots. Incremental snapshots are left out since they cannot be
+// downloaded as plain archives.
+func (m *Manager) PublishFeed(ctx context.Context, opts PublishOptions) (*FeedIndex, error) {
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("a base URL is required to publish snapshots")
+	}
+	if opts.OutputDir == "" && opts.Storage == nil {
+		return nil, fmt.Errorf("an output directory or storage backend is required")
+	}
+	base := strings.TrimSuffix(opts.BaseURL, "/")
+
+	snapshots, err := m.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	index := &FeedIndex{
+		Version:     feedVersion,
+		Title:       opts.Title,
+		GeneratedAt: time.Now().UTC(),
+		Snapshots:   []FeedEntry{},
+	}
+	for _, s := range snapshots {
+		if s.Integrity != IntegrityOK || s.Type == SnapshotTypeIncremental {
+			continue
+		}
+		manifest, err := readManifest(s.Path)
+		if err != nil {
+			continue
+		}
+		dirName := filepath.Base(s.Path)
+		if opts.Storage != nil {
+			if _, err := opts.Storage.Stat(ctx, path.Join(dirName, manifestFileName)); err != nil {
+				m.logger.Debug().Int64("height", s.Height).Msg("Snapshot not pushed, leaving it out of the feed")
+				continue
+			}
+		}
+
+		entry := FeedEntry{
+			ChainID:     manifest.ChainID,
+			Height:      manifest.Height,
+			CreatedAt:   manifest.CreatedAt,
+			Size:        manifest.TotalSize(),
+			SeidVersion: manifest.SeidVersion,
+			Pruning:     manifest.Pruning,
+		}
+		dataArchive := filepath.Base(findDataArchive(s.Path))
+		for _, a := range manifest.Archives {
+			archive := FeedArchive{SnapshotArchive: a, URL: base + "/" + dirName + "/" + a.Name}
+			if a.Name == dataArchive {
+				entry.URL = archive.URL
+				entry.SHA256 = a.SHA256
+			}
+			entry.Archives = append(entry.Archives, archive)
+		}
+		index.Snapshots = append(index.Snapshots, entry)
+	}
+
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal feed index: %w", err)
+	}
+	atomData, err := renderAtomFeed(index, base)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{
+		feedIndexFileName: indexData,
+		feedAtomFileName:  atomData,
+	}
+	for name, data := range files {
+		if opts.OutputDir != "" {
+			if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if err := os.WriteFile(filepath.Join(opts.OutputDir, name), data, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", name, err)
+			}
+		}
+		if opts.Storage != nil {
+			if err := opts.Storage.Put(ctx, name, bytes.NewReader(data), int64(len(data))); err != nil {
+				return nil, fmt.Errorf("failed to upload %s: %w", name, err)
+			}
+		}
+	}
+
+	m.logger.Info().Int("snapshots", len(index.Snapshots)).Msg("Snapshot feed published")
+	return index, nil
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+}
+
+func renderAtomFeed(index *FeedIndex, base string) ([]byte, error) {
+	title := index.Title
+	if title == "" {
+		title = "Sei snapshots"
+	}
+
+	feed := atomFeed{
+		Title:   title,
+		ID:      base + "/" + feedAtomFileName,
+		Updated: index.GeneratedAt.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: base + "/" + feedAtomFileName, Rel: "self"},
+			{Href: base + "/" + feedIndexFileName, Rel: "alternate", Type: "application/json"},
+		},
+	}
+	for _, e := range index.Snapshots {
+		summary := fmt.Sprintf("height=%d size=%d sha256=%s", e.Height, e.Size, e.SHA256)
+		if e.SeidVersion != "" {
+			summary += " seid=" + e.SeidVersion
+		}
+		if e.Pruning != "" {
+			summary += " pruning=" + e.Pruning
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   fmt.Sprintf("%s snapshot at height %d", e.ChainID, e.Height),
+			ID:      e.URL,
+			Updated: e.CreatedAt.Format(time.RFC3339),
+			Link:    atomLink{Href: e.URL, Rel: "enclosure", Type: "application/gzip", Length: e.Size},
+			Summary: summary,
+		})
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render feed: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// RestoreFromFeed downloads the newest snapshot for the local chain ID listed
+// in a published index.json and restores it. The snapshot is not kept. feedURL may point at the index
+// itself or at the directory holding it.
+func (m *Manager) RestoreFromFeed(ctx context.Context, feedURL string) error {
+	chainID := m.localChainID()
+	if chainID == "" {
+		return fmt.Errorf("cannot determine the local chain ID, initialise the node first")
+	}
+
+	index, err := fetchFeedIndex(ctx, feedURL)
+	if err != nil {
+		return err
+	}
+
+	var entry *FeedEntry
+	for i := range index.Snapshots {
+		e := &index.Snapshots[i]
+		if e.ChainID == chainID && (entry == nil || e.Height > entry.Height) {
+			entry = e
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("feed %s has no snapshot for chain %s", feedURL, chainID)
+	}
+
+	m.logger.Info().
+		Int64("height", entry.Height).
+		Str("seid_version", entry.SeidVersion).
+		Str("pruning", entry.Pruning).
+		Msg("Selected snapshot from feed")
+
+	for _, a := range entry.Archives {
+		if a.Name != filepath.Base(a.Name) || a.Name == manifestFileName {
+			return fmt.Errorf("refusing to download unsafe archive name %q", a.Name)
+		}
+	}
+
+	// Archives are downloaded and extracted one at a time, so at most one is
+	// on disk next to the staged data. The node keeps running until the swap.
+	dir := filepath.Join(m.config.Global.BackupDir, feedDownloadPrefix+strconv.FormatInt(entry.Height, 10))
+	tx, err := m.beginRestore(feedURL, false)
+	if err != nil {
+		return err
+	}
+	for _, a := range entry.Archives {
+		if err := m.applyFeedArchive(ctx, a, dir, tx.staging); err != nil {
+			return tx.abort(err)
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		m.logger.Warn().Err(err).Str("path", dir).Msg("Failed to remove download directory")
+	}
+	if err := tx.verify(); err != nil {
+		return tx.abort(fmt.Errorf("staged snapshot failed verification: %w", err))
+	}
+
+	if err := m.stopNode(ctx); err != nil {
+		return tx.abort(fmt.Errorf("failed to stop node: %w", err))
+	}
+	if err := m.backupCurrentState(); err != nil {
+		return tx.abort(fmt.Errorf("failed to backup current state: %w", err))
+	}
+	if err := tx.commit(); err != nil {
+		return err
+	}
+
+	m.logger.Info().Int64("height", entry.Height).Msg("Snapshot restored successfully")
+	return nil
+}
+
+// applyFeedArchive downloads one archive into dir, extracts it into staging
+// and deletes it. A partial download is kept so a re-run resumes it.
+func (m *Manager) applyFeedArchive(ctx context.Context, a FeedArchive, dir, staging string) error {
+	path := filepath.Join(dir, a.Name)
+	if err := m.downloadURL(ctx, a.URL, path, a.SHA256); err != nil {
+		return fmt.Errorf("failed to download %s: %w", a.Name, err)
+	}
+	defer os.Remove(path)
+	m.logger.Info().Str("archive", a.Name).Msg("Downloaded")
+
+	switch {
+	case strings.HasPrefix(a.Name, "data"):
+		return m.restoreData(ctx, dir, staging)
+	case a.Name == "wasm.tar.gz":
+		return m.restoreWasm(ctx, path, staging)
+	default:
+		// priv_validator_state.json is also inside the data archive
+		return nil
+	}
+}
+
+func fetchFeedIndex(ctx context.Context, feedURL string) (*FeedIndex, error) {
+	if !strings.HasSuffix(feedURL, ".json") {
+		feedURL = strings.TrimSuffix(feedURL, "/") + "/" + feedIndexFileName
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch feed: %s", resp.Status)
+	}
+
+	index := &FeedIndex{}
+	if err := json.NewDecoder(resp.Body).Decode(index); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+	if index.Version != feedVersion {
+		return nil, fmt.Errorf("unsupported feed version %d", index.Version)
+	}
+	return index, nil
+}
+
+// downloadURL fetches url into dest, resuming interrupted transfers, also
+// from the .partial file of an earlier run, and verifying the checksum before
+// dest is replaced
+func (m *Manager) downloadURL(ctx context.Context, url, dest, checksum string) error {
+	body := &resumableBody{
+		ctx:        ctx,
+		client:     &http.Client{},
+		url:        url,
+		offset:     partialSize(dest),
+		maxRetries: m.config.Global.MaxRetries,
+		delay:      m.config.Global.GetRetryDelay(),
+		m:          m,
+	}
+	defer body.Close()
+
+	if err := body.open(); err != nil {
+		if body.offset == 0 {
+			return err
+		}
+		m.logger.Warn().Err(err).Str("url", url).Msg("Cannot resume partial download, starting over")
+		body.offset = 0
+		if err := body.open(); err != nil {
+			return err
+		}
+	}
+
+	return writeVerified(body, dest, checksum, body.offset)
+}
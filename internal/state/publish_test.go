@@ -0,0 +1,132 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishFeed(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, os.WriteFile(filepath.Join(manager.config.Global.HomeDir, "config", "app.toml"),
+		[]byte("pruning = \"nothing\"\n"), 0644))
+
+	writeTarSnapshot(t, manager, 100, map[string][]byte{"data/state.db": []byte("state")})
+	writeNodeData(t, manager.config.Global.HomeDir, map[string][]byte{"data/a.db": []byte("a")})
+	require.NoError(t, manager.CreateIncrementalSnapshot(ctx, 200))
+
+	out := t.TempDir()
+	index, err := manager.PublishFeed(ctx, PublishOptions{BaseURL: "https://snapshots.example.com/", OutputDir: out})
+	require.NoError(t, err)
+	require.Len(t, index.Snapshots, 1, "incremental snapshots are not published")
+
+	entry := index.Snapshots[0]
+	assert.Equal(t, int64(100), entry.Height)
+	assert.Equal(t, "test-1", entry.ChainID)
+	assert.Equal(t, "nothing", entry.Pruning)
+	assert.Equal(t, "https://snapshots.example.com/snapshot_100/data_100.tar.gz", entry.URL)
+	assert.Len(t, entry.SHA256, 64)
+	assert.Len(t, entry.Archives, 2)
+
+	data, err := os.ReadFile(filepath.Join(out, feedIndexFileName))
+	require.NoError(t, err)
+	var written FeedIndex
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, index.Snapshots, written.Snapshots)
+
+	data, err = os.ReadFile(filepath.Join(out, feedAtomFileName))
+	require.NoError(t, err)
+	var feed atomFeed
+	require.NoError(t, xml.Unmarshal(data, &feed))
+	require.Len(t, feed.Entries, 1)
+	assert.Equal(t, entry.URL, feed.Entries[0].Link.Href)
+	assert.Contains(t, feed.Entries[0].Summary, "pruning=nothing")
+}
+
+func TestPublishFeedToStorage(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	ctx := context.Background()
+	storage := NewLocalStorage(t.TempDir())
+
+	writeTarSnapshot(t, manager, 100, map[string][]byte{"data/state.db": []byte("state")})
+	writeTarSnapshot(t, manager, 200, map[string][]byte{"data/state.db": []byte("state")})
+	require.NoError(t, manager.PushSnapshot(ctx, storage, "200"))
+
+	index, err := manager.PublishFeed(ctx, PublishOptions{BaseURL: "https://cdn.example.com", Storage: storage})
+	require.NoError(t, err)
+	require.Len(t, index.Snapshots, 1, "only pushed snapshots are published")
+	assert.Equal(t, int64(200), index.Snapshots[0].Height)
+
+	_, err = storage.Stat(ctx, feedIndexFileName)
+	assert.NoError(t, err)
+	_, err = storage.Stat(ctx, feedAtomFileName)
+	assert.NoError(t, err)
+}
+
+func TestRestoreFromFeed(t *testing.T) {
+	ctx := context.Background()
+
+	// The publisher pushes to storage and a static web server exposes it
+	publisher, _ := setupTestManager(t)
+	root := t.TempDir()
+	storage := NewLocalStorage(root)
+	srv := httptest.NewServer(http.FileServer(http.Dir(root)))
+	t.Cleanup(srv.Close)
+
+	writeTarSnapshot(t, publisher, 100, map[string][]byte{"data/state.db": []byte("old")})
+	writeTarSnapshot(t, publisher, 200, map[string][]byte{"data/state.db": []byte("new")})
+	require.NoError(t, publisher.PushSnapshot(ctx, storage, "100"))
+	require.NoError(t, publisher.PushSnapshot(ctx, storage, "200"))
+	_, err := publisher.PublishFeed(ctx, PublishOptions{BaseURL: srv.URL, Storage: storage})
+	require.NoError(t, err)
+
+	consumer, _ := setupTestManager(t)
+	require.NoError(t, consumer.RestoreFromFeed(ctx, srv.URL))
+	assertFileContent(t, filepath.Join(consumer.config.Global.HomeDir, "data", "state.db"), "new")
+
+	// Archives are deleted once applied and the snapshot is not kept
+	entries, err := os.ReadDir(consumer.config.Global.BackupDir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.True(t, strings.HasPrefix(e.Name(), backupDirPrefix), e.Name())
+	}
+
+	// A corrupted archive on the host is rejected before anything is restored
+	require.NoError(t, os.WriteFile(filepath.Join(root, "snapshot_200", "data_200.tar.gz"), []byte("evil"), 0644))
+	other, _ := setupTestManager(t)
+	err = other.RestoreFromFeed(ctx, srv.URL+"/index.json")
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
+
+func TestDownloadURLResumesPartial(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	content := []byte("0123456789")
+	sum := sha256.Sum256(content)
+
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "data.tar.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+
+	dest := filepath.Join(t.TempDir(), "data.tar.gz")
+	require.NoError(t, os.WriteFile(dest+".partial", content[:6], 0644))
+	require.NoError(t, manager.downloadURL(context.Background(), srv.URL, dest, hex.EncodeToString(sum[:])))
+	assertFileContent(t, dest, string(content))
+	assert.Equal(t, []string{"bytes=6-"}, ranges)
+}
@@ -0,0 +1,273 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Reset levels, from least to most destructive
+const (
+	// ResetData removes the chain data
+	ResetData = "data"
+	// ResetWasm also removes the wasm contract cache
+	ResetWasm = "wasm"
+	// ResetFull also removes the address book
+	ResetFull = "full"
+)
+
+const (
+	privValStateFile = "priv_validator_state.json"
+	privValKeyFile   = "priv_validator_key.json"
+)
+
+// ErrResetNotConfirmed is returned when a node with a validator key is reset
+// without the confirmation phrase
+var ErrResetNotConfirmed = errors.New("reset not confirmed")
+
+// ResetOptions configures ResetNode
+type ResetOptions struct {
+	Level string
+	// NoBackup skips backing up data and wasm before the reset
+	NoBackup bool
+	// Confirm must equal ResetConfirmation() when a validator key is present
+	Confirm string
+}
+
+// ResetResult describes what ResetNode did
+type ResetResult struct {
+	Level   string   `json:"level"`
+	Removed []string `json:"removed"`
+	Backup  string   `json:"backup,omitempty"`
+	// SignState is the validator signing state kept across the reset
+	SignState *SignState `json:"sign_state,omitempty"`
+	// SignStateSource is the file the kept signing state came from
+	SignStateSource string `json:"sign_state_source,omitempty"`
+}
+
+// SignState is the last vote a validator signed, as stored in
+// priv_validator_state.json
+type SignState struct {
+	Height int64 `json:"height"`
+	Round  int32 `json:"round"`
+	Step   int8  `json:"step"`
+	raw    []byte
+}
+
+// HasValidatorKey reports whether the node home holds a validator key
+func (m *Manager) HasValidatorKey() bool {
+	_, err := os.Stat(filepath.Join(m.config.Global.HomeDir, "config", privValKeyFile))
+	return err == nil
+}
+
+// ResetConfirmation returns the phrase that must be typed to reset a
+// validator: the chain ID, or "reset" when it is unknown
+func (m *Manager) ResetConfirmation() string {
+	if chainID := m.localChainID(); chainID != "" {
+		return chainID
+	}
+	return "reset"
+}
+
+// ResetNode wipes node state at the given level. Keys are never touched and
+// the highest validator signing state found in the node home or seictl's
+// backups is written back, so a reset can never lead to double signing.
+func (m *Manager) ResetNode(ctx context.Context, opts ResetOptions) (*ResetResult, error) {
+	home := m.config.Global.HomeDir
+	var targets []string
+	switch opts.Level {
+	case ResetData, "":
+		opts.Level = ResetData
+		targets = []string{"data"}
+	case ResetWasm:
+		targets = []string{"data", "wasm"}
+	case ResetFull:
+		targets = []string{"data", "wasm", filepath.Join("config", "addrbook.json")}
+	default:
+		return nil, fmt.Errorf("unknown reset level %q, use %s, %s or %s", opts.Level, ResetData, ResetWasm, ResetFull)
+	}
+
+	if m.HasValidatorKey() && opts.Confirm != m.ResetConfirmation() {
+		return nil, fmt.Errorf("%w: this node has a validator key, confirm with %q", ErrResetNotConfirmed, m.ResetConfirmation())
+	}
+	if m.isNodeRunning() {
+		return nil, fmt.Errorf("seid is running, stop it before resetting")
+	}
+
+	result := &ResetResult{Level: opts.Level}
+	if !opts.NoBackup {
+		before := m.listBackupDirs()
+		if err := m.backupCurrentState(); err != nil {
+			return nil, fmt.Errorf("failed to backup current state: %w", err)
+		}
+		for _, dir := range m.listBackupDirs() {
+			if !containsString(before, dir) {
+				result.Backup = dir
+			}
+		}
+	}
+
+	// Find the highest signing state before anything is removed
+	statePath := m.privValStatePath()
+	best, source, err := m.highestSignState(statePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		paths := []string{filepath.Join(home, target)}
+		if target == "data" && !containsString(paths, m.dataDir()) {
+			// db_dir keeps the databases apart from the WAL in data/
+			paths = append(paths, m.dataDir())
+		}
+		for _, path := range paths {
+			info, err := os.Lstat(path)
+			if err != nil {
+				continue
+			}
+			if real, err := os.Stat(path); err == nil && real.IsDir() && info.Mode()&os.ModeSymlink != 0 {
+				// Only the contents go, so data moved to another volume stays
+				// there behind its symlink
+				err = clearDir(path, 0700)
+			} else {
+				err = os.RemoveAll(path)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+			result.Removed = append(result.Removed, path)
+			m.logger.Info().Str("path", path).Msg("Removed")
+		}
+	}
+
+	// Recreate the data directory with the signing state seid expects
+	if err := os.MkdirAll(m.dataDir(), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if best == nil {
+		// seid refuses to start with a validator key but no signing state
+		best = &SignState{raw: []byte(`{"height":"0","round":0,"step":0}`)}
+		source = "initial"
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(statePath), err)
+	}
+	if err := os.WriteFile(statePath, best.raw, 0600); err != nil {
+		return nil, fmt.Errorf("failed to restore validator state: %w", err)
+	}
+	result.SignState, result.SignStateSource = best, source
+	m.logger.Info().
+		Int64("height", best.Height).
+		Int32("round", best.Round).
+		Int8("step", best.Step).
+		Str("source", source).
+		Msg("Validator signing state preserved")
+
+	return result, nil
+}
+
+// privValStatePath is where seid keeps the validator signing state
+func (m *Manager) privValStatePath() string {
+	path := m.nodeConfigValue("config.toml", "priv_validator_state_file")
+	if path == "" {
+		path = filepath.Join("data", privValStateFile)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.config.Global.HomeDir, path)
+	}
+	return path
+}
+
+// highestSignState returns the most advanced signing state among the node's
+// current file and the copies in seictl's backups
+func (m *Manager) highestSignState(current string) (*SignState, string, error) {
+	candidates := []string{current}
+	for _, dir := range m.listBackupDirs() {
+		candidates = append(candidates, filepath.Join(dir, "data", privValStateFile))
+	}
+
+	var best *SignState
+	var source string
+	for _, path := range candidates {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		state, err := parseSignState(data)
+		if err != nil {
+			// A corrupt current file must not be silently replaced by an
+			// older backup
+			if path == current {
+				return nil, "", fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			m.logger.Warn().Err(err).Str("path", path).Msg("Skipping unreadable validator state")
+			continue
+		}
+		if best == nil || state.after(best) {
+			best, source = state, path
+		}
+	}
+	return best, source, nil
+}
+
+// parseSignState parses priv_validator_state.json, where the height is
+// encoded as a string
+func parseSignState(data []byte) (*SignState, error) {
+	var raw struct {
+		Height json.RawMessage `json:"height"`
+		Round  int32           `json:"round"`
+		Step   int8            `json:"step"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	height, err := strconv.ParseInt(strings.Trim(string(raw.Height), `"`), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid height %s", raw.Height)
+	}
+	return &SignState{Height: height, Round: raw.Round, Step: raw.Step, raw: data}, nil
+}
+
+// after reports whether s is further along than o
+func (s *SignState) after(o *SignState) bool {
+	if s.Height != o.Height {
+		return s.Height > o.Height
+	}
+	if s.Round != o.Round {
+		return s.Round > o.Round
+	}
+	return s.Step > o.Step
+}
+
+// listBackupDirs returns the backup directories created by
+// backupCurrentState, oldest first
+func (m *Manager) listBackupDirs() []string {
+	entries, err := os.ReadDir(m.config.Global.BackupDir)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), backupDirPrefix) {
+			dirs = append(dirs, filepath.Join(m.config.Global.BackupDir, e.Name()))
+		}
+	}
+	return dirs
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signStateJSON(height int64, round int32, step int8) string {
+	return fmt.Sprintf(`{"height":"%d","round":%d,"step":%d,"signature":"c2ln"}`, height, round, step)
+}
+
+// setupResetHome writes a node home with data, wasm, keys and an address book
+func setupResetHome(t *testing.T, m *Manager, validator bool) {
+	home := m.config.Global.HomeDir
+	files := map[string]string{
+		"data/application.db/000001.ldb": "app",
+		"data/" + privValStateFile:       signStateJSON(100, 0, 3),
+		"wasm/wasm/state/code":           "wasm",
+		"config/node_key.json":           "node key",
+		"config/addrbook.json":           "{}",
+		"config/genesis.json":            `{"chain_id":"test-1"}`,
+	}
+	if validator {
+		files["config/"+privValKeyFile] = "validator key"
+	}
+	for name, content := range files {
+		path := filepath.Join(home, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+}
+
+func TestResetDataKeepsHighestSignState(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	setupResetHome(t, manager, false)
+	home := manager.config.Global.HomeDir
+
+	// An earlier backup signed further than the current file, e.g. before a
+	// restore from an older snapshot
+	older := filepath.Join(manager.config.Global.BackupDir, backupDirPrefix+"20240101_000000", "data")
+	require.NoError(t, os.MkdirAll(older, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(older, privValStateFile), []byte(signStateJSON(150, 1, 2)), 0600))
+
+	result, err := manager.ResetNode(context.Background(), ResetOptions{Level: ResetData})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, result.Backup)
+	assert.FileExists(t, filepath.Join(result.Backup, "data", "application.db", "000001.ldb"))
+	assert.Equal(t, []string{filepath.Join(home, "data")}, result.Removed)
+
+	require.NotNil(t, result.SignState)
+	assert.Equal(t, int64(150), result.SignState.Height)
+	data, err := os.ReadFile(filepath.Join(home, "data", privValStateFile))
+	require.NoError(t, err)
+	assert.Equal(t, signStateJSON(150, 1, 2), string(data))
+
+	assert.NoDirExists(t, filepath.Join(home, "data", "application.db"))
+	assert.DirExists(t, filepath.Join(home, "wasm"))
+	assert.FileExists(t, filepath.Join(home, "config", "addrbook.json"))
+	assert.FileExists(t, filepath.Join(home, "config", "node_key.json"))
+}
+
+func TestResetFull(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	setupResetHome(t, manager, false)
+	home := manager.config.Global.HomeDir
+
+	result, err := manager.ResetNode(context.Background(), ResetOptions{Level: ResetFull, NoBackup: true})
+	require.NoError(t, err)
+
+	assert.Empty(t, result.Backup)
+	assert.Len(t, result.Removed, 3)
+	assert.NoDirExists(t, filepath.Join(home, "wasm"))
+	assert.NoFileExists(t, filepath.Join(home, "config", "addrbook.json"))
+	assert.FileExists(t, filepath.Join(home, "config", "node_key.json"))
+	assert.Equal(t, int64(100), result.SignState.Height)
+	assert.FileExists(t, filepath.Join(home, "data", privValStateFile))
+}
+
+func TestResetValidatorNeedsConfirmation(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	setupResetHome(t, manager, true)
+	home := manager.config.Global.HomeDir
+
+	_, err := manager.ResetNode(context.Background(), ResetOptions{Level: ResetData, NoBackup: true, Confirm: "yes"})
+	assert.ErrorIs(t, err, ErrResetNotConfirmed)
+	assert.DirExists(t, filepath.Join(home, "data", "application.db"))
+
+	assert.Equal(t, "test-1", manager.ResetConfirmation())
+	_, err = manager.ResetNode(context.Background(), ResetOptions{Level: ResetData, NoBackup: true, Confirm: "test-1"})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(home, "config", privValKeyFile))
+}
+
+func TestResetRefusesCorruptSignState(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	setupResetHome(t, manager, false)
+	home := manager.config.Global.HomeDir
+	require.NoError(t, os.WriteFile(filepath.Join(home, "data", privValStateFile), []byte("{"), 0600))
+
+	_, err := manager.ResetNode(context.Background(), ResetOptions{Level: ResetData, NoBackup: true})
+	assert.Error(t, err)
+	assert.DirExists(t, filepath.Join(home, "data", "application.db"))
+}
+
+func TestResetUnknownLevel(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	_, err := manager.ResetNode(context.Background(), ResetOptions{Level: "everything"})
+	assert.ErrorContains(t, err, "unknown reset level")
+}
+
+// moveDataToVolume moves data/ out of the node home and leaves a symlink, as
+// seictl data move does, returning the new location
+func moveDataToVolume(t *testing.T, home string) string {
+	volume := filepath.Join(t.TempDir(), "volume", "data")
+	require.NoError(t, os.MkdirAll(filepath.Dir(volume), 0755))
+	require.NoError(t, os.Rename(filepath.Join(home, "data"), volume))
+	require.NoError(t, os.Symlink(volume, filepath.Join(home, "data")))
+	return volume
+}
+
+func TestResetDataOnMovedVolume(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	setupResetHome(t, manager, false)
+	home := manager.config.Global.HomeDir
+	volume := moveDataToVolume(t, home)
+
+	result, err := manager.ResetNode(context.Background(), ResetOptions{Level: ResetData})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(result.Backup, "data", "application.db", "000001.ldb"))
+	assert.FileExists(t, filepath.Join(result.Backup, "data", privValStateFile))
+
+	// The symlink and the directory on the volume survive, the database does not
+	info, err := os.Lstat(filepath.Join(home, "data"))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	assert.NoDirExists(t, filepath.Join(volume, "application.db"))
+	assert.FileExists(t, filepath.Join(volume, privValStateFile))
+}
@@ -0,0 +1,463 @@
+package state
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+const (
+	restoreStateFile    = ".seictl-restore.json"
+	restoreStateFlushes = 500
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	lz4Magic  = []byte{0x04, 0x22, 0x4d, 0x18}
+)
+
+// URLRestoreOptions configures RestoreFromURL
+type URLRestoreOptions struct {
+	// Checksum is the expected SHA256 of the downloaded archive. When empty,
+	// <url>.sha256 is tried.
+	Checksum string
+	// NoResume discards progress recorded by an earlier interrupted restore
+	NoResume bool
+}
+
+// restoreProgress is persisted while extracting so an interrupted restore of
+// the same URL can skip entries that were already written
+type restoreProgress struct {
+	URL       string           `json:"url"`
+	StartedAt time.Time        `json:"started_at"`
+	Completed map[string]int64 `json:"completed"`
+}
+
+// RestoreFromURL downloads, decompresses and extracts a snapshot archive in a
+// single pass, without storing the archive on disk. Supported formats are
+// .tar, .tar.gz, .tar.lz4 and .tar.zst.
+func (m *Manager) RestoreFromURL(ctx context.Context, url string, opts URLRestoreOptions) error {
+	m.logger.Info().Str("url", url).Msg("Restoring from snapshot URL")
+
+	client := &http.Client{}
+	checksum := strings.ToLower(strings.TrimSpace(opts.Checksum))
+	if checksum == "" {
+		checksum = m.fetchRemoteChecksum(ctx, client, url)
+	}
+	if checksum == "" {
+		m.logger.Warn().Msg("No checksum available, archive integrity will not be verified")
+	}
+
+	home := m.config.Global.HomeDir
+	statePath := filepath.Join(home, restoreStateFile)
+	progress := m.loadRestoreProgress(statePath, url)
+	if opts.NoResume || progress == nil {
+		progress = &restoreProgress{URL: url, StartedAt: time.Now().UTC(), Completed: make(map[string]int64)}
+	}
+	resuming := len(progress.Completed) > 0
+
+	if err := m.stopNode(ctx); err != nil {
+		return fmt.Errorf("failed to stop node: %w", err)
+	}
+
+	if resuming {
+		m.logger.Info().Int("completed_entries", len(progress.Completed)).Msg("Resuming interrupted restore")
+	} else {
+		if err := m.backupCurrentState(); err != nil {
+			return fmt.Errorf("failed to backup current state: %w", err)
+		}
+	}
+
+	// A resumed restore continues in the staging directory it left behind
+	tx, err := m.beginRestore(url, resuming)
+	if err != nil {
+		return err
+	}
+
+	body := &resumableBody{
+		ctx:        ctx,
+		client:     client,
+		url:        url,
+		maxRetries: m.config.Global.MaxRetries,
+		delay:      m.config.Global.GetRetryDelay(),
+		m:          m,
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	if err := m.extractStream(io.TeeReader(body, h), tx.staging, progress, statePath); err != nil {
+		// Staging is kept for resuming; the live data has not been touched
+		return fmt.Errorf("failed to extract snapshot: %w", err)
+	}
+
+	// Drain trailing bytes (tar padding, compression footers) so the hash
+	// covers the whole archive
+	if _, err := io.Copy(h, body); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	if err := verifyStreamChecksum(h, checksum); err != nil {
+		os.Remove(statePath)
+		return tx.abort(err)
+	}
+	if err := tx.verify(); err != nil {
+		return tx.abort(fmt.Errorf("staged snapshot failed verification: %w", err))
+	}
+	if err := tx.commit(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		m.logger.Warn().Err(err).Msg("Failed to remove restore progress file")
+	}
+
+	m.logger.Info().Int64("bytes", body.offset).Msg("Snapshot restored successfully")
+	return nil
+}
+
+func verifyStreamChecksum(h hash.Hash, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != expected {
+		return fmt.Errorf("archive checksum mismatch: expected %s, got %s", expected, sum)
+	}
+	return nil
+}
+
+// fetchRemoteChecksum looks for a <url>.sha256 file next to the archive
+func (m *Manager) fetchRemoteChecksum(ctx context.Context, client *http.Client, url string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+".sha256", nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || !isValidHash(fields[0]) {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+func (m *Manager) loadRestoreProgress(path, url string) *restoreProgress {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	progress := &restoreProgress{}
+	if err := json.Unmarshal(data, progress); err != nil || progress.URL != url {
+		return nil
+	}
+	if progress.Completed == nil {
+		progress.Completed = make(map[string]int64)
+	}
+	return progress
+}
+
+func saveRestoreProgress(path string, progress *restoreProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// extractStream decompresses r and extracts its tar entries below home.
+// Entries not under data/ or wasm/ are placed in data/, matching archives
+// that were created from inside the data directory.
+func (m *Manager) extractStream(r io.Reader, home string, progress *restoreProgress, statePath string) (err error) {
+	decompressed, err := newDecompressor(r)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	// Progress is saved on every exit so a failed run can be resumed
+	defer func() {
+		if saveErr := saveRestoreProgress(statePath, progress); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}()
+
+	tr := tar.NewReader(decompressed)
+	written := 0
+	lastLog := time.Now()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := restoreTarget(home, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+
+		if size, ok := progress.Completed[hdr.Name]; ok && size == hdr.Size {
+			if info, err := os.Lstat(target); err == nil && (info.IsDir() || info.Size() == size) {
+				continue
+			}
+		}
+
+		if err := writeTarEntry(tr, hdr, home, target); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+		}
+
+		progress.Completed[hdr.Name] = hdr.Size
+		written++
+		if written%restoreStateFlushes == 0 {
+			if err := saveRestoreProgress(statePath, progress); err != nil {
+				m.logger.Warn().Err(err).Msg("Failed to save restore progress")
+			}
+		}
+		if time.Since(lastLog) > 10*time.Second {
+			m.logger.Info().Int("entries", len(progress.Completed)).Str("current", hdr.Name).Msg("Extracting")
+			lastLog = time.Now()
+		}
+	}
+
+	return nil
+}
+
+// restoreTarget maps an archive entry name to a path below home. An empty
+// result means the entry is skipped.
+func restoreTarget(home, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(name, "./")))
+	if clean == "." {
+		return "", nil
+	}
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry escapes the node home: %s", name)
+	}
+
+	top := strings.SplitN(filepath.ToSlash(clean), "/", 2)[0]
+	if top == "data" || top == "wasm" {
+		return filepath.Join(home, clean), nil
+	}
+	return filepath.Join(home, "data", clean), nil
+}
+
+func writeTarEntry(tr *tar.Reader, hdr *tar.Header, home, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, os.FileMode(hdr.Mode)|0700)
+	case tar.TypeReg:
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)|0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	case tar.TypeSymlink:
+		resolved := hdr.Linkname
+		if !filepath.IsAbs(resolved) {
+			resolved = filepath.Join(filepath.Dir(target), resolved)
+		}
+		if !strings.HasPrefix(filepath.Clean(resolved), filepath.Clean(home)+string(filepath.Separator)) {
+			return fmt.Errorf("symlink points outside the node home: %s", hdr.Linkname)
+		}
+		os.Remove(target)
+		return os.Symlink(hdr.Linkname, target)
+	case tar.TypeLink:
+		source, err := restoreTarget(home, hdr.Linkname)
+		if err != nil || source == "" {
+			return fmt.Errorf("invalid hard link target: %s", hdr.Linkname)
+		}
+		os.Remove(target)
+		return os.Link(source, target)
+	default:
+		// Devices, fifos and the like have no place in a snapshot
+		return nil
+	}
+}
+
+type nopReadCloser struct{ io.Reader }
+
+func (nopReadCloser) Close() error { return nil }
+
+type zstdReadCloser struct{ *zstd.Decoder }
+
+func (z zstdReadCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// newDecompressor detects the compression format from the stream's magic
+// bytes and returns a reader producing the uncompressed tar stream
+func newDecompressor(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read archive header: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		dec, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zstdReadCloser{dec}, nil
+	case bytes.HasPrefix(magic, lz4Magic):
+		return nopReadCloser{lz4.NewReader(br)}, nil
+	default:
+		return nopReadCloser{br}, nil
+	}
+}
+
+// resumableBody streams an HTTP resource and transparently reconnects with a
+// Range request when the connection fails midway
+type resumableBody struct {
+	ctx        context.Context
+	client     *http.Client
+	url        string
+	header     http.Header
+	body       io.ReadCloser
+	offset     int64
+	total      int64
+	retries    int
+	maxRetries int
+	delay      time.Duration
+	m          *Manager
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	for {
+		if b.body == nil {
+			if err := b.open(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := b.body.Read(p)
+		b.offset += int64(n)
+
+		if err == io.EOF && (b.total <= 0 || b.offset >= b.total) {
+			return n, io.EOF
+		}
+		if err == nil {
+			return n, nil
+		}
+
+		// The connection dropped, or the server closed it early
+		b.body.Close()
+		b.body = nil
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if b.retries >= b.maxRetries || b.ctx.Err() != nil {
+			return n, fmt.Errorf("download failed at byte %d: %w", b.offset, err)
+		}
+		b.retries++
+		b.m.logger.Warn().
+			Err(err).
+			Int64("offset", b.offset).
+			Int("attempt", b.retries).
+			Msg("Download interrupted, resuming")
+
+		select {
+		case <-b.ctx.Done():
+			return n, b.ctx.Err()
+		case <-time.After(b.delay):
+		}
+
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (b *resumableBody) open() error {
+	req, err := http.NewRequestWithContext(b.ctx, http.MethodGet, b.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range b.header {
+		req.Header[key] = values
+	}
+	if b.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download snapshot: %w", err)
+	}
+
+	switch {
+	case b.offset == 0 && resp.StatusCode == http.StatusOK:
+		b.total = resp.ContentLength
+	case b.offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		if b.total <= 0 && resp.ContentLength >= 0 {
+			b.total = b.offset + resp.ContentLength
+		}
+	default:
+		resp.Body.Close()
+		if b.offset > 0 {
+			return fmt.Errorf("server cannot resume download: %s", resp.Status)
+		}
+		return fmt.Errorf("snapshot download failed: %s", resp.Status)
+	}
+
+	b.body = resp.Body
+	return nil
+}
+
+func (b *resumableBody) Close() error {
+	if b.body == nil {
+		return nil
+	}
+	err := b.body.Close()
+	b.body = nil
+	return err
+}
@@ -0,0 +1,182 @@
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testArchiveFiles = map[string]string{
+	"data/application.db/000001.ldb": strings.Repeat("application", 1000),
+	"data/blockstore.db/000002.ldb":  strings.Repeat("blockstore", 1000),
+	"wasm/wasm/contract.wasm":        "wasm",
+}
+
+func buildTestArchive(t *testing.T, format string) []byte {
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	for _, name := range []string{"data/application.db/000001.ldb", "data/blockstore.db/000002.ldb", "wasm/wasm/contract.wasm"} {
+		content := testArchiveFiles[name]
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	var out bytes.Buffer
+	switch format {
+	case "tar":
+		return tarBuf.Bytes()
+	case "gz":
+		w := gzip.NewWriter(&out)
+		_, err := w.Write(tarBuf.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	case "zst":
+		w, err := zstd.NewWriter(&out)
+		require.NoError(t, err)
+		_, err = w.Write(tarBuf.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	case "lz4":
+		w := lz4.NewWriter(&out)
+		_, err := w.Write(tarBuf.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+	return out.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// serveArchive serves data with Range support. The first failures requests
+// are cut off after cutAt bytes.
+func serveArchive(t *testing.T, data []byte, failures int32, cutAt int) *httptest.Server {
+	var served int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			w.Write([]byte(sha256Hex(data) + "  snapshot.tar\n"))
+			return
+		}
+		if atomic.AddInt32(&served, 1) <= failures {
+			var start int
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start)
+			end := start + cutAt
+			if end > len(data) {
+				end = len(data)
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)-start))
+			if start > 0 {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(data)-1, len(data)))
+				w.WriteHeader(http.StatusPartialContent)
+			}
+			w.Write(data[start:end])
+			return
+		}
+		http.ServeContent(w, r, "snapshot", time.Now(), bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func assertRestoredFiles(t *testing.T, home string) {
+	for name, content := range testArchiveFiles {
+		data, err := os.ReadFile(filepath.Join(home, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, content, string(data), name)
+	}
+	_, err := os.Stat(filepath.Join(home, restoreStateFile))
+	assert.True(t, os.IsNotExist(err), "progress file should be removed")
+}
+
+func TestRestoreFromURL(t *testing.T) {
+	for _, format := range []string{"tar", "gz", "zst", "lz4"} {
+		t.Run(format, func(t *testing.T) {
+			manager, _ := setupTestManager(t)
+			srv := serveArchive(t, buildTestArchive(t, format), 0, 0)
+
+			err := manager.RestoreFromURL(context.Background(), srv.URL+"/snapshot.tar."+format, URLRestoreOptions{})
+			require.NoError(t, err)
+			assertRestoredFiles(t, manager.config.Global.HomeDir)
+		})
+	}
+}
+
+func TestRestoreFromURLReconnects(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	manager.config.Global.MaxRetries = 3
+	manager.config.Global.RetryDelay = "0"
+
+	data := buildTestArchive(t, "gz")
+	srv := serveArchive(t, data, 2, len(data)/3)
+
+	err := manager.RestoreFromURL(context.Background(), srv.URL+"/snapshot.tar.gz", URLRestoreOptions{})
+	require.NoError(t, err)
+	assertRestoredFiles(t, manager.config.Global.HomeDir)
+}
+
+func TestRestoreFromURLResumes(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	manager.config.Global.RetryDelay = "0"
+
+	data := buildTestArchive(t, "tar")
+	// Cut after the first entry so only it is recorded as complete
+	srv := serveArchive(t, data, 1, 512+len(testArchiveFiles["data/application.db/000001.ldb"])+100)
+	url := srv.URL + "/snapshot.tar"
+
+	err := manager.RestoreFromURL(context.Background(), url, URLRestoreOptions{})
+	require.Error(t, err)
+
+	progress := manager.loadRestoreProgress(filepath.Join(manager.config.Global.HomeDir, restoreStateFile), url)
+	require.NotNil(t, progress)
+	assert.Contains(t, progress.Completed, "data/application.db/000001.ldb")
+
+	require.NoError(t, manager.RestoreFromURL(context.Background(), url, URLRestoreOptions{}))
+	assertRestoredFiles(t, manager.config.Global.HomeDir)
+}
+
+func TestRestoreFromURLChecksumMismatch(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	srv := serveArchive(t, buildTestArchive(t, "gz"), 0, 0)
+
+	err := manager.RestoreFromURL(context.Background(), srv.URL+"/snapshot.tar.gz", URLRestoreOptions{
+		Checksum: strings.Repeat("0", 64),
+	})
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
+
+func TestRestoreTarget(t *testing.T) {
+	home := "/home/sei"
+
+	target, err := restoreTarget(home, "./data/state.db/CURRENT")
+	require.NoError(t, err)
+	assert.Equal(t, "/home/sei/data/state.db/CURRENT", target)
+
+	target, err = restoreTarget(home, "state.db/CURRENT")
+	require.NoError(t, err)
+	assert.Equal(t, "/home/sei/data/state.db/CURRENT", target)
+
+	_, err = restoreTarget(home, "../../etc/passwd")
+	assert.Error(t, err)
+}
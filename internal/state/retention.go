@@ -0,0 +1,308 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-org/seictl/internal/utils"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+const (
+	backupDirPrefix = "backup_"
+	backupTimeFmt   = "20060102_150405"
+)
+
+// Retention item kinds
+const (
+	KindSnapshot = "snapshot"
+	KindBackup   = "backup"
+)
+
+// RetentionItem is a snapshot or backup directory considered for pruning
+type RetentionItem struct {
+	Kind      string    `json:"kind"`
+	Path      string    `json:"path"`
+	Height    int64     `json:"height,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+	Reason    string    `json:"reason"`
+}
+
+// PrunePlan lists what a retention run keeps and removes
+type PrunePlan struct {
+	DryRun     bool            `json:"dry_run"`
+	Keep       []RetentionItem `json:"keep"`
+	Prune      []RetentionItem `json:"prune"`
+	FreedBytes int64           `json:"freed_bytes"`
+	Warnings   []string        `json:"warnings,omitempty"`
+}
+
+// PruneSnapshots applies the configured retention policies to snapshots and
+// backups in the backup directory. With dryRun set nothing is deleted.
+func (m *Manager) PruneSnapshots(dryRun bool) (*PrunePlan, error) {
+	snapshots, err := m.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	backups, err := m.listBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var free int64 = -1
+	if m.config.Snapshots.Retention.MinFreeDisk != "" || m.config.Snapshots.BackupRetention.MinFreeDisk != "" {
+		free, err = utils.DiskFree(m.config.Global.BackupDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read free disk space: %w", err)
+		}
+	}
+
+	plan := &PrunePlan{DryRun: dryRun}
+
+	// Snapshots are ordered by height, so one whose height is unknown cannot
+	// be placed and is never pruned
+	snapshotItems := make([]RetentionItem, 0, len(snapshots))
+	for _, s := range snapshots {
+		item := RetentionItem{
+			Kind:      KindSnapshot,
+			Path:      s.Path,
+			Height:    s.Height,
+			CreatedAt: s.CreatedAt,
+			Size:      s.Size,
+		}
+		if s.Height <= 0 {
+			item.Reason = "height unknown, excluded from retention"
+			plan.Keep = append(plan.Keep, item)
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s has no known height and is never pruned; remove it by hand if unneeded", s.Path))
+			m.logger.Warn().Str("path", s.Path).Msg("Snapshot height unknown, excluded from retention")
+			continue
+		}
+		snapshotItems = append(snapshotItems, item)
+	}
+
+	keep, prune, err := planRetention(snapshotItems, m.config.Snapshots.Retention, free)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot retention: %w", err)
+	}
+	plan.Keep = append(plan.Keep, keep...)
+	plan.Prune = append(plan.Prune, prune...)
+
+	// Space freed by pruning snapshots counts towards the backup free-disk rule
+	if free >= 0 {
+		for _, p := range prune {
+			free += p.Size
+		}
+	}
+
+	keep, prune, err = planRetention(backups, m.config.Snapshots.BackupRetention, free)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup retention: %w", err)
+	}
+	plan.Keep = append(plan.Keep, keep...)
+	plan.Prune = append(plan.Prune, prune...)
+
+	for _, item := range plan.Prune {
+		plan.FreedBytes += item.Size
+	}
+
+	if dryRun {
+		return plan, nil
+	}
+
+	for _, item := range plan.Prune {
+		if err := os.RemoveAll(item.Path); err != nil {
+			return plan, fmt.Errorf("failed to remove %s: %w", item.Path, err)
+		}
+		m.logger.Info().
+			Str("kind", item.Kind).
+			Str("path", item.Path).
+			Str("reason", item.Reason).
+			Msg("Pruned by retention policy")
+	}
+
+	return plan, nil
+}
+
+// applyRetention prunes after a new snapshot is created. Failures are logged
+// rather than returned so a good snapshot is never reported as failed.
+func (m *Manager) applyRetention() {
+	plan, err := m.PruneSnapshots(false)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to apply retention policy")
+		return
+	}
+	if len(plan.Prune) == 0 {
+		return
+	}
+
+	m.logger.Info().
+		Int("pruned", len(plan.Prune)).
+		Int64("freed_bytes", plan.FreedBytes).
+		Msg("Retention policy applied")
+
+	// Pruned incremental snapshots may leave chunks nothing references
+	if _, err := m.GarbageCollectChunks(false); err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to garbage collect chunks")
+	}
+}
+
+func (m *Manager) listBackups() ([]RetentionItem, error) {
+	entries, err := os.ReadDir(m.config.Global.BackupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []RetentionItem
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), backupDirPrefix) {
+			continue
+		}
+		created, err := time.ParseInLocation(backupTimeFmt, strings.TrimPrefix(entry.Name(), backupDirPrefix), time.Local)
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(m.config.Global.BackupDir, entry.Name())
+		size, err := dirSize(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to size backup %s: %w", entry.Name(), err)
+		}
+
+		backups = append(backups, RetentionItem{
+			Kind:      KindBackup,
+			Path:      path,
+			CreatedAt: created,
+			Size:      size,
+		})
+	}
+
+	return backups, nil
+}
+
+// planRetention splits items into those to keep and those to prune. free is
+// the current free disk space in bytes, or negative when unknown.
+func planRetention(items []RetentionItem, policy types.RetentionConfig, free int64) ([]RetentionItem, []RetentionItem, error) {
+	maxTotal, err := utils.ParseSize(policy.MaxTotalSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	minFree, err := utils.ParseSize(policy.MinFreeDisk)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sorted := make([]RetentionItem, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Height != sorted[j].Height {
+			return sorted[i].Height > sorted[j].Height
+		}
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	reasons := make([]string, len(sorted))
+	if !policy.HasKeepRules() {
+		for i := range reasons {
+			reasons[i] = "no keep rules"
+		}
+	}
+
+	for i := 0; i < len(sorted) && i < policy.KeepLast; i++ {
+		reasons[i] = "keep_last"
+	}
+
+	if policy.KeepEveryBlocks > 0 {
+		seen := make(map[int64]bool)
+		for i, item := range sorted {
+			if item.Height == 0 {
+				continue
+			}
+			bucket := item.Height / policy.KeepEveryBlocks
+			if !seen[bucket] {
+				seen[bucket] = true
+				if reasons[i] == "" {
+					reasons[i] = "keep_every_blocks"
+				}
+			}
+		}
+	}
+
+	keepPerPeriod(sorted, reasons, policy.KeepDaily, "keep_daily", func(t time.Time) string {
+		return t.Local().Format("2006-01-02")
+	})
+	keepPerPeriod(sorted, reasons, policy.KeepWeekly, "keep_weekly", func(t time.Time) string {
+		year, week := t.Local().ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	})
+
+	var keep, prune []RetentionItem
+	for i, item := range sorted {
+		if reasons[i] != "" {
+			item.Reason = reasons[i]
+			keep = append(keep, item)
+		} else {
+			item.Reason = "not selected by keep rules"
+			prune = append(prune, item)
+		}
+	}
+
+	// Size limits evict the oldest kept items, but never the newest one
+	if maxTotal > 0 {
+		var total int64
+		for _, item := range keep {
+			total += item.Size
+		}
+		for total > maxTotal && len(keep) > 1 {
+			oldest := keep[len(keep)-1]
+			keep = keep[:len(keep)-1]
+			oldest.Reason = "max_total_size exceeded"
+			prune = append(prune, oldest)
+			total -= oldest.Size
+		}
+	}
+
+	if minFree > 0 && free >= 0 {
+		for _, item := range prune {
+			free += item.Size
+		}
+		for free < minFree && len(keep) > 1 {
+			oldest := keep[len(keep)-1]
+			keep = keep[:len(keep)-1]
+			oldest.Reason = "min_free_disk not met"
+			prune = append(prune, oldest)
+			free += oldest.Size
+		}
+	}
+
+	return keep, prune, nil
+}
+
+// keepPerPeriod marks the newest item in each of the n most recent periods
+func keepPerPeriod(sorted []RetentionItem, reasons []string, n int, reason string, period func(time.Time) string) {
+	if n <= 0 {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i, item := range sorted {
+		key := period(item.CreatedAt)
+		if seen[key] {
+			continue
+		}
+		if len(seen) >= n {
+			return
+		}
+		seen[key] = true
+		if reasons[i] == "" {
+			reasons[i] = reason
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+func retentionItems(now time.Time, heights ...int64) []RetentionItem {
+	items := make([]RetentionItem, 0, len(heights))
+	for i, h := range heights {
+		items = append(items, RetentionItem{
+			Kind:      KindSnapshot,
+			Path:      snapshotDirName(h),
+			Height:    h,
+			CreatedAt: now.Add(-time.Duration(len(heights)-i) * 12 * time.Hour),
+			Size:      100,
+		})
+	}
+	return items
+}
+
+func heightsOf(items []RetentionItem) []int64 {
+	var heights []int64
+	for _, item := range items {
+		heights = append(heights, item.Height)
+	}
+	return heights
+}
+
+func TestPlanRetention(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	items := retentionItems(now, 100, 200, 300, 400, 500)
+
+	tests := []struct {
+		name      string
+		policy    types.RetentionConfig
+		free      int64
+		wantKeep  []int64
+		wantPrune []int64
+	}{
+		{
+			name:     "no rules keeps everything",
+			policy:   types.RetentionConfig{},
+			free:     -1,
+			wantKeep: []int64{500, 400, 300, 200, 100},
+		},
+		{
+			name:      "keep last",
+			policy:    types.RetentionConfig{KeepLast: 2},
+			free:      -1,
+			wantKeep:  []int64{500, 400},
+			wantPrune: []int64{300, 200, 100},
+		},
+		{
+			name:      "keep every blocks",
+			policy:    types.RetentionConfig{KeepEveryBlocks: 250},
+			free:      -1,
+			wantKeep:  []int64{500, 400, 200},
+			wantPrune: []int64{300, 100},
+		},
+		{
+			name:      "keep daily",
+			policy:    types.RetentionConfig{KeepDaily: 3},
+			free:      -1,
+			wantKeep:  []int64{500, 400, 200},
+			wantPrune: []int64{300, 100},
+		},
+		{
+			name:      "max total size",
+			policy:    types.RetentionConfig{MaxTotalSize: "250"},
+			free:      -1,
+			wantKeep:  []int64{500, 400},
+			wantPrune: []int64{100, 200, 300},
+		},
+		{
+			name:      "min free disk",
+			policy:    types.RetentionConfig{MinFreeDisk: "150"},
+			free:      0,
+			wantKeep:  []int64{500, 400, 300},
+			wantPrune: []int64{100, 200},
+		},
+		{
+			name:      "newest is never pruned",
+			policy:    types.RetentionConfig{MaxTotalSize: "1"},
+			free:      -1,
+			wantKeep:  []int64{500},
+			wantPrune: []int64{100, 200, 300, 400},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, prune, err := planRetention(items, tt.policy, tt.free)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKeep, heightsOf(keep))
+			assert.Equal(t, tt.wantPrune, heightsOf(prune))
+		})
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	manager.config.Snapshots.Retention = types.RetentionConfig{KeepLast: 1}
+	manager.config.Snapshots.BackupRetention = types.RetentionConfig{KeepLast: 1}
+
+	writeTestSnapshot(t, manager, 100, map[string]string{"data_100.tar.gz": "aaaa"})
+	writeTestSnapshot(t, manager, 200, map[string]string{"data_200.tar.gz": "bbbb"})
+
+	for _, ts := range []string{"20240101_000000", "20240102_000000"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(manager.config.Global.BackupDir, backupDirPrefix+ts, "data"), 0755))
+	}
+
+	plan, err := manager.PruneSnapshots(true)
+	require.NoError(t, err)
+	assert.Len(t, plan.Prune, 2)
+	_, err = os.Stat(filepath.Join(manager.config.Global.BackupDir, snapshotDirName(100)))
+	assert.NoError(t, err, "dry run must not delete")
+
+	_, err = manager.PruneSnapshots(false)
+	require.NoError(t, err)
+
+	snapshots, err := manager.ListSnapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, int64(200), snapshots[0].Height)
+
+	backups, err := manager.listBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Contains(t, backups[0].Path, "20240102_000000")
+}
+
+func TestPruneSnapshotsUnknownHeight(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	manager.config.Snapshots.Retention = types.RetentionConfig{KeepLast: 1}
+
+	writeTestSnapshot(t, manager, 0, map[string]string{"data_0.tar.gz": "aaaa"})
+	writeTestSnapshot(t, manager, 100, map[string]string{"data_100.tar.gz": "bbbb"})
+	writeTestSnapshot(t, manager, 200, map[string]string{"data_200.tar.gz": "cccc"})
+
+	plan, err := manager.PruneSnapshots(false)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{100}, heightsOf(plan.Prune))
+	assert.ElementsMatch(t, []int64{0, 200}, heightsOf(plan.Keep))
+	require.Len(t, plan.Warnings, 1)
+	assert.Contains(t, plan.Warnings[0], snapshotDirName(0))
+}
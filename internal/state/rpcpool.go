@@ -0,0 +1,384 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/seictl/internal/rpc"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// Weights of the health score. A healthy, fast, error-free endpoint at the
+// chain tip scores 100.
+const (
+	maxLatencyPenalty = 30.0
+	errorRatePenalty  = 60.0
+	failingPenalty    = 40.0
+	lagPenalty        = 20.0
+	catchingUpPenalty = 40.0
+	uncheckedScore    = 50.0
+
+	// errorRateDecay is the weight of the newest outcome in the error rate
+	errorRateDecay = 0.2
+)
+
+// EndpointHealth is the scoreboard entry of an RPC endpoint
+type EndpointHealth struct {
+	URL         string    `json:"url"`
+	Score       float64   `json:"score"`
+	Latency     Seconds   `json:"latency_seconds"`
+	Height      int64     `json:"height"`
+	CatchingUp  bool      `json:"catching_up"`
+	ErrorRate   float64   `json:"error_rate"`
+	RateLimit   float64   `json:"rate_limit,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastChecked time.Time `json:"last_checked,omitempty"`
+}
+
+// RPCPool routes RPC calls for one environment to its healthiest endpoint,
+// failing over to the next best on errors
+type RPCPool struct {
+	name   string
+	cfg    types.RPCPoolConfig
+	logger zerolog.Logger
+	m      *Manager
+
+	mu        sync.Mutex
+	endpoints []*poolEndpoint
+	checked   time.Time
+}
+
+type poolEndpoint struct {
+	url     string
+	limiter *rateLimiter
+
+	latency    time.Duration
+	height     int64
+	catchingUp bool
+	errorRate  float64
+	lastErr    string
+	lastCheck  time.Time
+}
+
+// RPCPool returns the shared pool of an environment's RPC endpoints. An
+// empty env selects the environment matching the local chain ID.
+func (m *Manager) RPCPool(env types.Environment) (*RPCPool, error) {
+	cfg, err := m.stateSyncEnv(env)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.RPCEndpoints) == 0 {
+		return nil, fmt.Errorf("no RPC endpoints configured for chain %s", cfg.ChainID)
+	}
+
+	m.poolsMu.Lock()
+	defer m.poolsMu.Unlock()
+	if pool, ok := m.pools[cfg.ChainID]; ok {
+		return pool, nil
+	}
+	if m.pools == nil {
+		m.pools = make(map[string]*RPCPool)
+	}
+	pool := m.newRPCPool(cfg.ChainID, cfg.RPCEndpoints, cfg.RPCPool)
+	m.pools[cfg.ChainID] = pool
+	return pool, nil
+}
+
+// poolFor returns the environment's pool, or a one-off pool when endpoints
+// override the configured ones
+func (m *Manager) poolFor(env types.Environment, endpoints []string) (*RPCPool, error) {
+	if len(endpoints) == 0 {
+		return m.RPCPool(env)
+	}
+	var poolCfg *types.RPCPoolConfig
+	if cfg, err := m.stateSyncEnv(env); err == nil {
+		poolCfg = cfg.RPCPool
+	}
+	return m.newRPCPool("override", endpoints, poolCfg), nil
+}
+
+func (m *Manager) newRPCPool(name string, endpoints []string, cfg *types.RPCPoolConfig) *RPCPool {
+	pool := &RPCPool{
+		name:   name,
+		logger: m.logger.With().Str("rpc_pool", name).Logger(),
+		m:      m,
+	}
+	if cfg != nil {
+		pool.cfg = *cfg
+	}
+	for _, url := range endpoints {
+		pool.endpoints = append(pool.endpoints, &poolEndpoint{
+			url:     url,
+			limiter: newRateLimiter(pool.cfg.GetRateLimit(url)),
+		})
+	}
+	return pool
+}
+
+// httpClient returns the HTTP client shared by all RPC calls
+func (m *Manager) httpClient() *http.Client {
+	m.clientOnce.Do(func() {
+		m.client = &http.Client{Timeout: m.config.Global.GetTimeout()}
+	})
+	return m.client
+}
+
+// rpcClientFor returns an RPC client for endpoint using the shared HTTP client
+func (m *Manager) rpcClientFor(endpoint string) (*rpc.Client, error) {
+	return rpc.NewClient(endpoint, m.httpClient())
+}
+
+// Endpoints returns the pool's endpoints, healthiest first
+func (p *RPCPool) Endpoints() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ranked := p.ranked()
+	urls := make([]string, len(ranked))
+	for i, e := range ranked {
+		urls[i] = e.url
+	}
+	return urls
+}
+
+// Do calls fn with endpoints in order of health until one succeeds. Health
+// is refreshed first when the last check is older than the configured
+// interval. The error of the last attempt is returned when all fail.
+func (p *RPCPool) Do(ctx context.Context, fn func(endpoint string) error) error {
+	p.mu.Lock()
+	stale := time.Since(p.checked) > p.cfg.GetHealthInterval()
+	p.mu.Unlock()
+	if stale {
+		p.Check(ctx)
+	}
+
+	p.mu.Lock()
+	ranked := p.ranked()
+	p.mu.Unlock()
+
+	var lastErr error
+	for _, e := range ranked {
+		if err := e.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		start := time.Now()
+		err := fn(e.url)
+		p.Record(e.url, time.Since(start), err)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		lastErr = err
+		p.logger.Warn().Str("endpoint", e.url).Err(err).Msg("RPC call failed, trying next endpoint")
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no RPC endpoints configured")
+	}
+	return lastErr
+}
+
+// Call runs fn against one specific endpoint, paced by that endpoint's rate
+// limit, and records the outcome in its health score
+func (p *RPCPool) Call(ctx context.Context, endpoint string, fn func(endpoint string) error) error {
+	p.mu.Lock()
+	var limiter *rateLimiter
+	for _, e := range p.endpoints {
+		if e.url == endpoint {
+			limiter = e.limiter
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	if limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	err := fn(endpoint)
+	p.Record(endpoint, time.Since(start), err)
+	return err
+}
+
+// Record feeds the outcome of a call made outside Do into the health score
+func (p *RPCPool) Record(endpoint string, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.endpoints {
+		if e.url == endpoint {
+			e.record(latency, err)
+			return
+		}
+	}
+}
+
+// Check probes every endpoint's /status concurrently and updates the scores
+func (p *RPCPool) Check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range p.endpoints {
+		wg.Add(1)
+		go func(e *poolEndpoint) {
+			defer wg.Done()
+			if err := e.limiter.Wait(ctx); err != nil {
+				return
+			}
+			start := time.Now()
+			status, err := p.m.queryStatus(ctx, e.url)
+			latency := time.Since(start)
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			e.record(latency, err)
+			e.lastCheck = time.Now()
+			if err == nil {
+				e.height = status.LatestHeight
+				e.catchingUp = status.Syncing
+			}
+		}(e)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	p.checked = time.Now()
+	p.mu.Unlock()
+}
+
+// Health returns the scoreboard, healthiest endpoint first
+func (p *RPCPool) Health() []EndpointHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	best := p.bestHeight()
+	var board []EndpointHealth
+	for _, e := range p.ranked() {
+		board = append(board, EndpointHealth{
+			URL:         e.url,
+			Score:       e.score(best, p.cfg.GetMaxHeightLag()),
+			Latency:     Seconds(e.latency),
+			Height:      e.height,
+			CatchingUp:  e.catchingUp,
+			ErrorRate:   e.errorRate,
+			RateLimit:   p.cfg.GetRateLimit(e.url),
+			LastError:   e.lastErr,
+			LastChecked: e.lastCheck,
+		})
+	}
+	return board
+}
+
+// ranked orders endpoints by score; ties keep the configured order.
+// Callers hold p.mu.
+func (p *RPCPool) ranked() []*poolEndpoint {
+	best := p.bestHeight()
+	maxLag := p.cfg.GetMaxHeightLag()
+	ranked := append([]*poolEndpoint(nil), p.endpoints...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score(best, maxLag) > ranked[j].score(best, maxLag)
+	})
+	return ranked
+}
+
+func (p *RPCPool) bestHeight() int64 {
+	var best int64
+	for _, e := range p.endpoints {
+		if e.height > best {
+			best = e.height
+		}
+	}
+	return best
+}
+
+func (e *poolEndpoint) record(latency time.Duration, err error) {
+	outcome := 0.0
+	if err != nil {
+		outcome = 1
+		e.lastErr = err.Error()
+	} else {
+		e.lastErr = ""
+		if e.latency == 0 {
+			e.latency = latency
+		} else {
+			e.latency = (e.latency*4 + latency) / 5
+		}
+	}
+	e.errorRate = e.errorRate*(1-errorRateDecay) + outcome*errorRateDecay
+}
+
+// score rates an endpoint from 0 to 100 using its latency, error rate, whether
+// its last call failed, whether it is catching up and how far it trails the
+// best known height
+func (e *poolEndpoint) score(bestHeight, maxLag int64) float64 {
+	if e.lastCheck.IsZero() && e.latency == 0 && e.errorRate == 0 {
+		return uncheckedScore
+	}
+
+	score := 100.0
+	penalty := float64(e.latency.Milliseconds()) / 20
+	if penalty > maxLatencyPenalty {
+		penalty = maxLatencyPenalty
+	}
+	score -= penalty
+	score -= e.errorRate * errorRatePenalty
+	if e.lastErr != "" {
+		score -= failingPenalty
+	}
+	if e.catchingUp {
+		score -= catchingUpPenalty
+	}
+	if e.height > 0 && bestHeight-e.height > maxLag {
+		score -= lagPenalty
+	}
+	if score < 0 {
+		score = 0
+	}
+	return score
+}
+
+// rateLimiter spaces requests to at most perSecond per second
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	l := &rateLimiter{}
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return l
+}
+
+// Wait blocks until the next request may be sent
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+func fakeStatusRPC(t *testing.T, height int64, catchingUp bool, calls *int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls != nil {
+			atomic.AddInt32(calls, 1)
+		}
+		fmt.Fprintf(w, `{"result":{"sync_info":{"catching_up":%t,"latest_block_height":"%d"}}}`, catchingUp, height)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRPCPoolRanksByHealth(t *testing.T) {
+	lagging := fakeStatusRPC(t, 900, false, nil)
+	catchingUp := fakeStatusRPC(t, 1000, true, nil)
+	healthy := fakeStatusRPC(t, 1000, false, nil)
+	down := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(down.Close)
+
+	manager := setupTrustManager(t, []string{down.URL, lagging.URL, catchingUp.URL, healthy.URL}, 0)
+	pool, err := manager.RPCPool("")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		pool.Check(context.Background())
+	}
+
+	board := pool.Health()
+	require.Len(t, board, 4)
+	assert.Equal(t, healthy.URL, board[0].URL)
+	assert.Equal(t, lagging.URL, board[1].URL)
+	assert.Equal(t, catchingUp.URL, board[2].URL)
+	assert.Equal(t, down.URL, board[3].URL)
+	assert.True(t, board[2].CatchingUp)
+	assert.Greater(t, board[3].ErrorRate, 0.0)
+	assert.NotEmpty(t, board[3].LastError)
+
+	status, err := manager.getNodeStatus(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), status.LatestHeight)
+	assert.False(t, status.Syncing)
+}
+
+func TestRPCPoolFailsOver(t *testing.T) {
+	manager := setupTrustManager(t, []string{"http://a", "http://b", "http://c"}, 0)
+	pool := manager.newRPCPool("test", []string{"http://a", "http://b", "http://c"}, &types.RPCPoolConfig{HealthIntervalSeconds: 3600})
+	pool.checked = time.Now()
+
+	var tried []string
+	err := pool.Do(context.Background(), func(endpoint string) error {
+		tried = append(tried, endpoint)
+		if endpoint != "http://c" {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://a", "http://b", "http://c"}, tried)
+
+	// The endpoint that answered is now preferred
+	assert.Equal(t, "http://c", pool.Endpoints()[0])
+
+	err = pool.Do(context.Background(), func(string) error { return errors.New("unavailable") })
+	assert.EqualError(t, err, "unavailable")
+}
+
+func TestRPCPoolRateLimit(t *testing.T) {
+	var calls int32
+	srv := fakeStatusRPC(t, 1000, false, &calls)
+	manager := setupTrustManager(t, []string{srv.URL}, 0)
+	pool := manager.newRPCPool("test", []string{srv.URL}, &types.RPCPoolConfig{
+		RateLimit:  1000,
+		RateLimits: map[string]float64{srv.URL: 20},
+	})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		pool.Check(context.Background())
+	}
+	// Four requests at 20/s are spaced at least 150ms apart in total
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+	assert.Equal(t, 20.0, pool.Health()[0].RateLimit)
+}
+
+func TestRateLimiterHonoursContext(t *testing.T) {
+	l := newRateLimiter(0.1)
+	require.NoError(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+}
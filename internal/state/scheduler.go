@@ -0,0 +1,427 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/your-org/seictl/internal/utils"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// Snapshot daemon modes
+const (
+	// ModeStop keeps the node stopped for the whole snapshot
+	ModeStop = "stop"
+	// ModeHardlink stops the node only long enough to hard-link its data
+	ModeHardlink = "hardlink"
+)
+
+const (
+	daemonStatusFile = "snapshot_daemon.json"
+	freezeDirName    = ".snapshot-freeze"
+	defaultRPCPort   = 26657
+)
+
+// NodeController stops and starts the local node around a snapshot
+type NodeController interface {
+	Stop(ctx context.Context) error
+	Start(ctx context.Context) error
+}
+
+// DaemonOptions configures the scheduled snapshot daemon
+type DaemonOptions struct {
+	Env         types.Environment
+	Interval    int64
+	Cron        string
+	Mode        string
+	RPC         string
+	Node        NodeController
+	Incremental bool
+}
+
+// DaemonStatus is persisted after every run so failures can be inspected
+// without reading the daemon logs
+type DaemonStatus struct {
+	LastHeight          int64     `json:"last_height,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastErrorAt         time.Time `json:"last_error_at,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+}
+
+// LocalRPCEndpoint returns the RPC address of the node running on this host
+func (m *Manager) LocalRPCEndpoint(env types.Environment) string {
+	port := defaultRPCPort
+	if chainCfg, ok := m.config.Environments[string(env)]; ok && chainCfg.Ports != nil && chainCfg.Ports.RPC != 0 {
+		port = chainCfg.Ports.RPC
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", port)
+}
+
+// NewNodeController returns a controller using the configured stop/start
+// commands, falling back to signalling seid directly
+func (m *Manager) NewNodeController() NodeController {
+	return &commandNodeController{
+		m:     m,
+		stop:  m.config.Snapshots.Schedule.StopCommand,
+		start: m.config.Snapshots.Schedule.StartCommand,
+	}
+}
+
+// RunSnapshotDaemon takes snapshots every N blocks or on a cron schedule until
+// the context is cancelled. Individual failures are recorded and the daemon
+// keeps running.
+func (m *Manager) RunSnapshotDaemon(ctx context.Context, opts DaemonOptions) error {
+	schedCfg := m.config.Snapshots.Schedule
+	if opts.Cron == "" {
+		opts.Cron = schedCfg.Cron
+	}
+	if opts.Mode == "" {
+		opts.Mode = schedCfg.Mode
+	}
+	if m.config.Snapshots.Incremental {
+		opts.Incremental = true
+	}
+	if opts.Mode == "" {
+		opts.Mode = ModeStop
+	}
+	if opts.Mode != ModeStop && opts.Mode != ModeHardlink {
+		return fmt.Errorf("unknown snapshot mode %q", opts.Mode)
+	}
+	if opts.Interval == 0 && opts.Cron == "" {
+		if chainCfg, ok := m.config.Environments[string(opts.Env)]; ok && chainCfg.StateSync != nil {
+			opts.Interval = chainCfg.StateSync.SnapshotInterval
+		}
+	}
+	if opts.Interval <= 0 && opts.Cron == "" {
+		return fmt.Errorf("either a block interval or a cron schedule is required")
+	}
+	if opts.RPC == "" {
+		opts.RPC = m.LocalRPCEndpoint(opts.Env)
+	}
+	if opts.Node == nil {
+		opts.Node = m.NewNodeController()
+	}
+
+	var schedule cron.Schedule
+	if opts.Cron != "" {
+		var err error
+		schedule, err = cron.ParseStandard(opts.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid cron schedule: %w", err)
+		}
+	}
+
+	var last int64
+	snapshots, err := m.ListSnapshots()
+	if err != nil {
+		return err
+	}
+	if len(snapshots) > 0 {
+		last = snapshots[0].Height
+	}
+
+	m.logger.Info().
+		Int64("interval", opts.Interval).
+		Str("cron", opts.Cron).
+		Str("mode", opts.Mode).
+		Int64("last_snapshot", last).
+		Msg("Snapshot daemon started")
+
+	poll := schedCfg.GetPollInterval()
+	for {
+		wait := poll
+		if schedule != nil {
+			wait = time.Until(schedule.Next(time.Now()))
+		}
+
+		select {
+		case <-ctx.Done():
+			m.logger.Info().Msg("Snapshot daemon stopped")
+			return nil
+		case <-time.After(wait):
+		}
+
+		status, err := m.queryStatus(ctx, opts.RPC)
+		if err != nil {
+			m.recordDaemonFailure(fmt.Errorf("failed to query local node: %w", err))
+			continue
+		}
+		if status.Syncing {
+			m.logger.Debug().Int64("height", status.LatestHeight).Msg("Node is catching up, skipping snapshot")
+			continue
+		}
+		if schedule == nil && !snapshotDue(status.LatestHeight, last, opts.Interval) {
+			continue
+		}
+
+		height, err := m.takeScheduledSnapshot(ctx, opts, status.LatestHeight)
+		if err != nil {
+			m.recordDaemonFailure(err)
+			continue
+		}
+
+		last = height
+		m.recordDaemonSuccess(last)
+	}
+}
+
+// ReadDaemonStatus returns the status persisted by the snapshot daemon
+func (m *Manager) ReadDaemonStatus() (*DaemonStatus, error) {
+	data, err := os.ReadFile(filepath.Join(m.config.Global.BackupDir, daemonStatusFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read daemon status: %w", err)
+	}
+
+	status := &DaemonStatus{}
+	if err := json.Unmarshal(data, status); err != nil {
+		return nil, fmt.Errorf("failed to parse daemon status: %w", err)
+	}
+	return status, nil
+}
+
+// takeScheduledSnapshot stops the node and snapshots it, returning the height
+// of the snapshot. reported is the height seen before the stop; the node keeps
+// committing blocks until it exits, so the snapshot is named after the height
+// in the stopped blockstore instead.
+func (m *Manager) takeScheduledSnapshot(ctx context.Context, opts DaemonOptions, reported int64) (int64, error) {
+	m.logger.Info().Int64("height", reported).Str("mode", opts.Mode).Msg("Taking scheduled snapshot")
+
+	if err := opts.Node.Stop(ctx); err != nil {
+		return 0, fmt.Errorf("failed to stop node: %w", err)
+	}
+	height := m.stoppedHeight(reported)
+
+	source := m.config.Global.HomeDir
+	if opts.Mode == ModeHardlink {
+		freezeDir := filepath.Join(m.config.Global.HomeDir, freezeDirName)
+		if err := freezeHome(m.config.Global.HomeDir, freezeDir); err != nil {
+			m.logger.Warn().Err(err).Msg("Hard-link freeze failed, snapshotting with node stopped")
+			os.RemoveAll(freezeDir)
+		} else {
+			defer os.RemoveAll(freezeDir)
+			source = freezeDir
+			if err := opts.Node.Start(ctx); err != nil {
+				return 0, fmt.Errorf("failed to restart node after freeze: %w", err)
+			}
+			return height, m.snapshotFrom(ctx, height, source, opts.Incremental)
+		}
+	}
+
+	snapErr := m.snapshotFrom(ctx, height, source, opts.Incremental)
+
+	// Always bring the node back, even when the snapshot failed
+	if err := opts.Node.Start(ctx); err != nil {
+		if snapErr != nil {
+			return 0, fmt.Errorf("snapshot failed: %v; failed to restart node: %w", snapErr, err)
+		}
+		return 0, fmt.Errorf("failed to restart node: %w", err)
+	}
+
+	return height, snapErr
+}
+
+// stoppedHeight reads the latest block from the stopped node's blockstore,
+// falling back to reported when it cannot be read
+func (m *Manager) stoppedHeight(reported int64) int64 {
+	inspection, err := m.InspectDatabases()
+	if err != nil || inspection.BlockStore == nil || inspection.BlockStore.Height == 0 {
+		m.logger.Warn().Int64("height", reported).Msg("Could not read the blockstore height, using the height reported before the stop")
+		return reported
+	}
+	if inspection.BlockStore.Height != reported {
+		m.logger.Info().
+			Int64("reported", reported).
+			Int64("height", inspection.BlockStore.Height).
+			Msg("Node committed more blocks before it stopped")
+	}
+	return inspection.BlockStore.Height
+}
+
+func (m *Manager) snapshotFrom(ctx context.Context, height int64, source string, incremental bool) error {
+	if incremental {
+		return m.createIncrementalSnapshotFrom(ctx, height, source)
+	}
+	return m.createSnapshotFrom(ctx, height, source)
+}
+
+func (m *Manager) recordDaemonSuccess(height int64) {
+	status, err := m.ReadDaemonStatus()
+	if err != nil {
+		status = &DaemonStatus{}
+	}
+	status.LastHeight = height
+	status.LastSuccess = time.Now().UTC()
+	status.ConsecutiveFailures = 0
+	m.writeDaemonStatus(status)
+}
+
+func (m *Manager) recordDaemonFailure(cause error) {
+	status, err := m.ReadDaemonStatus()
+	if err != nil {
+		status = &DaemonStatus{}
+	}
+	status.LastError = cause.Error()
+	status.LastErrorAt = time.Now().UTC()
+	status.ConsecutiveFailures++
+
+	m.logger.Error().
+		Err(cause).
+		Int("consecutive_failures", status.ConsecutiveFailures).
+		Msg("Scheduled snapshot failed")
+
+	m.writeDaemonStatus(status)
+}
+
+func (m *Manager) writeDaemonStatus(status *DaemonStatus) {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(m.config.Global.BackupDir, daemonStatusFile), data, 0644)
+	}
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to write daemon status")
+	}
+}
+
+// snapshotDue reports whether the chain has crossed an interval boundary
+// since the last snapshot
+func snapshotDue(height, last, interval int64) bool {
+	if interval <= 0 || height <= 0 {
+		return false
+	}
+	boundary := (height / interval) * interval
+	return boundary > 0 && boundary > last
+}
+
+// freezeHome hard-links the data and wasm directories of home into dst. Files
+// that the database keeps appending to are copied instead, so the frozen tree
+// is not modified once the node restarts.
+func freezeHome(home, dst string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+
+	for _, name := range []string{"data", "wasm"} {
+		src, err := filepath.EvalSymlinks(filepath.Join(home, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if err := linkTree(src, filepath.Join(dst, name)); err != nil {
+			return fmt.Errorf("failed to freeze %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// linkTree hard links the immutable files under src into dst and copies the
+// rest. dst may be inside src and is skipped. Files that disappear during the
+// walk are skipped too.
+func linkTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if path == dst {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return utils.EnsureDir(target)
+		case isMutableDBFile(path):
+			err = utils.CopyFile(path, target)
+		default:
+			err = os.Link(path, target)
+		}
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// isMutableDBFile reports whether a file may be modified in place after the
+// node restarts, as opposed to immutable SST/ldb tables
+func isMutableDBFile(path string) bool {
+	name := filepath.Base(path)
+	switch {
+	case name == "LOCK", name == "LOG", name == "LOG.old", name == "CURRENT":
+		return true
+	case strings.HasPrefix(name, "MANIFEST-"), strings.HasPrefix(name, "OPTIONS-"):
+		return true
+	case strings.HasSuffix(name, ".log"), strings.HasSuffix(name, ".wal"), strings.HasSuffix(name, ".json"):
+		return true
+	case strings.Contains(path, string(filepath.Separator)+"cs.wal"+string(filepath.Separator)):
+		return true
+	}
+	return false
+}
+
+type commandNodeController struct {
+	m     *Manager
+	stop  string
+	start string
+}
+
+func (c *commandNodeController) Stop(ctx context.Context) error {
+	if c.stop == "" {
+		return c.m.stopNode(ctx)
+	}
+	return runShell(ctx, c.stop)
+}
+
+func (c *commandNodeController) Start(ctx context.Context) error {
+	if c.start != "" {
+		return runShell(ctx, c.start)
+	}
+
+	logFile, err := os.OpenFile(filepath.Join(c.m.config.Global.HomeDir, "seid.log"),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open node log: %w", err)
+	}
+	defer logFile.Close()
+
+	// Not tied to ctx: the node must outlive the daemon
+	cmd := exec.Command("seid", "start", "--home", c.m.config.Global.HomeDir)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start node: %w", err)
+	}
+
+	return cmd.Process.Release()
+}
+
+func runShell(ctx context.Context, command string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%q failed: %w", command, err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNode struct {
+	stops  int
+	starts int
+}
+
+func (f *fakeNode) Stop(context.Context) error {
+	f.stops++
+	return nil
+}
+
+func (f *fakeNode) Start(context.Context) error {
+	f.starts++
+	return nil
+}
+
+func writeTestHome(t *testing.T, home string) {
+	files := map[string]string{
+		"data/priv_validator_state.json":  `{"height":"0"}`,
+		"data/application.db/000001.ldb":  "table",
+		"data/application.db/CURRENT":     "MANIFEST-000002",
+		"data/application.db/MANIFEST-02": "manifest",
+		"wasm/contract.wasm":              "wasm",
+	}
+	for name, content := range files {
+		path := filepath.Join(home, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestSnapshotDue(t *testing.T) {
+	tests := []struct {
+		height, last, interval int64
+		want                   bool
+	}{
+		{height: 99, last: 0, interval: 100, want: false},
+		{height: 100, last: 0, interval: 100, want: true},
+		{height: 150, last: 100, interval: 100, want: false},
+		{height: 205, last: 100, interval: 100, want: true},
+		{height: 205, last: 201, interval: 100, want: false},
+		{height: 500, last: 0, interval: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, snapshotDue(tt.height, tt.last, tt.interval),
+			"height=%d last=%d interval=%d", tt.height, tt.last, tt.interval)
+	}
+}
+
+func TestFreezeHome(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+	writeTestHome(t, home)
+
+	frozen := filepath.Join(home, freezeDirName)
+	require.NoError(t, freezeHome(home, frozen))
+
+	sameFile := func(rel string) bool {
+		a, err := os.Stat(filepath.Join(home, rel))
+		require.NoError(t, err)
+		b, err := os.Stat(filepath.Join(frozen, rel))
+		require.NoError(t, err)
+		return os.SameFile(a, b)
+	}
+
+	assert.True(t, sameFile("data/application.db/000001.ldb"), "tables should be hard-linked")
+	assert.True(t, sameFile("wasm/contract.wasm"))
+	assert.False(t, sameFile("data/application.db/CURRENT"), "mutable files should be copied")
+	assert.False(t, sameFile("data/priv_validator_state.json"))
+}
+
+func TestTakeScheduledSnapshot(t *testing.T) {
+	for _, mode := range []string{ModeStop, ModeHardlink} {
+		t.Run(mode, func(t *testing.T) {
+			manager, _ := setupTestManager(t)
+			writeTestHome(t, manager.config.Global.HomeDir)
+
+			node := &fakeNode{}
+			height, err := manager.takeScheduledSnapshot(context.Background(), DaemonOptions{Mode: mode, Node: node}, 1000)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1000), height)
+			assert.Equal(t, 1, node.stops)
+			assert.Equal(t, 1, node.starts)
+
+			result, err := manager.VerifySnapshotIntegrity(1000)
+			require.NoError(t, err)
+			assert.True(t, result.OK)
+
+			_, err = os.Stat(filepath.Join(manager.config.Global.HomeDir, freezeDirName))
+			assert.True(t, os.IsNotExist(err), "freeze directory should be cleaned up")
+		})
+	}
+}
+
+func TestTakeScheduledSnapshotUsesStoppedHeight(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	writeTestHome(t, manager.config.Global.HomeDir)
+	// The node committed five more blocks between the status query and the stop
+	writeTestDB(t, manager, BackendGoLevelDB, "blockstore", map[string][]byte{
+		"blockStore": append(appendVarintField(nil, 1, 1), appendVarintField(nil, 2, 1005)...),
+		"H:1005":     testBlockMeta(1005, []byte{0xAA}, time.Now()),
+	})
+
+	height, err := manager.takeScheduledSnapshot(context.Background(), DaemonOptions{Mode: ModeStop, Node: &fakeNode{}}, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1005), height)
+	_, err = os.Stat(filepath.Join(manager.config.Global.BackupDir, snapshotDirName(1005)))
+	assert.NoError(t, err)
+}
+
+func TestDaemonStatus(t *testing.T) {
+	manager, _ := setupTestManager(t)
+
+	manager.recordDaemonFailure(errors.New("boom"))
+	manager.recordDaemonFailure(errors.New("boom again"))
+
+	status, err := manager.ReadDaemonStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.Equal(t, "boom again", status.LastError)
+
+	manager.recordDaemonSuccess(500)
+	status, err = manager.ReadDaemonStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	assert.Equal(t, int64(500), status.LastHeight)
+}
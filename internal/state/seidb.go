@@ -0,0 +1,380 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// Storage layouts of the application state
+const (
+	// LayoutLegacy is the IAVL store pruned by pruning-keep-recent/keep-every
+	LayoutLegacy = "legacy"
+	// LayoutSeiDB is SeiDB, configured by [state-commit] and [state-store]
+	LayoutSeiDB = "seidb"
+)
+
+const (
+	stateCommitSection = "state-commit"
+	stateStoreSection  = "state-store"
+)
+
+// seidbCommitterDir is the state commit (memiavl) directory in the data dir
+const seidbCommitterDir = "committer.db"
+
+// seidbMinVersion is the first seid release that reads the [state-commit]
+// and [state-store] sections
+const seidbMinVersion = "v3.6.0"
+
+var ssBackends = []string{"pebbledb", "rocksdb", "sqlite"}
+
+// defaultSeiDB mirrors seid's defaults for keys missing from app.toml
+var defaultSeiDB = types.SeiDBConfig{
+	StateCommit: types.StateCommitConfig{
+		AsyncCommitBuffer: 100,
+		KeepRecent:        1,
+		SnapshotInterval:  10000,
+	},
+	StateStore: types.StateStoreConfig{
+		Backend:              "pebbledb",
+		KeepRecent:           100000,
+		PruneIntervalSeconds: 600,
+		AsyncWriteBuffer:     100,
+	},
+}
+
+// LegacyPruning is the top-level pruning configuration of app.toml
+type LegacyPruning struct {
+	Mode       string `json:"mode"`
+	KeepRecent int64  `json:"keep_recent"`
+	KeepEvery  int64  `json:"keep_every"`
+	Interval   int64  `json:"interval"`
+}
+
+// PruningSettings is the storage and pruning configuration of the node
+type PruningSettings struct {
+	// Layout is LayoutSeiDB when state commit is enabled
+	Layout string            `json:"layout"`
+	Legacy LegacyPruning     `json:"legacy"`
+	SeiDB  types.SeiDBConfig `json:"seidb"`
+}
+
+// Archive reports whether the node keeps all historical state
+func (p *PruningSettings) Archive() bool {
+	if p.Layout == LayoutSeiDB {
+		return p.SeiDB.StateStore.Enable && p.SeiDB.StateStore.KeepRecent == 0
+	}
+	return p.Legacy.Mode == "nothing"
+}
+
+// Describe summarises the pruning of the layout in use
+func (p *PruningSettings) Describe() string {
+	if p.Layout == LayoutSeiDB {
+		ss := p.SeiDB.StateStore
+		switch {
+		case !ss.Enable:
+			return "seidb (no state store, historical queries disabled)"
+		case ss.KeepRecent == 0:
+			return fmt.Sprintf("seidb archive (%s state store keeps everything)", ss.Backend)
+		default:
+			return fmt.Sprintf("seidb (%s state store keeps %d versions, pruned every %ds)", ss.Backend, ss.KeepRecent, ss.PruneIntervalSeconds)
+		}
+	}
+	l := p.Legacy
+	switch l.Mode {
+	case "":
+		return "unknown"
+	case "custom":
+		return fmt.Sprintf("custom (keep-recent %d, keep-every %d, interval %d)", l.KeepRecent, l.KeepEvery, l.Interval)
+	default:
+		return l.Mode
+	}
+}
+
+// ReadPruning reads the pruning configuration of both storage layouts from
+// app.toml and reports which one the node uses
+func (m *Manager) ReadPruning() (*PruningSettings, error) {
+	path := filepath.Join(m.config.Global.HomeDir, "config", "app.toml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read app.toml: %w", err)
+	}
+	return parsePruning(string(data)), nil
+}
+
+func parsePruning(content string) *PruningSettings {
+	num := func(section, key string) (int64, bool) {
+		v, ok := tomlValue(content, section, key)
+		if !ok {
+			return 0, false
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	flag := func(section, key string) bool {
+		v, _ := tomlValue(content, section, key)
+		return v == "true"
+	}
+
+	p := &PruningSettings{Layout: LayoutLegacy, SeiDB: defaultSeiDB}
+	p.Legacy.Mode, _ = tomlValue(content, "", "pruning")
+	p.Legacy.KeepRecent, _ = num("", "pruning-keep-recent")
+	p.Legacy.KeepEvery, _ = num("", "pruning-keep-every")
+	p.Legacy.Interval, _ = num("", "pruning-interval")
+
+	sc := &p.SeiDB.StateCommit
+	sc.Enable = flag(stateCommitSection, "sc-enable")
+	if n, ok := num(stateCommitSection, "sc-async-commit-buffer"); ok {
+		sc.AsyncCommitBuffer = int(n)
+	}
+	if n, ok := num(stateCommitSection, "sc-keep-recent"); ok {
+		sc.KeepRecent = int(n)
+	}
+	if n, ok := num(stateCommitSection, "sc-snapshot-interval"); ok {
+		sc.SnapshotInterval = n
+	}
+
+	ss := &p.SeiDB.StateStore
+	ss.Enable = flag(stateStoreSection, "ss-enable")
+	if v, ok := tomlValue(content, stateStoreSection, "ss-backend"); ok && v != "" {
+		ss.Backend = v
+	}
+	if n, ok := num(stateStoreSection, "ss-keep-recent"); ok {
+		ss.KeepRecent = n
+	}
+	if n, ok := num(stateStoreSection, "ss-prune-interval"); ok {
+		ss.PruneIntervalSeconds = int(n)
+	}
+	if n, ok := num(stateStoreSection, "ss-async-write-buffer"); ok {
+		ss.AsyncWriteBuffer = int(n)
+	}
+
+	if sc.Enable {
+		p.Layout = LayoutSeiDB
+	}
+	return p
+}
+
+// ValidateSeiDB checks a SeiDB configuration and that seidVersion supports
+// it. An empty version skips the version check with a warning. Warnings do
+// not prevent the configuration from being applied.
+func ValidateSeiDB(cfg types.SeiDBConfig, seidVersion string) ([]string, error) {
+	sc, ss := cfg.StateCommit, cfg.StateStore
+	var warnings []string
+
+	if ss.Enable && !sc.Enable {
+		return nil, fmt.Errorf("the state store is fed by state commit; enable state commit too")
+	}
+	if sc.AsyncCommitBuffer < 0 || sc.KeepRecent < 0 || sc.SnapshotInterval < 0 {
+		return nil, fmt.Errorf("state commit settings must not be negative")
+	}
+	if ss.KeepRecent < 0 || ss.PruneIntervalSeconds < 0 || ss.AsyncWriteBuffer < 0 {
+		return nil, fmt.Errorf("state store settings must not be negative")
+	}
+	if ss.Enable {
+		if !containsString(ssBackends, ss.Backend) {
+			return nil, fmt.Errorf("unknown state store backend %q, use %s", ss.Backend, strings.Join(ssBackends, ", "))
+		}
+		if ss.Backend == "rocksdb" {
+			warnings = append(warnings, "the rocksdb backend needs a seid built with rocksdb support")
+		}
+		if ss.KeepRecent > 0 && ss.PruneIntervalSeconds == 0 {
+			warnings = append(warnings, "ss-prune-interval is 0, so old versions are never pruned despite ss-keep-recent")
+		}
+	}
+	if sc.Enable && sc.SnapshotInterval == 0 {
+		warnings = append(warnings, "sc-snapshot-interval is 0: no memiavl snapshots are taken, so restarts replay from genesis and state sync cannot be served")
+	}
+
+	if sc.Enable {
+		if seidVersion == "" {
+			warnings = append(warnings, fmt.Sprintf("could not determine the seid version; SeiDB needs %s or later", seidbMinVersion))
+		} else if v, ok := parseVersion(seidVersion); !ok {
+			warnings = append(warnings, fmt.Sprintf("could not parse seid version %q; SeiDB needs %s or later", seidVersion, seidbMinVersion))
+		} else if required, _ := parseVersion(seidbMinVersion); compareVersions(v, required) < 0 {
+			return nil, fmt.Errorf("seid %s does not support SeiDB, upgrade to %s or later", seidVersion, seidbMinVersion)
+		}
+	}
+	return warnings, nil
+}
+
+// ApplySeiDB validates cfg and writes it to the [state-commit] and
+// [state-store] sections of app.toml. When seidVersion is empty the installed
+// seid is asked for its version. Turning state commit on for a node whose
+// application.db holds IAVL state is refused unless force is set, as SeiDB
+// does not read it. seid must be restarted to pick it up.
+func (m *Manager) ApplySeiDB(cfg types.SeiDBConfig, seidVersion string, force bool) ([]string, error) {
+	if seidVersion == "" {
+		seidVersion = m.seidVersion()
+	}
+	warnings, err := ValidateSeiDB(cfg, seidVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(m.config.Global.HomeDir, "config", "app.toml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read app.toml: %w", err)
+	}
+
+	sc, ss := cfg.StateCommit, cfg.StateStore
+	content := string(data)
+	if sc.Enable && !force && parsePruning(content).Layout != LayoutSeiDB && m.hasLegacyAppState() {
+		return nil, fmt.Errorf("application.db holds IAVL state that SeiDB does not read; " +
+			"state sync or restore a SeiDB snapshot after switching, and pass --force to switch anyway")
+	}
+	for _, kv := range seidbKeys(cfg) {
+		value := fmt.Sprint(kv.value)
+		if s, ok := kv.value.(string); ok {
+			value = strconv.Quote(s)
+		}
+		content = updateSectionConfig(content, kv.section, kv.key, value)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write app.toml: %w", err)
+	}
+	m.logger.Info().
+		Bool("sc_enable", sc.Enable).
+		Bool("ss_enable", ss.Enable).
+		Str("ss_backend", ss.Backend).
+		Int64("ss_keep_recent", ss.KeepRecent).
+		Msg("SeiDB configuration updated")
+	return warnings, nil
+}
+
+// CheckSeiDB validates the SeiDB configuration already in app.toml, e.g. as
+// rendered by init, against seidVersion, asking the installed seid when it
+// is empty
+func (m *Manager) CheckSeiDB(seidVersion string) ([]string, error) {
+	settings, err := m.ReadPruning()
+	if err != nil {
+		return nil, err
+	}
+	if seidVersion == "" {
+		seidVersion = m.seidVersion()
+	}
+	warnings, err := ValidateSeiDB(settings.SeiDB, seidVersion)
+	if err != nil {
+		return nil, err
+	}
+	if settings.SeiDB.StateCommit.Enable && m.hasLegacyAppState() {
+		return nil, fmt.Errorf("state commit is enabled but application.db holds IAVL state that SeiDB does not read; " +
+			"disable it or clear the data directory")
+	}
+	return warnings, nil
+}
+
+// SeiDBSections renders cfg as the [state-commit] and [state-store] tables
+// of app.toml
+func SeiDBSections(cfg types.SeiDBConfig) map[string]interface{} {
+	sections := map[string]interface{}{}
+	for _, kv := range seidbKeys(cfg) {
+		table, ok := sections[kv.section].(map[string]interface{})
+		if !ok {
+			table = map[string]interface{}{}
+			sections[kv.section] = table
+		}
+		table[kv.key] = kv.value
+	}
+	return sections
+}
+
+type seidbKey struct {
+	section, key string
+	value        interface{}
+}
+
+// seidbKeys lists the app.toml keys of cfg in the order seid documents them
+func seidbKeys(cfg types.SeiDBConfig) []seidbKey {
+	sc, ss := cfg.StateCommit, cfg.StateStore
+	return []seidbKey{
+		{stateCommitSection, "sc-enable", sc.Enable},
+		{stateCommitSection, "sc-async-commit-buffer", sc.AsyncCommitBuffer},
+		{stateCommitSection, "sc-keep-recent", sc.KeepRecent},
+		{stateCommitSection, "sc-snapshot-interval", sc.SnapshotInterval},
+		{stateStoreSection, "ss-enable", ss.Enable},
+		{stateStoreSection, "ss-backend", ss.Backend},
+		{stateStoreSection, "ss-keep-recent", ss.KeepRecent},
+		{stateStoreSection, "ss-prune-interval", ss.PruneIntervalSeconds},
+		{stateStoreSection, "ss-async-write-buffer", ss.AsyncWriteBuffer},
+	}
+}
+
+// hasLegacyAppState reports whether the data directory holds a non-empty
+// IAVL application.db
+func (m *Manager) hasLegacyAppState() bool {
+	entries, err := os.ReadDir(filepath.Join(m.dataDir(), "application.db"))
+	return err == nil && len(entries) > 0
+}
+
+// tomlValue returns the value of key in section of a TOML document; an empty
+// section means the top level. Dashed and underscored spellings both match.
+func tomlValue(content, section, key string) (string, bool) {
+	alt := strings.ReplaceAll(key, "-", "_")
+	current := ""
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			current = strings.Trim(trimmed, "[]")
+			continue
+		}
+		if current != section {
+			continue
+		}
+		name, value, ok := strings.Cut(trimmed, "=")
+		if name = strings.TrimSpace(name); ok && (name == key || name == alt) {
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = value[:i]
+			}
+			return strings.Trim(strings.TrimSpace(value), `"`), true
+		}
+	}
+	return "", false
+}
+
+// parseVersion parses the first "vMAJOR.MINOR.PATCH" found in s, with or
+// without the leading v
+func parseVersion(s string) ([3]int, bool) {
+	for _, field := range strings.Fields(s) {
+		var v [3]int
+		field = strings.TrimPrefix(field, "v")
+		if i := strings.IndexAny(field, "-+"); i >= 0 {
+			field = field[:i]
+		}
+		parts := strings.Split(field, ".")
+		if len(parts) < 2 || len(parts) > 3 {
+			continue
+		}
+		ok := true
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				ok = false
+				break
+			}
+			v[i] = n
+		}
+		if ok {
+			return v, true
+		}
+	}
+	return [3]int{}, false
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
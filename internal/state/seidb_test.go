@@ -0,0 +1,165 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const seidbAppToml = `pruning = "default"
+pruning-keep-recent = "0"
+
+[state-commit]
+sc-enable = true
+sc-async-commit-buffer = 50
+sc-snapshot-interval = 2000
+
+[state-store]
+ss-enable = true
+ss-backend = "pebbledb" # historical queries
+ss-keep-recent = 0
+`
+
+func writeAppToml(t *testing.T, m *Manager, content string) string {
+	path := filepath.Join(m.config.Global.HomeDir, "config", "app.toml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestReadPruningLayouts(t *testing.T) {
+	manager, _ := setupTestManager(t)
+
+	writeAppToml(t, manager, "pruning = \"custom\"\npruning-keep-recent = \"100\"\npruning-keep-every = \"0\"\npruning-interval = \"10\"\n")
+	settings, err := manager.ReadPruning()
+	require.NoError(t, err)
+	assert.Equal(t, LayoutLegacy, settings.Layout)
+	assert.Equal(t, int64(100), settings.Legacy.KeepRecent)
+	assert.False(t, settings.Archive())
+	// Missing SeiDB keys fall back to seid's defaults
+	assert.Equal(t, "pebbledb", settings.SeiDB.StateStore.Backend)
+	assert.Equal(t, int64(10000), settings.SeiDB.StateCommit.SnapshotInterval)
+
+	writeAppToml(t, manager, seidbAppToml)
+	settings, err = manager.ReadPruning()
+	require.NoError(t, err)
+	assert.Equal(t, LayoutSeiDB, settings.Layout)
+	assert.Equal(t, 50, settings.SeiDB.StateCommit.AsyncCommitBuffer)
+	assert.Equal(t, int64(2000), settings.SeiDB.StateCommit.SnapshotInterval)
+	assert.Equal(t, 1, settings.SeiDB.StateCommit.KeepRecent)
+	assert.True(t, settings.Archive())
+	assert.Equal(t, "nothing", manager.pruningMode())
+	assert.Contains(t, settings.Describe(), "archive")
+}
+
+func TestValidateSeiDB(t *testing.T) {
+	cfg := defaultSeiDB
+	cfg.StateCommit.Enable = true
+	cfg.StateStore.Enable = true
+
+	warnings, err := ValidateSeiDB(cfg, "v5.9.0-hotfix")
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	_, err = ValidateSeiDB(cfg, "v3.5.2")
+	assert.ErrorContains(t, err, "does not support SeiDB")
+
+	warnings, err = ValidateSeiDB(cfg, "")
+	require.NoError(t, err)
+	assert.Len(t, warnings, 1)
+
+	bad := cfg
+	bad.StateStore.Backend = "badger"
+	_, err = ValidateSeiDB(bad, "v5.0.0")
+	assert.ErrorContains(t, err, "backend")
+
+	bad = cfg
+	bad.StateCommit.Enable = false
+	_, err = ValidateSeiDB(bad, "v5.0.0")
+	assert.ErrorContains(t, err, "state commit")
+
+	rocks := cfg
+	rocks.StateStore.Backend = "rocksdb"
+	warnings, err = ValidateSeiDB(rocks, "5.0.0")
+	require.NoError(t, err)
+	assert.Len(t, warnings, 1)
+
+	// A legacy node on an old seid needs no SeiDB support
+	_, err = ValidateSeiDB(defaultSeiDB, "v3.0.0")
+	assert.NoError(t, err)
+}
+
+func TestApplySeiDB(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	path := writeAppToml(t, manager, "pruning = \"custom\"\n\n[api]\nenable = true\n")
+
+	cfg := defaultSeiDB
+	cfg.StateCommit.Enable = true
+	cfg.StateStore.Enable = true
+	cfg.StateStore.KeepRecent = 5000
+	_, err := manager.ApplySeiDB(cfg, "v5.9.0", false)
+	require.NoError(t, err)
+
+	settings, err := manager.ReadPruning()
+	require.NoError(t, err)
+	assert.Equal(t, LayoutSeiDB, settings.Layout)
+	assert.Equal(t, cfg, settings.SeiDB)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "[api]\nenable = true")
+	assert.Contains(t, string(data), `ss-backend = "pebbledb"`)
+
+	// UpdatePruning follows the layout in use
+	require.NoError(t, manager.UpdatePruning(context.Background(), 1000, 0, 10))
+	settings, err = manager.ReadPruning()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), settings.SeiDB.StateStore.KeepRecent)
+	assert.Empty(t, settings.Legacy.KeepRecent)
+	assert.Error(t, manager.UpdatePruning(context.Background(), 1000, 500, 10))
+	// ss-keep-recent 0 would make an archive, which needs --ss-keep-recent
+	assert.ErrorContains(t, manager.UpdatePruning(context.Background(), 0, 0, 10), "keeps all history")
+
+	_, err = manager.ApplySeiDB(cfg, "v2.0.0", false)
+	assert.Error(t, err)
+}
+
+func TestApplySeiDBRefusesLegacyState(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	writeAppToml(t, manager, "pruning = \"custom\"\n")
+	writeSizedFile(t, filepath.Join(manager.config.Global.HomeDir, "data", "application.db", "000001.ldb"), 1<<10)
+
+	cfg := defaultSeiDB
+	cfg.StateCommit.Enable = true
+	_, err := manager.ApplySeiDB(cfg, "v5.9.0", false)
+	assert.ErrorContains(t, err, "IAVL state")
+	settings, err := manager.ReadPruning()
+	require.NoError(t, err)
+	assert.Equal(t, LayoutLegacy, settings.Layout)
+
+	_, err = manager.ApplySeiDB(cfg, "v5.9.0", true)
+	require.NoError(t, err)
+
+	// Once on SeiDB, later changes are not refused
+	cfg.StateStore.Enable = true
+	_, err = manager.ApplySeiDB(cfg, "v5.9.0", false)
+	require.NoError(t, err)
+}
+
+func TestParseVersion(t *testing.T) {
+	v, ok := parseVersion("v5.9.0-hotfix")
+	require.True(t, ok)
+	assert.Equal(t, [3]int{5, 9, 0}, v)
+
+	v, ok = parseVersion("seid version 3.6\n")
+	require.True(t, ok)
+	assert.Equal(t, [3]int{3, 6, 0}, v)
+
+	_, ok = parseVersion("latest")
+	assert.False(t, ok)
+	assert.Equal(t, -1, compareVersions([3]int{3, 5, 9}, [3]int{3, 6, 0}))
+}
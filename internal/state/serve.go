@@ -0,0 +1,402 @@
+package state
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	serveIndexPath = "/snapshots"
+	serveListPath  = "/list"
+	serveFilesPath = "/files/"
+)
+
+// errReadOnlyStorage is returned when writing to a snapshot server
+var errReadOnlyStorage = errors.New("snapshot server storage is read-only")
+
+// ServedSnapshot is a snapshot advertised by a snapshot server
+type ServedSnapshot struct {
+	Name string `json:"name"`
+	SnapshotManifest
+}
+
+// ServedIndex is the catalog returned by a snapshot server, newest first
+type ServedIndex struct {
+	ChainID   string           `json:"chain_id,omitempty"`
+	Snapshots []ServedSnapshot `json:"snapshots"`
+}
+
+// SnapshotServer exposes the local snapshot catalog over HTTP. Only complete
+// snapshots and the chunk repository are served; backups never leave the host.
+type SnapshotServer struct {
+	m     *Manager
+	token string
+	mux   *http.ServeMux
+}
+
+// NewSnapshotServer creates a handler serving the backup directory. An empty
+// token disables authentication.
+func (m *Manager) NewSnapshotServer(token string) *SnapshotServer {
+	s := &SnapshotServer{m: m, token: token, mux: http.NewServeMux()}
+	s.mux.HandleFunc(serveIndexPath, s.handleIndex)
+	s.mux.HandleFunc(serveListPath, s.handleList)
+	s.mux.HandleFunc(serveFilesPath, s.handleFile)
+	return s
+}
+
+// ServeSnapshots runs a snapshot server on listen until the context is cancelled
+func (m *Manager) ServeSnapshots(ctx context.Context, listen, token string) error {
+	srv := &http.Server{
+		Addr:              listen,
+		Handler:           m.NewSnapshotServer(token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	m.logger.Info().Str("listen", listen).Bool("auth", token != "").Msg("Serving snapshots")
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("snapshot server failed: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+func (s *SnapshotServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *SnapshotServer) handleIndex(w http.ResponseWriter, _ *http.Request) {
+	index, err := s.m.servedIndex()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(index)
+}
+
+func (s *SnapshotServer) handleList(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	dir, err := s.m.servedPath(strings.TrimSuffix(prefix, "/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	objects := []ObjectInfo{}
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !isServedFile(info.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.m.config.Global.BackupDir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(objects)
+}
+
+func (s *SnapshotServer) handleFile(w http.ResponseWriter, r *http.Request) {
+	p, err := s.m.servedPath(strings.TrimPrefix(r.URL.Path, serveFilesPath))
+	if err != nil || !isServedFile(filepath.Base(p)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	// ServeContent handles Range requests, so interrupted downloads resume
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// servedIndex lists complete snapshots, newest first
+func (m *Manager) servedIndex() (*ServedIndex, error) {
+	snapshots, err := m.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	index := &ServedIndex{ChainID: m.localChainID(), Snapshots: []ServedSnapshot{}}
+	for _, s := range snapshots {
+		if s.Integrity != IntegrityOK {
+			continue
+		}
+		manifest, err := readManifest(s.Path)
+		if err != nil {
+			continue
+		}
+		index.Snapshots = append(index.Snapshots, ServedSnapshot{
+			Name:             filepath.Base(s.Path),
+			SnapshotManifest: *manifest,
+		})
+	}
+
+	return index, nil
+}
+
+// servedPath maps a key to a path in the backup directory. Keys must lie in
+// a snapshot directory with a manifest or in the chunk repository.
+func (m *Manager) servedPath(key string) (string, error) {
+	if key == "" || path.Clean("/"+key) != "/"+key {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	top := strings.SplitN(key, "/", 2)[0]
+	dir := filepath.Join(m.config.Global.BackupDir, top)
+	switch {
+	case top == chunkStoreDirName:
+	case strings.HasPrefix(top, snapshotDirPrefix):
+		if _, ok := parseSnapshotDirName(top); !ok {
+			return "", fmt.Errorf("invalid key %q", key)
+		}
+		// Snapshots still being written have no manifest yet
+		if _, err := os.Stat(filepath.Join(dir, manifestFileName)); err != nil {
+			return "", fmt.Errorf("snapshot %s is not complete", top)
+		}
+	default:
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	return filepath.Join(m.config.Global.BackupDir, filepath.FromSlash(key)), nil
+}
+
+// isServedFile filters out lock files and partially written files
+func isServedFile(name string) bool {
+	return !strings.HasPrefix(name, ".") && !strings.HasSuffix(name, ".partial") && !strings.Contains(name, ".tmp")
+}
+
+// HTTPStorage is a read-only Storage backed by a snapshot server
+type HTTPStorage struct {
+	base   string
+	token  string
+	client *http.Client
+	m      *Manager
+}
+
+// NewHTTPStorage creates a client for the snapshot server at base
+func (m *Manager) NewHTTPStorage(base, token string) *HTTPStorage {
+	return &HTTPStorage{
+		base:   strings.TrimSuffix(base, "/"),
+		token:  token,
+		client: &http.Client{},
+		m:      m,
+	}
+}
+
+func (s *HTTPStorage) header() http.Header {
+	h := http.Header{}
+	if s.token != "" {
+		h.Set("Authorization", "Bearer "+s.token)
+	}
+	return h
+}
+
+func (s *HTTPStorage) do(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header = s.header()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", rawURL, os.ErrNotExist)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("snapshot server returned %s", resp.Status)
+	}
+}
+
+func (s *HTTPStorage) fileURL(key string) string {
+	return s.base + serveFilesPath + (&url.URL{Path: key}).EscapedPath()
+}
+
+// Index fetches the server's snapshot catalog
+func (s *HTTPStorage) Index(ctx context.Context) (*ServedIndex, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.base+serveIndexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch snapshot index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	index := &ServedIndex{}
+	if err := json.NewDecoder(resp.Body).Decode(index); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot index: %w", err)
+	}
+	return index, nil
+}
+
+func (s *HTTPStorage) Put(_ context.Context, _ string, _ io.Reader, _ int64) error {
+	return errReadOnlyStorage
+}
+
+// Get streams an object, resuming with Range requests if the connection drops
+func (s *HTTPStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetFrom(ctx, key, 0)
+}
+
+// GetFrom streams an object starting at offset
+func (s *HTTPStorage) GetFrom(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	body := &resumableBody{
+		ctx:        ctx,
+		client:     s.client,
+		url:        s.fileURL(key),
+		header:     s.header(),
+		offset:     offset,
+		maxRetries: s.m.config.Global.MaxRetries,
+		delay:      s.m.config.Global.GetRetryDelay(),
+		m:          s.m,
+	}
+	if err := body.open(); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (s *HTTPStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, s.fileURL(key))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &ObjectInfo{Key: key, Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+func (s *HTTPStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.base+serveListPath+"?prefix="+url.QueryEscape(prefix))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var objects []ObjectInfo
+	if err := json.NewDecoder(resp.Body).Decode(&objects); err != nil {
+		return nil, fmt.Errorf("failed to parse object list: %w", err)
+	}
+	return objects, nil
+}
+
+func (s *HTTPStorage) Delete(_ context.Context, _ string) error {
+	return errReadOnlyStorage
+}
+
+// RestoreFromServer downloads the newest snapshot matching the local chain
+// ID from a snapshot server and restores it
+func (m *Manager) RestoreFromServer(ctx context.Context, server, token string) error {
+	chainID := m.localChainID()
+	if chainID == "" {
+		return fmt.Errorf("cannot determine the local chain ID, initialise the node first")
+	}
+
+	storage := m.NewHTTPStorage(server, token)
+	index, err := storage.Index(ctx)
+	if err != nil {
+		return err
+	}
+
+	snapshot := newestCompatible(index, chainID)
+	if snapshot == nil {
+		return fmt.Errorf("server %s has no snapshot for chain %s", server, chainID)
+	}
+
+	m.logger.Info().
+		Str("server", server).
+		Int64("height", snapshot.Height).
+		Str("chain_id", chainID).
+		Msg("Selected snapshot")
+
+	name := strconv.FormatInt(snapshot.Height, 10)
+	if result, err := m.VerifySnapshotIntegrity(snapshot.Height); err == nil && result.OK {
+		m.logger.Info().Int64("height", snapshot.Height).Msg("Snapshot already present locally")
+	} else if err := m.PullSnapshot(ctx, storage, name); err != nil {
+		return fmt.Errorf("failed to download snapshot: %w", err)
+	}
+
+	return m.RestoreSnapshot(ctx, filepath.Join(m.config.Global.BackupDir, snapshotDirName(snapshot.Height)))
+}
+
+// newestCompatible returns the highest snapshot for chainID, if any
+func newestCompatible(index *ServedIndex, chainID string) *ServedSnapshot {
+	var best *ServedSnapshot
+	for i := range index.Snapshots {
+		s := &index.Snapshots[i]
+		if s.ChainID != chainID {
+			continue
+		}
+		if best == nil || s.Height > best.Height {
+			best = s
+		}
+	}
+	return best
+}
@@ -0,0 +1,115 @@
+package state
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveTestSnapshots(t *testing.T, m *Manager, token string) *httptest.Server {
+	srv := httptest.NewServer(m.NewSnapshotServer(token))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSnapshotServerAuth(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	srv := serveTestSnapshots(t, manager, "secret")
+
+	resp, err := http.Get(srv.URL + serveIndexPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, err = manager.NewHTTPStorage(srv.URL, "wrong").Index(context.Background())
+	assert.ErrorContains(t, err, "401")
+
+	index, err := manager.NewHTTPStorage(srv.URL, "secret").Index(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "test-1", index.ChainID)
+}
+
+func TestSnapshotServerFiles(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	writeTestSnapshot(t, manager, 100, map[string]string{"data_100.tar.gz": "0123456789"})
+	require.NoError(t, os.MkdirAll(filepath.Join(manager.config.Global.BackupDir, "snapshot_200"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(manager.config.Global.BackupDir, "backup_20240101_000000"), 0755))
+	srv := serveTestSnapshots(t, manager, "")
+
+	index, err := manager.NewHTTPStorage(srv.URL, "").Index(context.Background())
+	require.NoError(t, err)
+	require.Len(t, index.Snapshots, 1, "incomplete snapshots are not advertised")
+	assert.Equal(t, int64(100), index.Snapshots[0].Height)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/files/snapshot_100/data_100.tar.gz", nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=4-")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "456789", string(body))
+
+	for _, p := range []string{
+		"/files/snapshot_200/manifest.json",
+		"/files/backup_20240101_000000/data",
+		"/files/snapshot_100/../backup_20240101_000000",
+		"/files/chunks/.lock",
+	} {
+		resp, err := http.Get(srv.URL + p)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, p)
+	}
+}
+
+func TestRestoreFromServer(t *testing.T) {
+	ctx := context.Background()
+
+	source, _ := setupTestManager(t)
+	writeTarSnapshot(t, source, 100, map[string][]byte{"data/state.db": []byte("old snapshot")})
+	writeTarSnapshot(t, source, 200, map[string][]byte{"data/state.db": []byte("new snapshot")})
+	// A newer snapshot of another chain must be ignored
+	other := writeTestSnapshot(t, source, 300, map[string]string{"data_300.tar.gz": "x"})
+	manifest, err := readManifest(other)
+	require.NoError(t, err)
+	manifest.ChainID = "other-1"
+	require.NoError(t, saveManifest(other, manifest))
+
+	srv := serveTestSnapshots(t, source, "secret")
+
+	target, _ := setupTestManager(t)
+	require.NoError(t, target.RestoreFromServer(ctx, srv.URL, "secret"))
+	assertFileContent(t, filepath.Join(target.config.Global.HomeDir, "data", "state.db"), "new snapshot")
+
+	result, err := target.VerifySnapshotIntegrity(200)
+	require.NoError(t, err)
+	assert.True(t, result.OK)
+}
+
+func TestRestoreFromServerIncremental(t *testing.T) {
+	ctx := context.Background()
+
+	source, _ := setupTestManager(t)
+	writeNodeData(t, source.config.Global.HomeDir, map[string][]byte{"data/state.db": randomBytes(6, 1<<20)})
+	require.NoError(t, source.CreateIncrementalSnapshot(ctx, 100))
+	srv := serveTestSnapshots(t, source, "")
+
+	target, _ := setupTestManager(t)
+	require.NoError(t, target.RestoreFromServer(ctx, srv.URL, ""))
+
+	want, err := os.ReadFile(filepath.Join(source.config.Global.HomeDir, "data", "state.db"))
+	require.NoError(t, err)
+	got, err := os.ReadFile(filepath.Join(target.config.Global.HomeDir, "data", "state.db"))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
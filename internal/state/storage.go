@@ -0,0 +1,527 @@
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-org/seictl/internal/utils"
+)
+
+// Storage backend types
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+// ObjectInfo describes an object held by a storage backend
+type ObjectInfo struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Storage is a location snapshots and backups can be copied to and from.
+// Keys are slash separated regardless of the backend.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// rangeStorage is implemented by backends that can read an object from an
+// offset, so an interrupted download continues from its .partial file
+type rangeStorage interface {
+	GetFrom(ctx context.Context, key string, offset int64) (io.ReadCloser, error)
+}
+
+// NewStorage creates the storage backend selected in the configuration
+func (m *Manager) NewStorage() (Storage, error) {
+	cfg := m.config.Snapshots.Storage
+
+	switch cfg.Type {
+	case StorageLocal:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("storage path is required for the local backend")
+		}
+		return NewLocalStorage(cfg.Path), nil
+	case StorageS3:
+		return NewS3Storage(cfg)
+	case "":
+		return nil, fmt.Errorf("no storage backend configured")
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", cfg.Type)
+	}
+}
+
+// PushSnapshot uploads a snapshot or backup directory from the backup
+// directory. name is a snapshot height or a directory name. The snapshot
+// manifest is uploaded last so a partial push is never mistaken for a
+// complete snapshot.
+func (m *Manager) PushSnapshot(ctx context.Context, storage Storage, name string) error {
+	dirName, err := resolveBackupEntry(name)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(m.config.Global.BackupDir, dirName)
+
+	var files []string
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dirName, err)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i] != manifestFileName && files[j] == manifestFileName
+	})
+
+	// Chunks go first so an uploaded index never references missing chunks
+	if isIncrementalSnapshot(dir) {
+		if err := m.pushChunks(ctx, storage, dir); err != nil {
+			return err
+		}
+	}
+
+	for _, rel := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		key := path.Join(dirName, rel)
+		if err := putFile(ctx, storage, key, filepath.Join(dir, filepath.FromSlash(rel))); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		m.logger.Info().Str("key", key).Msg("Uploaded")
+	}
+
+	m.logger.Info().Str("name", dirName).Int("files", len(files)).Msg("Push completed")
+	return nil
+}
+
+// PullSnapshot downloads a snapshot or backup into the backup directory.
+// Snapshot archives are verified against the manifest while downloading.
+func (m *Manager) PullSnapshot(ctx context.Context, storage Storage, name string) error {
+	dirName, err := resolveBackupEntry(name)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(m.config.Global.BackupDir, dirName)
+
+	objects, err := storage.List(ctx, dirName+"/")
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dirName, err)
+	}
+	if len(objects) == 0 {
+		return fmt.Errorf("%s not found in storage", dirName)
+	}
+
+	// Fetch the manifest first so archives can be checked as they arrive
+	checksums := make(map[string]string)
+	incremental := false
+	manifestKey := path.Join(dirName, manifestFileName)
+	for _, obj := range objects {
+		if obj.Key != manifestKey {
+			continue
+		}
+		if err := getFile(ctx, storage, obj.Key, filepath.Join(dir, manifestFileName), ""); err != nil {
+			return fmt.Errorf("failed to download manifest: %w", err)
+		}
+		manifest, err := readManifest(dir)
+		if err != nil {
+			return err
+		}
+		for _, a := range manifest.Archives {
+			checksums[a.Name] = a.SHA256
+		}
+		incremental = manifest.Type == SnapshotTypeIncremental
+	}
+
+	for _, obj := range objects {
+		if obj.Key == manifestKey {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel := strings.TrimPrefix(obj.Key, dirName+"/")
+		if path.Clean("/"+rel) != "/"+rel {
+			return fmt.Errorf("refusing to download unsafe key %q", obj.Key)
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := getFile(ctx, storage, obj.Key, dest, checksums[rel]); err != nil {
+			return fmt.Errorf("failed to download %s: %w", obj.Key, err)
+		}
+		m.logger.Info().Str("key", obj.Key).Msg("Downloaded")
+	}
+
+	if incremental {
+		if err := m.pullChunks(ctx, storage, dir); err != nil {
+			return err
+		}
+	}
+
+	m.logger.Info().Str("name", dirName).Str("path", dir).Msg("Pull completed")
+	return nil
+}
+
+// pushChunks uploads the chunks referenced by an incremental snapshot that
+// the storage backend does not have yet
+func (m *Manager) pushChunks(ctx context.Context, storage Storage, snapshotDir string) error {
+	index, err := readIndex(snapshotDir)
+	if err != nil {
+		return err
+	}
+
+	store, err := openChunkStore(m.chunkStoreDir())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	uploaded := 0
+	for _, hash := range indexChunks(index) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		key := chunkKey(hash)
+		if _, err := storage.Stat(ctx, key); err == nil {
+			continue
+		}
+		if err := putFile(ctx, storage, key, store.path(hash)); err != nil {
+			return fmt.Errorf("failed to upload chunk %s: %w", hash, err)
+		}
+		uploaded++
+	}
+
+	m.logger.Info().Int("chunks", uploaded).Msg("Uploaded new chunks")
+	return nil
+}
+
+// pullChunks downloads the chunks referenced by an incremental snapshot that
+// are missing from the local repository, verifying each one
+func (m *Manager) pullChunks(ctx context.Context, storage Storage, snapshotDir string) error {
+	index, err := readIndex(snapshotDir)
+	if err != nil {
+		return err
+	}
+
+	store, err := openChunkStore(m.chunkStoreDir())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	downloaded := 0
+	for _, hash := range indexChunks(index) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if store.has(hash) {
+			continue
+		}
+
+		r, err := storage.Get(ctx, chunkKey(hash))
+		if err != nil {
+			return fmt.Errorf("failed to download chunk %s: %w", hash, err)
+		}
+		compressed, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("failed to download chunk %s: %w", hash, err)
+		}
+
+		if err := store.writeRaw(hash, compressed); err != nil {
+			return err
+		}
+		if _, err := store.get(hash); err != nil {
+			os.Remove(store.path(hash))
+			return err
+		}
+		downloaded++
+	}
+
+	m.logger.Info().Int("chunks", downloaded).Msg("Downloaded missing chunks")
+	return nil
+}
+
+func chunkKey(hash string) string {
+	return path.Join(chunkStoreDirName, hash[:2], hash)
+}
+
+// indexChunks returns the distinct chunks referenced by an index
+func indexChunks(index *snapshotIndex) []string {
+	seen := make(map[string]bool)
+	var hashes []string
+	for _, entry := range index.Entries {
+		for _, hash := range entry.Chunks {
+			if !seen[hash] {
+				seen[hash] = true
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	return hashes
+}
+
+// resolveBackupEntry maps a height or directory name to a backup directory name
+func resolveBackupEntry(name string) (string, error) {
+	if _, ok := parseSnapshotDirName(snapshotDirPrefix + name); ok {
+		return snapshotDirPrefix + name, nil
+	}
+	if strings.HasPrefix(name, snapshotDirPrefix) || strings.HasPrefix(name, backupDirPrefix) {
+		if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			return "", fmt.Errorf("invalid name: %s", name)
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("expected a snapshot height or a %s/%s directory name, got %q",
+		snapshotDirPrefix, backupDirPrefix, name)
+}
+
+func putFile(ctx context.Context, storage Storage, key, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return storage.Put(ctx, key, f, info.Size())
+}
+
+// getFile downloads key to dest through a temporary file, checking the
+// SHA256 checksum when one is given. A .partial file left by an interrupted
+// download is continued when the backend supports ranged reads.
+func getFile(ctx context.Context, storage Storage, key, dest, checksum string) error {
+	var r io.ReadCloser
+	offset := partialSize(dest)
+	if rs, ok := storage.(rangeStorage); ok && offset > 0 {
+		// On failure, such as an object that changed size, start over
+		r, _ = rs.GetFrom(ctx, key, offset)
+	}
+	if r == nil {
+		var err error
+		offset = 0
+		if r, err = storage.Get(ctx, key); err != nil {
+			return err
+		}
+	}
+	defer r.Close()
+
+	return writeVerified(r, dest, checksum, offset)
+}
+
+// partialSize returns the size of the .partial file an interrupted download
+// of dest left behind, or 0
+func partialSize(dest string) int64 {
+	info, err := os.Stat(dest + ".partial")
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+	return info.Size()
+}
+
+// writeVerified writes r to dest through a .partial file that only replaces
+// dest once the optional checksum matches. With offset set, r continues the
+// existing .partial file from that byte. The .partial file is kept when the
+// transfer fails so the next attempt can resume it.
+func writeVerified(r io.Reader, dest, checksum string, offset int64) error {
+	if err := utils.EnsureDir(filepath.Dir(dest)); err != nil {
+		return err
+	}
+
+	tmp := dest + ".partial"
+	h := sha256.New()
+	var out *os.File
+	var err error
+	if offset > 0 {
+		out, err = os.OpenFile(tmp, os.O_RDWR, 0644)
+		if err != nil {
+			return err
+		}
+		// The checksum covers the bytes downloaded earlier too
+		n, err := io.Copy(h, out)
+		if err == nil && n != offset {
+			err = fmt.Errorf("partial download changed size")
+		}
+		if err != nil {
+			out.Close()
+			return fmt.Errorf("failed to read partial download: %w", err)
+		}
+	} else if out, err = os.Create(tmp); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(io.MultiWriter(out, h), r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	if checksum != "" {
+		if sum := hex.EncodeToString(h.Sum(nil)); sum != checksum {
+			os.Remove(tmp)
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", checksum, sum)
+		}
+	}
+
+	return os.Rename(tmp, dest)
+}
+
+// LocalStorage stores objects as files below a root directory
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates a filesystem storage backend
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{root: root}
+}
+
+// Put implements Storage
+func (s *LocalStorage) Put(_ context.Context, key string, r io.Reader, _ int64) error {
+	dest, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := utils.EnsureDir(filepath.Dir(dest)); err != nil {
+		return err
+	}
+
+	tmp := dest + ".partial"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, dest)
+}
+
+// Get implements Storage
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetFrom(ctx, key, 0)
+}
+
+// GetFrom reads an object starting at offset
+func (s *LocalStorage) GetFrom(_ context.Context, key string, offset int64) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		info, err := f.Stat()
+		if err == nil && offset > info.Size() {
+			err = fmt.Errorf("offset %d is beyond the end of %s", offset, key)
+		}
+		if err == nil {
+			_, err = f.Seek(offset, io.SeekStart)
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Stat implements Storage
+func (s *LocalStorage) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// List implements Storage
+func (s *LocalStorage) List(_ context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.Walk(s.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(p, ".partial") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		}
+		return nil
+	})
+	return objects, err
+}
+
+// Delete implements Storage
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid key: %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,570 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/your-org/seictl/internal/utils"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+const (
+	defaultS3Region   = "us-east-1"
+	defaultS3PartSize = 64 << 20
+	minS3PartSize     = 5 << 20
+	s3TimeFormat      = "20060102T150405Z"
+	s3DateFormat      = "20060102"
+)
+
+// maxS3Parts is the most parts S3 accepts in one multipart upload
+var maxS3Parts = 10000
+
+// S3Storage stores objects in an S3-compatible bucket. Requests are signed
+// with AWS Signature Version 4 and objects larger than the part size are
+// uploaded with multipart uploads.
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	partSize  int64
+	// parts recycles part buffers across multipart uploads
+	parts  sync.Pool
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Storage creates an S3-compatible storage backend
+func NewS3Storage(cfg types.StorageConfig) (*S3Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 storage requires an endpoint and a bucket")
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %s", cfg.Endpoint)
+	}
+
+	partSize := int64(defaultS3PartSize)
+	if cfg.PartSize != "" {
+		partSize, err = utils.ParseSize(cfg.PartSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid part size: %w", err)
+		}
+		if partSize < minS3PartSize {
+			return nil, fmt.Errorf("part size must be at least 5MiB")
+		}
+	}
+
+	s := &S3Storage{
+		endpoint:  endpoint,
+		region:    cfg.Region,
+		bucket:    cfg.Bucket,
+		prefix:    strings.Trim(cfg.Prefix, "/"),
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		pathStyle: cfg.PathStyle,
+		partSize:  partSize,
+		client:    &http.Client{},
+		now:       time.Now,
+	}
+	s.parts.New = func() interface{} {
+		buf := make([]byte, partSize)
+		return &buf
+	}
+	if s.region == "" {
+		s.region = defaultS3Region
+	}
+	if s.accessKey == "" {
+		s.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if s.secretKey == "" {
+		s.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+
+	return s, nil
+}
+
+// Put implements Storage. Objects smaller than the part size are read into a
+// buffer of their own size; a negative size means unknown. A known size too
+// large for maxS3Parts parts raises the part size for this upload.
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if size >= 0 && size < s.partSize {
+		// The extra byte detects a reader longer than size
+		buf := make([]byte, size+1)
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return s.putObject(ctx, key, buf[:n])
+		}
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%s is longer than its declared size of %d bytes", key, size)
+	}
+
+	var buf []byte
+	if partSize := (size + int64(maxS3Parts) - 1) / int64(maxS3Parts); partSize > s.partSize {
+		buf = make([]byte, partSize)
+	} else {
+		pooled := s.parts.Get().(*[]byte)
+		defer s.parts.Put(pooled)
+		buf = *pooled
+	}
+
+	// Metadata is fixed when the upload is created, so the checksum needs a
+	// reader that can be read twice
+	checksum, err := seekableSHA256(r)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", key, err)
+	}
+
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s.putObject(ctx, key, buf[:n])
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.putMultipart(ctx, key, checksum, buf, r)
+}
+
+// seekableSHA256 hashes the rest of r and rewinds it. It returns an empty
+// checksum when r cannot seek, e.g. a pipe.
+func seekableSHA256(r io.Reader) (string, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return "", nil
+	}
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Get implements Storage
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetFrom reads an object starting at offset with a Range request
+func (s *S3Storage) GetFrom(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	headers := http.Header{}
+	headers.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	resp, err := s.do(ctx, http.MethodGet, key, nil, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("range request for %s returned %s", key, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Stat implements Storage
+func (s *S3Storage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &ObjectInfo{Key: key, Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+// List implements Storage
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {s.fullKey(prefix)},
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode list response: %w", err)
+		}
+
+		for _, c := range result.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:     s.relativeKey(c.Key),
+				Size:    c.Size,
+				ModTime: c.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Delete implements Storage
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) putObject(ctx context.Context, key string, body []byte) error {
+	sum := sha256.Sum256(body)
+	headers := http.Header{}
+	headers.Set("Content-MD5", contentMD5(body))
+	headers.Set("X-Amz-Meta-Sha256", hex.EncodeToString(sum[:]))
+
+	resp, err := s.do(ctx, http.MethodPut, key, nil, headers, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3Storage) putMultipart(ctx context.Context, key, checksum string, first []byte, rest io.Reader) error {
+	uploadID, err := s.createMultipartUpload(ctx, key, checksum)
+	if err != nil {
+		return err
+	}
+
+	parts, err := s.uploadParts(ctx, key, uploadID, first, rest)
+	if err == nil {
+		err = s.completeMultipartUpload(ctx, key, uploadID, parts)
+	}
+	if err != nil {
+		// Abort so the bucket does not accumulate orphaned parts
+		if resp, abortErr := s.do(context.Background(), http.MethodDelete, key,
+			url.Values{"uploadId": {uploadID}}, nil, nil); abortErr == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *S3Storage) createMultipartUpload(ctx context.Context, key, checksum string) (string, error) {
+	headers := http.Header{}
+	if checksum != "" {
+		headers.Set("X-Amz-Meta-Sha256", checksum)
+	}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, headers, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode multipart upload response: %w", err)
+	}
+	if result.UploadID == "" {
+		return "", fmt.Errorf("no upload ID in multipart upload response")
+	}
+
+	return result.UploadID, nil
+}
+
+func (s *S3Storage) uploadParts(ctx context.Context, key, uploadID string, first []byte, rest io.Reader) ([]s3CompletedPart, error) {
+	var parts []s3CompletedPart
+	buf := first
+
+	for partNumber := 1; ; partNumber++ {
+		if partNumber > maxS3Parts {
+			return nil, fmt.Errorf("%s needs more than %d parts of %d bytes, raise the part size", key, maxS3Parts, len(first))
+		}
+		headers := http.Header{}
+		headers.Set("Content-MD5", contentMD5(buf))
+		query := url.Values{
+			"partNumber": {strconv.Itoa(partNumber)},
+			"uploadId":   {uploadID},
+		}
+
+		resp, err := s.do(ctx, http.MethodPut, key, query, headers, buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+		}
+		resp.Body.Close()
+		parts = append(parts, s3CompletedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+
+		buf = first[:cap(first)]
+		n, err := io.ReadFull(rest, buf)
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		buf = buf[:n]
+	}
+}
+
+func (s *S3Storage) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []s3CompletedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// S3 may report a failure inside a 200 response
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, []byte("<Error>")) {
+		return fmt.Errorf("failed to complete multipart upload: %s", string(data))
+	}
+
+	return nil
+}
+
+// do sends a signed request and returns the response for 2xx status codes
+func (s *S3Storage) do(ctx context.Context, method, key string, query url.Values, headers http.Header, body []byte) (*http.Response, error) {
+	u := s.objectURL(key)
+	if query != nil {
+		u.RawQuery = encodeS3Query(query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 request failed: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s not found: %w", key, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("s3 %s %s failed: %s: %s", method, key, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	return resp, nil
+}
+
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	objectPath := ""
+	if key != "" {
+		objectPath = "/" + s.fullKey(key)
+	}
+
+	if s.pathStyle {
+		u.Path = "/" + s.bucket + objectPath
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = objectPath
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawPath = s3EscapePath(u.Path)
+
+	return &u
+}
+
+func (s *S3Storage) fullKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+func (s *S3Storage) relativeKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, s.prefix+"/")
+}
+
+// sign adds AWS Signature Version 4 headers to the request
+func (s *S3Storage) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format(s3TimeFormat)
+	date := now.Format(s3DateFormat)
+	payloadHash := sha256.Sum256(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	if s.accessKey == "" {
+		// Anonymous access to public buckets
+		return
+	}
+
+	signedNames := []string{"host"}
+	canonicalHeaders := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-md5" || strings.HasPrefix(lower, "x-amz-") {
+			signedNames = append(signedNames, lower)
+			canonicalHeaders[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	sort.Strings(signedNames)
+
+	var headerBlock strings.Builder
+	for _, name := range signedNames {
+		headerBlock.WriteString(name + ":" + canonicalHeaders[name] + "\n")
+	}
+	signedHeaders := strings.Join(signedNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headerBlock.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func contentMD5(body []byte) string {
+	sum := md5.Sum(body)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// encodeS3Query encodes query parameters sorted by key with RFC 3986
+// escaping, which is also the canonical form required for signing
+func encodeS3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func s3EscapePath(p string) string {
+	return s3Escape(p, false)
+}
+
+// s3Escape percent-encodes everything except RFC 3986 unreserved characters,
+// and slashes unless escapeSlash is set
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
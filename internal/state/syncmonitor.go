@@ -0,0 +1,574 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// SyncPhase is the stage a state syncing node is in
+type SyncPhase string
+
+const (
+	PhaseStarting     SyncPhase = "starting"
+	PhaseDiscovering  SyncPhase = "discovering"
+	PhaseRestoring    SyncPhase = "restoring"
+	PhaseBlockSyncing SyncPhase = "blocksyncing"
+	PhaseCaughtUp     SyncPhase = "caught_up"
+)
+
+const (
+	nodeLogFile            = "seid.log"
+	defaultMonitorInterval = 5 * time.Second
+	defaultStallTimeout    = 10 * time.Minute
+	monitorReportInterval  = 30 * time.Second
+	maxLogLine             = 64 * 1024
+	caughtUpLag            = 5
+)
+
+// SyncProgress is a snapshot of state sync progress
+type SyncProgress struct {
+	Phase          SyncPhase `json:"phase"`
+	SnapshotsFound int       `json:"snapshots_found"`
+	SnapshotHeight int64     `json:"snapshot_height,omitempty"`
+	ChunksApplied  int       `json:"chunks_applied"`
+	ChunksTotal    int       `json:"chunks_total,omitempty"`
+	NodeHeight     int64     `json:"node_height,omitempty"`
+	TargetHeight   int64     `json:"target_height,omitempty"`
+	ETA            Seconds   `json:"eta_seconds,omitempty"`
+	LastProgress   time.Time `json:"last_progress"`
+	Stalled        bool      `json:"stalled"`
+	LastError      string    `json:"last_error,omitempty"`
+	Advice         []string  `json:"advice,omitempty"`
+	RejectedCount  int       `json:"rejected_snapshots,omitempty"`
+}
+
+// Summary renders the progress as a single human readable line
+func (p SyncProgress) Summary() string {
+	var s string
+	switch p.Phase {
+	case PhaseDiscovering:
+		s = fmt.Sprintf("discovering snapshots (%d found)", p.SnapshotsFound)
+	case PhaseRestoring:
+		s = fmt.Sprintf("restoring snapshot %d: %d/%d chunks", p.SnapshotHeight, p.ChunksApplied, p.ChunksTotal)
+		if p.ChunksTotal > 0 {
+			s += fmt.Sprintf(" (%d%%)", p.ChunksApplied*100/p.ChunksTotal)
+		}
+	case PhaseBlockSyncing:
+		s = fmt.Sprintf("blocksyncing at height %d", p.NodeHeight)
+		if p.TargetHeight > 0 {
+			s += fmt.Sprintf(" of %d", p.TargetHeight)
+		}
+	case PhaseCaughtUp:
+		return fmt.Sprintf("caught up at height %d", p.NodeHeight)
+	default:
+		s = "waiting for the node to start"
+	}
+	if p.ETA > 0 {
+		s += ", ETA " + p.ETA.Duration().Round(time.Second).String()
+	}
+	if p.Stalled {
+		s += fmt.Sprintf(", STALLED for %s", time.Since(p.LastProgress).Round(time.Second))
+	}
+	return s
+}
+
+// MonitorOptions configures MonitorStateSync
+type MonitorOptions struct {
+	// Env selects the environment whose local RPC port is used
+	Env types.Environment
+	// RPC overrides the local node's RPC address
+	RPC string
+	// LogPath is the seid log to tail; defaults to seid.log in the home directory
+	LogPath string
+	// FromStart reads the log from the beginning instead of only new lines
+	FromStart bool
+	// Interval between status polls
+	Interval time.Duration
+	// StallTimeout is how long without progress before a stall is reported
+	StallTimeout time.Duration
+	// Progress, when set, receives every update instead of the default log output
+	Progress func(SyncProgress)
+}
+
+// MonitorStateSync follows a state syncing local node until it has caught
+// up. Progress comes from the node's log (snapshot discovery, chunk fetch and
+// apply events) and its local RPC status; the chain tip is taken from the
+// environment's RPC pool to estimate the remaining blocksync time.
+func (m *Manager) MonitorStateSync(ctx context.Context, opts MonitorOptions) error {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultMonitorInterval
+	}
+	if opts.StallTimeout <= 0 {
+		opts.StallTimeout = defaultStallTimeout
+	}
+	if opts.LogPath == "" {
+		opts.LogPath = filepath.Join(m.config.Global.HomeDir, nodeLogFile)
+	}
+	if opts.RPC == "" {
+		opts.RPC = m.localRPCFor(opts.Env)
+	}
+	report := opts.Progress
+	if report == nil {
+		report = m.logSyncProgress()
+	}
+
+	tail := &logTail{path: opts.LogPath}
+	if !opts.FromStart {
+		tail.skipExisting()
+	}
+	tracker := newSyncTracker(opts.StallTimeout, time.Now())
+
+	m.logger.Info().Str("rpc", opts.RPC).Str("log", opts.LogPath).Msg("Monitoring state sync")
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		for _, line := range tail.readLines() {
+			tracker.feed(line, now)
+		}
+
+		if status, err := m.queryStatus(ctx, opts.RPC); err == nil {
+			target := int64(0)
+			if tracker.progress.Phase == PhaseBlockSyncing || !status.Syncing {
+				if remote, err := m.getNodeStatus(ctx, m.localEnv(opts.Env)); err == nil {
+					target = remote.LatestHeight
+				}
+			}
+			tracker.status(status, target, now)
+		} else {
+			m.logger.Debug().Err(err).Msg("Local node RPC not reachable yet")
+		}
+		tracker.checkStall(now)
+
+		report(tracker.progress)
+		if tracker.progress.Phase == PhaseCaughtUp {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// localRPCFor returns the local RPC address for env, or for the environment
+// matching the local chain ID when env is empty
+func (m *Manager) localRPCFor(env types.Environment) string {
+	return m.LocalRPCEndpoint(m.localEnv(env))
+}
+
+// localEnv returns env, or the environment matching the local chain ID when
+// env is empty
+func (m *Manager) localEnv(env types.Environment) types.Environment {
+	if env == "" {
+		if chainID := m.localChainID(); chainID != "" {
+			for name, cfg := range m.config.Environments {
+				if cfg.ChainID == chainID {
+					return types.Environment(name)
+				}
+			}
+		}
+	}
+	return env
+}
+
+// logSyncProgress logs progress on phase changes, stalls and otherwise every
+// monitorReportInterval
+func (m *Manager) logSyncProgress() func(SyncProgress) {
+	var lastPhase SyncPhase
+	var lastReport time.Time
+	var stallReported bool
+	return func(p SyncProgress) {
+		if p.Stalled && !stallReported {
+			event := m.logger.Warn().Str("phase", string(p.Phase))
+			if p.LastError != "" {
+				event = event.Str("last_error", p.LastError)
+			}
+			event.Msg("State sync stalled: " + p.Summary())
+			for _, advice := range p.Advice {
+				m.logger.Warn().Msg("  - " + advice)
+			}
+		}
+		stallReported = p.Stalled
+
+		if p.Phase != lastPhase || time.Since(lastReport) >= monitorReportInterval {
+			m.logger.Info().Str("phase", string(p.Phase)).Msg(p.Summary())
+			lastPhase = p.Phase
+			lastReport = time.Now()
+		}
+	}
+}
+
+// syncTracker turns log events and status polls into SyncProgress
+type syncTracker struct {
+	progress     SyncProgress
+	stallTimeout time.Duration
+	snapshots    map[string]bool
+
+	restoreStarted  time.Time
+	blockSyncStart  time.Time
+	blockSyncHeight int64
+	blockSyncTarget int64
+}
+
+func newSyncTracker(stallTimeout time.Duration, now time.Time) *syncTracker {
+	return &syncTracker{
+		progress:     SyncProgress{Phase: PhaseStarting, LastProgress: now},
+		stallTimeout: stallTimeout,
+		snapshots:    make(map[string]bool),
+	}
+}
+
+func (t *syncTracker) advance(now time.Time) {
+	t.progress.LastProgress = now
+	t.progress.Stalled = false
+	t.progress.Advice = nil
+}
+
+func (t *syncTracker) setPhase(phase SyncPhase, now time.Time) {
+	if t.progress.Phase == PhaseCaughtUp {
+		return
+	}
+	if t.progress.Phase != phase {
+		t.progress.Phase = phase
+		t.progress.ETA = 0
+		t.advance(now)
+	}
+}
+
+// feed applies one line of the node's log
+func (t *syncTracker) feed(line string, now time.Time) {
+	msg, fields := parseLogLine(line)
+	if msg == "" {
+		return
+	}
+	lower := strings.ToLower(msg)
+	p := &t.progress
+
+	switch {
+	case strings.Contains(lower, "discovered new snapshot"), strings.Contains(lower, "discovered snapshot"):
+		if t.progress.Phase == PhaseStarting {
+			t.setPhase(PhaseDiscovering, now)
+		}
+		key := fields["height"] + "/" + fields["format"] + "/" + fields["hash"]
+		if !t.snapshots[key] {
+			t.snapshots[key] = true
+			p.SnapshotsFound++
+			t.advance(now)
+		}
+
+	case strings.Contains(lower, "snapshot accepted"), strings.Contains(lower, "restoring snapshot"):
+		t.setPhase(PhaseRestoring, now)
+		p.SnapshotHeight = fieldInt(fields, "height")
+		p.ChunksApplied = 0
+		p.ChunksTotal = int(fieldInt(fields, "chunks", "total"))
+		t.restoreStarted = now
+		t.advance(now)
+
+	case strings.Contains(lower, "applied snapshot chunk"):
+		t.setPhase(PhaseRestoring, now)
+		if h := fieldInt(fields, "height"); h > 0 {
+			p.SnapshotHeight = h
+		}
+		if total := fieldInt(fields, "total"); total > 0 {
+			p.ChunksTotal = int(total)
+		}
+		p.ChunksApplied++
+		if chunk, ok := fields["chunk"]; ok {
+			if n, err := strconv.Atoi(chunk); err == nil && n+1 > p.ChunksApplied {
+				p.ChunksApplied = n + 1
+			}
+		}
+		if t.restoreStarted.IsZero() {
+			t.restoreStarted = now
+		}
+		t.estimateRestore(now)
+		t.advance(now)
+
+	case strings.Contains(lower, "fetching snapshot chunk"):
+		if total := fieldInt(fields, "total"); total > 0 {
+			p.ChunksTotal = int(total)
+		}
+
+	case strings.Contains(lower, "snapshot restored"), strings.Contains(lower, "verified abci app"),
+		strings.Contains(lower, "switching to blocksync"), strings.Contains(lower, "switching to block sync"),
+		strings.Contains(lower, "switching to fast sync"):
+		if p.ChunksTotal > 0 {
+			p.ChunksApplied = p.ChunksTotal
+		}
+		t.setPhase(PhaseBlockSyncing, now)
+
+	case strings.Contains(lower, "rejected snapshot"), strings.Contains(lower, "snapshot rejected"):
+		p.RejectedCount++
+		p.LastError = msg + formatFields(fields, "height", "err")
+
+	case fields["module"] == "statesync" && (strings.Contains(lower, "fail") || strings.Contains(lower, "error")):
+		p.LastError = msg + formatFields(fields, "err", "error")
+	}
+}
+
+// status applies a poll of the local node's RPC status. target is the chain
+// tip according to the remote endpoints, zero when unknown.
+func (t *syncTracker) status(s *NodeStatus, target int64, now time.Time) {
+	p := &t.progress
+	if target > 0 {
+		p.TargetHeight = target
+	}
+	if s.LatestHeight > p.NodeHeight {
+		if p.NodeHeight > 0 || p.Phase == PhaseBlockSyncing {
+			t.advance(now)
+		}
+		p.NodeHeight = s.LatestHeight
+	}
+
+	if s.LatestHeight > 0 && p.Phase != PhaseBlockSyncing && p.Phase != PhaseCaughtUp {
+		// The node only reports a height once the snapshot is restored
+		if p.Phase == PhaseRestoring || p.SnapshotHeight > 0 {
+			t.setPhase(PhaseBlockSyncing, now)
+		}
+	}
+
+	if !s.Syncing && s.LatestHeight > 0 &&
+		(p.TargetHeight == 0 || p.TargetHeight-s.LatestHeight <= caughtUpLag) {
+		p.Phase = PhaseCaughtUp
+		p.ETA = 0
+		p.Stalled = false
+		p.Advice = nil
+		return
+	}
+
+	if p.Phase == PhaseBlockSyncing {
+		t.estimateBlockSync(now)
+	}
+}
+
+func (t *syncTracker) estimateRestore(now time.Time) {
+	p := &t.progress
+	elapsed := now.Sub(t.restoreStarted)
+	if p.ChunksTotal == 0 || p.ChunksApplied == 0 || elapsed <= 0 {
+		return
+	}
+	perChunk := elapsed / time.Duration(p.ChunksApplied)
+	p.ETA = Seconds(perChunk * time.Duration(p.ChunksTotal-p.ChunksApplied))
+}
+
+func (t *syncTracker) estimateBlockSync(now time.Time) {
+	p := &t.progress
+	if t.blockSyncStart.IsZero() {
+		t.blockSyncStart, t.blockSyncHeight, t.blockSyncTarget = now, p.NodeHeight, p.TargetHeight
+		return
+	}
+	synced := p.NodeHeight - t.blockSyncHeight
+	elapsed := now.Sub(t.blockSyncStart)
+	if synced <= 0 || elapsed <= 0 || p.TargetHeight <= p.NodeHeight {
+		p.ETA = 0
+		return
+	}
+	// The chain keeps growing while we sync, so the gap closes at the sync
+	// rate minus the chain's block rate
+	rate := float64(synced-(p.TargetHeight-t.blockSyncTarget)) / elapsed.Seconds()
+	if rate <= 0 {
+		p.ETA = 0
+		return
+	}
+	p.ETA = Seconds(float64(p.TargetHeight-p.NodeHeight) / rate * float64(time.Second))
+}
+
+// checkStall flags a lack of progress and attaches advice for the phase
+func (t *syncTracker) checkStall(now time.Time) {
+	p := &t.progress
+	if p.Phase == PhaseCaughtUp || now.Sub(p.LastProgress) < t.stallTimeout {
+		return
+	}
+	p.Stalled = true
+	p.Advice = stallAdvice(p)
+}
+
+func stallAdvice(p *SyncProgress) []string {
+	var advice []string
+	lastErr := strings.ToLower(p.LastError)
+
+	switch p.Phase {
+	case PhaseStarting:
+		advice = append(advice,
+			"the node has not logged any state sync activity; check that seid is running and that [statesync] enable = true in config.toml")
+	case PhaseDiscovering:
+		if p.SnapshotsFound == 0 {
+			advice = append(advice,
+				"no snapshots were discovered: add peers that serve snapshots to persistent_peers or seeds in config.toml",
+				"peers may have pruned snapshots at the trust height: rerun state sync to choose a newer trust height")
+		} else {
+			advice = append(advice,
+				"snapshots were found but none was accepted: choose a newer trust height close to a recent snapshot interval")
+		}
+	case PhaseRestoring:
+		advice = append(advice,
+			"chunk fetches are not progressing: add peers serving this snapshot, or raise chunk_fetchers and chunk_request_timeout in [statesync]")
+	case PhaseBlockSyncing:
+		advice = append(advice,
+			"the node stopped gaining height: add persistent peers and check that disk and CPU keep up")
+	}
+
+	if p.RejectedCount > 0 || strings.Contains(lastErr, "trust") || strings.Contains(lastErr, "hash") ||
+		strings.Contains(lastErr, "verif") {
+		advice = append(advice,
+			"snapshots are being rejected or failing verification: choose a newer trust height and make sure the trust hash matches it")
+	}
+	return advice
+}
+
+var logFieldPattern = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_.]*)=("(?:[^"\\]|\\.)*"|\S*)`)
+
+// parseLogLine extracts the message and key=value fields of a seid log line
+// in either JSON or plain text format
+func parseLogLine(line string) (string, map[string]string) {
+	line = strings.TrimSpace(stripANSI(line))
+	if line == "" {
+		return "", nil
+	}
+
+	if strings.HasPrefix(line, "{") {
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return "", nil
+		}
+		fields := make(map[string]string, len(raw))
+		var msg string
+		for k, v := range raw {
+			value := fmt.Sprint(v)
+			if f, ok := v.(float64); ok {
+				value = strconv.FormatFloat(f, 'f', -1, 64)
+			}
+			switch k {
+			case "_msg", "msg", "message":
+				msg = value
+			default:
+				fields[k] = value
+			}
+		}
+		return msg, fields
+	}
+
+	fields := make(map[string]string)
+	first := len(line)
+	for _, match := range logFieldPattern.FindAllStringSubmatchIndex(line, -1) {
+		if match[0] < first {
+			first = match[0]
+		}
+		value := line[match[4]:match[5]]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		fields[line[match[2]:match[3]]] = value
+	}
+
+	// Drop the level and timestamp prefix: "I[2024-01-01|00:00:00.000] msg"
+	// or "3:04PM INF msg"
+	msg := strings.TrimSpace(line[:first])
+	if i := strings.Index(msg, "] "); i >= 0 && i < 40 {
+		msg = msg[i+2:]
+	} else if parts := strings.SplitN(msg, " ", 3); len(parts) == 3 && isLevelToken(parts[1]) {
+		msg = parts[2]
+	}
+	return strings.TrimSpace(msg), fields
+}
+
+func isLevelToken(s string) bool {
+	switch s {
+	case "DBG", "INF", "WRN", "ERR", "FTL", "debug", "info", "warn", "error":
+		return true
+	}
+	return false
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+func fieldInt(fields map[string]string, keys ...string) int64 {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+func formatFields(fields map[string]string, keys ...string) string {
+	var b strings.Builder
+	for _, k := range keys {
+		if v, ok := fields[k]; ok && v != "" {
+			fmt.Fprintf(&b, " %s=%s", k, v)
+		}
+	}
+	return b.String()
+}
+
+// logTail reads lines appended to a file, coping with truncation and with
+// the file not existing yet
+type logTail struct {
+	path    string
+	offset  int64
+	partial []byte
+}
+
+func (t *logTail) skipExisting() {
+	if info, err := os.Stat(t.path); err == nil {
+		t.offset = info.Size()
+	}
+}
+
+func (t *logTail) readLines() []string {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil && info.Size() < t.offset {
+		// Truncated or rotated
+		t.offset, t.partial = 0, nil
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil
+	}
+	t.offset += int64(len(data))
+
+	data = append(t.partial, data...)
+	var lines []string
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		lines = append(lines, string(data[:i]))
+		data = data[i+1:]
+	}
+	if len(data) > maxLogLine {
+		data = nil
+	}
+	t.partial = append([]byte(nil), data...)
+	return lines
+}
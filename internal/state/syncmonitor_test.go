@@ -0,0 +1,217 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		msg    string
+		fields map[string]string
+	}{
+		{
+			line:   `I[2024-01-01|00:00:00.000] Discovered new snapshot                      module=statesync height=3000 format=2 hash=ABCD`,
+			msg:    "Discovered new snapshot",
+			fields: map[string]string{"module": "statesync", "height": "3000", "format": "2", "hash": "ABCD"},
+		},
+		{
+			line:   "3:04PM INF Applied snapshot chunk to ABCI app chunk=4 format=2 height=3000 module=statesync total=10",
+			msg:    "Applied snapshot chunk to ABCI app",
+			fields: map[string]string{"chunk": "4", "format": "2", "height": "3000", "module": "statesync", "total": "10"},
+		},
+		{
+			line:   `{"level":"info","module":"statesync","height":3000,"chunks":10,"_msg":"Snapshot accepted, restoring"}`,
+			msg:    "Snapshot accepted, restoring",
+			fields: map[string]string{"level": "info", "module": "statesync", "height": "3000", "chunks": "10"},
+		},
+		{
+			line:   "\x1b[90m3:04PM\x1b[0m \x1b[32mERR\x1b[0m State sync failed err=\"no suitable snapshots found\" module=statesync",
+			msg:    "State sync failed",
+			fields: map[string]string{"err": "no suitable snapshots found", "module": "statesync"},
+		},
+	}
+
+	for _, tt := range tests {
+		msg, fields := parseLogLine(tt.line)
+		assert.Equal(t, tt.msg, msg)
+		assert.Equal(t, tt.fields, fields)
+	}
+}
+
+func TestSyncTrackerPhases(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := newSyncTracker(time.Minute, start)
+
+	tr.feed("INF Discovered new snapshot format=2 hash=AA height=3000 module=statesync", start)
+	tr.feed("INF Discovered new snapshot format=2 hash=AA height=3000 module=statesync", start)
+	tr.feed("INF Discovered new snapshot format=2 hash=BB height=2000 module=statesync", start)
+	assert.Equal(t, PhaseDiscovering, tr.progress.Phase)
+	assert.Equal(t, 2, tr.progress.SnapshotsFound)
+
+	tr.feed("INF Snapshot accepted, restoring format=2 hash=AA height=3000 module=statesync chunks=10", start)
+	assert.Equal(t, PhaseRestoring, tr.progress.Phase)
+	assert.Equal(t, 10, tr.progress.ChunksTotal)
+
+	for i := 0; i < 4; i++ {
+		tr.feed(fmt.Sprintf("INF Applied snapshot chunk to ABCI app chunk=%d format=2 height=3000 module=statesync total=10", i),
+			start.Add(time.Duration(i+1)*10*time.Second))
+	}
+	assert.Equal(t, 4, tr.progress.ChunksApplied)
+	// 4 chunks in 40s leaves 6 chunks at 10s each
+	assert.Equal(t, time.Minute, tr.progress.ETA.Duration())
+	assert.Contains(t, tr.progress.Summary(), "restoring snapshot 3000: 4/10 chunks (40%)")
+
+	now := start.Add(time.Minute)
+	tr.feed("INF Snapshot restored height=3000 module=statesync", now)
+	assert.Equal(t, PhaseBlockSyncing, tr.progress.Phase)
+	assert.Equal(t, 10, tr.progress.ChunksApplied)
+
+	tr.status(&NodeStatus{Syncing: true, LatestHeight: 3000}, 4000, now)
+	tr.status(&NodeStatus{Syncing: true, LatestHeight: 3100}, 4000, now.Add(10*time.Second))
+	// 100 blocks per 10s, 900 blocks to go
+	assert.Equal(t, 90*time.Second, tr.progress.ETA.Duration())
+	tr.status(&NodeStatus{Syncing: true, LatestHeight: 3200}, 4100, now.Add(20*time.Second))
+	// The chain grew 100 blocks too, so the gap of 900 closes at 5 blocks/s
+	assert.Equal(t, 180*time.Second, tr.progress.ETA.Duration())
+	tr.status(&NodeStatus{Syncing: true, LatestHeight: 3300}, 4400, now.Add(30*time.Second))
+	// The chain grows faster than the node syncs
+	assert.Zero(t, tr.progress.ETA.Duration())
+
+	tr.status(&NodeStatus{Syncing: false, LatestHeight: 3998}, 4000, now.Add(time.Minute))
+	assert.Equal(t, PhaseCaughtUp, tr.progress.Phase)
+}
+
+func TestSyncTrackerStallAdvice(t *testing.T) {
+	start := time.Now()
+	tr := newSyncTracker(time.Minute, start)
+	tr.feed("INF Discovered new snapshot format=2 hash=AA height=3000 module=statesync", start)
+	tr.feed("INF Rejected snapshot height=3000 err=\"trusted header mismatch\" module=statesync", start)
+
+	tr.checkStall(start.Add(30 * time.Second))
+	assert.False(t, tr.progress.Stalled)
+
+	tr.checkStall(start.Add(2 * time.Minute))
+	assert.True(t, tr.progress.Stalled)
+	assert.Contains(t, tr.progress.LastError, "trusted header mismatch")
+	require.NotEmpty(t, tr.progress.Advice)
+	assert.Contains(t, tr.progress.Advice[len(tr.progress.Advice)-1], "newer trust height")
+
+	// No snapshots at all points at peers
+	tr = newSyncTracker(time.Minute, start)
+	tr.setPhase(PhaseDiscovering, start)
+	tr.checkStall(start.Add(2 * time.Minute))
+	assert.Contains(t, tr.progress.Advice[0], "add peers")
+
+	// Progress clears the stall
+	tr.feed("INF Discovered new snapshot format=2 hash=AA height=3000 module=statesync", start.Add(3*time.Minute))
+	assert.False(t, tr.progress.Stalled)
+	assert.Empty(t, tr.progress.Advice)
+}
+
+func TestLogTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seid.log")
+	tail := &logTail{path: path}
+	assert.Empty(t, tail.readLines())
+
+	require.NoError(t, os.WriteFile(path, []byte("old line\n"), 0644))
+	tail.skipExisting()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	f.WriteString("first\nsec")
+	assert.Equal(t, []string{"first"}, tail.readLines())
+	f.WriteString("ond\n")
+	assert.Equal(t, []string{"second"}, tail.readLines())
+
+	// Truncation starts over
+	require.NoError(t, os.WriteFile(path, []byte("new\n"), 0644))
+	assert.Equal(t, []string{"new"}, tail.readLines())
+}
+
+func TestMonitorStateSyncUsesLocalNode(t *testing.T) {
+	var height int64 = 0
+	var syncing int32 = 1
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"result":{"sync_info":{"catching_up":%t,"latest_block_height":"%d"}}}`,
+			atomic.LoadInt32(&syncing) == 1, atomic.LoadInt64(&height))
+	}))
+	t.Cleanup(local.Close)
+	// The remote endpoint is never catching up; it must not end the monitor
+	remote := fakeBlockRPC(t, 3010, canonicalHash)
+
+	manager := setupTrustManager(t, []string{remote.URL}, 0)
+	logPath := filepath.Join(t.TempDir(), "seid.log")
+	require.NoError(t, os.WriteFile(logPath, nil, 0644))
+
+	var updates []SyncProgress
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.MonitorStateSync(context.Background(), MonitorOptions{
+			RPC:      local.URL,
+			LogPath:  logPath,
+			Interval: 10 * time.Millisecond,
+			Progress: func(p SyncProgress) { updates = append(updates, p) },
+		})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("monitor ended early: %v", err)
+	default:
+	}
+
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	f.WriteString("INF Snapshot accepted, restoring height=3000 chunks=2 module=statesync\n")
+	f.WriteString("INF Applied snapshot chunk to ABCI app chunk=0 height=3000 total=2 module=statesync\n")
+	time.Sleep(50 * time.Millisecond)
+
+	atomic.StoreInt64(&height, 3008)
+	atomic.StoreInt32(&syncing, 0)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor did not finish")
+	}
+
+	var phases []SyncPhase
+	for _, u := range updates {
+		if len(phases) == 0 || phases[len(phases)-1] != u.Phase {
+			phases = append(phases, u.Phase)
+		}
+	}
+	assert.Equal(t, []SyncPhase{PhaseStarting, PhaseRestoring, PhaseCaughtUp}, phases)
+	last := updates[len(updates)-1]
+	assert.Equal(t, int64(3008), last.NodeHeight)
+	assert.Equal(t, int64(3010), last.TargetHeight)
+}
+
+func TestMonitorTargetFollowsEnv(t *testing.T) {
+	testnet := fakeBlockRPC(t, 3010, canonicalHash)
+	mainnet := fakeBlockRPC(t, 9000, canonicalHash)
+
+	manager := setupTrustManager(t, []string{testnet.URL}, 0)
+	manager.config.Environments["mainnet"] = types.ChainConfig{ChainID: "main-1", RPCEndpoints: []string{mainnet.URL}}
+
+	status, err := manager.getNodeStatus(context.Background(), manager.localEnv("mainnet"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(9000), status.LatestHeight)
+}
@@ -0,0 +1,216 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMounter records mounts without touching the system mount table
+type fakeMounter struct {
+	mounts map[string]int64
+}
+
+func (f *fakeMounter) Mount(ctx context.Context, target string, size int64) error {
+	f.mounts[target] = size
+	return nil
+}
+
+func (f *fakeMounter) Unmount(ctx context.Context, target string) error {
+	delete(f.mounts, target)
+	// Unmounting a tmpfs drops its contents
+	entries, _ := os.ReadDir(target)
+	for _, e := range entries {
+		os.RemoveAll(filepath.Join(target, e.Name()))
+	}
+	return nil
+}
+
+func (f *fakeMounter) Mounted(target string) (bool, error) {
+	_, ok := f.mounts[target]
+	return ok, nil
+}
+
+func setupTmpfsManager(t *testing.T, mem int64) (*Manager, *fakeMounter) {
+	manager, _ := setupTestManager(t)
+	mounter := &fakeMounter{mounts: map[string]int64{}}
+	manager.SetMounter(mounter)
+
+	orig := memAvailable
+	memAvailable = func() (int64, error) { return mem, nil }
+	t.Cleanup(func() { memAvailable = orig })
+
+	writeSizedFile(t, filepath.Join(manager.config.Global.HomeDir, "data", "application.db", "000001.ldb"), 4<<10)
+	return manager, mounter
+}
+
+func TestTmpfsEnableSyncDisable(t *testing.T) {
+	manager, mounter := setupTmpfsManager(t, 16<<30)
+	home := manager.config.Global.HomeDir
+	appDB := filepath.Join(home, "data", "application.db")
+	ctx := context.Background()
+
+	state, err := manager.EnableTmpfs(ctx, TmpfsOptions{Size: "1G"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data/application.db"}, state.Dirs)
+	assert.Equal(t, int64(1<<30), mounter.mounts[appDB])
+	assert.FileExists(t, filepath.Join(appDB, "000001.ldb"))
+	assert.FileExists(t, filepath.Join(home, tmpfsStateDir, "data", "application.db", "000001.ldb"))
+
+	_, err = manager.EnableTmpfs(ctx, TmpfsOptions{Size: "1G"})
+	assert.ErrorContains(t, err, "already enabled")
+
+	// New writes only reach disk after a sync
+	writeSizedFile(t, filepath.Join(appDB, "000002.ldb"), 1<<10)
+	persisted := filepath.Join(home, tmpfsStateDir, "data", "application.db", "000002.ldb")
+	assert.NoFileExists(t, persisted)
+	require.NoError(t, manager.SyncTmpfs(ctx))
+	assert.FileExists(t, persisted)
+	assert.NoDirExists(t, filepath.Join(appDB, tmpfsCheckpointDir))
+	assert.NoDirExists(t, filepath.Join(home, tmpfsStateDir, "data", "application.db", tmpfsCheckpointDir))
+
+	status, err := manager.TmpfsStatus()
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	require.Len(t, status.Dirs, 1)
+	assert.True(t, status.Dirs[0].Mounted)
+
+	writeSizedFile(t, filepath.Join(appDB, "000003.ldb"), 1<<10)
+	require.NoError(t, manager.DisableTmpfs(ctx))
+	assert.Empty(t, mounter.mounts)
+	assert.FileExists(t, filepath.Join(appDB, "000002.ldb"))
+	assert.FileExists(t, filepath.Join(appDB, "000003.ldb"))
+	assert.NoDirExists(t, filepath.Join(home, tmpfsStateDir))
+
+	status, err = manager.TmpfsStatus()
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+}
+
+// pausingMounter holds each Mounted call until the test releases it
+type pausingMounter struct {
+	*fakeMounter
+	calls   chan struct{}
+	release chan struct{}
+}
+
+func (p *pausingMounter) Mounted(target string) (bool, error) {
+	p.calls <- struct{}{}
+	<-p.release
+	return p.fakeMounter.Mounted(target)
+}
+
+func TestRunTmpfsSyncFinalSyncAfterCancel(t *testing.T) {
+	manager, mounter := setupTmpfsManager(t, 16<<30)
+	home := manager.config.Global.HomeDir
+	_, err := manager.EnableTmpfs(context.Background(), TmpfsOptions{Size: "1G"})
+	require.NoError(t, err)
+
+	pausing := &pausingMounter{fakeMounter: mounter, calls: make(chan struct{}), release: make(chan struct{})}
+	manager.SetMounter(pausing)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- manager.RunTmpfsSync(ctx, time.Millisecond) }()
+
+	// Cancel while a periodic sync is in progress
+	<-pausing.calls
+	cancel()
+	pausing.release <- struct{}{}
+
+	// Data written after that sync must still reach disk through the final one
+	<-pausing.calls
+	writeSizedFile(t, filepath.Join(home, "data", "application.db", "000009.ldb"), 1<<10)
+	pausing.release <- struct{}{}
+
+	require.NoError(t, <-done)
+	assert.FileExists(t, filepath.Join(home, tmpfsStateDir, "data", "application.db", "000009.ldb"))
+}
+
+func TestCheckpointTmpfs(t *testing.T) {
+	src := t.TempDir()
+	writeSizedFile(t, filepath.Join(src, "000001.ldb"), 1<<10)
+	writeSizedFile(t, filepath.Join(src, "000002.log"), 1<<10)
+	writeSizedFile(t, filepath.Join(src, "MANIFEST-000003"), 1<<10)
+	writeSizedFile(t, filepath.Join(src, "CURRENT"), 16)
+
+	checkpoint, err := checkpointTmpfs(src)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(src, tmpfsCheckpointDir), checkpoint)
+
+	// Tables are linked, so they share the inode with the live file
+	live, err := os.Stat(filepath.Join(src, "000001.ldb"))
+	require.NoError(t, err)
+	linked, err := os.Stat(filepath.Join(checkpoint, "000001.ldb"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(live, linked))
+
+	// Logs and manifests are appended to in place, so they are copies
+	for _, name := range []string{"000002.log", "MANIFEST-000003"} {
+		live, err := os.Stat(filepath.Join(src, name))
+		require.NoError(t, err)
+		copied, err := os.Stat(filepath.Join(checkpoint, name))
+		require.NoError(t, err)
+		assert.False(t, os.SameFile(live, copied), name)
+	}
+	assert.FileExists(t, filepath.Join(checkpoint, "CURRENT"))
+
+	// A second checkpoint replaces the first instead of nesting it
+	checkpoint, err = checkpointTmpfs(src)
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(checkpoint, tmpfsCheckpointDir))
+}
+
+func TestTmpfsRestoreAfterReboot(t *testing.T) {
+	manager, mounter := setupTmpfsManager(t, 16<<30)
+	appDB := filepath.Join(manager.config.Global.HomeDir, "data", "application.db")
+	ctx := context.Background()
+
+	_, err := manager.EnableTmpfs(ctx, TmpfsOptions{Size: "1G"})
+	require.NoError(t, err)
+
+	// A reboot loses the mount and its contents
+	require.NoError(t, mounter.Unmount(ctx, appDB))
+	assert.ErrorContains(t, manager.SyncTmpfs(ctx), "not mounted")
+
+	restored, err := manager.RestoreTmpfs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data/application.db"}, restored)
+	assert.FileExists(t, filepath.Join(appDB, "000001.ldb"))
+
+	restored, err = manager.RestoreTmpfs(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+}
+
+func TestTmpfsMemoryCheck(t *testing.T) {
+	manager, mounter := setupTmpfsManager(t, 2<<30)
+
+	_, err := manager.EnableTmpfs(context.Background(), TmpfsOptions{
+		Size: "1G",
+		Dirs: []string{"data/application.db", "wasm"},
+	})
+	assert.ErrorContains(t, err, "memory")
+	assert.Empty(t, mounter.mounts)
+	assert.FileExists(t, filepath.Join(manager.config.Global.HomeDir, "data", "application.db", "000001.ldb"))
+}
+
+func TestTmpfsRejectsUnsafeDirs(t *testing.T) {
+	manager, _ := setupTmpfsManager(t, 16<<30)
+
+	for _, dir := range []string{"data", "config", "../elsewhere", "/tmp"} {
+		_, err := manager.EnableTmpfs(context.Background(), TmpfsOptions{Size: "1G", Dirs: []string{dir}})
+		assert.Error(t, err, dir)
+	}
+}
+
+func TestTmpfsTooSmall(t *testing.T) {
+	manager, _ := setupTmpfsManager(t, 16<<30)
+
+	_, err := manager.EnableTmpfs(context.Background(), TmpfsOptions{Size: "4K"})
+	assert.ErrorContains(t, err, "too much")
+}
@@ -0,0 +1,407 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	restoreStagingDir  = ".seictl-staging"
+	restorePreviousDir = ".seictl-previous"
+	restoreJournalFile = ".seictl-restore-txn.json"
+)
+
+// restoreComponents are the directories of the node home a restore replaces
+var restoreComponents = []string{"data", "wasm"}
+
+// restoreJournal is written before the swap starts so a restore interrupted
+// between renames can still be rolled back
+type restoreJournal struct {
+	Source    string    `json:"source"`
+	StartedAt time.Time `json:"started_at"`
+	// Displaced lists live directories moved aside into the previous directory
+	Displaced []string `json:"displaced"`
+	// Swapped lists staged directories moved into the node home
+	Swapped []string `json:"swapped"`
+	// Paths maps each component to the real directory it was restored to,
+	// which a data move or db_dir may have put on another volume
+	Paths map[string]string `json:"paths,omitempty"`
+}
+
+// restoreTxn extracts a snapshot next to the node home and only touches the
+// live directories once the staged copy is complete. Each component is staged
+// next to its real directory so every swap is a rename on the same filesystem.
+// Archives are always extracted into staging; a component living elsewhere is
+// reached through a symlink there.
+type restoreTxn struct {
+	m           *Manager
+	home        string
+	realHome    string
+	staging     string
+	journalPath string
+	journal     restoreJournal
+}
+
+func (m *Manager) newRestoreTxn(source string) *restoreTxn {
+	home := m.config.Global.HomeDir
+	paths := make(map[string]string, len(restoreComponents))
+	for _, name := range restoreComponents {
+		paths[name] = m.componentDir(name)
+	}
+	return &restoreTxn{
+		m:           m,
+		home:        home,
+		realHome:    resolvePath(home),
+		staging:     filepath.Join(home, restoreStagingDir),
+		journalPath: filepath.Join(home, restoreJournalFile),
+		journal:     restoreJournal{Source: source, StartedAt: time.Now().UTC(), Paths: paths},
+	}
+}
+
+// livePath is the real directory of a component
+func (tx *restoreTxn) livePath(name string) string {
+	if path, ok := tx.journal.Paths[name]; ok {
+		return path
+	}
+	return filepath.Join(tx.realHome, name)
+}
+
+// stagedPath is where a component is staged, on the same volume as livePath
+func (tx *restoreTxn) stagedPath(name string) string {
+	return filepath.Join(filepath.Dir(tx.livePath(name)), restoreStagingDir, name)
+}
+
+// previousPath is where the live component is moved aside during the swap
+func (tx *restoreTxn) previousPath(name string) string {
+	return filepath.Join(filepath.Dir(tx.livePath(name)), restorePreviousDir, name)
+}
+
+// external reports whether a component lives outside the node home
+func (tx *restoreTxn) external(name string) bool {
+	return filepath.Dir(tx.livePath(name)) != tx.realHome
+}
+
+// roots returns the staging or previous directories of every volume
+func (tx *restoreTxn) roots(dirName string) []string {
+	roots := []string{filepath.Join(tx.home, dirName)}
+	for _, name := range restoreComponents {
+		if tx.external(name) {
+			root := filepath.Join(filepath.Dir(tx.livePath(name)), dirName)
+			if !containsString(roots, root) {
+				roots = append(roots, root)
+			}
+		}
+	}
+	return roots
+}
+
+// beginRestore prepares an empty staging directory. With keepStaging set a
+// staging directory left by an interrupted restore is reused.
+func (m *Manager) beginRestore(source string, keepStaging bool) (*restoreTxn, error) {
+	if err := m.recoverInterruptedRestore(); err != nil {
+		return nil, err
+	}
+
+	tx := m.newRestoreTxn(source)
+	if !keepStaging {
+		for _, root := range tx.roots(restoreStagingDir) {
+			if err := os.RemoveAll(root); err != nil {
+				return nil, fmt.Errorf("failed to clear staging directory: %w", err)
+			}
+		}
+	}
+	if err := os.MkdirAll(tx.staging, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	// Components on another volume are staged there and linked into staging
+	for _, name := range restoreComponents {
+		if !tx.external(name) {
+			continue
+		}
+		staged := tx.stagedPath(name)
+		if err := os.MkdirAll(staged, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		link := filepath.Join(tx.staging, name)
+		if target, err := os.Readlink(link); err == nil && target == staged {
+			continue
+		}
+		if err := os.RemoveAll(link); err != nil {
+			return nil, fmt.Errorf("failed to clear staging directory: %w", err)
+		}
+		if err := os.Symlink(staged, link); err != nil {
+			return nil, fmt.Errorf("failed to link staging directory: %w", err)
+		}
+	}
+
+	return tx, nil
+}
+
+// recoverInterruptedRestore rolls back a swap that never finished, which
+// only happens when seictl itself was killed mid-restore
+func (m *Manager) recoverInterruptedRestore() error {
+	tx := m.newRestoreTxn("")
+	data, err := os.ReadFile(tx.journalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read restore journal: %w", err)
+	}
+	if err := json.Unmarshal(data, &tx.journal); err != nil {
+		return fmt.Errorf("failed to parse restore journal: %w", err)
+	}
+
+	m.logger.Warn().
+		Str("source", tx.journal.Source).
+		Time("started_at", tx.journal.StartedAt).
+		Msg("Found interrupted restore, rolling back")
+
+	return tx.rollback()
+}
+
+// verify checks that the staged copy looks like a usable node home
+func (tx *restoreTxn) verify() error {
+	entries, err := os.ReadDir(filepath.Join(tx.staging, "data"))
+	if err != nil {
+		return fmt.Errorf("staged restore has no data directory: %w", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("staged data directory is empty")
+	}
+	return nil
+}
+
+// commit swaps the staged directories into the node home. A failed swap is
+// rolled back before returning.
+func (tx *restoreTxn) commit() error {
+	if err := tx.keepSignState(); err != nil {
+		return tx.abort(err)
+	}
+	for _, root := range tx.roots(restorePreviousDir) {
+		if err := os.RemoveAll(root); err != nil {
+			return tx.abort(fmt.Errorf("failed to clear previous directory: %w", err))
+		}
+		if err := os.MkdirAll(root, 0755); err != nil {
+			return tx.abort(fmt.Errorf("failed to create previous directory: %w", err))
+		}
+	}
+	if err := tx.saveJournal(); err != nil {
+		return tx.abort(err)
+	}
+
+	for _, name := range restoreComponents {
+		staged := tx.stagedPath(name)
+		if entries, err := os.ReadDir(staged); err != nil || len(entries) == 0 {
+			// Nothing restored for this component, e.g. a snapshot without wasm
+			continue
+		}
+
+		live := tx.livePath(name)
+		if _, err := os.Lstat(live); err == nil {
+			if err := os.Rename(live, tx.previousPath(name)); err != nil {
+				return tx.abort(fmt.Errorf("failed to move %s aside: %w", name, err))
+			}
+			tx.journal.Displaced = append(tx.journal.Displaced, name)
+			if err := tx.saveJournal(); err != nil {
+				return tx.abort(err)
+			}
+		}
+
+		if err := os.Rename(staged, live); err != nil {
+			return tx.abort(fmt.Errorf("failed to swap in %s: %w", name, err))
+		}
+		tx.journal.Swapped = append(tx.journal.Swapped, name)
+		if err := tx.saveJournal(); err != nil {
+			return tx.abort(err)
+		}
+	}
+
+	// The swap is complete; the old directories are covered by the backup
+	if err := os.Remove(tx.journalPath); err != nil {
+		return fmt.Errorf("failed to remove restore journal: %w", err)
+	}
+	tx.cleanup()
+
+	return nil
+}
+
+// keepSignState makes sure the swap never moves the validator signing state
+// backwards: when the live file, or one in a backup, is ahead of the staged
+// copy, it replaces the staged copy. Signing at a height the validator already
+// signed would be a double sign.
+func (tx *restoreTxn) keepSignState() error {
+	live := tx.m.privValStatePath()
+	name, rel := tx.componentOf(resolvePath(live))
+	if name == "" {
+		// A state file outside the swapped directories is left alone
+		return nil
+	}
+
+	best, source, err := tx.m.highestSignState(live)
+	if err != nil {
+		return err
+	}
+	if best == nil {
+		return nil
+	}
+
+	staged := filepath.Join(tx.stagedPath(name), rel)
+	if data, err := os.ReadFile(staged); err == nil {
+		if state, err := parseSignState(data); err == nil && !best.after(state) {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(staged), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(staged), err)
+	}
+	if err := os.WriteFile(staged, best.raw, 0600); err != nil {
+		return fmt.Errorf("failed to keep validator state: %w", err)
+	}
+	tx.m.logger.Info().
+		Int64("height", best.Height).
+		Str("source", source).
+		Msg("Kept the newer validator signing state over the restored one")
+	return nil
+}
+
+// componentOf returns the component a real path lies in and the path
+// relative to it, or an empty name when a restore does not replace it
+func (tx *restoreTxn) componentOf(path string) (string, string) {
+	for _, name := range restoreComponents {
+		if rel, ok := relWithin(tx.livePath(name), path); ok {
+			return name, rel
+		}
+	}
+	return "", ""
+}
+
+// abort rolls the transaction back and returns cause, annotated with any
+// rollback failure
+func (tx *restoreTxn) abort(cause error) error {
+	if err := tx.rollback(); err != nil {
+		return fmt.Errorf("%v; rollback failed: %w", cause, err)
+	}
+	tx.m.logger.Warn().Err(cause).Msg("Restore failed, node data rolled back")
+	return cause
+}
+
+// rollback undoes completed renames in reverse order and discards staging
+func (tx *restoreTxn) rollback() error {
+	var errs []error
+
+	for i := len(tx.journal.Swapped) - 1; i >= 0; i-- {
+		name := tx.journal.Swapped[i]
+		if err := os.RemoveAll(tx.livePath(name)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove restored %s: %w", name, err))
+		}
+	}
+	for i := len(tx.journal.Displaced) - 1; i >= 0; i-- {
+		name := tx.journal.Displaced[i]
+		if err := os.Rename(tx.previousPath(name), tx.livePath(name)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to put back %s: %w", name, err))
+		}
+	}
+
+	// Keep the journal when something could not be put back so the next run retries
+	if len(errs) > 0 {
+		for _, err := range errs[1:] {
+			tx.m.logger.Error().Err(err).Msg("Rollback step failed")
+		}
+		return errs[0]
+	}
+
+	if err := os.Remove(tx.journalPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove restore journal: %w", err)
+	}
+	tx.cleanup()
+
+	return nil
+}
+
+func (tx *restoreTxn) cleanup() {
+	dirs := append(tx.roots(restoreStagingDir), tx.roots(restorePreviousDir)...)
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			tx.m.logger.Warn().Err(err).Str("path", dir).Msg("Failed to remove restore directory")
+		}
+	}
+}
+
+func (tx *restoreTxn) saveJournal() error {
+	data, err := json.MarshalIndent(tx.journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal restore journal: %w", err)
+	}
+
+	tmp := tx.journalPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write restore journal: %w", err)
+	}
+	return os.Rename(tmp, tx.journalPath)
+}
+
+// RollbackRestore puts the node back to the state saved before the last
+// restore. An interrupted restore is undone from its journal; otherwise the
+// newest backup_<timestamp> directory is swapped back in.
+func (m *Manager) RollbackRestore(ctx context.Context) error {
+	if err := m.stopNode(ctx); err != nil {
+		return fmt.Errorf("failed to stop node: %w", err)
+	}
+
+	tx := m.newRestoreTxn("")
+	if _, err := os.Stat(tx.journalPath); err == nil {
+		return m.recoverInterruptedRestore()
+	}
+
+	backups, err := m.listBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return fmt.Errorf("no backup found to roll back to")
+	}
+	latest := backups[0]
+	for _, b := range backups[1:] {
+		if b.CreatedAt.After(latest.CreatedAt) {
+			latest = b
+		}
+	}
+	if _, err := os.Stat(filepath.Join(latest.Path, "data")); err != nil {
+		return fmt.Errorf("backup %s has no data directory", filepath.Base(latest.Path))
+	}
+
+	m.logger.Info().Str("backup", latest.Path).Msg("Rolling back to backup")
+
+	tx, err = m.beginRestore(latest.Path, false)
+	if err != nil {
+		return err
+	}
+	for _, name := range restoreComponents {
+		src := filepath.Join(latest.Path, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return tx.abort(err)
+		}
+		if err := copyDir(src, filepath.Join(tx.staging, name)); err != nil {
+			return tx.abort(fmt.Errorf("failed to stage %s from backup: %w", name, err))
+		}
+	}
+	if err := tx.verify(); err != nil {
+		return tx.abort(err)
+	}
+	if err := tx.commit(); err != nil {
+		return err
+	}
+
+	m.logger.Info().Str("backup", latest.Path).Msg("Rollback complete")
+	return nil
+}
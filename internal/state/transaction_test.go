@@ -0,0 +1,180 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTarSnapshot(t *testing.T, m *Manager, height int64, files map[string][]byte) string {
+	src := t.TempDir()
+	writeNodeData(t, src, files)
+
+	dir := filepath.Join(m.config.Global.BackupDir, snapshotDirName(height))
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, copyFile(filepath.Join(src, "data", "priv_validator_state.json"), filepath.Join(dir, "priv_validator_state.json")))
+	archive := fmt.Sprintf("data_%d.tar.gz", height)
+	out, err := exec.Command("tar", "-czf", filepath.Join(dir, archive), "-C", filepath.Join(src, "data"), ".").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	_, err = m.writeManifest(dir, height, []string{archive, "priv_validator_state.json"})
+	require.NoError(t, err)
+
+	return dir
+}
+
+func assertFileContent(t *testing.T, path, content string) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func assertNoRestoreLeftovers(t *testing.T, home string) {
+	for _, name := range []string{restoreStagingDir, restorePreviousDir, restoreJournalFile} {
+		_, err := os.Stat(filepath.Join(home, name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+}
+
+func TestRestoreSnapshotSwapAndRollback(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	ctx := context.Background()
+	home := manager.config.Global.HomeDir
+
+	writeNodeData(t, home, map[string][]byte{"data/state.db": []byte("old")})
+	snapshot := writeTarSnapshot(t, manager, 1, map[string][]byte{"data/state.db": []byte("new")})
+
+	require.NoError(t, manager.RestoreSnapshot(ctx, snapshot))
+	assertFileContent(t, filepath.Join(home, "data", "state.db"), "new")
+	assertNoRestoreLeftovers(t, home)
+
+	require.NoError(t, manager.RollbackRestore(ctx))
+	assertFileContent(t, filepath.Join(home, "data", "state.db"), "old")
+	assertNoRestoreLeftovers(t, home)
+}
+
+func TestRestoreSnapshotFailureKeepsData(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+
+	writeNodeData(t, home, map[string][]byte{"data/state.db": []byte("old")})
+	snapshot := writeTarSnapshot(t, manager, 1, map[string][]byte{"data/state.db": []byte("new")})
+	require.NoError(t, os.WriteFile(filepath.Join(snapshot, "data_1.tar.gz"), []byte("not a tarball"), 0644))
+
+	err := manager.RestoreSnapshot(context.Background(), snapshot)
+	require.Error(t, err)
+	assertFileContent(t, filepath.Join(home, "data", "state.db"), "old")
+	assertNoRestoreLeftovers(t, home)
+}
+
+func TestRestoreFromURLChecksumMismatchKeepsData(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+	writeNodeData(t, home, map[string][]byte{"data/state.db": []byte("old")})
+
+	srv := serveArchive(t, buildTestArchive(t, "gz"), 0, 0)
+	err := manager.RestoreFromURL(context.Background(), srv.URL+"/snapshot.tar.gz", URLRestoreOptions{
+		Checksum: sha256Hex([]byte("something else")),
+	})
+	assert.ErrorContains(t, err, "checksum mismatch")
+	assertFileContent(t, filepath.Join(home, "data", "state.db"), "old")
+	assertNoRestoreLeftovers(t, home)
+}
+
+func TestRecoverInterruptedRestore(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+
+	// Simulate a crash after data was moved aside but before staging was swapped in
+	writeNodeData(t, filepath.Join(home, restorePreviousDir), map[string][]byte{"data/state.db": []byte("old")})
+	writeNodeData(t, filepath.Join(home, restoreStagingDir), map[string][]byte{"data/state.db": []byte("new")})
+	journal, err := json.Marshal(restoreJournal{Displaced: []string{"data"}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(home, restoreJournalFile), journal, 0644))
+
+	require.NoError(t, manager.RollbackRestore(context.Background()))
+	assertFileContent(t, filepath.Join(home, "data", "state.db"), "old")
+	assertNoRestoreLeftovers(t, home)
+}
+
+func TestBackupCurrentStateLinks(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	home := manager.config.Global.HomeDir
+	writeNodeData(t, home, map[string][]byte{
+		"data/application.db/000001.ldb": []byte("table"),
+		"data/application.db/000002.log": []byte("wal"),
+	})
+
+	require.NoError(t, manager.backupCurrentState())
+	backups, err := manager.listBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+
+	sameFile := func(name string) bool {
+		live, err := os.Stat(filepath.Join(home, "data", "application.db", name))
+		require.NoError(t, err)
+		saved, err := os.Stat(filepath.Join(backups[0].Path, "data", "application.db", name))
+		require.NoError(t, err)
+		return os.SameFile(live, saved)
+	}
+	assert.True(t, sameFile("000001.ldb"), "immutable tables are linked")
+	assert.False(t, sameFile("000002.log"), "logs written in place are copied")
+}
+
+func TestRestoreSnapshotKeepsNewerSignState(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	ctx := context.Background()
+	home := manager.config.Global.HomeDir
+	statePath := filepath.Join(home, "data", "priv_validator_state.json")
+
+	writeNodeData(t, home, map[string][]byte{
+		"data/state.db":                  []byte("old"),
+		"data/priv_validator_state.json": []byte(`{"height":"500","round":0,"step":3}`),
+	})
+	older := writeTarSnapshot(t, manager, 100, map[string][]byte{
+		"data/state.db":                  []byte("new"),
+		"data/priv_validator_state.json": []byte(`{"height":"100","round":0,"step":3}`),
+	})
+	require.NoError(t, manager.RestoreSnapshot(ctx, older))
+	assertFileContent(t, filepath.Join(home, "data", "state.db"), "new")
+	assertFileContent(t, statePath, `{"height":"500","round":0,"step":3}`)
+
+	newer := writeTarSnapshot(t, manager, 900, map[string][]byte{
+		"data/state.db":                  []byte("newer"),
+		"data/priv_validator_state.json": []byte(`{"height":"900","round":0,"step":3}`),
+	})
+	require.NoError(t, manager.RestoreSnapshot(ctx, newer))
+	assertFileContent(t, statePath, `{"height":"900","round":0,"step":3}`)
+}
+
+func TestRestoreSnapshotOnMovedVolume(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	ctx := context.Background()
+	home := manager.config.Global.HomeDir
+
+	writeNodeData(t, home, map[string][]byte{"data/state.db": []byte("old")})
+	volume := moveDataToVolume(t, home)
+	snapshot := writeTarSnapshot(t, manager, 1, map[string][]byte{"data/state.db": []byte("new")})
+
+	require.NoError(t, manager.RestoreSnapshot(ctx, snapshot))
+	target, err := os.Readlink(filepath.Join(home, "data"))
+	require.NoError(t, err)
+	assert.Equal(t, volume, target)
+	assertFileContent(t, filepath.Join(volume, "state.db"), "new")
+	assertNoRestoreLeftovers(t, home)
+	assertNoRestoreLeftovers(t, filepath.Dir(volume))
+
+	require.NoError(t, manager.RollbackRestore(ctx))
+	target, err = os.Readlink(filepath.Join(home, "data"))
+	require.NoError(t, err)
+	assert.Equal(t, volume, target)
+	assertFileContent(t, filepath.Join(volume, "state.db"), "old")
+	assertNoRestoreLeftovers(t, filepath.Dir(volume))
+}
@@ -0,0 +1,272 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/your-org/seictl/pkg/types"
+)
+
+const defaultTrustHeightDelta = 2000
+
+// ErrNoTrustQuorum is returned when too few RPC endpoints agree on the trust block
+var ErrNoTrustQuorum = errors.New("RPC endpoints did not reach quorum on the trust block")
+
+// StateSyncOptions configures SyncState
+type StateSyncOptions struct {
+	// Env selects the environment whose RPC endpoints are used. When empty
+	// the environment matching the local chain ID is used.
+	Env types.Environment
+	// TrustHeight pins the trust height; zero computes it from the latest height
+	TrustHeight int64
+	// RPC overrides the environment's RPC endpoints
+	RPC []string
+	// Quorum is the number of endpoints that must agree on the trust hash.
+	// Zero uses the environment setting, or a majority of the endpoints.
+	Quorum int
+	// VerifyLight chains the trust block back to the stored light client
+	// checkpoint. The environment's light_verify setting enables it too.
+	VerifyLight bool
+	// ManualTrust prompts for the trust height and hash when the endpoints
+	// do not reach quorum. The entered block is not checked against them.
+	ManualTrust bool
+}
+
+// TrustResult is the outcome of resolving a trust block across RPC endpoints
+type TrustResult struct {
+	Block
+	Quorum    int               `json:"quorum"`
+	Agreeing  []string          `json:"agreeing"`
+	Disagreed map[string]string `json:"disagreed,omitempty"`
+	Failed    map[string]string `json:"failed,omitempty"`
+}
+
+// stateSyncEnv returns the environment state sync runs against
+func (m *Manager) stateSyncEnv(env types.Environment) (types.ChainConfig, error) {
+	if env != "" {
+		cfg, ok := m.config.Environments[string(env)]
+		if !ok {
+			return types.ChainConfig{}, fmt.Errorf("unknown environment %q", env)
+		}
+		return cfg, nil
+	}
+
+	if chainID := m.localChainID(); chainID != "" {
+		for _, cfg := range m.config.Environments {
+			if cfg.ChainID == chainID {
+				return cfg, nil
+			}
+		}
+	}
+
+	var candidates []types.ChainConfig
+	for _, cfg := range m.config.Environments {
+		if len(cfg.RPCEndpoints) > 0 {
+			candidates = append(candidates, cfg)
+		}
+	}
+	if len(candidates) != 1 {
+		return types.ChainConfig{}, fmt.Errorf("cannot pick an environment for state sync, pass one explicitly")
+	}
+	return candidates[0], nil
+}
+
+// ResolveTrustBlock picks the trust height and fetches its hash from every
+// endpoint, failing with ErrNoTrustQuorum unless enough of them agree
+func (m *Manager) ResolveTrustBlock(ctx context.Context, opts StateSyncOptions) (*TrustResult, error) {
+	env, err := m.stateSyncEnv(opts.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := opts.RPC
+	if len(endpoints) == 0 {
+		endpoints = env.RPCEndpoints
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no RPC endpoints configured")
+	}
+
+	quorum := opts.Quorum
+	if quorum == 0 && env.StateSync != nil {
+		quorum = env.StateSync.TrustQuorum
+	}
+	if quorum == 0 {
+		quorum = len(endpoints)/2 + 1
+	}
+	if quorum > len(endpoints) {
+		return nil, fmt.Errorf("quorum of %d cannot be met by %d endpoints", quorum, len(endpoints))
+	}
+	// Anything short of a majority lets a minority of endpoints pick the hash
+	if quorum <= len(endpoints)/2 {
+		return nil, fmt.Errorf("quorum of %d is not a majority of %d endpoints", quorum, len(endpoints))
+	}
+
+	// Outcomes feed the pool's health scores
+	pool, err := m.poolFor(opts.Env, opts.RPC)
+	if err != nil {
+		return nil, err
+	}
+
+	height := opts.TrustHeight
+	if height == 0 {
+		height, err = m.computeTrustHeight(ctx, pool, endpoints, env.StateSync)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &TrustResult{
+		Block:     Block{Height: height},
+		Quorum:    quorum,
+		Disagreed: make(map[string]string),
+		Failed:    make(map[string]string),
+	}
+
+	hashes := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, endpoint := range endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			var block *Block
+			err := pool.Call(ctx, endpoint, func(endpoint string) (err error) {
+				block, err = m.queryBlockFromRPC(ctx, endpoint, height)
+				return err
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Failed[endpoint] = err.Error()
+				return
+			}
+			hashes[endpoint] = strings.ToUpper(block.Hash)
+		}(endpoint)
+	}
+	wg.Wait()
+
+	votes := make(map[string][]string)
+	for endpoint, hash := range hashes {
+		votes[hash] = append(votes[hash], endpoint)
+	}
+	reached := 0
+	for hash, voters := range votes {
+		if len(voters) >= quorum {
+			reached++
+		}
+		if len(voters) > len(result.Agreeing) || (len(voters) == len(result.Agreeing) && hash < result.Hash) {
+			result.Hash = hash
+			result.Agreeing = voters
+		}
+	}
+	sort.Strings(result.Agreeing)
+
+	for endpoint, hash := range hashes {
+		if hash != result.Hash {
+			result.Disagreed[endpoint] = hash
+			m.logger.Error().
+				Str("endpoint", endpoint).
+				Int64("height", height).
+				Str("hash", hash).
+				Str("majority_hash", result.Hash).
+				Msg("RPC endpoint DISAGREES on the trust block hash; it may be malicious or on a fork")
+		}
+	}
+	for endpoint, reason := range result.Failed {
+		m.logger.Warn().Str("endpoint", endpoint).Str("error", reason).Msg("RPC endpoint did not return the trust block")
+	}
+
+	if reached > 1 {
+		return result, fmt.Errorf("%w: %d different hashes reached quorum at height %d",
+			ErrNoTrustQuorum, reached, height)
+	}
+	if len(result.Agreeing) < quorum {
+		return result, fmt.Errorf("%w: %d of %d endpoints agree at height %d, %d required",
+			ErrNoTrustQuorum, len(result.Agreeing), len(endpoints), height, quorum)
+	}
+
+	m.logger.Info().
+		Int64("height", height).
+		Str("hash", result.Hash).
+		Int("agreeing", len(result.Agreeing)).
+		Int("quorum", quorum).
+		Msg("Trust block agreed")
+
+	return result, nil
+}
+
+// computeTrustHeight subtracts the configured delta from the median latest
+// height reported by the endpoints and rounds down to the snapshot interval
+func (m *Manager) computeTrustHeight(ctx context.Context, pool *RPCPool, endpoints []string, cfg *types.StateSyncConfig) (int64, error) {
+	var heights []int64
+	for _, endpoint := range endpoints {
+		var status *NodeStatus
+		err := pool.Call(ctx, endpoint, func(endpoint string) (err error) {
+			status, err = m.queryStatus(ctx, endpoint)
+			return err
+		})
+		if err != nil {
+			m.logger.Warn().Str("endpoint", endpoint).Err(err).Msg("Failed to query RPC status")
+			continue
+		}
+		heights = append(heights, status.LatestHeight)
+	}
+	if len(heights) == 0 {
+		return 0, fmt.Errorf("no RPC endpoint reported its latest height")
+	}
+
+	// The median keeps a single endpoint from dragging the height around
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	latest := heights[len(heights)/2]
+
+	var delta, interval int64 = defaultTrustHeightDelta, 0
+	if cfg != nil {
+		if cfg.TrustHeightDelta > 0 {
+			delta = cfg.TrustHeightDelta
+		}
+		interval = cfg.SnapshotInterval
+	}
+
+	height := trustHeightFor(latest, delta, interval)
+	if height <= 0 {
+		return 0, fmt.Errorf("chain height %d is too low for a trust height delta of %d", latest, delta)
+	}
+
+	m.logger.Info().
+		Int64("latest", latest).
+		Int64("delta", delta).
+		Int64("snapshot_interval", interval).
+		Int64("trust_height", height).
+		Msg("Computed trust height")
+
+	return height, nil
+}
+
+func trustHeightFor(latest, delta, interval int64) int64 {
+	height := latest - delta
+	if interval > 0 {
+		height -= height % interval
+	}
+	return height
+}
+
+// verifyTrustLight runs light client verification of the trust block when
+// it is enabled for the state sync run
+func (m *Manager) verifyTrustLight(ctx context.Context, opts StateSyncOptions, trust *TrustResult) error {
+	env, err := m.stateSyncEnv(opts.Env)
+	if err != nil {
+		return err
+	}
+	if !opts.VerifyLight && (env.StateSync == nil || !env.StateSync.LightVerify) {
+		return nil
+	}
+	if _, err := m.VerifyTrustBlock(ctx, env.ChainID, trust.Agreeing, trust.Block); err != nil {
+		return fmt.Errorf("light client verification failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/seictl/pkg/types"
+)
+
+// fakeBlockRPC serves /status at latest and /block with hashFor(height)
+func fakeBlockRPC(t *testing.T, latest int64, hashFor func(int64) string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			fmt.Fprintf(w, `{"result":{"sync_info":{"catching_up":false,"latest_block_height":"%d"}}}`, latest)
+		case "/block":
+			height, _ := strconv.ParseInt(r.URL.Query().Get("height"), 10, 64)
+			fmt.Fprintf(w, `{"result":{"block_id":{"hash":"%s"},"block":{"header":{"height":"%d"}}}}`, hashFor(height), height)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func canonicalHash(height int64) string {
+	return fmt.Sprintf("%064X", height)
+}
+
+func setupTrustManager(t *testing.T, endpoints []string, quorum int) *Manager {
+	manager, _ := setupTestManager(t)
+	manager.config.Environments = map[string]types.ChainConfig{
+		"testnet": {
+			ChainID:      "test-1",
+			RPCEndpoints: endpoints,
+			StateSync: &types.StateSyncConfig{
+				TrustHeightDelta: 2000,
+				SnapshotInterval: 1000,
+				TrustQuorum:      quorum,
+			},
+		},
+	}
+	return manager
+}
+
+func TestTrustHeightFor(t *testing.T) {
+	assert.Equal(t, int64(8000), trustHeightFor(10500, 2000, 1000))
+	assert.Equal(t, int64(8500), trustHeightFor(10500, 2000, 0))
+	assert.Equal(t, int64(0), trustHeightFor(2500, 2000, 1000))
+}
+
+func TestResolveTrustBlockQuorum(t *testing.T) {
+	a := fakeBlockRPC(t, 10500, canonicalHash)
+	b := fakeBlockRPC(t, 10400, canonicalHash)
+	forked := fakeBlockRPC(t, 99999999, func(int64) string { return "DEADBEEF" })
+
+	manager := setupTrustManager(t, []string{a.URL, b.URL, forked.URL}, 0)
+	trust, err := manager.ResolveTrustBlock(context.Background(), StateSyncOptions{})
+	require.NoError(t, err)
+
+	// The median height ignores the forked endpoint's inflated height
+	assert.Equal(t, int64(8000), trust.Height)
+	assert.Equal(t, canonicalHash(8000), trust.Hash)
+	assert.Equal(t, 2, trust.Quorum)
+	assert.ElementsMatch(t, []string{a.URL, b.URL}, trust.Agreeing)
+	assert.Equal(t, map[string]string{forked.URL: "DEADBEEF"}, trust.Disagreed)
+}
+
+func TestResolveTrustBlockNoQuorum(t *testing.T) {
+	a := fakeBlockRPC(t, 10500, canonicalHash)
+	forked := fakeBlockRPC(t, 10500, func(int64) string { return "DEADBEEF" })
+
+	manager := setupTrustManager(t, []string{a.URL, forked.URL}, 2)
+	trust, err := manager.ResolveTrustBlock(context.Background(), StateSyncOptions{TrustHeight: 5000})
+	assert.ErrorIs(t, err, ErrNoTrustQuorum)
+	require.NotNil(t, trust)
+	assert.Len(t, trust.Disagreed, 1)
+
+	// A fork is never silently replaced by interactive input
+	_, err = manager.fetchTrustBlock(context.Background(), StateSyncOptions{TrustHeight: 5000})
+	assert.ErrorIs(t, err, ErrNoTrustQuorum)
+}
+
+func TestResolveTrustBlockUnreachable(t *testing.T) {
+	a := fakeBlockRPC(t, 10500, canonicalHash)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	b := fakeBlockRPC(t, 10500, canonicalHash)
+	manager := setupTrustManager(t, []string{a.URL, b.URL, down.URL}, 2)
+	trust, err := manager.ResolveTrustBlock(context.Background(), StateSyncOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.URL, b.URL}, trust.Agreeing)
+	assert.Contains(t, trust.Failed, down.URL)
+}
+
+func TestResolveTrustBlockNeedsMajority(t *testing.T) {
+	a := fakeBlockRPC(t, 10500, canonicalHash)
+	forked := fakeBlockRPC(t, 10500, func(int64) string { return "DEADBEEF" })
+
+	// With a quorum of one, either side of the fork could win
+	manager := setupTrustManager(t, []string{a.URL, forked.URL}, 1)
+	_, err := manager.ResolveTrustBlock(context.Background(), StateSyncOptions{TrustHeight: 5000})
+	assert.ErrorContains(t, err, "not a majority")
+}
+
+func TestResolveTrustBlockRateLimited(t *testing.T) {
+	a := fakeBlockRPC(t, 10500, canonicalHash)
+	manager := setupTrustManager(t, []string{a.URL}, 0)
+	env := manager.config.Environments["testnet"]
+	env.RPCPool = &types.RPCPoolConfig{RateLimit: 10}
+	manager.config.Environments["testnet"] = env
+
+	// The status and block queries are spaced by the endpoint's rate limit
+	start := time.Now()
+	_, err := manager.ResolveTrustBlock(context.Background(), StateSyncOptions{})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestFetchTrustBlockNeedsQuorum(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	// Without --manual-trust a failed quorum is an error, not a prompt
+	manager := setupTrustManager(t, []string{down.URL}, 1)
+	_, err := manager.fetchTrustBlock(context.Background(), StateSyncOptions{TrustHeight: 5000})
+	assert.ErrorIs(t, err, ErrNoTrustQuorum)
+	assert.ErrorContains(t, err, "--manual-trust")
+}
+
+func TestSetupStateSync(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	configPath := filepath.Join(manager.config.Global.HomeDir, "config", "config.toml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`moniker = "node"
+
+[statesync]
+enable = false
+rpc_servers = ""
+trust_height = 0
+
+[p2p]
+laddr = "tcp://0.0.0.0:26656"
+`), 0644))
+
+	require.NoError(t, manager.setupStateSync(context.Background(), []string{"http://a:26657"}, 8000, "ABCD"))
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, `moniker = "node"
+
+[statesync]
+trust-hash = "ABCD"
+enable = true
+rpc_servers = "http://a:26657,http://a:26657"
+trust_height = 8000
+
+[p2p]
+laddr = "tcp://0.0.0.0:26656"
+`, string(data))
+}
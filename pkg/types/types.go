@@ -19,6 +19,8 @@ type Config struct {
 	Global       GlobalConfig           `yaml:"global"`
 	Environments map[string]ChainConfig `yaml:"environments"`
 	NodeConfigs  NodeConfigs            `yaml:"node_configs"`
+	Snapshots    SnapshotConfig         `yaml:"snapshots,omitempty"`
+	Tmpfs        TmpfsConfig            `yaml:"tmpfs,omitempty"`
 }
 
 // GlobalConfig contains global settings
@@ -54,12 +56,17 @@ type ChainConfig struct {
 	BinaryURL         string   `yaml:"binary_url,omitempty"`
 	BinaryChecksumURL string   `yaml:"binary_checksum_url,omitempty"`
 	// Local development options
-	BinaryPath      string           `yaml:"binary_path,omitempty"`
-	BuildCommand    string           `yaml:"build_command,omitempty"`
-	StateSync       *StateSyncConfig `yaml:"state_sync,omitempty"`
-	Ports           *NodePorts       `yaml:"ports,omitempty"`
-	GenesisAccounts []Account        `yaml:"genesis_accounts,omitempty"`
-	GenesisParams   GenesisParams    `yaml:"genesis_params,omitempty"`
+	BinaryPath   string           `yaml:"binary_path,omitempty"`
+	BuildCommand string           `yaml:"build_command,omitempty"`
+	StateSync    *StateSyncConfig `yaml:"state_sync,omitempty"`
+	RPCPool      *RPCPoolConfig   `yaml:"rpc_pool,omitempty"`
+	Ports        *NodePorts       `yaml:"ports,omitempty"`
+	// EVMChainID is the chain ID reported by eth_chainId, e.g. 1329
+	EVMChainID      uint64        `yaml:"evm_chain_id,omitempty"`
+	GenesisAccounts []Account     `yaml:"genesis_accounts,omitempty"`
+	GenesisParams   GenesisParams `yaml:"genesis_params,omitempty"`
+	// Upgrades lists the chain's software upgrades, oldest first
+	Upgrades []ChainUpgrade `yaml:"upgrades,omitempty"`
 }
 
 // StateSyncConfig contains state sync specific configuration
@@ -67,6 +74,69 @@ type StateSyncConfig struct {
 	TrustHeightDelta int64 `yaml:"trust_height_delta"`
 	BlockTimeSeconds int   `yaml:"block_time_seconds"`
 	SnapshotInterval int64 `yaml:"snapshot_interval"`
+	// TrustQuorum is how many RPC endpoints must agree on the trust hash;
+	// zero means a majority, and anything less than a majority is refused
+	TrustQuorum int `yaml:"trust_quorum,omitempty"`
+	// LightVerify verifies the trust block against a stored checkpoint
+	LightVerify bool `yaml:"light_verify,omitempty"`
+	// TrustPeriod is how long a light client checkpoint stays usable
+	TrustPeriod string `yaml:"trust_period,omitempty"`
+}
+
+// GetTrustPeriod returns the light client trusting period, defaulting to one week
+func (s StateSyncConfig) GetTrustPeriod() time.Duration {
+	d, err := time.ParseDuration(s.TrustPeriod)
+	if err != nil || d <= 0 {
+		return 168 * time.Hour
+	}
+	return d
+}
+
+// ChainUpgrade is a software upgrade the chain went through. Blocks from
+// Height on require Version or later.
+type ChainUpgrade struct {
+	Name    string `yaml:"name"`
+	Height  int64  `yaml:"height"`
+	Version string `yaml:"version"`
+}
+
+// RPCPoolConfig tunes health checking and rate limiting of an environment's
+// RPC endpoints
+type RPCPoolConfig struct {
+	// HealthIntervalSeconds is how often endpoints are re-scored
+	HealthIntervalSeconds int `yaml:"health_interval_seconds,omitempty"`
+	// MaxHeightLag is how many blocks an endpoint may trail the best one
+	// before it is penalised
+	MaxHeightLag int64 `yaml:"max_height_lag,omitempty"`
+	// RateLimit is the default number of requests per second sent to each
+	// endpoint; zero is unlimited
+	RateLimit float64 `yaml:"rate_limit,omitempty"`
+	// RateLimits overrides RateLimit for individual endpoints
+	RateLimits map[string]float64 `yaml:"rate_limits,omitempty"`
+}
+
+// GetHealthInterval returns how often endpoints are re-scored
+func (r RPCPoolConfig) GetHealthInterval() time.Duration {
+	if r.HealthIntervalSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(r.HealthIntervalSeconds) * time.Second
+}
+
+// GetMaxHeightLag returns the tolerated height lag, defaulting to 10 blocks
+func (r RPCPoolConfig) GetMaxHeightLag() int64 {
+	if r.MaxHeightLag <= 0 {
+		return 10
+	}
+	return r.MaxHeightLag
+}
+
+// GetRateLimit returns the requests per second allowed for endpoint
+func (r RPCPoolConfig) GetRateLimit(endpoint string) float64 {
+	if limit, ok := r.RateLimits[endpoint]; ok {
+		return limit
+	}
+	return r.RateLimit
 }
 
 // NodePorts contains port configuration
@@ -77,6 +147,10 @@ type NodePorts struct {
 	GRPC    int `yaml:"grpc"`
 	GRPCWeb int `yaml:"grpc_web"`
 	PProf   int `yaml:"pprof"`
+	// EVMRPC and EVMWS serve Ethereum JSON-RPC over HTTP and websocket on
+	// Sei v2 nodes
+	EVMRPC int `yaml:"evm_rpc,omitempty"`
+	EVMWS  int `yaml:"evm_ws,omitempty"`
 }
 
 // Account represents a genesis account
@@ -100,4 +174,204 @@ type GenesisParams struct {
 type NodeConfigs struct {
 	AppToml    map[string]interface{} `yaml:"app_toml"`
 	ConfigToml map[string]interface{} `yaml:"config_toml"`
+	// SeiDB configures the [state-commit] and [state-store] sections of
+	// app.toml used by Sei v5 nodes
+	SeiDB *SeiDBConfig `yaml:"seidb,omitempty"`
+	// EVM configures the [evm] section of app.toml served by Sei v2 nodes
+	EVM *EVMConfig `yaml:"evm,omitempty"`
+	// Roles overrides keys of the built-in role presets, by role name
+	Roles map[string]RoleConfig `yaml:"roles,omitempty"`
+}
+
+// EVMConfig is the [evm] section of app.toml. The listening ports come from
+// the environment's evm_rpc and evm_ws ports.
+type EVMConfig struct {
+	HTTPEnabled bool `yaml:"http_enabled"`
+	WSEnabled   bool `yaml:"ws_enabled"`
+	// CORSOrigins and WSOrigins are comma separated, "*" allows any origin
+	CORSOrigins string `yaml:"cors_origins,omitempty"`
+	WSOrigins   string `yaml:"ws_origins,omitempty"`
+	// SimulationGasLimit caps eth_call and eth_estimateGas
+	SimulationGasLimit uint64 `yaml:"simulation_gas_limit,omitempty"`
+	// MaxBlocksForLog caps the block range of eth_getLogs
+	MaxBlocksForLog         int64  `yaml:"max_blocks_for_log,omitempty"`
+	MaxSubscriptionsNewHead uint64 `yaml:"max_subscriptions_new_head,omitempty"`
+}
+
+// RoleConfig holds keys layered over a role preset
+type RoleConfig struct {
+	AppToml    map[string]interface{} `yaml:"app_toml,omitempty"`
+	ConfigToml map[string]interface{} `yaml:"config_toml,omitempty"`
+}
+
+// SeiDBConfig configures SeiDB, which replaces the IAVL application store with
+// a state commitment store (memiavl) and a historical state store
+type SeiDBConfig struct {
+	StateCommit StateCommitConfig `yaml:"state_commit" json:"state_commit"`
+	StateStore  StateStoreConfig  `yaml:"state_store" json:"state_store"`
+}
+
+// StateCommitConfig is the [state-commit] section of app.toml
+type StateCommitConfig struct {
+	Enable bool `yaml:"enable" json:"enable"`
+	// AsyncCommitBuffer is how many blocks may be committed asynchronously;
+	// zero commits synchronously
+	AsyncCommitBuffer int `yaml:"async_commit_buffer" json:"async_commit_buffer"`
+	// KeepRecent is how many old memiavl snapshots are kept
+	KeepRecent int `yaml:"keep_recent" json:"keep_recent"`
+	// SnapshotInterval is how many blocks apart memiavl snapshots are taken
+	SnapshotInterval int64 `yaml:"snapshot_interval" json:"snapshot_interval"`
+}
+
+// StateStoreConfig is the [state-store] section of app.toml
+type StateStoreConfig struct {
+	Enable bool `yaml:"enable" json:"enable"`
+	// Backend is pebbledb, rocksdb or sqlite
+	Backend string `yaml:"backend" json:"backend"`
+	// KeepRecent is how many versions of historical state are kept; zero
+	// keeps everything, as on an archive node
+	KeepRecent int64 `yaml:"keep_recent" json:"keep_recent"`
+	// PruneIntervalSeconds is how often old versions are pruned
+	PruneIntervalSeconds int `yaml:"prune_interval_seconds" json:"prune_interval_seconds"`
+	AsyncWriteBuffer     int `yaml:"async_write_buffer" json:"async_write_buffer"`
+}
+
+// SnapshotConfig contains snapshot and backup management settings
+type SnapshotConfig struct {
+	Retention       RetentionConfig `yaml:"retention,omitempty"`
+	BackupRetention RetentionConfig `yaml:"backup_retention,omitempty"`
+	Schedule        ScheduleConfig  `yaml:"schedule,omitempty"`
+	Storage         StorageConfig   `yaml:"storage,omitempty"`
+	Serve           ServeConfig     `yaml:"serve,omitempty"`
+	Publish         PublishConfig   `yaml:"publish,omitempty"`
+	// Incremental stores snapshots as deduplicated chunks instead of tarballs
+	Incremental bool `yaml:"incremental,omitempty"`
+}
+
+// ServeConfig configures `seictl snapshot serve` and clients restoring from it
+type ServeConfig struct {
+	// Listen is the address the snapshot server binds to
+	Listen string `yaml:"listen,omitempty"`
+	// Token, when set, is required as a bearer token by the server and sent by clients
+	Token string `yaml:"token,omitempty"`
+}
+
+// GetListen returns the listen address, defaulting to :8090
+func (s ServeConfig) GetListen() string {
+	if s.Listen == "" {
+		return ":8090"
+	}
+	return s.Listen
+}
+
+// PublishConfig configures the static snapshot index and feed
+type PublishConfig struct {
+	// BaseURL is the public URL the backup directory layout is reachable at
+	BaseURL string `yaml:"base_url,omitempty"`
+	// Title names the feed
+	Title string `yaml:"title,omitempty"`
+}
+
+// StorageConfig selects where snapshots and backups are pushed to and pulled from
+type StorageConfig struct {
+	// Type is "local" or "s3"
+	Type string `yaml:"type,omitempty"`
+	// Path is the root directory for the local backend
+	Path string `yaml:"path,omitempty"`
+
+	// S3-compatible backend settings. Credentials fall back to the
+	// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.
+	Endpoint        string `yaml:"endpoint,omitempty"`
+	Region          string `yaml:"region,omitempty"`
+	Bucket          string `yaml:"bucket,omitempty"`
+	Prefix          string `yaml:"prefix,omitempty"`
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+	PathStyle       bool   `yaml:"path_style,omitempty"`
+	PartSize        string `yaml:"part_size,omitempty"`
+}
+
+// ScheduleConfig controls the scheduled snapshot daemon
+type ScheduleConfig struct {
+	// Cron is a standard five-field cron expression. When empty, snapshots are
+	// taken every state_sync.snapshot_interval blocks instead.
+	Cron                string `yaml:"cron,omitempty"`
+	Mode                string `yaml:"mode,omitempty"`
+	PollIntervalSeconds int    `yaml:"poll_interval_seconds,omitempty"`
+	StopCommand         string `yaml:"stop_command,omitempty"`
+	StartCommand        string `yaml:"start_command,omitempty"`
+}
+
+// GetPollInterval returns how often the daemon checks the node height
+func (s ScheduleConfig) GetPollInterval() time.Duration {
+	if s.PollIntervalSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(s.PollIntervalSeconds) * time.Second
+}
+
+// RetentionConfig defines which snapshots or backups are kept when pruning.
+// A zero value keeps everything.
+type RetentionConfig struct {
+	KeepLast        int    `yaml:"keep_last,omitempty"`
+	KeepEveryBlocks int64  `yaml:"keep_every_blocks,omitempty"`
+	KeepDaily       int    `yaml:"keep_daily,omitempty"`
+	KeepWeekly      int    `yaml:"keep_weekly,omitempty"`
+	MaxTotalSize    string `yaml:"max_total_size,omitempty"`
+	MinFreeDisk     string `yaml:"min_free_disk,omitempty"`
+}
+
+// HasKeepRules reports whether any rule selecting items to keep is set
+func (r RetentionConfig) HasKeepRules() bool {
+	return r.KeepLast > 0 || r.KeepEveryBlocks > 0 || r.KeepDaily > 0 || r.KeepWeekly > 0
+}
+
+// TmpfsConfig configures `seictl optimize`, which keeps chosen node
+// directories in RAM and syncs them back to disk
+type TmpfsConfig struct {
+	// Size limits each tmpfs mount, e.g. "12G"
+	Size string `yaml:"size,omitempty"`
+	// Dirs are relative to the node home
+	Dirs                []string `yaml:"dirs,omitempty"`
+	SyncIntervalSeconds int      `yaml:"sync_interval_seconds,omitempty"`
+}
+
+// GetDirs returns the directories to accelerate, defaulting to application.db
+func (t TmpfsConfig) GetDirs() []string {
+	if len(t.Dirs) == 0 {
+		return []string{"data/application.db"}
+	}
+	return t.Dirs
+}
+
+// GetSyncInterval returns how often tmpfs contents are synced to disk,
+// defaulting to 15 minutes
+func (t TmpfsConfig) GetSyncInterval() time.Duration {
+	if t.SyncIntervalSeconds <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(t.SyncIntervalSeconds) * time.Second
+}
+
+// Topology describes a validator and the sentries shielding it, rendered
+// into per-host p2p configuration by `seictl topology render`
+type Topology struct {
+	Validator TopologyNode   `yaml:"validator"`
+	Sentries  []TopologyNode `yaml:"sentries"`
+	// Seeds and PersistentPeers are public "id@host:port" peers the sentries
+	// connect to
+	Seeds           []string `yaml:"seeds,omitempty"`
+	PersistentPeers []string `yaml:"persistent_peers,omitempty"`
+}
+
+// TopologyNode is one host of a topology
+type TopologyNode struct {
+	Name string `yaml:"name"`
+	// NodeKey is the node_key.json path, relative to the topology file;
+	// defaults to keys/<name>/node_key.json
+	NodeKey string `yaml:"node_key,omitempty"`
+	// Address is the host:port the other nodes of the topology dial
+	Address string `yaml:"address"`
+	// ExternalAddress is the public host:port a sentry advertises
+	ExternalAddress string `yaml:"external_address,omitempty"`
 }